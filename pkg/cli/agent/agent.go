@@ -0,0 +1,334 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package agent provides a background process that keeps the keys of the
+// encrypted databases in memory, so that a passphrase is asked once in a
+// session rather than for every command
+package agent
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+// CommandName is the name of the subcommand of the encryption command that
+// runs the agent
+const CommandName = "agent"
+
+const (
+	actionGet    = "get"
+	actionAdd    = "add"
+	actionRemove = "remove"
+	actionLock   = "lock"
+)
+
+// dialTimeout is the maximum duration to connect to the agent
+var dialTimeout = time.Second
+
+// startTimeout is the maximum duration for which a started agent is waited for
+var startTimeout = 2 * time.Second
+
+// idleTimeout is the duration after which an agent without any key exits
+var idleTimeout = 10 * time.Second
+
+// expireInterval is the interval at which the expired keys are removed
+var expireInterval = time.Second
+
+type request struct {
+	Action string        `json:"action"`
+	DBPath string        `json:"db_path,omitempty"`
+	Key    []byte        `json:"key,omitempty"`
+	TTL    time.Duration `json:"ttl,omitempty"`
+}
+
+type response struct {
+	Key   []byte `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// GetSocketPath returns the path to the socket on which the agent listens
+func GetSocketPath(paths context.Paths) string {
+	return filepath.Join(paths.Cache, consts.DnoteDirName, consts.AgentDirName, consts.AgentSocketName)
+}
+
+type entry struct {
+	key    []byte
+	expiry time.Time
+}
+
+type server struct {
+	mu   sync.Mutex
+	keys map[string]entry
+	// idleSince is the time since which no key has been kept
+	idleSince time.Time
+	done      chan struct{}
+	once      sync.Once
+	// idleTimeout and expireInterval are read once when the agent starts
+	idleTimeout    time.Duration
+	expireInterval time.Duration
+}
+
+func (s *server) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *server) handle(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Action {
+	case actionGet:
+		e, ok := s.keys[req.DBPath]
+		if !ok || time.Now().After(e.expiry) {
+			return response{}
+		}
+
+		return response{Key: e.key}
+	case actionAdd:
+		if len(req.Key) == 0 || req.TTL <= 0 {
+			return response{Error: "a key and a duration are required"}
+		}
+
+		s.keys[req.DBPath] = entry{key: req.Key, expiry: time.Now().Add(req.TTL)}
+		return response{}
+	case actionRemove:
+		delete(s.keys, req.DBPath)
+		if len(s.keys) == 0 {
+			s.idleSince = time.Now()
+		}
+
+		return response{}
+	case actionLock:
+		s.keys = map[string]entry{}
+		s.stop()
+
+		return response{}
+	default:
+		return response{Error: "unknown action " + req.Action}
+	}
+}
+
+// expire removes the expired keys, and stops the agent once it has been
+// without a key for the idle timeout
+func (s *server) expire() {
+	ticker := time.NewTicker(s.expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for path, e := range s.keys {
+				if now.After(e.expiry) {
+					delete(s.keys, path)
+					if len(s.keys) == 0 {
+						s.idleSince = now
+					}
+				}
+			}
+			idle := len(s.keys) == 0 && now.Sub(s.idleSince) >= s.idleTimeout
+			s.mu.Unlock()
+
+			if idle {
+				s.stop()
+			}
+		}
+	}
+}
+
+func (s *server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	json.NewEncoder(conn).Encode(s.handle(req))
+}
+
+// Serve runs an agent on the socket at the given path until it is locked or
+// has been without a key for a while
+func Serve(socketPath string) error {
+	if conn, err := net.DialTimeout("unix", socketPath, dialTimeout); err == nil {
+		conn.Close()
+		return errors.New("an agent is already running")
+	}
+
+	// The socket is created in a private directory so that the other users
+	// cannot connect to it before its permissions are set
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "creating the socket directory")
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return errors.Wrap(err, "setting the permissions of the socket directory")
+	}
+	// A socket left by an agent that did not exit cleanly
+	os.Remove(socketPath)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return errors.Wrap(err, "listening")
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		l.Close()
+		return errors.Wrap(err, "setting the permissions of the socket")
+	}
+
+	s := &server{
+		keys:      map[string]entry{},
+		idleSince: time.Now(),
+		done:      make(chan struct{}),
+
+		idleTimeout:    idleTimeout,
+		expireInterval: expireInterval,
+	}
+	go s.expire()
+	go func() {
+		<-s.done
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				s.stop()
+				return errors.Wrap(err, "accepting a connection")
+			}
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// call sends the request to the agent. It returns false if the agent is not
+// running.
+func call(socketPath string, req request) (response, bool, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return response{}, false, nil
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, true, errors.Wrap(err, "sending the request")
+	}
+
+	var res response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return response{}, true, errors.Wrap(err, "reading the response")
+	}
+	if res.Error != "" {
+		return res, true, errors.New(res.Error)
+	}
+
+	return res, true, nil
+}
+
+// start runs an agent in the background with the current executable
+func start(socketPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "finding the executable")
+	}
+
+	cmd := exec.Command(exe, "encryption", CommandName, socketPath)
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "starting the agent")
+	}
+	if err := cmd.Process.Release(); err != nil {
+		return errors.Wrap(err, "releasing the agent")
+	}
+
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("unix", socketPath, dialTimeout); err == nil {
+			conn.Close()
+			return nil
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return errors.New("timed out waiting for the agent to start")
+}
+
+// GetKey returns the key kept for the database at the given path, or nil if
+// the agent is not running or does not have it
+func GetKey(socketPath, dbPath string) ([]byte, error) {
+	res, _, err := call(socketPath, request{Action: actionGet, DBPath: dbPath})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Key, nil
+}
+
+// AddKey keeps the key of the database at the given path for the duration,
+// starting the agent if it is not running
+func AddKey(socketPath, dbPath string, key []byte, ttl time.Duration) error {
+	req := request{Action: actionAdd, DBPath: dbPath, Key: key, TTL: ttl}
+
+	_, ok, err := call(socketPath, req)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	if err := start(socketPath); err != nil {
+		return err
+	}
+	if _, _, err := call(socketPath, req); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveKey forgets the key of the database at the given path
+func RemoveKey(socketPath, dbPath string) error {
+	_, _, err := call(socketPath, request{Action: actionRemove, DBPath: dbPath})
+
+	return err
+}
+
+// Lock forgets all the keys and stops the agent. It returns false if the agent
+// was not running.
+func Lock(socketPath string) (bool, error) {
+	_, ok, err := call(socketPath, request{Action: actionLock})
+
+	return ok, err
+}
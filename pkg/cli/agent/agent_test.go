@@ -0,0 +1,159 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+var socketPath = "../tmp/agent/agent.sock"
+
+// runTestAgent runs an agent and returns a channel that receives the error of
+// Serve when it returns
+func runTestAgent(t *testing.T) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(socketPath)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the agent")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitStop(t *testing.T, done chan error) {
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "serving"))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the agent should stop")
+	}
+}
+
+func TestAgent(t *testing.T) {
+	// set up
+	if err := os.MkdirAll("../tmp", 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the tmp directory"))
+	}
+	done := runTestAgent(t)
+
+	fi, err := os.Stat(filepath.Dir(socketPath))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the socket directory"))
+	}
+	assert.Equal(t, fi.Mode().Perm(), os.FileMode(0700), "only the user should access the socket directory")
+
+	// execute
+	if err := AddKey(socketPath, "/db1", []byte("key1"), time.Hour); err != nil {
+		t.Fatal(errors.Wrap(err, "adding key1"))
+	}
+	if err := AddKey(socketPath, "/db2", []byte("key2"), time.Hour); err != nil {
+		t.Fatal(errors.Wrap(err, "adding key2"))
+	}
+
+	// test
+	key, err := GetKey(socketPath, "/db1")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting key1"))
+	}
+	assert.Equal(t, string(key), "key1", "key1 mismatch")
+
+	key, err = GetKey(socketPath, "/db3")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting an unknown key"))
+	}
+	assert.Equal(t, len(key), 0, "an unknown key should be empty")
+
+	if err := RemoveKey(socketPath, "/db1"); err != nil {
+		t.Fatal(errors.Wrap(err, "removing key1"))
+	}
+	key, err = GetKey(socketPath, "/db1")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting a removed key"))
+	}
+	assert.Equal(t, len(key), 0, "a removed key should be empty")
+
+	ok, err := Lock(socketPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "locking"))
+	}
+	assert.Equal(t, ok, true, "the agent should be running")
+	waitStop(t, done)
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Fatal("the socket should be removed")
+	}
+
+	ok, err = Lock(socketPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "locking a stopped agent"))
+	}
+	assert.Equal(t, ok, false, "the agent should not be running")
+
+	key, err = GetKey(socketPath, "/db2")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting a key from a stopped agent"))
+	}
+	assert.Equal(t, len(key), 0, "a stopped agent should have no key")
+}
+
+func TestAgent_expiry(t *testing.T) {
+	// set up
+	interval, timeout := expireInterval, idleTimeout
+	expireInterval, idleTimeout = 10*time.Millisecond, 100*time.Millisecond
+	defer func() { expireInterval, idleTimeout = interval, timeout }()
+
+	if err := os.MkdirAll("../tmp", 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the tmp directory"))
+	}
+	done := runTestAgent(t)
+
+	// execute
+	if err := AddKey(socketPath, "/db1", []byte("key1"), 50*time.Millisecond); err != nil {
+		t.Fatal(errors.Wrap(err, "adding key1"))
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// test
+	key, err := GetKey(socketPath, "/db1")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting an expired key"))
+	}
+	assert.Equal(t, len(key), 0, "an expired key should be empty")
+
+	waitStop(t, done)
+}
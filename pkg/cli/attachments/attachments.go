@@ -0,0 +1,125 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package attachments provides a content-addressed storage for the blobs of
+// the attachments in the dnote data directory
+package attachments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+var hashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidateHash validates that the given string is a sha256 hash in hex
+func ValidateHash(hash string) error {
+	if !hashRegex.MatchString(hash) {
+		return errors.Errorf("invalid blob hash '%s'", hash)
+	}
+
+	return nil
+}
+
+// Dir returns the path to the directory containing the blobs
+func Dir(ctx context.DnoteCtx) string {
+	return filepath.Join(ctx.Paths.Data, consts.DnoteDirName, consts.AttachmentsDirName)
+}
+
+// Path returns the path to the blob with the given hash. Blobs are spread
+// over subdirectories named after the first two characters of their hashes.
+func Path(ctx context.DnoteCtx, hash string) string {
+	return filepath.Join(Dir(ctx), hash[:2], hash)
+}
+
+// Exists checks if the blob with the given hash is in the storage
+func Exists(ctx context.DnoteCtx, hash string) (bool, error) {
+	if err := ValidateHash(hash); err != nil {
+		return false, err
+	}
+
+	_, err := os.Stat(Path(ctx, hash))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "checking the blob")
+	}
+
+	return true, nil
+}
+
+// Open opens the blob with the given hash for reading
+func Open(ctx context.DnoteCtx, hash string) (*os.File, error) {
+	if err := ValidateHash(hash); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(Path(ctx, hash))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("blob %s is missing", hash)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "opening the blob")
+	}
+
+	return f, nil
+}
+
+// Put writes the content of the given reader to the storage and returns its
+// hash and size. Storing the same content more than once is a no-op.
+func Put(ctx context.DnoteCtx, r io.Reader) (string, int64, error) {
+	dir := Dir(ctx)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, errors.Wrap(err, "creating the attachments directory")
+	}
+
+	tmp, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return "", 0, errors.Wrap(err, "creating a temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(tmp, io.TeeReader(r, h))
+	if err != nil {
+		tmp.Close()
+		return "", 0, errors.Wrap(err, "writing the blob")
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, errors.Wrap(err, "closing the temporary file")
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	path := Path(ctx, hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", 0, errors.Wrap(err, "creating the blob directory")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, errors.Wrap(err, "moving the blob")
+	}
+
+	return hash, size, nil
+}
@@ -0,0 +1,97 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package attachments
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+func TestPut(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	// sha256 of "foo"
+	expectedHash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	ok, err := Exists(ctx, expectedHash)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "checking existence before put"))
+	}
+	assert.Equal(t, ok, false, "blob should not exist before put")
+
+	for i := 0; i < 2; i++ {
+		hash, size, err := Put(ctx, strings.NewReader("foo"))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "putting the blob"))
+		}
+
+		assert.Equal(t, hash, expectedHash, "hash mismatch")
+		assert.Equal(t, size, int64(3), "size mismatch")
+	}
+
+	ok, err = Exists(ctx, expectedHash)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "checking existence after put"))
+	}
+	assert.Equal(t, ok, true, "blob should exist after put")
+
+	f, err := Open(ctx, expectedHash)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the blob"))
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the blob"))
+	}
+	assert.Equal(t, string(b), "foo", "content mismatch")
+
+	files, err := ioutil.ReadDir(Dir(ctx))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the attachments directory"))
+	}
+	assert.Equal(t, len(files), 1, "temporary files should be cleaned up")
+}
+
+func TestValidateHash(t *testing.T) {
+	testCases := []struct {
+		hash     string
+		expected bool
+	}{
+		{"2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", true},
+		{"2C26B46B68FFC68FF99B453C1D30413413422D706483BFA0F98A5E886266E7AE", false},
+		{"2c26b46b", false},
+		{"../../../../../../../../../../../../../../../../../../etc/passwd", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hash, func(t *testing.T) {
+			err := ValidateHash(tc.hash)
+			assert.Equal(t, err == nil, tc.expected, "result mismatch")
+		})
+	}
+}
@@ -0,0 +1,265 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package backup takes and restores snapshots of the local database
+package backup
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+const (
+	filePrefix = "dnote-"
+	fileExt    = ".db"
+	// timeLayout is the layout of the time in the name of a backup, in UTC.
+	// The names of the backups sort in the order in which they were taken.
+	timeLayout = "20060102T150405.000000"
+)
+
+// GetDir returns the path to the directory containing the backups of the
+// database in use. It is next to the database so that each profile has its
+// own backups.
+func GetDir(ctx context.DnoteCtx) string {
+	return filepath.Join(filepath.Dir(ctx.DB.Filepath), consts.BackupsDirName)
+}
+
+func isBackupName(name string) bool {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileExt) {
+		return false
+	}
+
+	t := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileExt)
+	_, err := time.Parse(timeLayout, t)
+
+	return err == nil
+}
+
+// List returns the names of the backups in the backup directory, the oldest first
+func List(ctx context.DnoteCtx) ([]string, error) {
+	entries, err := os.ReadDir(GetDir(ctx))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading the backup directory")
+	}
+
+	ret := []string{}
+	for _, e := range entries {
+		if e.Type().IsRegular() && isBackupName(e.Name()) {
+			ret = append(ret, e.Name())
+		}
+	}
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// Create takes a snapshot of the database in use and returns the path to it
+func Create(ctx context.DnoteCtx) (string, error) {
+	dir := GetDir(ctx)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrap(err, "creating the backup directory")
+	}
+
+	name := filePrefix + ctx.Clock.Now().UTC().Format(timeLayout) + fileExt
+	path := filepath.Join(dir, name)
+
+	if _, err := os.Stat(path); err == nil {
+		return "", errors.Errorf("backup %s already exists", path)
+	}
+
+	if err := ctx.DB.BackupTo(path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Rotate removes the oldest backups, keeping the given number of the most
+// recent ones, and returns the paths to the removed backups. It keeps all
+// backups if the limit is 0.
+func Rotate(ctx context.DnoteCtx, limit int) ([]string, error) {
+	removed := []string{}
+	if limit <= 0 {
+		return removed, nil
+	}
+
+	names, err := List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) <= limit {
+		return removed, nil
+	}
+
+	for _, name := range names[:len(names)-limit] {
+		path := filepath.Join(GetDir(ctx), name)
+		if err := os.Remove(path); err != nil {
+			return removed, errors.Wrapf(err, "removing %s", path)
+		}
+
+		removed = append(removed, path)
+	}
+
+	return removed, nil
+}
+
+// Resolve returns the path to the backup with the given path or name. A name
+// refers to a backup in the backup directory.
+func Resolve(ctx context.DnoteCtx, pathOrName string) (string, error) {
+	if _, err := os.Stat(pathOrName); err == nil {
+		return pathOrName, nil
+	} else if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "checking %s", pathOrName)
+	}
+
+	if filepath.Base(pathOrName) == pathOrName {
+		path := filepath.Join(GetDir(ctx), pathOrName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", errors.Errorf("backup '%s' not found", pathOrName)
+}
+
+// GetSchema checks the integrity of the existing database at the given path
+// and returns its local schema version. An encrypted backup is decrypted with
+// the key of the database in use.
+func GetSchema(ctx context.DnoteCtx, path string) (int, error) {
+	db, err := ctx.DB.OpenCopy(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "opening the backup")
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return 0, errors.Wrapf(err, "checking the integrity of %s", path)
+	}
+	if check != "ok" {
+		return 0, errors.Errorf("%s is corrupt: %s", path, check)
+	}
+
+	var schema int
+	err = db.QueryRow("SELECT value FROM system WHERE key = ?", consts.SystemSchema).Scan(&schema)
+	if err == sql.ErrNoRows || (err != nil && strings.Contains(err.Error(), "no such table")) {
+		return 0, errors.Errorf("%s is not a dnote database", path)
+	} else if err != nil {
+		return 0, errors.Wrap(err, "reading the schema version")
+	}
+
+	return schema, nil
+}
+
+// Restore replaces the content of the database in use with that of the backup
+// at the given path, and returns the path to a backup of the replaced content.
+// It fails if the schema version of the backup is newer than latestSchema, the
+// latest one supported.
+func Restore(ctx context.DnoteCtx, path string, latestSchema int) (string, error) {
+	schema, err := GetSchema(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if schema > latestSchema {
+		return "", errors.Errorf("the backup has the schema version %d, but this version of dnote supports up to %d. Upgrade dnote to restore it", schema, latestSchema)
+	}
+
+	current, err := Create(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "backing up the current database")
+	}
+
+	if err := ctx.DB.RestoreFrom(path); err != nil {
+		return current, errors.Wrap(err, "restoring the database")
+	}
+
+	return current, nil
+}
+
+// EncryptAll encrypts the plaintext backups with the key of the encrypted
+// database in use, and returns the number of backups it encrypted
+func EncryptAll(ctx context.DnoteCtx) (int, error) {
+	names, err := List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		path := filepath.Join(GetDir(ctx), name)
+
+		_, encrypted, err := database.ReadEncryptionHeader(path)
+		if err != nil {
+			return count, err
+		}
+		if encrypted {
+			continue
+		}
+
+		if err := ctx.DB.EncryptFile(path); err != nil {
+			return count, errors.Wrapf(err, "encrypting %s", path)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// DecryptAll writes the backups encrypted with the key of the database in use
+// back in plaintext, and returns the number of backups it decrypted. The
+// backups encrypted with another passphrase are left as they are.
+func DecryptAll(ctx context.DnoteCtx) (int, error) {
+	names, err := List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		path := filepath.Join(GetDir(ctx), name)
+
+		_, encrypted, err := database.ReadEncryptionHeader(path)
+		if err != nil {
+			return count, err
+		}
+		if !encrypted {
+			continue
+		}
+
+		if err := ctx.DB.DecryptFile(path); err == database.ErrWrongKey {
+			continue
+		} else if err != nil {
+			return count, errors.Wrapf(err, "decrypting %s", path)
+		}
+		count++
+	}
+
+	return count, nil
+}
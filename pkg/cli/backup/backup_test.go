@@ -0,0 +1,254 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/pkg/errors"
+)
+
+var paths = context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}
+
+func mustCreate(t *testing.T, ctx context.DnoteCtx, now time.Time) string {
+	ctx.Clock.(*clock.Mock).SetNow(now)
+
+	path, err := Create(ctx)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a backup"))
+	}
+
+	return path
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	database.MustExec(t, "inserting b1", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+	now := time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC)
+	path := mustCreate(t, ctx, now)
+
+	assert.Equal(t, path, filepath.Join(paths.Data, consts.DnoteDirName, consts.BackupsDirName, "dnote-20240311T093000.000000.db"), "path mismatch")
+
+	schema, err := GetSchema(ctx, path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the schema"))
+	}
+	assert.Equal(t, schema, 12, "schema mismatch")
+
+	if _, err := Create(ctx); err == nil {
+		t.Fatal("creating a backup with the same name should fail")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	start := time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC)
+	var created []string
+	for i := 0; i < 4; i++ {
+		created = append(created, mustCreate(t, ctx, start.Add(time.Duration(i)*time.Hour)))
+	}
+
+	// a file that is not a backup should be left alone
+	other := filepath.Join(GetDir(ctx), "notes.db")
+	if err := os.WriteFile(other, []byte("foo"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing a file"))
+	}
+
+	removed, err := Rotate(ctx, 0)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "rotating without a limit"))
+	}
+	assert.Equal(t, len(removed), 0, "no backup should be removed without a limit")
+
+	removed, err = Rotate(ctx, 2)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "rotating"))
+	}
+	assert.DeepEqual(t, removed, created[:2], "removed mismatch")
+
+	names, err := List(ctx)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "listing"))
+	}
+	assert.DeepEqual(t, names, []string{filepath.Base(created[2]), filepath.Base(created[3])}, "names mismatch")
+
+	if _, err := os.Stat(other); err != nil {
+		t.Fatal(errors.Wrap(err, "the other file should remain"))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	path := mustCreate(t, ctx, time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC))
+
+	got, err := Resolve(ctx, path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "resolving a path"))
+	}
+	assert.Equal(t, got, path, "path mismatch")
+
+	got, err = Resolve(ctx, filepath.Base(path))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "resolving a name"))
+	}
+	assert.Equal(t, got, path, "path from the name mismatch")
+
+	if _, err := Resolve(ctx, "dnote-20000101T000000.000000.db"); err == nil {
+		t.Fatal("resolving a missing backup should fail")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, paths, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		database.MustExec(t, "inserting b1", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+		path := mustCreate(t, ctx, time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC))
+		database.MustExec(t, "inserting b2", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+
+		ctx.Clock.(*clock.Mock).SetNow(time.Date(2024, time.March, 12, 9, 30, 0, 0, time.UTC))
+		current, err := Restore(ctx, path, 12)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "restoring"))
+		}
+
+		var count int
+		database.MustScan(t, "counting books", ctx.DB.QueryRow("SELECT count(*) FROM books"), &count)
+		assert.Equal(t, count, 1, "book count mismatch")
+
+		// the replaced content should be backed up
+		db, err := database.Open(current)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "opening the backup of the replaced database"))
+		}
+		defer db.Close()
+		database.MustScan(t, "counting books in the replaced database", db.QueryRow("SELECT count(*) FROM books"), &count)
+		assert.Equal(t, count, 2, "replaced book count mismatch")
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, paths, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		path := mustCreate(t, ctx, time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC))
+		database.MustExec(t, "inserting b1", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+		if _, err := Restore(ctx, path, 11); err == nil {
+			t.Fatal("restoring a backup with a newer schema should fail")
+		}
+
+		var count int
+		database.MustScan(t, "counting books", ctx.DB.QueryRow("SELECT count(*) FROM books"), &count)
+		assert.Equal(t, count, 1, "the database should not change")
+	})
+
+	t.Run("not a dnote database", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, paths, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		path := "../tmp/other.db"
+		db, err := database.Open(path)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "opening a database"))
+		}
+		database.MustExec(t, "creating a table", db, "CREATE TABLE foo (bar text)")
+		db.Close()
+
+		if _, err := Restore(ctx, path, 12); err == nil {
+			t.Fatal("restoring a database that is not a dnote database should fail")
+		}
+	})
+}
+
+func TestEncryptAll(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	p1 := mustCreate(t, ctx, time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC))
+	p2 := mustCreate(t, ctx, time.Date(2024, time.March, 12, 9, 30, 0, 0, time.UTC))
+
+	header, err := database.NewEncryptionHeader(1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "making the header"))
+	}
+	key, err := header.DeriveKey("pass")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "deriving the key"))
+	}
+	if err := ctx.DB.Encrypt(header, key); err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting the database"))
+	}
+
+	// execute
+	count, err := EncryptAll(ctx)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting the backups"))
+	}
+
+	// test
+	assert.Equal(t, count, 2, "encrypted count mismatch")
+	for _, path := range []string{p1, p2} {
+		_, encrypted, err := database.ReadEncryptionHeader(path)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the header"))
+		}
+		assert.Equal(t, encrypted, true, "the backup should be encrypted")
+	}
+
+	schema, err := GetSchema(ctx, p1)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the schema"))
+	}
+	assert.Equal(t, schema, 12, "schema mismatch")
+
+	t.Run("decrypt", func(t *testing.T) {
+		count, err := DecryptAll(ctx)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "decrypting the backups"))
+		}
+		assert.Equal(t, count, 2, "decrypted count mismatch")
+
+		db, err := database.Open(p2)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "opening the backup"))
+		}
+		defer db.Close()
+
+		var schema int
+		database.MustScan(t, "getting the schema", db.QueryRow("SELECT value FROM system WHERE key = ?", consts.SystemSchema), &schema)
+		assert.Equal(t, schema, 12, "schema mismatch")
+	})
+}
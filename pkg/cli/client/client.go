@@ -23,6 +23,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -55,9 +56,9 @@ var defaultRequestOptions = requestOptions{
 	ExpectedContentType: &contentTypeApplicationJSON,
 }
 
-func getReq(ctx context.DnoteCtx, path, method, body string) (*http.Request, error) {
+func getReq(ctx context.DnoteCtx, path, method string, body io.Reader) (*http.Request, error) {
 	endpoint := fmt.Sprintf("%s%s", ctx.APIEndpoint, path)
-	req, err := http.NewRequest(method, endpoint, strings.NewReader(body))
+	req, err := http.NewRequest(method, endpoint, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "constructing http request")
 	}
@@ -117,6 +118,12 @@ func checkContentType(res *http.Response, options *requestOptions) error {
 
 // doReq does a http request to the given path in the api endpoint
 func doReq(ctx context.DnoteCtx, method, path, body string, options *requestOptions) (*http.Response, error) {
+	return doStreamReq(ctx, method, path, strings.NewReader(body), options)
+}
+
+// doStreamReq does a http request to the given path in the api endpoint, streaming
+// the request body from the given reader
+func doStreamReq(ctx context.DnoteCtx, method, path string, body io.Reader, options *requestOptions) (*http.Response, error) {
 	req, err := getReq(ctx, path, method, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting request")
@@ -153,6 +160,16 @@ func doAuthorizedReq(ctx context.DnoteCtx, method, path, body string, options *r
 	return doReq(ctx, method, path, body, options)
 }
 
+// doAuthorizedStreamReq is like doAuthorizedReq but streams the request body from
+// the given reader
+func doAuthorizedStreamReq(ctx context.DnoteCtx, method, path string, body io.Reader, options *requestOptions) (*http.Response, error) {
+	if ctx.SessionKey == "" {
+		return nil, errors.New("no session key found")
+	}
+
+	return doStreamReq(ctx, method, path, body, options)
+}
+
 // GetSyncStateResp is the response get sync state endpoint
 type GetSyncStateResp struct {
 	FullSyncBefore int   `json:"full_sync_before"`
@@ -194,6 +211,10 @@ type SyncFragNote struct {
 	Body      string    `json:"content"`
 	Public    bool      `json:"public"`
 	Deleted   bool      `json:"deleted"`
+	// Encrypted tells if the body holds the encrypted content and tags
+	Encrypted bool `json:"encrypted"`
+	// Tags is nil if the server does not support tags
+	Tags []string `json:"tags"`
 }
 
 // SyncFragBook represents a book in a sync fragment and contains only the necessary information
@@ -206,6 +227,7 @@ type SyncFragBook struct {
 	AddedOn   int64     `json:"added_on"`
 	Label     string    `json:"label"`
 	Deleted   bool      `json:"deleted"`
+	Encrypted bool      `json:"encrypted"`
 }
 
 // SyncFragment contains a piece of information about the server's state.
@@ -258,7 +280,8 @@ type RespBook struct {
 
 // CreateBookPayload is a payload for creating a book
 type CreateBookPayload struct {
-	Name string `json:"name"`
+	Name      string `json:"name"`
+	Encrypted bool   `json:"encrypted"`
 }
 
 // CreateBookResp is the response from create book api
@@ -266,10 +289,11 @@ type CreateBookResp struct {
 	Book RespBook `json:"book"`
 }
 
-// CreateBook creates a new book in the server
-func CreateBook(ctx context.DnoteCtx, label string) (CreateBookResp, error) {
+// CreateBook creates a new book in the server. encrypted tells if the label is encrypted.
+func CreateBook(ctx context.DnoteCtx, label string, encrypted bool) (CreateBookResp, error) {
 	payload := CreateBookPayload{
-		Name: label,
+		Name:      label,
+		Encrypted: encrypted,
 	}
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -290,7 +314,8 @@ func CreateBook(ctx context.DnoteCtx, label string) (CreateBookResp, error) {
 }
 
 type updateBookPayload struct {
-	Name *string `json:"name"`
+	Name      *string `json:"name"`
+	Encrypted bool    `json:"encrypted"`
 }
 
 // UpdateBookResp is the response from create book api
@@ -298,10 +323,11 @@ type UpdateBookResp struct {
 	Book RespBook `json:"book"`
 }
 
-// UpdateBook updates a book in the server
-func UpdateBook(ctx context.DnoteCtx, label, uuid string) (UpdateBookResp, error) {
+// UpdateBook updates a book in the server. encrypted tells if the label is encrypted.
+func UpdateBook(ctx context.DnoteCtx, label, uuid string, encrypted bool) (UpdateBookResp, error) {
 	payload := updateBookPayload{
-		Name: &label,
+		Name:      &label,
+		Encrypted: encrypted,
 	}
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -346,8 +372,10 @@ func DeleteBook(ctx context.DnoteCtx, uuid string) (DeleteBookResp, error) {
 
 // CreateNotePayload is a payload for creating a note
 type CreateNotePayload struct {
-	BookUUID string `json:"book_uuid"`
-	Body     string `json:"content"`
+	BookUUID  string   `json:"book_uuid"`
+	Body      string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Encrypted bool     `json:"encrypted"`
 }
 
 // CreateNoteResp is the response from create note endpoint
@@ -377,11 +405,13 @@ type RespNote struct {
 	User      respNoteUser `json:"user"`
 }
 
-// CreateNote creates a note in the server
-func CreateNote(ctx context.DnoteCtx, bookUUID, content string) (CreateNoteResp, error) {
+// CreateNote creates a note in the server. encrypted tells if the content is encrypted.
+func CreateNote(ctx context.DnoteCtx, bookUUID, content string, tags []string, encrypted bool) (CreateNoteResp, error) {
 	payload := CreateNotePayload{
-		BookUUID: bookUUID,
-		Body:     content,
+		BookUUID:  bookUUID,
+		Body:      content,
+		Tags:      tags,
+		Encrypted: encrypted,
 	}
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -402,9 +432,11 @@ func CreateNote(ctx context.DnoteCtx, bookUUID, content string) (CreateNoteResp,
 }
 
 type updateNotePayload struct {
-	BookUUID *string `json:"book_uuid"`
-	Body     *string `json:"content"`
-	Public   *bool   `json:"public"`
+	BookUUID  *string   `json:"book_uuid"`
+	Body      *string   `json:"content"`
+	Public    *bool     `json:"public"`
+	Tags      *[]string `json:"tags"`
+	Encrypted *bool     `json:"encrypted"`
 }
 
 // UpdateNoteResp is the response from create book api
@@ -413,12 +445,14 @@ type UpdateNoteResp struct {
 	Result RespNote `json:"result"`
 }
 
-// UpdateNote updates a note in the server
-func UpdateNote(ctx context.DnoteCtx, uuid, bookUUID, content string, public bool) (UpdateNoteResp, error) {
+// UpdateNote updates a note in the server. encrypted tells if the content is encrypted.
+func UpdateNote(ctx context.DnoteCtx, uuid, bookUUID, content string, public bool, tags []string, encrypted bool) (UpdateNoteResp, error) {
 	payload := updateNotePayload{
-		BookUUID: &bookUUID,
-		Body:     &content,
-		Public:   &public,
+		BookUUID:  &bookUUID,
+		Body:      &content,
+		Public:    &public,
+		Tags:      &tags,
+		Encrypted: &encrypted,
 	}
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -482,6 +516,75 @@ func GetBooks(ctx context.DnoteCtx, sessionKey string) (GetBooksResp, error) {
 	return resp, nil
 }
 
+// EncryptionSettings is the end-to-end encryption settings of an account. The
+// cipher key is encrypted with a key derived from the encryption passphrase and
+// the salt.
+type EncryptionSettings struct {
+	Enabled      bool   `json:"enabled"`
+	Salt         string `json:"salt"`
+	Iteration    int    `json:"iteration"`
+	CipherKeyEnc string `json:"cipher_key_enc"`
+	KeyCheck     string `json:"key_check"`
+}
+
+// GetEncryption gets the end-to-end encryption settings of the account. It
+// returns disabled settings if the server does not support the encryption.
+func GetEncryption(ctx context.DnoteCtx) (EncryptionSettings, error) {
+	res, err := doAuthorizedReq(ctx, "GET", "/v3/encryption", "", nil)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return EncryptionSettings{}, nil
+	} else if err != nil {
+		return EncryptionSettings{}, errors.Wrap(err, "making http request")
+	}
+
+	var resp EncryptionSettings
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return EncryptionSettings{}, errors.Wrap(err, "decoding payload")
+	}
+
+	return resp, nil
+}
+
+// UpdateEncryptionPayload is a payload for updating the encryption settings
+type UpdateEncryptionPayload struct {
+	Password     string `json:"password"`
+	Salt         string `json:"salt"`
+	Iteration    int    `json:"iteration"`
+	CipherKeyEnc string `json:"cipher_key_enc"`
+	KeyCheck     string `json:"key_check"`
+}
+
+// UpdateEncryption stores the end-to-end encryption settings of the account in
+// the server. The password is the current password of the account, which
+// authorizes the change. It is not the passphrase encrypting the cipher key.
+func UpdateEncryption(ctx context.DnoteCtx, password string, settings EncryptionSettings) (EncryptionSettings, error) {
+	payload := UpdateEncryptionPayload{
+		Password:     password,
+		Salt:         settings.Salt,
+		Iteration:    settings.Iteration,
+		CipherKeyEnc: settings.CipherKeyEnc,
+		KeyCheck:     settings.KeyCheck,
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return EncryptionSettings{}, errors.Wrap(err, "marshaling payload")
+	}
+
+	res, err := doAuthorizedReq(ctx, "PUT", "/v3/encryption", string(b), nil)
+	if res != nil && res.StatusCode == http.StatusUnauthorized {
+		return EncryptionSettings{}, ErrInvalidLogin
+	} else if err != nil {
+		return EncryptionSettings{}, errors.Wrap(err, "making http request")
+	}
+
+	var resp EncryptionSettings
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return EncryptionSettings{}, errors.Wrap(err, "decoding payload")
+	}
+
+	return resp, nil
+}
+
 // PresigninResponse is a reponse from /v3/presignin endpoint
 type PresigninResponse struct {
 	Iteration int `json:"iteration"`
@@ -560,3 +663,134 @@ func Signout(ctx context.DnoteCtx, sessionKey string) error {
 
 	return nil
 }
+
+var contentTypeOctetStream = "application/octet-stream"
+
+// HasBlob checks if the server has the blob with the given hash
+func HasBlob(ctx context.DnoteCtx, hash string) (bool, error) {
+	if ctx.SessionKey == "" {
+		return false, errors.New("no session key found")
+	}
+
+	req, err := getReq(ctx, fmt.Sprintf("/v3/blobs/%s", hash), "HEAD", nil)
+	if err != nil {
+		return false, errors.Wrap(err, "getting request")
+	}
+
+	hc := http.Client{}
+	res, err := hc.Do(req)
+	if err != nil {
+		return false, errors.Wrap(err, "making http request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err := checkRespErr(res); err != nil {
+		return false, errors.Wrap(err, "server responded with an error")
+	}
+
+	return true, nil
+}
+
+// UploadBlob uploads the content of a blob with the given hash to the server
+func UploadBlob(ctx context.DnoteCtx, hash string, r io.Reader) error {
+	endpoint := fmt.Sprintf("/v3/blobs/%s", hash)
+	res, err := doAuthorizedStreamReq(ctx, "PUT", endpoint, r, nil)
+	if err != nil {
+		return errors.Wrap(err, "uploading a blob to the server")
+	}
+	defer res.Body.Close()
+
+	return nil
+}
+
+// DownloadBlob downloads the content of the blob with the given hash from the server
+// and writes it to the given writer
+func DownloadBlob(ctx context.DnoteCtx, hash string, w io.Writer) error {
+	opts := requestOptions{
+		ExpectedContentType: &contentTypeOctetStream,
+	}
+
+	endpoint := fmt.Sprintf("/v3/blobs/%s", hash)
+	res, err := doAuthorizedReq(ctx, "GET", endpoint, "", &opts)
+	if err != nil {
+		return errors.Wrap(err, "downloading a blob from the server")
+	}
+	defer res.Body.Close()
+
+	if _, err := io.Copy(w, res.Body); err != nil {
+		return errors.Wrap(err, "reading the response body")
+	}
+
+	return nil
+}
+
+// RespAttachment is an attachment in the response
+type RespAttachment struct {
+	UUID     string `json:"uuid"`
+	NoteUUID string `json:"note_uuid"`
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	AddedOn  int64  `json:"added_on"`
+}
+
+// CreateAttachmentPayload is a payload for creating an attachment
+type CreateAttachmentPayload struct {
+	UUID     string `json:"uuid"`
+	NoteUUID string `json:"note_uuid"`
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	AddedOn  int64  `json:"added_on"`
+}
+
+// CreateAttachmentResp is the response from create attachment endpoint
+type CreateAttachmentResp struct {
+	Attachment RespAttachment `json:"attachment"`
+}
+
+// CreateAttachment creates an attachment in the server. The blob of the attachment
+// must have been uploaded beforehand.
+func CreateAttachment(ctx context.DnoteCtx, payload CreateAttachmentPayload) (CreateAttachmentResp, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return CreateAttachmentResp{}, errors.Wrap(err, "marshaling payload")
+	}
+
+	res, err := doAuthorizedReq(ctx, "POST", "/v3/attachments", string(b), nil)
+	if err != nil {
+		return CreateAttachmentResp{}, errors.Wrap(err, "posting an attachment to the server")
+	}
+	defer res.Body.Close()
+
+	var resp CreateAttachmentResp
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return resp, errors.Wrap(err, "decoding response payload")
+	}
+
+	return resp, nil
+}
+
+// GetAttachmentsResp is the response from get attachments endpoint
+type GetAttachmentsResp struct {
+	Attachments []RespAttachment `json:"attachments"`
+}
+
+// GetAttachments gets all attachments of the user from the server
+func GetAttachments(ctx context.DnoteCtx) (GetAttachmentsResp, error) {
+	res, err := doAuthorizedReq(ctx, "GET", "/v3/attachments", "", nil)
+	if err != nil {
+		return GetAttachmentsResp{}, errors.Wrap(err, "getting attachments from the server")
+	}
+	defer res.Body.Close()
+
+	var resp GetAttachmentsResp
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return resp, errors.Wrap(err, "decoding response payload")
+	}
+
+	return resp, nil
+}
@@ -20,14 +20,18 @@ package add
 
 import (
 	"database/sql"
+	"io/ioutil"
 	"time"
 	"os"
 
+	"github.com/dnote/dnote/pkg/cli/completion"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/tags"
+	"github.com/dnote/dnote/pkg/cli/templates"
 	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/dnote/dnote/pkg/cli/upgrade"
 	"github.com/dnote/dnote/pkg/cli/utils"
@@ -37,6 +41,8 @@ import (
 )
 
 var contentFlag string
+var tagFlag []string
+var templateFlag string
 
 var example = `
  * Open an editor to write content
@@ -45,6 +51,12 @@ var example = `
  * Skip the editor by providing content directly
  dnote add git -c "time is a part of the commit hash"
 
+ * Tag the note. Hashtags in the content such as #internals also become tags
+ dnote add git -c "time is a part of the commit hash" -t internals -t hash
+
+ * Open an editor with the content filled in from a template
+ dnote add incidents --template incident
+
  * Send stdin content to a note
  echo "a branch is just a pointer to a commit" | dnote add git
  # or
@@ -63,24 +75,64 @@ func preRun(cmd *cobra.Command, args []string) error {
 // NewCmd returns a new add command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "add <book>",
-		Short:   "Add a new note",
-		Aliases: []string{"a", "n", "new"},
-		Example: example,
-		PreRunE: preRun,
-		RunE:    newRun(ctx),
+		Use:               "add <book>",
+		Short:             "Add a new note",
+		Aliases:           []string{"a", "n", "new"},
+		Example:           example,
+		PreRunE:           preRun,
+		ValidArgsFunction: completion.Books(ctx),
+		RunE:              newRun(ctx),
 	}
 
 	f := cmd.Flags()
 	f.StringVarP(&contentFlag, "content", "c", "", "The new content for the note")
+	f.StringSliceVarP(&tagFlag, "tag", "t", []string{}, "A tag for the note. Can be repeated or separated by commas")
+	f.StringVarP(&templateFlag, "template", "", "", "The name of a template to fill in the editor with. See 'dnote template ls'")
 
 	return cmd
 }
 
-func getContent(ctx context.DnoteCtx) (string, error) {
+// prompt gets the user input for a prompt in a template
+func prompt(message string) (string, error) {
+	var input string
+	if err := ui.PromptInput(message, &input); err != nil {
+		return "", err
+	}
+
+	return input, nil
+}
+
+// getTemplateContent renders the template and lets the user edit the result
+func getTemplateContent(ctx context.DnoteCtx, bookName string) (string, error) {
+	tmpl, err := templates.Render(ctx, templateFlag, templates.NewData(ctx, bookName), prompt)
+	if err != nil {
+		return "", errors.Wrap(err, "rendering the template")
+	}
+
+	fpath, err := ui.GetTmpContentPath(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "getting temporarily content file path")
+	}
+
+	if err := ioutil.WriteFile(fpath, []byte(tmpl), 0644); err != nil {
+		return "", errors.Wrap(err, "preparing tmp content file")
+	}
+
+	c, err := ui.GetEditorInput(ctx, fpath)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get editor input")
+	}
+
+	return c, nil
+}
+
+func getContent(ctx context.DnoteCtx, bookName string) (string, error) {
 	if contentFlag != "" {
 		return contentFlag, nil
 	}
+	if templateFlag != "" {
+		return getTemplateContent(ctx, bookName)
+	}
 
 	// check for piped content
 	fInfo, _ := os.Stdin.Stat()
@@ -111,8 +163,17 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 		if err := validate.BookName(bookName); err != nil {
 			return errors.Wrap(err, "invalid book name")
 		}
+		for _, t := range tagFlag {
+			if err := tags.Validate(t); err != nil {
+				return errors.Wrap(err, "invalid tag")
+			}
+		}
 
-		content, err := getContent(ctx)
+		if contentFlag != "" && templateFlag != "" {
+			return errors.New("--content and --template cannot be used together")
+		}
+
+		content, err := getContent(ctx, bookName)
 		if err != nil {
 			return errors.Wrap(err, "getting content")
 		}
@@ -121,7 +182,8 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 		}
 
 		ts := time.Now().UnixNano()
-		noteRowID, err := writeNote(ctx, bookName, content, ts)
+		noteTags := tags.Union(tagFlag, tags.Extract(content))
+		noteRowID, err := writeNote(ctx, bookName, content, noteTags, ts)
 		if err != nil {
 			return errors.Wrap(err, "Failed to write note")
 		}
@@ -134,7 +196,9 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			return err
 		}
 
-		output.NoteInfo(info)
+		if err := output.NoteInfo(info); err != nil {
+			return errors.Wrap(err, "printing the note")
+		}
 
 		if err := upgrade.Check(ctx); err != nil {
 			log.Error(errors.Wrap(err, "automatically checking updates").Error())
@@ -144,7 +208,7 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	}
 }
 
-func writeNote(ctx context.DnoteCtx, bookLabel string, content string, ts int64) (int, error) {
+func writeNote(ctx context.DnoteCtx, bookLabel string, content string, noteTags []string, ts int64) (int, error) {
 	tx, err := ctx.DB.Begin()
 	if err != nil {
 		return 0, errors.Wrap(err, "beginning a transaction")
@@ -181,6 +245,16 @@ func writeNote(ctx context.DnoteCtx, bookLabel string, content string, ts int64)
 		return 0, errors.Wrap(err, "creating the note")
 	}
 
+	if err := database.SetNoteTags(tx, noteUUID, noteTags); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "setting tags")
+	}
+
+	if err := database.IndexNoteLinks(tx, noteUUID, content); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "indexing links")
+	}
+
 	var noteRowID int
 	err = tx.QueryRow(`SELECT notes.rowid
 			FROM notes
@@ -0,0 +1,131 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package attach
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/attachments"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * Attach a screenshot to a note
+ dnote attach 12 ~/Pictures/screenshot.png`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new attach command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "attach <note id> <file>",
+		Short:   "Attach a file to a note",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+// attachFile stores the content of the file at the given path as a blob and
+// attaches it to the given note
+func attachFile(ctx context.DnoteCtx, note database.Note, path string) (database.Attachment, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return database.Attachment{}, errors.Wrap(err, "reading the file")
+	}
+	if fi.IsDir() {
+		return database.Attachment{}, errors.Errorf("'%s' is a directory", path)
+	}
+	if fi.Size() > consts.MaxAttachmentSize {
+		return database.Attachment{}, errors.Errorf("'%s' is larger than the maximum size of %d bytes", path, consts.MaxAttachmentSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return database.Attachment{}, errors.Wrap(err, "opening the file")
+	}
+	defer f.Close()
+
+	hash, size, err := attachments.Put(ctx, f)
+	if err != nil {
+		return database.Attachment{}, errors.Wrap(err, "storing the file")
+	}
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return database.Attachment{}, errors.Wrap(err, "generating uuid")
+	}
+
+	a := database.Attachment{
+		UUID:     uuid,
+		NoteUUID: note.UUID,
+		Name:     fi.Name(),
+		Hash:     hash,
+		Size:     size,
+		AddedOn:  ctx.Clock.Now().UnixNano(),
+		Dirty:    true,
+	}
+	if err := a.Insert(ctx.DB); err != nil {
+		return database.Attachment{}, errors.Wrap(err, "saving the attachment")
+	}
+
+	return a, nil
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+
+		note, err := database.GetActiveNote(ctx.DB, rowID)
+		if err == sql.ErrNoRows {
+			return errors.Errorf("note %d not found", rowID)
+		} else if err != nil {
+			return errors.Wrap(err, "querying the note")
+		}
+
+		a, err := attachFile(ctx, note, filepath.Clean(args[1]))
+		if err != nil {
+			return err
+		}
+
+		log.Successf("attached %s to note %d\n", a.Name, rowID)
+		return nil
+	}
+}
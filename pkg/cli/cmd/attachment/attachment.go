@@ -0,0 +1,47 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package attachmentcmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Save an attachment in the current directory
+  dnote attachment get 3
+
+  * Save an attachment at a path
+  dnote attachment get 3 ~/Downloads/diagram.png
+
+  * Print an attachment to the standard output
+  dnote attachment get 3 -`
+
+// NewCmd returns a new attachment command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "attachment",
+		Short:   "Manage attachments",
+		Example: example,
+	}
+
+	cmd.AddCommand(newGetCmd(ctx))
+
+	return cmd
+}
@@ -0,0 +1,138 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package attachmentcmd
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/attachments"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func preRunGet(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func newGetCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "get <attachment id> [path]",
+		Short:   "Save an attachment to a file",
+		PreRunE: preRunGet,
+		RunE:    newGetRun(ctx),
+	}
+
+	return cmd
+}
+
+// getDestination returns the path to which the given attachment is saved. If
+// the path is a directory, the attachment is saved in it under its name.
+func getDestination(a database.Attachment, args []string) (string, error) {
+	if len(args) < 2 {
+		return a.Name, nil
+	}
+
+	path := args[1]
+	fi, err := os.Stat(path)
+	if err == nil && fi.IsDir() {
+		return filepath.Join(path, a.Name), nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "checking the path")
+	}
+
+	return path, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return errors.Errorf("'%s' already exists", path)
+	} else if err != nil {
+		return errors.Wrap(err, "creating the file")
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return errors.Wrap(err, "writing the file")
+	}
+
+	return f.Close()
+}
+
+func newGetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+
+		a, err := database.GetAttachment(ctx.DB, rowID)
+		if err == sql.ErrNoRows {
+			return errors.Errorf("attachment %d not found", rowID)
+		} else if err != nil {
+			return errors.Wrap(err, "querying the attachment")
+		}
+
+		ok, err := attachments.Exists(ctx, a.Hash)
+		if err != nil {
+			return errors.Wrap(err, "checking the blob")
+		}
+		if !ok {
+			return errors.Errorf("the content of attachment %d has not been downloaded. Please run 'dnote sync'", rowID)
+		}
+
+		f, err := attachments.Open(ctx, a.Hash)
+		if err != nil {
+			return errors.Wrap(err, "opening the blob")
+		}
+		defer f.Close()
+
+		if len(args) == 2 && args[1] == "-" {
+			if _, err := io.Copy(os.Stdout, f); err != nil {
+				return errors.Wrap(err, "writing the attachment")
+			}
+
+			return nil
+		}
+
+		path, err := getDestination(a, args)
+		if err != nil {
+			return err
+		}
+
+		if err := writeFile(path, f); err != nil {
+			return err
+		}
+
+		log.Successf("saved %s\n", path)
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package backupcmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Back up the notes
+  dnote backup
+
+  * Back up the notes of a profile
+  dnote backup --profile work`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new backup command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Take a snapshot of the local database",
+		Long: `Take a snapshot of the local database in the backups directory next to it.
+The snapshot is consistent even if other dnote commands are running. Only the
+most recent backups are kept, up to backupLimit in the configuration file.`,
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		path, err := backup.Create(ctx)
+		if err != nil {
+			return errors.Wrap(err, "backing up the database")
+		}
+
+		removed, err := backup.Rotate(ctx, ctx.BackupLimit)
+		if err != nil {
+			return errors.Wrap(err, "rotating the backups")
+		}
+		for _, p := range removed {
+			log.Debug("removed the old backup %s\n", p)
+		}
+
+		log.Successf("backed up to %s\n", path)
+
+		return nil
+	}
+}
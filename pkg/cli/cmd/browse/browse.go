@@ -0,0 +1,362 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/sync"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/tags"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  dnote browse
+
+  Keybindings:
+    j/k, up/down    move the selection
+    tab, h/l        switch between the books and the notes
+    /               filter the notes by keywords
+    esc             clear the filter
+    e               edit the selected note
+    m               move the selected note to another book
+    d               delete the selected note
+    s               sync with the server
+    q               quit`
+
+// NewCmd returns a new browse command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "browse",
+		Short:   "Browse books and notes in a full-screen terminal interface",
+		Example: example,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+// browser runs the terminal interface
+type browser struct {
+	ctx    context.DnoteCtx
+	screen *ui.Screen
+	m      *model
+}
+
+// loadBooks reloads the books
+func (b *browser) loadBooks() error {
+	books, err := loadBooks(b.ctx.DB)
+	if err != nil {
+		return errors.Wrap(err, "loading books")
+	}
+
+	b.m.setBooks(books)
+
+	return nil
+}
+
+// loadNotes reloads the notes in the selected book matching the filter. An
+// invalid filter is reported in the status line rather than as an error.
+func (b *browser) loadNotes() error {
+	selected, _ := b.m.selectedBook()
+
+	notes, err := loadNotes(b.ctx.DB, selected.UUID, b.m.filter)
+	if err != nil {
+		if b.m.filter == "" {
+			return errors.Wrap(err, "loading notes")
+		}
+
+		b.m.setNotes([]note{})
+		b.m.status = "invalid filter"
+		return nil
+	}
+
+	b.m.setNotes(notes)
+
+	return nil
+}
+
+// reload reloads the books and the notes after they were changed
+func (b *browser) reload() error {
+	if err := b.loadBooks(); err != nil {
+		return err
+	}
+
+	return b.loadNotes()
+}
+
+// suspend runs the given function with the terminal restored, for instance
+// to launch an editor
+func (b *browser) suspend(fn func() error) error {
+	if err := b.screen.Suspend(); err != nil {
+		return errors.Wrap(err, "suspending the screen")
+	}
+
+	fnErr := fn()
+
+	if err := b.screen.Resume(); err != nil {
+		return errors.Wrap(err, "resuming the screen")
+	}
+
+	return fnErr
+}
+
+// waitKey waits until the user presses a key so that the output of a command
+// can be read before returning to the browser
+func waitKey() error {
+	fmt.Print("\npress enter to return to the browser")
+
+	if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+		return errors.Wrap(err, "reading input")
+	}
+
+	return nil
+}
+
+func (b *browser) editNote() error {
+	selected, _ := b.m.selectedNote()
+
+	n, err := database.GetActiveNote(b.ctx.DB, selected.RowID)
+	if err != nil {
+		return errors.Wrap(err, "finding the note")
+	}
+
+	var content string
+	err = b.suspend(func() error {
+		fpath, err := ui.GetTmpContentPath(b.ctx)
+		if err != nil {
+			return errors.Wrap(err, "getting temporarily content file path")
+		}
+		if err := ioutil.WriteFile(fpath, []byte(n.Body), 0644); err != nil {
+			return errors.Wrap(err, "preparing tmp content file")
+		}
+
+		content, err = ui.GetEditorInput(b.ctx, fpath)
+		if err != nil {
+			return errors.Wrap(err, "getting editor input")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if content == n.Body || content == "" {
+		b.m.status = "nothing changed"
+		return nil
+	}
+
+	tx, err := b.ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	if err := database.UpdateNoteContent(tx, b.ctx.Clock, n.RowID, content, b.ctx.RevisionLimit); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "updating the note")
+	}
+
+	current, err := database.GetNoteTags(tx, n.UUID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "getting tags")
+	}
+	newTags := tags.Union(tags.Difference(current, tags.Extract(n.Body)), tags.Extract(content))
+	if !tags.Equal(current, newTags) {
+		if err := database.UpdateNoteTags(tx, b.ctx.Clock, n.RowID, n.UUID, newTags); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "updating tags")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	b.m.status = "note updated"
+
+	return b.reload()
+}
+
+func (b *browser) moveNote() error {
+	selected, _ := b.m.selectedNote()
+	bookName := b.m.input
+	b.m.input = ""
+
+	bookUUID, err := database.GetBookUUID(b.ctx.DB, bookName)
+	if err != nil {
+		b.m.status = fmt.Sprintf("book '%s' not found", bookName)
+		return nil
+	}
+
+	if selected.BookLabel == bookName {
+		b.m.status = "book has not changed"
+		return nil
+	}
+
+	if err := database.UpdateNoteBook(b.ctx.DB, b.ctx.Clock, selected.RowID, bookUUID); err != nil {
+		return errors.Wrap(err, "moving the note")
+	}
+
+	b.m.status = fmt.Sprintf("moved to %s", bookName)
+
+	return b.reload()
+}
+
+func (b *browser) deleteNote() error {
+	selected, _ := b.m.selectedNote()
+
+	tx, err := b.ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	if err := database.TrashNote(tx, b.ctx.Clock, selected.UUID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "removing the note")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	b.m.status = fmt.Sprintf("removed from %s", selected.BookLabel)
+
+	return b.reload()
+}
+
+func (b *browser) sync() error {
+	err := b.suspend(func() error {
+		cmd := sync.NewCmd(b.ctx)
+		if err := cmd.RunE(cmd, []string{}); err != nil {
+			log.Errorf("%s\n", err.Error())
+		}
+
+		return waitKey()
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.reload()
+}
+
+// perform performs the action requested by the model
+func (b *browser) perform(action int) error {
+	switch action {
+	case actionLoadNotes:
+		return b.loadNotes()
+	case actionEdit:
+		return b.editNote()
+	case actionMove:
+		return b.moveNote()
+	case actionDelete:
+		return b.deleteNote()
+	case actionSync:
+		return b.sync()
+	}
+
+	return nil
+}
+
+func (b *browser) draw() error {
+	width, height, err := b.screen.Size()
+	if err != nil {
+		return err
+	}
+
+	return b.screen.Draw(render(b.m, width, height))
+}
+
+// run runs the event loop until the user quits
+func (b *browser) run() error {
+	if err := b.reload(); err != nil {
+		return err
+	}
+
+	for {
+		if err := b.draw(); err != nil {
+			return errors.Wrap(err, "drawing the screen")
+		}
+
+		keys, err := b.screen.ReadKeys()
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			action := b.m.handleKey(k)
+			if action == actionQuit {
+				return nil
+			}
+
+			if err := b.perform(action); err != nil {
+				if errors.Cause(err) == sql.ErrNoRows {
+					b.m.status = "the note no longer exists"
+					if err := b.reload(); err != nil {
+						return err
+					}
+					continue
+				}
+
+				return err
+			}
+		}
+	}
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if !ui.IsTerminal() {
+			return errors.New("browse requires an interactive terminal")
+		}
+
+		screen, err := ui.NewScreen()
+		if err != nil {
+			return errors.Wrap(err, "initializing the screen")
+		}
+
+		b := browser{
+			ctx:    ctx,
+			screen: screen,
+			m:      newModel(),
+		}
+
+		runErr := b.run()
+
+		if err := screen.Close(); err != nil {
+			return errors.Wrap(err, "restoring the terminal")
+		}
+
+		return runErr
+	}
+}
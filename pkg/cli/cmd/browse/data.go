@@ -0,0 +1,116 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+// allBooksLabel is the label of the item representing all books
+const allBooksLabel = "(all)"
+
+// filterMatch turns the filter typed by the user into an FTS5 MATCH
+// expression. Every term is treated as a prefix so that the results are
+// updated while the user types.
+func filterMatch(filter string) string {
+	var terms []string
+	for _, t := range strings.Fields(filter) {
+		terms = append(terms, fmt.Sprintf(`"%s"*`, strings.ReplaceAll(t, `"`, `""`)))
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+// loadBooks returns the books with the number of notes in them, preceded by
+// an item representing all books
+func loadBooks(db *database.DB) ([]book, error) {
+	rows, err := db.Query(`SELECT books.uuid, books.label, count(notes.uuid) note_count
+	FROM books
+	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
+	WHERE books.deleted = false
+	GROUP BY books.uuid
+	ORDER BY books.label ASC;`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	all := book{Label: allBooksLabel}
+	ret := []book{all}
+	for rows.Next() {
+		var b book
+		if err := rows.Scan(&b.UUID, &b.Label, &b.NoteCount); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, b)
+		ret[0].NoteCount += b.NoteCount
+	}
+
+	return ret, nil
+}
+
+// loadNotes returns the notes in the book matching the filter. If bookUUID is
+// empty, notes in all books are returned. The notes are ordered by relevance
+// if a filter is given, and by the newest first otherwise.
+func loadNotes(db *database.DB, bookUUID, filter string) ([]note, error) {
+	query := fmt.Sprintf(`SELECT notes.rowid, notes.uuid, books.label, notes.body, notes.added_on, notes.edited_on, %s
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid`, database.NoteTagsColumn)
+	conds := []string{"notes.deleted = false"}
+	var args []interface{}
+	order := "notes.added_on DESC"
+
+	if match := filterMatch(filter); match != "" {
+		query = fmt.Sprintf("%s INNER JOIN note_fts ON note_fts.rowid = notes.rowid", query)
+		conds = append(conds, "note_fts MATCH ?")
+		args = append(args, match)
+		order = "bm25(note_fts), notes.added_on DESC"
+	}
+	if bookUUID != "" {
+		conds = append(conds, "notes.book_uuid = ?")
+		args = append(args, bookUUID)
+	}
+
+	query = fmt.Sprintf("%s WHERE %s ORDER BY %s", query, strings.Join(conds, " AND "), order)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []note{}
+	for rows.Next() {
+		var n note
+		var noteTags string
+		if err := rows.Scan(&n.RowID, &n.UUID, &n.BookLabel, &n.Body, &n.AddedOn, &n.EditedOn, &noteTags); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+		n.Tags = database.SplitTags(noteTags)
+
+		ret = append(ret, n)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,89 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestFilterMatch(t *testing.T) {
+	assert.Equal(t, filterMatch(""), "", "empty filter mismatch")
+	assert.Equal(t, filterMatch(" foo  ba"), `"foo"* AND "ba"*`, "filter mismatch")
+	assert.Equal(t, filterMatch(`say"hi`), `"say""hi"*`, "quote mismatch")
+}
+
+func TestLoadBooksAndNotes(t *testing.T) {
+	db := database.InitTestDB(t, "../../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b3-uuid", "deleted", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "closures capture variables", 1)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "promises and closures", 2)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b2-uuid", "flexbox", 3)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n4-uuid", "b2-uuid", "", 4, true)
+	database.MustExec(t, "inserting n1 tag", db, "INSERT INTO note_tags (note_uuid, tag) VALUES (?, ?)", "n1-uuid", "fn")
+
+	books, err := loadBooks(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "loading books"))
+	}
+	assert.DeepEqual(t, books, []book{
+		{Label: allBooksLabel, NoteCount: 3},
+		{UUID: "b2-uuid", Label: "css", NoteCount: 1},
+		{UUID: "b1-uuid", Label: "js", NoteCount: 2},
+	}, "books mismatch")
+
+	testCases := []struct {
+		bookUUID string
+		filter   string
+		expected []string
+	}{
+		{bookUUID: "", filter: "", expected: []string{"n3-uuid", "n2-uuid", "n1-uuid"}},
+		{bookUUID: "b1-uuid", filter: "", expected: []string{"n2-uuid", "n1-uuid"}},
+		{bookUUID: "", filter: "clos", expected: []string{"n2-uuid", "n1-uuid"}},
+		{bookUUID: "", filter: "clos capt", expected: []string{"n1-uuid"}},
+		{bookUUID: "b2-uuid", filter: "clos", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		notes, err := loadNotes(db, tc.bookUUID, tc.filter)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "loading notes"))
+		}
+
+		uuids := []string{}
+		for _, n := range notes {
+			uuids = append(uuids, n.UUID)
+		}
+
+		assert.DeepEqual(t, uuids, tc.expected, "notes mismatch for "+tc.bookUUID+" "+tc.filter)
+	}
+
+	notes, err := loadNotes(db, "b1-uuid", "capture")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "loading notes"))
+	}
+	assert.DeepEqual(t, notes, []note{{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "closures capture variables", AddedOn: 1, Tags: []string{"fn"}}}, "note mismatch")
+}
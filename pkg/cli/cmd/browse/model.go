@@ -0,0 +1,384 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"github.com/dnote/dnote/pkg/cli/ui"
+)
+
+const (
+	// paneBooks is the list of books
+	paneBooks = iota
+	// paneNotes is the list of notes
+	paneNotes
+)
+
+const (
+	// modeNormal is the mode for navigating the lists
+	modeNormal = iota
+	// modeFilter is the mode for typing the filter
+	modeFilter
+	// modeMove is the mode for typing the name of the book to move a note to
+	modeMove
+	// modeConfirmDelete is the mode for confirming the deletion of a note
+	modeConfirmDelete
+)
+
+const (
+	// actionNone requires no further action
+	actionNone = iota
+	// actionQuit exits the browser
+	actionQuit
+	// actionLoadNotes reloads the notes after the book or the filter changed
+	actionLoadNotes
+	// actionEdit edits the selected note in the editor
+	actionEdit
+	// actionMove moves the selected note to the book in the input
+	actionMove
+	// actionDelete deletes the selected note
+	actionDelete
+	// actionSync syncs with the server
+	actionSync
+)
+
+// book is an item in the list of books. The first item, whose UUID is empty,
+// represents all books.
+type book struct {
+	UUID      string
+	Label     string
+	NoteCount int
+}
+
+// note is an item in the list of notes
+type note struct {
+	RowID     int
+	UUID      string
+	BookLabel string
+	Body      string
+	AddedOn   int64
+	EditedOn  int64
+	Tags      []string
+}
+
+// list keeps track of the selected item and the scroll position of a list
+type list struct {
+	idx    int
+	offset int
+}
+
+// move moves the selection by the given delta within the given length
+func (l *list) move(delta, length int) {
+	l.idx += delta
+	if l.idx >= length {
+		l.idx = length - 1
+	}
+	if l.idx < 0 {
+		l.idx = 0
+	}
+}
+
+// scroll adjusts the scroll position so that the selection is visible in the
+// given height
+func (l *list) scroll(height int) {
+	if l.idx < l.offset {
+		l.offset = l.idx
+	}
+	if height > 0 && l.idx >= l.offset+height {
+		l.offset = l.idx - height + 1
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+}
+
+// model is the state of the browser
+type model struct {
+	books    []book
+	notes    []note
+	bookList list
+	noteList list
+	pane     int
+	mode     int
+	filter   string
+	input    string
+	status   string
+	// pageSize is the number of rows visible in the lists
+	pageSize int
+}
+
+func newModel() *model {
+	return &model{
+		pageSize: 10,
+	}
+}
+
+// selectedBook returns the selected book
+func (m *model) selectedBook() (book, bool) {
+	if m.bookList.idx >= len(m.books) {
+		return book{}, false
+	}
+
+	return m.books[m.bookList.idx], true
+}
+
+// selectedNote returns the selected note
+func (m *model) selectedNote() (note, bool) {
+	if m.noteList.idx >= len(m.notes) {
+		return note{}, false
+	}
+
+	return m.notes[m.noteList.idx], true
+}
+
+// setBooks replaces the books, keeping the selection on the same book if possible
+func (m *model) setBooks(books []book) {
+	selected, ok := m.selectedBook()
+
+	m.books = books
+	m.bookList.idx = 0
+
+	if ok {
+		for i, b := range books {
+			if b.UUID == selected.UUID {
+				m.bookList.idx = i
+				break
+			}
+		}
+	}
+}
+
+// setNotes replaces the notes, keeping the selection on the same note if possible
+func (m *model) setNotes(notes []note) {
+	selected, ok := m.selectedNote()
+	prevIdx := m.noteList.idx
+
+	m.notes = notes
+	m.noteList.idx = 0
+
+	if ok {
+		found := false
+		for i, n := range notes {
+			if n.UUID == selected.UUID {
+				m.noteList.idx = i
+				found = true
+				break
+			}
+		}
+
+		// stay around the previous position if the note is gone
+		if !found {
+			m.noteList.move(prevIdx, len(notes))
+		}
+	}
+}
+
+// resetNotes clears the selection of the notes before loading the notes of another book
+func (m *model) resetNotes() {
+	m.notes = nil
+	m.noteList = list{}
+}
+
+// handleKey updates the state for the given key and returns the action to be
+// performed by the caller
+func (m *model) handleKey(k ui.Key) int {
+	switch m.mode {
+	case modeFilter:
+		return m.handleFilterKey(k)
+	case modeMove:
+		return m.handleMoveKey(k)
+	case modeConfirmDelete:
+		return m.handleConfirmDeleteKey(k)
+	default:
+		return m.handleNormalKey(k)
+	}
+}
+
+func (m *model) moveSelection(delta int) int {
+	if m.pane == paneBooks {
+		prev := m.bookList.idx
+		m.bookList.move(delta, len(m.books))
+
+		if prev != m.bookList.idx {
+			m.resetNotes()
+			return actionLoadNotes
+		}
+
+		return actionNone
+	}
+
+	m.noteList.move(delta, len(m.notes))
+
+	return actionNone
+}
+
+func (m *model) togglePane() {
+	if m.pane == paneBooks {
+		m.pane = paneNotes
+	} else {
+		m.pane = paneBooks
+	}
+}
+
+func (m *model) handleNormalKey(k ui.Key) int {
+	m.status = ""
+
+	switch k.Code {
+	case ui.KeyCtrlC:
+		return actionQuit
+	case ui.KeyEsc:
+		if m.filter != "" {
+			m.filter = ""
+			return actionLoadNotes
+		}
+	case ui.KeyUp:
+		return m.moveSelection(-1)
+	case ui.KeyDown:
+		return m.moveSelection(1)
+	case ui.KeyPageUp:
+		return m.moveSelection(-m.pageSize)
+	case ui.KeyPageDown:
+		return m.moveSelection(m.pageSize)
+	case ui.KeyHome:
+		return m.moveSelection(-len(m.books) - len(m.notes))
+	case ui.KeyEnd:
+		return m.moveSelection(len(m.books) + len(m.notes))
+	case ui.KeyTab:
+		m.togglePane()
+	case ui.KeyLeft:
+		m.pane = paneBooks
+	case ui.KeyRight, ui.KeyEnter:
+		m.pane = paneNotes
+	case ui.KeyRune:
+		return m.handleNormalRune(k.Rune)
+	}
+
+	return actionNone
+}
+
+func (m *model) handleNormalRune(r rune) int {
+	switch r {
+	case 'q':
+		return actionQuit
+	case 'k':
+		return m.moveSelection(-1)
+	case 'j':
+		return m.moveSelection(1)
+	case 'g':
+		return m.moveSelection(-len(m.books) - len(m.notes))
+	case 'G':
+		return m.moveSelection(len(m.books) + len(m.notes))
+	case 'h':
+		m.pane = paneBooks
+	case 'l':
+		m.pane = paneNotes
+	case '/':
+		m.mode = modeFilter
+	case 's':
+		return actionSync
+	case 'e', 'm', 'd':
+		if _, ok := m.selectedNote(); !ok || m.pane != paneNotes {
+			m.status = "select a note first"
+			return actionNone
+		}
+
+		if r == 'e' {
+			return actionEdit
+		}
+		if r == 'm' {
+			m.mode = modeMove
+			m.input = ""
+		} else {
+			m.mode = modeConfirmDelete
+		}
+	}
+
+	return actionNone
+}
+
+func (m *model) handleFilterKey(k ui.Key) int {
+	prev := m.filter
+	m.status = ""
+
+	switch k.Code {
+	case ui.KeyEnter:
+		m.mode = modeNormal
+		m.pane = paneNotes
+		return actionNone
+	case ui.KeyEsc, ui.KeyCtrlC:
+		m.mode = modeNormal
+		m.filter = ""
+	case ui.KeyBackspace:
+		if r := []rune(m.filter); len(r) > 0 {
+			m.filter = string(r[:len(r)-1])
+		}
+	case ui.KeyCtrlU:
+		m.filter = ""
+	case ui.KeyUp, ui.KeyDown:
+		delta := 1
+		if k.Code == ui.KeyUp {
+			delta = -1
+		}
+		m.noteList.move(delta, len(m.notes))
+	case ui.KeyRune:
+		m.filter += string(k.Rune)
+	}
+
+	if m.filter != prev {
+		return actionLoadNotes
+	}
+
+	return actionNone
+}
+
+func (m *model) handleMoveKey(k ui.Key) int {
+	switch k.Code {
+	case ui.KeyEnter:
+		m.mode = modeNormal
+		if m.input == "" {
+			return actionNone
+		}
+
+		return actionMove
+	case ui.KeyEsc, ui.KeyCtrlC:
+		m.mode = modeNormal
+		m.input = ""
+	case ui.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case ui.KeyCtrlU:
+		m.input = ""
+	case ui.KeyRune:
+		m.input += string(k.Rune)
+	}
+
+	return actionNone
+}
+
+func (m *model) handleConfirmDeleteKey(k ui.Key) int {
+	m.mode = modeNormal
+
+	if k.Code == ui.KeyRune && (k.Rune == 'y' || k.Rune == 'Y') {
+		return actionDelete
+	}
+
+	m.status = "aborted"
+
+	return actionNone
+}
@@ -0,0 +1,156 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/ui"
+)
+
+func runeKey(r rune) ui.Key {
+	return ui.Key{Code: ui.KeyRune, Rune: r}
+}
+
+func getTestModel() *model {
+	m := newModel()
+	m.books = []book{{Label: allBooksLabel, NoteCount: 3}, {UUID: "b1-uuid", Label: "js", NoteCount: 2}, {UUID: "b2-uuid", Label: "linux", NoteCount: 1}}
+	m.notes = []note{{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "n1 body"}, {RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body"}, {RowID: 3, UUID: "n3-uuid", BookLabel: "linux", Body: "n3 body"}}
+
+	return m
+}
+
+func TestHandleKey_navigation(t *testing.T) {
+	m := getTestModel()
+
+	assert.Equal(t, m.handleKey(runeKey('j')), actionLoadNotes, "moving the book selection should load notes")
+	assert.Equal(t, m.bookList.idx, 1, "book idx mismatch")
+	assert.Equal(t, len(m.notes), 0, "notes should be reset")
+
+	m.notes = getTestModel().notes
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyEnd}), actionLoadNotes, "moving to the end should load notes")
+	assert.Equal(t, m.bookList.idx, 2, "book idx mismatch")
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyDown}), actionNone, "moving past the end should not load notes")
+
+	m.notes = getTestModel().notes
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyTab}), actionNone, "tab action mismatch")
+	assert.Equal(t, m.pane, paneNotes, "pane mismatch")
+	m.handleKey(ui.Key{Code: ui.KeyDown})
+	m.handleKey(runeKey('j'))
+	m.handleKey(runeKey('j'))
+	assert.Equal(t, m.noteList.idx, 2, "note idx mismatch")
+	m.handleKey(runeKey('g'))
+	assert.Equal(t, m.noteList.idx, 0, "note idx mismatch after g")
+	assert.Equal(t, m.bookList.idx, 2, "book idx should not change when navigating notes")
+
+	m.handleKey(runeKey('h'))
+	assert.Equal(t, m.pane, paneBooks, "pane mismatch after h")
+
+	assert.Equal(t, m.handleKey(runeKey('q')), actionQuit, "q should quit")
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyCtrlC}), actionQuit, "ctrl-c should quit")
+}
+
+func TestHandleKey_filter(t *testing.T) {
+	m := getTestModel()
+
+	m.handleKey(runeKey('/'))
+	assert.Equal(t, m.mode, modeFilter, "mode mismatch")
+
+	assert.Equal(t, m.handleKey(runeKey('f')), actionLoadNotes, "typing should load notes")
+	assert.Equal(t, m.handleKey(runeKey('o')), actionLoadNotes, "typing should load notes")
+	assert.Equal(t, m.handleKey(runeKey('q')), actionLoadNotes, "q should be typed in the filter")
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyBackspace}), actionLoadNotes, "backspace should load notes")
+	assert.Equal(t, m.filter, "fo", "filter mismatch")
+
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyEnter}), actionNone, "enter action mismatch")
+	assert.Equal(t, m.mode, modeNormal, "mode mismatch after enter")
+	assert.Equal(t, m.pane, paneNotes, "pane mismatch after enter")
+	assert.Equal(t, m.filter, "fo", "filter should be kept")
+
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyEsc}), actionLoadNotes, "esc should clear the filter")
+	assert.Equal(t, m.filter, "", "filter mismatch after esc")
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyEsc}), actionNone, "esc without a filter should do nothing")
+}
+
+func TestHandleKey_noteActions(t *testing.T) {
+	m := getTestModel()
+
+	assert.Equal(t, m.handleKey(runeKey('e')), actionNone, "edit should require the notes pane")
+	assert.Equal(t, m.status, "select a note first", "status mismatch")
+
+	m.handleKey(ui.Key{Code: ui.KeyRight})
+	assert.Equal(t, m.handleKey(runeKey('e')), actionEdit, "edit action mismatch")
+
+	m.handleKey(runeKey('m'))
+	assert.Equal(t, m.mode, modeMove, "mode mismatch")
+	for _, r := range "linux" {
+		assert.Equal(t, m.handleKey(runeKey(r)), actionNone, "typing the book name action mismatch")
+	}
+	assert.Equal(t, m.handleKey(ui.Key{Code: ui.KeyEnter}), actionMove, "move action mismatch")
+	assert.Equal(t, m.input, "linux", "input mismatch")
+
+	m.handleKey(runeKey('d'))
+	assert.Equal(t, m.mode, modeConfirmDelete, "mode mismatch")
+	assert.Equal(t, m.handleKey(runeKey('n')), actionNone, "declining should not delete")
+	assert.Equal(t, m.status, "aborted", "status mismatch")
+	m.handleKey(runeKey('d'))
+	assert.Equal(t, m.handleKey(runeKey('y')), actionDelete, "delete action mismatch")
+
+	assert.Equal(t, m.handleKey(runeKey('s')), actionSync, "sync action mismatch")
+}
+
+func TestSetNotes(t *testing.T) {
+	m := getTestModel()
+	m.noteList.idx = 1
+
+	notes := getTestModel().notes
+	m.setNotes([]note{notes[1], notes[2]})
+	assert.Equal(t, m.noteList.idx, 0, "selection should follow the note")
+
+	m.noteList.idx = 1
+	m.setNotes([]note{notes[0]})
+	assert.Equal(t, m.noteList.idx, 0, "selection should be clamped when the note is gone")
+
+	m.setNotes([]note{})
+	_, ok := m.selectedNote()
+	assert.Equal(t, ok, false, "no note should be selected")
+}
+
+func TestRender(t *testing.T) {
+	m := getTestModel()
+	m.pane = paneNotes
+	m.noteList.idx = 1
+
+	lines := render(m, 80, 10)
+	assert.Equal(t, len(lines), 10, "number of lines mismatch")
+	assert.Equal(t, m.pageSize, 7, "page size mismatch")
+	assert.Equal(t, strings.Contains(lines[1], "(all) (3)"), true, "books should be rendered")
+	assert.Equal(t, strings.Contains(lines[2], ui.Reverse(ui.Fit("> (2) n2 body", 25))), true, "the selected note should be highlighted")
+	assert.Equal(t, strings.Contains(lines[1], "book: js  id: 2"), true, "the preview should be rendered")
+
+	m.mode = modeMove
+	m.input = "lin"
+	lines = render(m, 40, 10)
+	assert.Equal(t, strings.TrimSpace(lines[8]), "move to book: lin", "status mismatch")
+
+	lines = render(m, 10, 3)
+	assert.Equal(t, len(lines), 1, "small terminal should render a message")
+}
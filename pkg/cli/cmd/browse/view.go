@@ -0,0 +1,191 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package browse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/ui"
+)
+
+// timeLayout is the layout of the timestamps in the preview
+const timeLayout = "Jan 2, 2006 3:04pm"
+
+// helpText is the list of keybindings shown at the bottom of the screen
+const helpText = "q quit  tab switch  / filter  e edit  m move  d delete  s sync"
+
+// separator is drawn between the panes
+const separator = "│"
+
+// minPreviewWidth is the minimum width of the terminal to show the preview pane
+const minPreviewWidth = 60
+
+// firstLine returns the first non-empty line of the given text
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if l := strings.TrimSpace(line); l != "" {
+			return l
+		}
+	}
+
+	return ""
+}
+
+// renderList renders the rows of a list pane. The selected row is marked and,
+// if the pane is focused, highlighted.
+func renderList(items []string, l *list, width, height int, focused bool) []string {
+	l.scroll(height)
+
+	ret := make([]string, height)
+	for i := 0; i < height; i++ {
+		idx := l.offset + i
+		if idx >= len(items) {
+			ret[i] = ui.Fit("", width)
+			continue
+		}
+
+		if idx != l.idx {
+			ret[i] = ui.Fit("  "+items[idx], width)
+			continue
+		}
+
+		row := ui.Fit("> "+items[idx], width)
+		if focused {
+			row = ui.Reverse(row)
+		}
+		ret[i] = row
+	}
+
+	return ret
+}
+
+// renderPreview renders the content of the selected note
+func renderPreview(m *model, width, height int) []string {
+	var lines []string
+
+	if n, ok := m.selectedNote(); ok {
+		lines = append(lines, fmt.Sprintf("book: %s  id: %d", n.BookLabel, n.RowID))
+		lines = append(lines, fmt.Sprintf("created: %s", time.Unix(0, n.AddedOn).Format(timeLayout)))
+		if n.EditedOn != 0 {
+			lines = append(lines, fmt.Sprintf("updated: %s", time.Unix(0, n.EditedOn).Format(timeLayout)))
+		}
+		if len(n.Tags) > 0 {
+			lines = append(lines, fmt.Sprintf("tags: %s", strings.Join(n.Tags, ", ")))
+		}
+		lines = append(lines, strings.Repeat("─", width))
+		lines = append(lines, ui.Wrap(n.Body, width)...)
+	}
+
+	ret := make([]string, height)
+	for i := range ret {
+		var line string
+		if i < len(lines) {
+			line = lines[i]
+		}
+
+		ret[i] = ui.Fit(line, width)
+	}
+
+	return ret
+}
+
+// renderStatus renders the line above the help, which shows the prompt of
+// the current mode or the status message
+func renderStatus(m *model) string {
+	switch m.mode {
+	case modeFilter:
+		if m.status != "" {
+			return fmt.Sprintf("/%s  (%s)", m.filter, m.status)
+		}
+
+		return fmt.Sprintf("/%s", m.filter)
+	case modeMove:
+		return fmt.Sprintf("move to book: %s", m.input)
+	case modeConfirmDelete:
+		return "delete this note? (y/N)"
+	}
+
+	if m.status != "" {
+		return m.status
+	}
+	if m.filter != "" {
+		return fmt.Sprintf("filter: %s (esc to clear)", m.filter)
+	}
+
+	return ""
+}
+
+// render draws the screen for the given state
+func render(m *model, width, height int) []string {
+	if width < 20 || height < 5 {
+		return []string{ui.Fit("terminal too small", width)}
+	}
+
+	bodyHeight := height - 3
+	m.pageSize = bodyHeight
+
+	bookWidth := width / 5
+	if bookWidth < 12 {
+		bookWidth = 12
+	}
+
+	var noteWidth, previewWidth int
+	if width >= minPreviewWidth {
+		noteWidth = (width - bookWidth) * 2 / 5
+		previewWidth = width - bookWidth - noteWidth - 2
+	} else {
+		noteWidth = width - bookWidth - 1
+	}
+
+	bookItems := make([]string, len(m.books))
+	for i, b := range m.books {
+		bookItems[i] = fmt.Sprintf("%s (%d)", b.Label, b.NoteCount)
+	}
+	noteItems := make([]string, len(m.notes))
+	for i, n := range m.notes {
+		noteItems[i] = fmt.Sprintf("(%d) %s", n.RowID, firstLine(n.Body))
+	}
+
+	bookRows := renderList(bookItems, &m.bookList, bookWidth, bodyHeight, m.pane == paneBooks)
+	noteRows := renderList(noteItems, &m.noteList, noteWidth, bodyHeight, m.pane == paneNotes)
+
+	var previewRows []string
+	if previewWidth > 0 {
+		previewRows = renderPreview(m, previewWidth, bodyHeight)
+	}
+
+	title := fmt.Sprintf(" dnote  %d notes", len(m.notes))
+	ret := []string{ui.Bold(ui.Fit(title, width))}
+
+	for i := 0; i < bodyHeight; i++ {
+		row := bookRows[i] + separator + noteRows[i]
+		if previewRows != nil {
+			row = row + separator + previewRows[i]
+		}
+
+		ret = append(ret, row)
+	}
+
+	ret = append(ret, ui.Fit(renderStatus(m), width))
+	ret = append(ret, ui.Reverse(ui.Fit(helpText, width)))
+
+	return ret
+}
@@ -87,12 +87,15 @@ func NewRun(ctx context.DnoteCtx, contentOnly bool) infra.RunEFunc {
 			return err
 		}
 
+		info.Links, err = database.GetNoteLinks(db, info.UUID)
+		if err != nil {
+			return errors.Wrap(err, "getting links")
+		}
+
 		if contentOnly {
-			output.NoteContent(info)
-		} else {
-			output.NoteInfo(info)
+			return output.NoteContent(info)
 		}
 
-		return nil
+		return output.NoteInfo(info)
 	}
 }
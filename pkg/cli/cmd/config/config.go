@@ -0,0 +1,99 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * List the settings
+  dnote config list
+
+  * See where each setting comes from
+  dnote config list --show-origin
+
+  * Change a setting
+  dnote config set editor nvim
+
+  * Reset a setting to its default value
+  dnote config unset revisionLimit
+
+  * Edit the config file in a text editor
+  dnote config edit`
+
+func preRunNoArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func preRunOneArg(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func preRunTwoArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// getLong returns the long description listing the settings
+func getLong() string {
+	var sb strings.Builder
+
+	sb.WriteString("Read and change the settings. The environment variables take precedence over the config file.\n\nSettings:\n")
+	for _, f := range config.Fields {
+		sb.WriteString(fmt.Sprintf("  %-20s %s (%s)\n", f.Key, f.Description, f.Env))
+	}
+
+	return sb.String()
+}
+
+// NewCmd returns a new config command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Read and change the settings",
+		Long:    getLong(),
+		Example: example,
+	}
+
+	cmd.AddCommand(newGetCmd(ctx))
+	cmd.AddCommand(newSetCmd(ctx))
+	cmd.AddCommand(newUnsetCmd(ctx))
+	cmd.AddCommand(newListCmd(ctx))
+	cmd.AddCommand(newEditCmd(ctx))
+
+	return cmd
+}
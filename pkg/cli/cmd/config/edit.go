@@ -0,0 +1,83 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newEditCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "edit",
+		Short:   "Edit the config file in a text editor",
+		Long:    "Edit the config file in a text editor. The config file is changed only if the edited content is valid.",
+		PreRunE: preRunNoArgs,
+		RunE:    newEditRun(ctx),
+	}
+
+	return cmd
+}
+
+func newEditRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		path := config.GetPath(ctx)
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "reading the config file")
+		}
+
+		// Edit a copy so that an invalid content does not replace the config file
+		tmpPath := fmt.Sprintf("%s/%s/%s.yml", ctx.Paths.Cache, consts.DnoteDirName, consts.ConfigFilename)
+		if err := ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+			return errors.Wrap(err, "writing a copy of the config file")
+		}
+
+		content, err := ui.GetEditorInput(ctx, tmpPath)
+		if err != nil {
+			return errors.Wrap(err, "getting editor input")
+		}
+
+		cf, err := config.Parse([]byte(content))
+		if err != nil {
+			return errors.Wrap(err, "the config file was not changed")
+		}
+		if err := cf.Validate(); err != nil {
+			return errors.Wrap(err, "the config file was not changed")
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return errors.Wrap(err, "writing the config file")
+		}
+
+		log.Success("edited the config file\n")
+
+		return nil
+	}
+}
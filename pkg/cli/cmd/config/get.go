@@ -0,0 +1,78 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newGetCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "get <key>",
+		Short:   "Print the value of a setting in use",
+		PreRunE: preRunOneArg,
+		RunE:    newGetRun(ctx),
+	}
+
+	return cmd
+}
+
+func newGetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+
+		if _, err := config.GetField(key); err != nil {
+			return err
+		}
+
+		values, err := readValues(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range values {
+			if v.Key == key {
+				fmt.Println(v.Value)
+			}
+		}
+
+		return nil
+	}
+}
+
+// readValues reads the values of the settings in use
+func readValues(ctx context.DnoteCtx) ([]config.Value, error) {
+	cf, err := config.Read(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config")
+	}
+
+	_, values, err := config.Resolve(ctx, cf)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving config")
+	}
+
+	return values, nil
+}
@@ -0,0 +1,63 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/spf13/cobra"
+)
+
+var showOriginFlag bool
+
+func newListCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the settings in use",
+		PreRunE: preRunNoArgs,
+		RunE:    newListRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&showOriginFlag, "show-origin", "", false, "show where each value came from: the config file, a profile or an environment variable")
+
+	return cmd
+}
+
+func newListRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		values, err := readValues(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range values {
+			if showOriginFlag {
+				fmt.Printf("%s\t%s=%s\n", v.Origin, v.Key, v.Value)
+			} else {
+				fmt.Printf("%s=%s\n", v.Key, v.Value)
+			}
+		}
+
+		return nil
+	}
+}
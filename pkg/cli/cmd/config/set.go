@@ -0,0 +1,79 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"os"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newSetCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "set <key> <value>",
+		Short:   "Change a setting",
+		Long:    "Change a setting. The editor and the API endpoint are changed for the profile in use.",
+		PreRunE: preRunTwoArgs,
+		RunE:    newSetRun(ctx),
+	}
+
+	return cmd
+}
+
+// warnEnvOverride warns if the setting with the given key is overridden by an
+// environment variable
+func warnEnvOverride(key string) {
+	f, err := config.GetField(key)
+	if err != nil {
+		return
+	}
+
+	if v := os.Getenv(f.Env); v != "" {
+		log.Warnf("%s is overridden by %s=%s\n", key, f.Env, v)
+	}
+}
+
+func newSetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		if err := cf.Set(ctx.Profile, key, value); err != nil {
+			return err
+		}
+
+		if err := config.Write(ctx, cf); err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		log.Successf("set %s to %s\n", key, value)
+		warnEnvOverride(key)
+
+		return nil
+	}
+}
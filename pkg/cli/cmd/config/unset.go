@@ -0,0 +1,64 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package configcmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newUnsetCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "unset <key>",
+		Short:   "Reset a setting to its default value",
+		Long:    "Reset a setting to its default value. In a profile other than the default profile, the editor and the API endpoint fall back to those of the default profile.",
+		PreRunE: preRunOneArg,
+		RunE:    newUnsetRun(ctx),
+	}
+
+	return cmd
+}
+
+func newUnsetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		if err := cf.Unset(ctx.Profile, key); err != nil {
+			return err
+		}
+
+		if err := config.Write(ctx, cf); err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		log.Successf("unset %s\n", key)
+		warnEnvOverride(key)
+
+		return nil
+	}
+}
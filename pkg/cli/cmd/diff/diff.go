@@ -0,0 +1,144 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package diffcmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/utils/diff"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// timeLayout is the layout of the timestamp of the revision in the header
+const timeLayout = "Jan 2, 2006 3:04pm (MST)"
+
+var example = `
+ * See what changed in the note 12 since the revision 3
+ dnote diff 12 3`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new diff command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff <note id> <revision id>",
+		Short:   "Show the changes to a note since a revision",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+// splitLines splits the text into lines without the line breaks
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// formatDiff returns the lines of a line-by-line diff from the old to the new
+// text. Each line is prefixed with '-' if it was removed, '+' if it was added,
+// or a space if it was unchanged.
+func formatDiff(oldText, newText string) []string {
+	ret := []string{}
+
+	for _, d := range diff.Do(oldText, newText) {
+		var prefix string
+		switch d.Type {
+		case diff.DiffDelete:
+			prefix = "-"
+		case diff.DiffInsert:
+			prefix = "+"
+		default:
+			prefix = " "
+		}
+
+		for _, line := range splitLines(d.Text) {
+			ret = append(ret, prefix+line)
+		}
+	}
+
+	return ret
+}
+
+// printDiff prints the diff lines, removed lines in red and added lines in green
+func printDiff(w io.Writer, lines []string) {
+	for _, line := range lines {
+		switch line[0] {
+		case '-':
+			log.ColorRed.Fprintln(w, line)
+		case '+':
+			log.ColorGreen.Fprintln(w, line)
+		default:
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+		revID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.Wrap(err, "invalid revision id")
+		}
+
+		info, err := database.GetNoteInfo(ctx.DB, rowID)
+		if err != nil {
+			return err
+		}
+
+		rev, err := database.GetNoteRevision(ctx.DB, info.UUID, revID)
+		if err != nil {
+			return err
+		}
+
+		w := color.Output
+		log.ColorRed.Fprintf(w, "--- revision %d (%s)\n", rev.RowID, time.Unix(0, rev.EditedOn).Format(timeLayout))
+		log.ColorGreen.Fprintf(w, "+++ note %d\n", info.RowID)
+
+		printDiff(w, formatDiff(rev.Body, info.Content))
+
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package diffcmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestFormatDiff(t *testing.T) {
+	testCases := []struct {
+		oldText  string
+		newText  string
+		expected []string
+	}{
+		{
+			oldText:  "foo\nbar\n",
+			newText:  "foo\nbar\n",
+			expected: []string{" foo", " bar"},
+		},
+		{
+			oldText:  "foo\nbar\nbaz\n",
+			newText:  "foo\nqux\nbaz\n",
+			expected: []string{" foo", "-bar", "+qux", " baz"},
+		},
+		{
+			oldText:  "foo",
+			newText:  "foo\nbar",
+			expected: []string{"-foo", "+foo", "+bar"},
+		},
+		{
+			oldText:  "",
+			newText:  "foo\n\nbar\n",
+			expected: []string{"+foo", "+", "+bar"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, formatDiff(tc.oldText, tc.newText), tc.expected, "result mismatch")
+		})
+	}
+}
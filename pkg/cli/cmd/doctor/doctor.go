@@ -0,0 +1,150 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package doctorcmd
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/doctor"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Check the local database
+  dnote doctor
+
+  * Check the local database and repair the problems
+  dnote doctor --fix`
+
+var fixFlag bool
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func getLong() string {
+	ret := "Check the local database for inconsistencies and optionally repair them.\n\nChecks:\n"
+	for _, c := range doctor.Checks {
+		ret += fmt.Sprintf("  %-12s %s\n", c.Name, c.Description)
+	}
+
+	return ret
+}
+
+// NewCmd returns a new doctor command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the local database and repair it",
+		Long:    getLong(),
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&fixFlag, "fix", "", false, "repair the problems that can be fixed, after backing up the database")
+
+	return cmd
+}
+
+func printResults(results []doctor.Result) {
+	for _, r := range results {
+		if len(r.Problems) == 0 {
+			log.Successf("%s\n", r.Check.Name)
+			continue
+		}
+
+		log.Warnf("%s\n", r.Check.Name)
+		for _, p := range r.Problems {
+			log.Plainf("  %s\n", p.Message)
+		}
+	}
+}
+
+func fix(ctx context.DnoteCtx, results []doctor.Result) ([]doctor.Result, error) {
+	path, err := backup.Create(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "backing up the database")
+	}
+	if _, err := backup.Rotate(ctx, ctx.BackupLimit); err != nil {
+		return nil, errors.Wrap(err, "rotating the backups")
+	}
+	log.Infof("backed up the database to %s\n", path)
+
+	if err := doctor.Fix(ctx, results); err != nil {
+		return nil, err
+	}
+
+	ret, err := doctor.Run(ctx.DB)
+	if err != nil {
+		return nil, errors.Wrap(err, "checking the repaired database")
+	}
+
+	return ret, nil
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		results, err := doctor.Run(ctx.DB)
+		if err != nil {
+			return err
+		}
+
+		printResults(results)
+
+		total, fixable := doctor.CountProblems(results)
+		if total == 0 {
+			log.Successf("no problems found\n")
+			return nil
+		}
+
+		if !fixFlag {
+			if fixable > 0 {
+				log.Infof("run 'dnote doctor --fix' to fix %d of them\n", fixable)
+			}
+
+			return errors.Errorf("found %d problems", total)
+		}
+
+		results, err = fix(ctx, results)
+		if err != nil {
+			return err
+		}
+
+		remaining, _ := doctor.CountProblems(results)
+		log.Successf("fixed %d problems\n", total-remaining)
+
+		if remaining > 0 {
+			printResults(results)
+			return errors.Errorf("%d problems could not be fixed", remaining)
+		}
+
+		return nil
+	}
+}
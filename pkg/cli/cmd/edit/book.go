@@ -19,6 +19,7 @@
 package edit
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/dnote/dnote/pkg/cli/context"
@@ -37,6 +38,12 @@ func validateRunBookFlags() error {
 	if bookFlag != "" {
 		return errors.New("--book is invalid for editing a book")
 	}
+	if len(tagFlag) > 0 || len(removeTagFlag) > 0 {
+		return errors.New("--tag and --remove-tag are invalid for editing a book")
+	}
+	if nameFlag != "" && mergeIntoFlag != "" {
+		return errors.New("--name and --merge-into cannot be used together")
+	}
 
 	return nil
 }
@@ -78,6 +85,10 @@ func runBook(ctx context.DnoteCtx, bookName string) error {
 		return errors.Wrap(err, "validating flags.")
 	}
 
+	if mergeIntoFlag != "" {
+		return mergeBook(ctx, bookName, mergeIntoFlag)
+	}
+
 	db := ctx.DB
 	uuid, err := database.GetBookUUID(db, bookName)
 	if err != nil {
@@ -118,7 +129,59 @@ func runBook(ctx context.DnoteCtx, bookName string) error {
 	}
 
 	log.Success("edited the book\n")
-	output.BookInfo(bookInfo)
+	return output.BookInfo(bookInfo)
+}
 
-	return nil
+// mergeBook moves all notes in the source book to the destination book and
+// removes the source book
+func mergeBook(ctx context.DnoteCtx, srcName, dstName string) error {
+	db := ctx.DB
+
+	srcUUID, err := database.GetBookUUID(db, srcName)
+	if err != nil {
+		return errors.Wrap(err, "finding the source book")
+	}
+	dstUUID, err := database.GetBookUUID(db, dstName)
+	if err != nil {
+		return errors.Wrap(err, "finding the destination book")
+	}
+	if srcUUID == dstUUID {
+		return errors.New("cannot merge a book into itself")
+	}
+
+	if !yesFlag {
+		ok, err := ui.Confirm(fmt.Sprintf("merge '%s' into '%s'? '%s' will be removed", srcName, dstName, srcName), false)
+		if err != nil {
+			return errors.Wrap(err, "getting confirmation")
+		}
+		if !ok {
+			log.Warnf("aborted by user\n")
+			return nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	count, err := database.MergeBook(tx, ctx.Clock, srcUUID, dstUUID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "merging the book")
+	}
+
+	bookInfo, err := database.GetBookInfo(tx, dstUUID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "getting book info")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	log.Successf("merged %s into %s and moved %d notes\n", srcName, dstName, count)
+	return output.BookInfo(bookInfo)
 }
@@ -19,9 +19,14 @@
 package edit
 
 import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/find"
+	"github.com/dnote/dnote/pkg/cli/completion"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -30,45 +35,74 @@ import (
 var contentFlag string
 var bookFlag string
 var nameFlag string
+var tagFlag []string
+var removeTagFlag []string
+var queryFlag string
+var yesFlag bool
+var mergeIntoFlag string
 
 var example = `
   * Edit a note by id
   dnote edit 3
 
+  * Choose a note to edit with a fuzzy finder
+  dnote edit
+
   * Edit a note without launching an editor
   dnote edit 3 -c "new content"
 
   * Move a note to another book
   dnote edit 3 -b javascript
 
+  * Move several notes by ids and ranges of ids to another book
+  dnote edit 3 5 8-12 -b javascript
+
+  * Move the notes matching a search query to another book
+  dnote edit --query "book:js tag:es6" -b es6
+
+  * Add and remove tags without launching an editor
+  dnote edit 3 -t es6 --remove-tag draft
+
   * Rename a book
   dnote edit javascript
 
   * Rename a book without launching an editor
   dnote edit javascript -n js
+
+  * Merge a book into another book
+  dnote edit js --merge-into javascript
 `
 
 // NewCmd returns a new edit command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "edit <note id|book name>",
-		Short:   "Edit a note or a book",
-		Aliases: []string{"e"},
-		Example: example,
-		PreRunE: preRun,
-		RunE:    newRun(ctx),
+		Use:               "edit <note id...?|book name?>",
+		Short:             "Edit a note or a book",
+		Aliases:           []string{"e"},
+		Example:           example,
+		PreRunE:           preRun,
+		RunE:              newRun(ctx),
+		ValidArgsFunction: completion.BooksOrNotes(ctx, true),
 	}
 
 	f := cmd.Flags()
 	f.StringVarP(&contentFlag, "content", "c", "", "a new content for the note")
 	f.StringVarP(&bookFlag, "book", "b", "", "the name of the book to move the note to")
 	f.StringVarP(&nameFlag, "name", "n", "", "a new name for a book")
+	f.StringSliceVarP(&tagFlag, "tag", "t", []string{}, "a tag to add to the note. Can be repeated or separated by commas")
+	f.StringSliceVarP(&removeTagFlag, "remove-tag", "", []string{}, "a tag to remove from the note. Can be repeated or separated by commas")
+	f.StringVarP(&queryFlag, "query", "q", "", "move the notes matching the search query. See 'dnote find --help' for the syntax")
+	f.StringVarP(&mergeIntoFlag, "merge-into", "", "", "the name of the book to merge the book into")
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+
+	cmd.RegisterFlagCompletionFunc("book", completion.BookFlag(ctx))
+	cmd.RegisterFlagCompletionFunc("merge-into", completion.BookFlag(ctx))
 
 	return cmd
 }
 
 func preRun(cmd *cobra.Command, args []string) error {
-	if len(args) != 1 && len(args) != 2 {
+	if len(args) > 2 && !utils.IsBulkIDs(args) {
 		return errors.New("Incorrect number of argument")
 	}
 
@@ -77,6 +111,19 @@ func preRun(cmd *cobra.Command, args []string) error {
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		bulk, err := find.IsBulkArgs(ctx.DB, args)
+		if err != nil {
+			return errors.Wrap(err, "reading the arguments")
+		}
+
+		if queryFlag != "" || bulk {
+			if err := runNotes(ctx, args); err != nil {
+				return errors.Wrap(err, "editing notes")
+			}
+
+			return nil
+		}
+
 		// DEPRECATED: Remove in 1.0.0
 		if len(args) == 2 {
 			log.Plain(log.ColorYellow.Sprintf("DEPRECATED: you no longer need to pass book name to the view command. e.g. `dnote view 123`.\n\n"))
@@ -90,6 +137,22 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			return nil
 		}
 
+		if len(args) == 0 {
+			rowID, err := ui.PickNote(ctx, "")
+			if err == ui.ErrPickerCancelled {
+				log.Warnf("aborted by user\n")
+				return nil
+			} else if err != nil {
+				return errors.Wrap(err, "choosing a note")
+			}
+
+			if err := runNote(ctx, strconv.Itoa(rowID)); err != nil {
+				return errors.Wrap(err, "editing note")
+			}
+
+			return nil
+		}
+
 		target := args[0]
 
 		if utils.IsNumber(target) {
@@ -27,6 +27,7 @@ import (
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/tags"
 	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/pkg/errors"
 )
@@ -35,6 +36,14 @@ func validateRunNoteFlags() error {
 	if nameFlag != "" {
 		return errors.New("--name is invalid for editing a book")
 	}
+	if mergeIntoFlag != "" {
+		return errors.New("--merge-into is invalid for editing a note")
+	}
+	for _, t := range append(tagFlag, removeTagFlag...) {
+		if err := tags.Validate(t); err != nil {
+			return errors.Wrap(err, "invalid tag")
+		}
+	}
 
 	return nil
 }
@@ -75,7 +84,7 @@ func changeContent(ctx context.DnoteCtx, tx *database.DB, note database.Note, co
 		return errors.New("Nothing changed")
 	}
 
-	if err := database.UpdateNoteContent(tx, ctx.Clock, note.RowID, content); err != nil {
+	if err := database.UpdateNoteContent(tx, ctx.Clock, note.RowID, content, ctx.RevisionLimit); err != nil {
 		return errors.Wrap(err, "updating the note")
 	}
 
@@ -99,6 +108,54 @@ func moveBook(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookNam
 	return nil
 }
 
+// getNewTags returns the tags of the note after the edit. Hashtags removed from
+// the content are untagged and the ones added to it are tagged.
+func getNewTags(current []string, oldBody, newBody string, added, removed []string) ([]string, error) {
+	ret := current
+	if newBody != "" {
+		ret = tags.Union(tags.Difference(current, tags.Extract(oldBody)), tags.Extract(newBody))
+	} else {
+		newBody = oldBody
+	}
+
+	hashtags := tags.Extract(newBody)
+	for _, t := range tags.Normalize(removed) {
+		for _, h := range hashtags {
+			if t == h {
+				return nil, errors.Errorf("cannot remove the tag '%s' because it is a hashtag in the content", t)
+			}
+		}
+	}
+
+	return tags.Difference(tags.Union(ret, added), removed), nil
+}
+
+func changeTags(ctx context.DnoteCtx, tx *database.DB, note database.Note, content string) error {
+	current, err := database.GetNoteTags(tx, note.UUID)
+	if err != nil {
+		return errors.Wrap(err, "getting tags")
+	}
+
+	newTags, err := getNewTags(current, note.Body, content, tagFlag, removeTagFlag)
+	if err != nil {
+		return err
+	}
+
+	if tags.Equal(current, newTags) {
+		if content == "" && (len(tagFlag) > 0 || len(removeTagFlag) > 0) {
+			return errors.New("tags have not changed")
+		}
+
+		return nil
+	}
+
+	if err := database.UpdateNoteTags(tx, ctx.Clock, note.RowID, note.UUID, newTags); err != nil {
+		return errors.Wrap(err, "updating tags")
+	}
+
+	return nil
+}
+
 func updateNote(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookName, content string) error {
 	if bookName != "" {
 		if err := moveBook(ctx, tx, note, bookName); err != nil {
@@ -110,6 +167,11 @@ func updateNote(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookN
 			return errors.Wrap(err, "changing content")
 		}
 	}
+	if content != "" || len(tagFlag) > 0 || len(removeTagFlag) > 0 {
+		if err := changeTags(ctx, tx, note, content); err != nil {
+			return errors.Wrap(err, "changing tags")
+		}
+	}
 
 	return nil
 }
@@ -136,7 +198,7 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 	content := contentFlag
 
 	// If no flag was provided, launch an editor to get the content
-	if bookFlag == "" && contentFlag == "" {
+	if bookFlag == "" && contentFlag == "" && len(tagFlag) == 0 && len(removeTagFlag) == 0 {
 		c, err := getContent(ctx, note)
 		if err != nil {
 			return errors.Wrap(err, "getting content from editor")
@@ -169,7 +231,5 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 	}
 
 	log.Success("edited the note\n")
-	output.NoteInfo(noteInfo)
-
-	return nil
+	return output.NoteInfo(noteInfo)
 }
@@ -0,0 +1,131 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edit
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/find"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+)
+
+func validateRunNotesFlags() error {
+	if bookFlag == "" {
+		return errors.New("--book is required for editing multiple notes")
+	}
+	if contentFlag != "" || nameFlag != "" || mergeIntoFlag != "" || len(tagFlag) > 0 || len(removeTagFlag) > 0 {
+		return errors.New("only --book can be used for editing multiple notes")
+	}
+
+	return nil
+}
+
+// runNotes moves the notes with the given ids or matching the query to
+// the book given by the book flag
+func runNotes(ctx context.DnoteCtx, args []string) error {
+	if err := validateRunNotesFlags(); err != nil {
+		return errors.Wrap(err, "validating flags")
+	}
+
+	if queryFlag != "" && len(args) > 0 {
+		return errors.New("--query cannot be used with note ids")
+	}
+
+	db := ctx.DB
+
+	ranges, err := utils.ParseIDRanges(args)
+	if err != nil {
+		return err
+	}
+
+	targetBookUUID, err := database.GetBookUUID(db, bookFlag)
+	if err != nil {
+		return errors.Wrap(err, "finding book uuid")
+	}
+
+	notes, err := find.SelectNotes(db, ranges, queryFlag)
+	if err != nil {
+		return errors.Wrap(err, "finding notes")
+	}
+
+	items := []output.Note{}
+	for _, n := range notes {
+		if n.BookLabel == bookFlag {
+			continue
+		}
+
+		items = append(items, output.Note{
+			RowID:     n.RowID,
+			UUID:      n.UUID,
+			BookLabel: n.BookLabel,
+			Body:      n.Content,
+			AddedOn:   n.AddedOn,
+			EditedOn:  n.EditedOn,
+			Tags:      n.Tags,
+		})
+	}
+	if len(notes) == 0 {
+		return errors.New("no notes found")
+	}
+	if len(items) == 0 {
+		return errors.New("book has not changed")
+	}
+
+	if err := output.NoteList("", items); err != nil {
+		return errors.Wrap(err, "printing the notes")
+	}
+
+	if !yesFlag {
+		ok, err := ui.Confirm(fmt.Sprintf("move %d notes to %s?", len(items), bookFlag), false)
+		if err != nil {
+			return errors.Wrap(err, "getting confirmation")
+		}
+		if !ok {
+			log.Warnf("aborted by user\n")
+			return nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	for _, n := range items {
+		if err := database.UpdateNoteBook(tx, ctx.Clock, n.RowID, targetBookUUID); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "moving note %d", n.RowID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	log.Successf("moved %d notes to %s\n", len(items), bookFlag)
+
+	return nil
+}
@@ -0,0 +1,84 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package encryptioncmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var yesFlag bool
+
+func newDisableCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "disable",
+		Short:   "Store the database and the backups in plaintext",
+		PreRunE: preRunNoArgs,
+		RunE:    newDisableRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+
+	return cmd
+}
+
+func newDisableRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if !ctx.DB.Encrypted() {
+			return errors.New("the database is not encrypted")
+		}
+
+		if !yesFlag {
+			ok, err := ui.Confirm("store the notes in plaintext?", false)
+			if err != nil {
+				return errors.Wrap(err, "getting confirmation")
+			}
+			if !ok {
+				log.Warnf("aborted by user\n")
+				return nil
+			}
+		}
+
+		count, err := backup.DecryptAll(ctx)
+		if err != nil {
+			return errors.Wrap(err, "decrypting the backups")
+		}
+		if count > 0 {
+			log.Successf("decrypted %d backups\n", count)
+		}
+
+		if err := ctx.DB.Decrypt(); err != nil {
+			return errors.Wrap(err, "decrypting the database")
+		}
+		log.Successf("decrypted %s\n", ctx.DB.Filepath)
+
+		if err := agent.RemoveKey(agent.GetSocketPath(ctx.Paths), ctx.DB.Filepath); err != nil {
+			log.Warnf("the key could not be removed from the agent: %s\n", err.Error())
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package encryptioncmd
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var passphraseStdinFlag bool
+
+func newEnableCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "enable",
+		Short:   "Encrypt the database with a new passphrase",
+		PreRunE: preRunNoArgs,
+		RunE:    newEnableRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&passphraseStdinFlag, "passphrase-stdin", "", false, "Read the passphrase from the first line of the standard input")
+
+	return cmd
+}
+
+// readPassphrase reads a new passphrase from the terminal, asking for it twice,
+// or from the first line of the standard input
+func readPassphrase(fromStdin bool) (string, error) {
+	if fromStdin {
+		s := bufio.NewScanner(os.Stdin)
+		s.Scan()
+		if err := s.Err(); err != nil {
+			return "", errors.Wrap(err, "reading the standard input")
+		}
+
+		return s.Text(), nil
+	}
+
+	if !ui.IsTerminal() {
+		return "", errors.New("the passphrase is read from a terminal. Use --passphrase-stdin to read it from the standard input")
+	}
+
+	var passphrase, confirmation string
+	if err := ui.PromptPassword("new passphrase", &passphrase); err != nil {
+		return "", errors.Wrap(err, "getting the passphrase")
+	}
+	if err := ui.PromptPassword("confirm the passphrase", &confirmation); err != nil {
+		return "", errors.Wrap(err, "getting the confirmation")
+	}
+	if passphrase != confirmation {
+		return "", errors.New("the passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+func newEnableRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if ctx.DB.Encrypted() {
+			return errors.New("the database is already encrypted")
+		}
+
+		passphrase, err := readPassphrase(passphraseStdinFlag)
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return errors.New("the passphrase is empty")
+		}
+
+		header, err := database.NewEncryptionHeader(consts.EncryptionIteration)
+		if err != nil {
+			return err
+		}
+		key, err := header.DeriveKey(passphrase)
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.DB.Encrypt(header, key); err != nil {
+			return errors.Wrap(err, "encrypting the database")
+		}
+		log.Successf("encrypted %s\n", ctx.DB.Filepath)
+
+		count, err := backup.EncryptAll(ctx)
+		if err != nil {
+			return errors.Wrap(err, "encrypting the backups")
+		}
+		if count > 0 {
+			log.Successf("encrypted %d backups\n", count)
+		}
+
+		if ui.IsTerminal() {
+			if err := agent.AddKey(agent.GetSocketPath(ctx.Paths), ctx.DB.Filepath, key, consts.DefaultUnlockTimeout); err != nil {
+				log.Warnf("the key could not be kept for the session: %s\n", err.Error())
+			}
+		}
+
+		log.Warnf("the attachments are not encrypted. Keep the passphrase safe: the notes cannot be recovered without it\n")
+
+		return nil
+	}
+}
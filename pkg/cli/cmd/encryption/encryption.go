@@ -0,0 +1,119 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package encryptioncmd
+
+import (
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Encrypt the database
+  dnote encryption enable
+
+  * Keep the key for an hour
+  dnote encryption unlock --timeout 1h
+
+  * Forget the keys
+  dnote encryption lock
+
+  * Unlock the database for a script
+  dnote encryption unlock
+  export DNOTE_ENCRYPTION_KEY=$(dnote encryption unlock --print --yes)
+
+  * Store the notes in plaintext again
+  dnote encryption disable`
+
+func preRunNoArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func newCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encryption",
+		Short: "Encrypt the local database with a passphrase",
+		Long: `Encrypt the local database with a passphrase.
+
+The notes, books, revisions, trash, search index and backups are encrypted.
+The attachments are not. The passphrase is asked at most once in a session,
+and the key is kept by an agent in the background until it is locked or the
+unlock timeout passes.`,
+		Example: example,
+	}
+}
+
+// NewCmd returns a new encryption command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := newCmd()
+
+	cmd.AddCommand(newEnableCmd(ctx))
+	cmd.AddCommand(newDisableCmd(ctx))
+	cmd.AddCommand(newUnlockCmd(ctx))
+	cmd.AddCommand(newLockCmd(ctx.Paths))
+
+	return cmd
+}
+
+// NewStandaloneCmd returns an encryption command with the subcommands that run
+// without the context. See IsStandalone.
+func NewStandaloneCmd(paths context.Paths) *cobra.Command {
+	cmd := newCmd()
+
+	cmd.AddCommand(newLockCmd(paths))
+	cmd.AddCommand(newAgentCmd())
+
+	return cmd
+}
+
+// IsStandalone returns true if the given command line arguments run a command
+// that does not use the database. Locking and the agent run without the
+// context, which would ask for the passphrase of a locked database.
+func IsStandalone(args []string) bool {
+	var names []string
+	for i := 0; i < len(args) && len(names) < 2; i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			// The global flags that take a value
+			if arg == "--profile" || arg == "--output" {
+				i++
+			}
+			continue
+		}
+
+		names = append(names, arg)
+	}
+
+	if len(names) < 2 || names[0] != "encryption" {
+		return false
+	}
+
+	return names[1] == "lock" || names[1] == agent.CommandName
+}
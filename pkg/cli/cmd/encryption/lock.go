@@ -0,0 +1,73 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package encryptioncmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newLockCmd(paths context.Paths) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "lock",
+		Short:   "Forget the keys kept by the agent",
+		PreRunE: preRunNoArgs,
+		RunE:    newLockRun(paths),
+	}
+
+	return cmd
+}
+
+func newLockRun(paths context.Paths) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		ok, err := agent.Lock(agent.GetSocketPath(paths))
+		if err != nil {
+			return errors.Wrap(err, "locking")
+		}
+
+		if ok {
+			log.Success("locked\n")
+		} else {
+			log.Info("no key was kept\n")
+		}
+
+		return nil
+	}
+}
+
+func newAgentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    agent.CommandName + " <socket>",
+		Short:  "Run the agent keeping the keys",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("Incorrect number of argument")
+			}
+
+			return agent.Serve(args[0])
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,105 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package encryptioncmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var timeoutFlag time.Duration
+var printFlag bool
+
+func newUnlockCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Keep the key of the database for the session",
+		Long: fmt.Sprintf(`Keep the key of the database for the session.
+
+The key is kept by an agent in the background until the timeout passes or the
+database is locked. The agent listens on a socket that is protected only by its
+file permissions, so any process of the user can get the key while it is kept.
+
+With --print, the key is printed instead so that it can be set in %s
+for a script. The key decrypts the database without the passphrase, and it
+stays in the scrollback of the terminal, the output of the script or its logs.
+Printing it asks for confirmation, and needs --yes if the output is not a
+terminal.`, consts.EncryptionKeyEnvName),
+		PreRunE: preRunNoArgs,
+		RunE:    newUnlockRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.DurationVarP(&timeoutFlag, "timeout", "t", consts.DefaultUnlockTimeout, "The duration for which the key is kept")
+	f.BoolVarP(&printFlag, "print", "", false, "Print the key instead of keeping it")
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+
+	return cmd
+}
+
+func newUnlockRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if !ctx.DB.Encrypted() {
+			return errors.New("the database is not encrypted")
+		}
+
+		key := ctx.DB.EncryptionKey()
+		if printFlag {
+			if !yesFlag {
+				if !ui.IsTerminal() {
+					return errors.New("the output is not a terminal. Use --yes to print the key anyway")
+				}
+
+				log.Warnf("the key decrypts the database without the passphrase, and stays in the scrollback of the terminal\n")
+				ok, err := ui.Confirm("print the key?", false)
+				if err != nil {
+					return errors.Wrap(err, "getting confirmation")
+				}
+				if !ok {
+					log.Warnf("aborted by user\n")
+					return nil
+				}
+			}
+
+			fmt.Println(base64.StdEncoding.EncodeToString(key))
+			return nil
+		}
+
+		if timeoutFlag <= 0 {
+			return errors.New("the timeout must be positive")
+		}
+		if err := agent.AddKey(agent.GetSocketPath(ctx.Paths), ctx.DB.Filepath, key, timeoutFlag); err != nil {
+			return errors.Wrap(err, "keeping the key")
+		}
+
+		log.Successf("unlocked for %s\n", timeoutFlag)
+
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/completion"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+	formatNDJSON   = "ndjson"
+)
+
+// sinceLayout is the layout of the date accepted by the --since flag
+const sinceLayout = "2006-01-02"
+
+var example = `
+  * Export all notes as a Markdown directory tree
+  dnote export ./notes
+
+  * Export the notes in a book
+  dnote export ./notes -b linux
+
+  * Export the notes added or edited since a date
+  dnote export ./notes --since 2024-01-01
+
+  * Print all notes as newline delimited JSON
+  dnote export --format ndjson | jq .content
+`
+
+var bookFlag string
+var sinceFlag string
+var formatFlag string
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	switch formatFlag {
+	case formatMarkdown:
+		if len(args) == 0 {
+			return errors.New("Missing the directory to export to")
+		}
+	case formatJSON, formatNDJSON:
+	default:
+		return errors.Errorf("Unsupported format '%s'", formatFlag)
+	}
+
+	return nil
+}
+
+// NewCmd returns a new export command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "export <path?>",
+		Short:   "Export notes as Markdown files or JSON",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&bookFlag, "book", "b", "", "the name of the book to export")
+	cmd.RegisterFlagCompletionFunc("book", completion.BookFlag(ctx))
+	f.StringVarP(&sinceFlag, "since", "", "", "only export notes added or edited on or after the date (YYYY-MM-DD)")
+	f.StringVarP(&formatFlag, "format", "f", formatMarkdown, "the export format. One of markdown, json, ndjson")
+
+	return cmd
+}
+
+// exportNote is a note as represented in an export
+type exportNote struct {
+	UUID      string   `json:"uuid"`
+	BookUUID  string   `json:"book_uuid"`
+	BookLabel string   `json:"book_label"`
+	Body      string   `json:"content"`
+	AddedOn   int64    `json:"added_on"`
+	EditedOn  int64    `json:"edited_on"`
+	Public    bool     `json:"public"`
+	Tags      []string `json:"tags"`
+}
+
+// exportParams is the set of filters for the notes to export
+type exportParams struct {
+	BookLabel string
+	Since     int64
+}
+
+// parseSince parses the value of the --since flag into a unix timestamp in nanoseconds
+func parseSince(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	t, err := time.ParseInLocation(sinceLayout, s, time.Local)
+	if err != nil {
+		return 0, errors.Errorf("invalid date '%s'. Use the format YYYY-MM-DD", s)
+	}
+
+	return t.UnixNano(), nil
+}
+
+// getNotes returns all active notes that satisfy the given params, ordered
+// by book and by the time they were added.
+func getNotes(db *database.DB, p exportParams) ([]exportNote, error) {
+	query := fmt.Sprintf(`SELECT notes.uuid, notes.book_uuid, books.label, notes.body, notes.added_on, notes.edited_on, notes.public, %s
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.deleted = false AND books.deleted = false`, database.NoteTagsColumn)
+	args := []interface{}{}
+
+	if p.BookLabel != "" {
+		cond, bookArgs := database.BookLabelFilter(p.BookLabel)
+		query = fmt.Sprintf("%s AND %s", query, cond)
+		args = append(args, bookArgs...)
+	}
+	if p.Since != 0 {
+		query = fmt.Sprintf("%s AND max(notes.added_on, notes.edited_on) >= ?", query)
+		args = append(args, p.Since)
+	}
+
+	query = fmt.Sprintf("%s ORDER BY books.label ASC, notes.added_on ASC", query)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []exportNote{}
+	for rows.Next() {
+		var n exportNote
+		var tags string
+		if err := rows.Scan(&n.UUID, &n.BookUUID, &n.BookLabel, &n.Body, &n.AddedOn, &n.EditedOn, &n.Public, &tags); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+		n.Tags = database.SplitTags(tags)
+
+		ret = append(ret, n)
+	}
+
+	return ret, nil
+}
+
+// writeJSON writes the notes to the writer as a single JSON array
+func writeJSON(w io.Writer, notes []exportNote) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(notes); err != nil {
+		return errors.Wrap(err, "encoding notes")
+	}
+
+	return nil
+}
+
+// writeNDJSON writes the notes to the writer, one JSON object per line
+func writeNDJSON(w io.Writer, notes []exportNote) error {
+	enc := json.NewEncoder(w)
+
+	for _, n := range notes {
+		if err := enc.Encode(n); err != nil {
+			return errors.Wrapf(err, "encoding note %s", n.UUID)
+		}
+	}
+
+	return nil
+}
+
+// exportJSON writes the notes in either JSON or NDJSON to the file at the given
+// path. If path is empty, it writes to the standard output.
+func exportJSON(notes []exportNote, path, format string) error {
+	var w io.Writer = os.Stdout
+
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return errors.Wrapf(err, "creating %s", path)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	if format == formatNDJSON {
+		return writeNDJSON(w, notes)
+	}
+
+	return writeJSON(w, notes)
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		since, err := parseSince(sinceFlag)
+		if err != nil {
+			return err
+		}
+
+		if bookFlag != "" {
+			if _, err := database.GetBookUUID(ctx.DB, bookFlag); err != nil {
+				return errors.Wrap(err, "finding the book")
+			}
+		}
+
+		notes, err := getNotes(ctx.DB, exportParams{
+			BookLabel: bookFlag,
+			Since:     since,
+		})
+		if err != nil {
+			return errors.Wrap(err, "getting notes")
+		}
+
+		var path string
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		if formatFlag != formatMarkdown {
+			if err := exportJSON(notes, path, formatFlag); err != nil {
+				return errors.Wrap(err, "exporting notes")
+			}
+
+			if path != "" {
+				log.Successf("exported %d notes to %s\n", len(notes), path)
+			}
+
+			return nil
+		}
+
+		if err := exportMarkdown(notes, path); err != nil {
+			return errors.Wrap(err, "exporting notes")
+		}
+
+		log.Successf("exported %d notes to %s\n", len(notes), path)
+
+		return nil
+	}
+}
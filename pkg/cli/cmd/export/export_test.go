@@ -0,0 +1,134 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+var dbPath = "../../tmp/dnote-test.db"
+
+func setupNotes(t *testing.T, db *database.DB) {
+	database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "linux")
+	database.MustExec(t, "setting up book 3", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b3-uuid", "deleted-book", true)
+
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on) VALUES (?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 100, 0)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, public) VALUES (?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 200, 500, true)
+	database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3 body", 300, 0)
+	database.MustExec(t, "setting up note 4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n4-uuid", "b2-uuid", "", 400, true)
+	database.MustExec(t, "setting up note 5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "b3-uuid", "n5 body", 500)
+}
+
+func getUUIDs(notes []exportNote) []string {
+	ret := []string{}
+	for _, n := range notes {
+		ret = append(ret, n.UUID)
+	}
+
+	return ret
+}
+
+func TestGetNotes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		params   exportParams
+		expected []string
+	}{
+		{
+			name:     "all",
+			params:   exportParams{},
+			expected: []string{"n1-uuid", "n2-uuid", "n3-uuid"},
+		},
+		{
+			name:     "book",
+			params:   exportParams{BookLabel: "linux"},
+			expected: []string{"n3-uuid"},
+		},
+		{
+			name:     "since",
+			params:   exportParams{Since: 250},
+			expected: []string{"n2-uuid", "n3-uuid"},
+		},
+		{
+			name:     "book and since",
+			params:   exportParams{BookLabel: "js", Since: 400},
+			expected: []string{"n2-uuid"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// set up
+			db := database.InitTestDB(t, dbPath, nil)
+			defer database.TeardownTestDB(t, db)
+			setupNotes(t, db)
+
+			// execute
+			got, err := getNotes(db, tc.params)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			// test
+			assert.DeepEqual(t, getUUIDs(got), tc.expected, "uuids mismatch")
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	got, err := parseSince("2024-01-02")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "parsing"))
+	}
+	assert.Equal(t, got, time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local).UnixNano(), "result mismatch")
+
+	got, err = parseSince("")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "parsing empty"))
+	}
+	assert.Equal(t, got, int64(0), "empty result mismatch")
+
+	if _, err := parseSince("01/02/2024"); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	notes := []exportNote{
+		{UUID: "n1-uuid", BookUUID: "b1-uuid", BookLabel: "js", Body: "n1 body", AddedOn: 1, EditedOn: 2, Tags: []string{"es6"}},
+		{UUID: "n2-uuid", BookUUID: "b1-uuid", BookLabel: "js", Body: "n2\nbody", AddedOn: 3, Public: true, Tags: []string{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, notes); err != nil {
+		t.Fatal(errors.Wrap(err, "writing"))
+	}
+
+	expected := `{"uuid":"n1-uuid","book_uuid":"b1-uuid","book_label":"js","content":"n1 body","added_on":1,"edited_on":2,"public":false,"tags":["es6"]}
+{"uuid":"n2-uuid","book_uuid":"b1-uuid","book_label":"js","content":"n2\nbody","added_on":3,"edited_on":0,"public":true,"tags":[]}
+`
+	assert.Equal(t, buf.String(), expected, "output mismatch")
+}
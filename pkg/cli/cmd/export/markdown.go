@@ -0,0 +1,153 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatterDelimiter is the line that opens and closes the YAML front matter
+const frontMatterDelimiter = "---"
+
+// maxSlugLength is the maximum length of the title part of a note filename
+const maxSlugLength = 50
+
+// frontMatter is the metadata of a note written at the top of an exported
+// Markdown file
+type frontMatter struct {
+	UUID     string   `yaml:"uuid"`
+	AddedOn  int64    `yaml:"added_on"`
+	EditedOn int64    `yaml:"edited_on"`
+	Public   bool     `yaml:"public"`
+	Tags     []string `yaml:"tags,omitempty,flow"`
+}
+
+var slugInvalidReg = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// slugify turns the first line of the given note body into a string that is
+// safe to use in a filename
+func slugify(body string) string {
+	firstLine := strings.TrimSpace(strings.SplitN(body, "\n", 2)[0])
+
+	s := slugInvalidReg.ReplaceAllString(strings.ToLower(firstLine), "-")
+	s = strings.Trim(s, "-")
+
+	runes := []rune(s)
+	if len(runes) > maxSlugLength {
+		s = strings.TrimRight(string(runes[:maxSlugLength]), "-")
+	}
+
+	return s
+}
+
+// getNoteFilename returns the filename for the note. The uuid prefix keeps
+// filenames unique within a book even if titles collide.
+func getNoteFilename(n exportNote) string {
+	shortUUID := n.UUID
+	if len(shortUUID) > 8 {
+		shortUUID = shortUUID[:8]
+	}
+
+	slug := slugify(n.Body)
+	if slug == "" {
+		return fmt.Sprintf("%s.md", shortUUID)
+	}
+
+	return fmt.Sprintf("%s-%s.md", slug, shortUUID)
+}
+
+// getBookDir returns the directory relative to the export root for the book
+// with the given label. Path segments that could escape the export root are
+// dropped.
+func getBookDir(label string) string {
+	var parts []string
+
+	for _, seg := range strings.Split(label, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			continue
+		}
+
+		parts = append(parts, seg)
+	}
+
+	return filepath.Join(parts...)
+}
+
+// renderMarkdown returns the content of the Markdown file for the note
+func renderMarkdown(n exportNote) ([]byte, error) {
+	fm := frontMatter{
+		UUID:     n.UUID,
+		AddedOn:  n.AddedOn,
+		EditedOn: n.EditedOn,
+		Public:   n.Public,
+		Tags:     n.Tags,
+	}
+
+	b, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling front matter")
+	}
+
+	var buf strings.Builder
+	buf.WriteString(frontMatterDelimiter + "\n")
+	buf.Write(b)
+	buf.WriteString(frontMatterDelimiter + "\n")
+	buf.WriteString(n.Body)
+
+	if !strings.HasSuffix(n.Body, "\n") {
+		buf.WriteString("\n")
+	}
+
+	return []byte(buf.String()), nil
+}
+
+// exportMarkdown writes the notes under the root directory, creating one
+// directory per book and one Markdown file per note
+func exportMarkdown(notes []exportNote, root string) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return errors.Wrapf(err, "creating %s", root)
+	}
+
+	for _, n := range notes {
+		dir := filepath.Join(root, getBookDir(n.BookLabel))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrapf(err, "creating the directory for book %s", n.BookLabel)
+		}
+
+		b, err := renderMarkdown(n)
+		if err != nil {
+			return errors.Wrapf(err, "rendering note %s", n.UUID)
+		}
+
+		path := filepath.Join(dir, getNoteFilename(n))
+		if err := ioutil.WriteFile(path, b, 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestSlugify(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "Booleans have toString()",
+			expected: "booleans-have-tostring",
+		},
+		{
+			input:    "# Merge sort\n\nsplit the list in halves",
+			expected: "merge-sort",
+		},
+		{
+			input:    "  ",
+			expected: "",
+		},
+		{
+			input:    "Über café 123",
+			expected: "über-café-123",
+		},
+		{
+			input:    "a very long title that goes on and on and on without ever seeming to end",
+			expected: "a-very-long-title-that-goes-on-and-on-and-on-witho",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("input %s", tc.input), func(t *testing.T) {
+			assert.Equal(t, slugify(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetBookDir(t *testing.T) {
+	testCases := []struct {
+		label    string
+		expected string
+	}{
+		{
+			label:    "js",
+			expected: "js",
+		},
+		{
+			label:    "work/infra/k8s",
+			expected: filepath.Join("work", "infra", "k8s"),
+		},
+		{
+			label:    "../../etc",
+			expected: "etc",
+		},
+		{
+			label:    "/abs//path/",
+			expected: filepath.Join("abs", "path"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("label %s", tc.label), func(t *testing.T) {
+			assert.Equal(t, getBookDir(tc.label), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetNoteFilename(t *testing.T) {
+	testCases := []struct {
+		note     exportNote
+		expected string
+	}{
+		{
+			note:     exportNote{UUID: "43827b9a-c2b0-4c06-a290-97991c896653", Body: "Booleans have toString()"},
+			expected: "booleans-have-tostring-43827b9a.md",
+		},
+		{
+			note:     exportNote{UUID: "43827b9a-c2b0-4c06-a290-97991c896653", Body: "***"},
+			expected: "43827b9a.md",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("body %s", tc.note.Body), func(t *testing.T) {
+			assert.Equal(t, getNoteFilename(tc.note), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	n := exportNote{
+		UUID:      "43827b9a-c2b0-4c06-a290-97991c896653",
+		BookLabel: "js",
+		Body:      "Booleans have toString()",
+		AddedOn:   1515199943000000000,
+		EditedOn:  1515199951000000000,
+		Public:    true,
+	}
+
+	b, err := renderMarkdown(n)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "rendering"))
+	}
+
+	expected := `---
+uuid: 43827b9a-c2b0-4c06-a290-97991c896653
+added_on: 1515199943000000000
+edited_on: 1515199951000000000
+public: true
+---
+Booleans have toString()
+`
+	assert.Equal(t, string(b), expected, "content mismatch")
+}
+
+func TestExportMarkdown(t *testing.T) {
+	root := "../../tmp/export"
+	defer os.RemoveAll(root)
+
+	notes := []exportNote{
+		{UUID: "n1-uuid-000", BookLabel: "js", Body: "n1 body"},
+		{UUID: "n2-uuid-000", BookLabel: "work/infra", Body: "n2 body\n"},
+	}
+
+	if err := exportMarkdown(notes, root); err != nil {
+		t.Fatal(errors.Wrap(err, "exporting"))
+	}
+
+	b1, err := ioutil.ReadFile(filepath.Join(root, "js", "n1-body-n1-uuid-.md"))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading n1"))
+	}
+	b2, err := ioutil.ReadFile(filepath.Join(root, "work", "infra", "n2-body-n2-uuid-.md"))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading n2"))
+	}
+
+	assert.Equal(t, string(b1), "---\nuuid: n1-uuid-000\nadded_on: 0\nedited_on: 0\npublic: false\n---\nn1 body\n", "n1 content mismatch")
+	assert.Equal(t, string(b2), "---\nuuid: n2-uuid-000\nadded_on: 0\nedited_on: 0\npublic: false\n---\nn2 body\n", "n2 content mismatch")
+}
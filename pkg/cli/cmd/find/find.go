@@ -23,9 +23,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/dnote/dnote/pkg/cli/completion"
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/tags"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -39,13 +43,70 @@ var example = `
 
 	# find notes within a book
 	dnote find "merge sort" -b algorithm
+
+	# find notes within a book and the books nested in it
+	dnote find "deployment" -b work/
+
+	# find notes with a tag
+	dnote find "merge sort" -t interview
+
+	# find notes by an exact phrase
+	dnote find '"merge sort"'
+
+	# find notes with either keyword, excluding a keyword
+	dnote find "(heap OR queue) -priority"
+
+	# find notes by a prefix
+	dnote find "merg*"
+
+	# find notes using filters
+	dnote find "sort book:algorithm tag:interview after:2024-01-01 before:2025-01-01 public:false"
+
+	# show the 10 most recent matches
+	dnote find sort --sort recent --limit 10
+
+	# show the next page of results with larger snippets
+	dnote find sort --limit 10 --offset 10 --context 40
 	`
 
+const (
+	// sortRank orders the results by relevance
+	sortRank = "rank"
+	// sortRecent orders the results by the newest first
+	sortRecent = "recent"
+	// sortOldest orders the results by the oldest first
+	sortOldest = "oldest"
+)
+
+// maxContext is the maximum number of tokens in a snippet supported by FTS5
+const maxContext = 64
+
 var bookName string
+var tagFlag []string
+var sortFlag string
+var limitFlag int
+var offsetFlag int
+var contextFlag int
 
 func preRun(cmd *cobra.Command, args []string) error {
-	if len(args) != 1 {
-		return errors.New("Incorrect number of argument")
+	if len(args) < 1 {
+		return errors.New("Missing query")
+	}
+
+	switch sortFlag {
+	case sortRank, sortRecent, sortOldest:
+	default:
+		return errors.Errorf("invalid sort '%s'. Use one of: %s, %s, %s", sortFlag, sortRank, sortRecent, sortOldest)
+	}
+
+	if limitFlag < 0 {
+		return errors.New("--limit cannot be negative")
+	}
+	if offsetFlag < 0 {
+		return errors.New("--offset cannot be negative")
+	}
+	if contextFlag < 1 || contextFlag > maxContext {
+		return errors.Errorf("--context must be between 1 and %d", maxContext)
 	}
 
 	return nil
@@ -55,7 +116,7 @@ func preRun(cmd *cobra.Command, args []string) error {
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "find",
-		Short:   "Find notes by keywords",
+		Short:   "Find notes by a search query",
 		Aliases: []string{"f"},
 		Example: example,
 		PreRunE: preRun,
@@ -63,39 +124,34 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	}
 
 	f := cmd.Flags()
-	f.StringVarP(&bookName, "book", "b", "", "book name to find notes in")
+	f.StringVarP(&bookName, "book", "b", "", "book name to find notes in. A name ending with '/' such as 'work/' includes the nested books")
+	cmd.RegisterFlagCompletionFunc("book", completion.BookFlag(ctx))
+	f.StringSliceVarP(&tagFlag, "tag", "t", []string{}, "find only the notes with the tag. Can be repeated to require all tags")
+	f.StringVar(&sortFlag, "sort", sortRank, "order of the results: rank, recent or oldest")
+	f.IntVar(&limitFlag, "limit", 0, "maximum number of results to show. 0 shows all results")
+	f.IntVar(&offsetFlag, "offset", 0, "number of results to skip")
+	f.IntVar(&contextFlag, "context", 28, fmt.Sprintf("number of tokens in each snippet, up to %d", maxContext))
 
 	return cmd
 }
 
-// noteInfo is an information about the note to be printed on screen
-type noteInfo struct {
-	RowID     int
-	BookLabel string
-	Body      string
-}
-
-// formatFTSSnippet turns the matched snippet from a full text search
-// into a format suitable for CLI output
-func formatFTSSnippet(s string) (string, error) {
+// renderFTSSnippet turns the matched snippet from a full text search
+// into a single line string, applying the highlight function to the matched terms
+func renderFTSSnippet(s string, highlight func(string) string) (string, error) {
 	// first, strip all new lines
 	body := newLineReg.ReplaceAllString(s, " ")
 
-	var format, buf strings.Builder
-	var args []interface{}
+	var ret, buf strings.Builder
 
 	toks := tokenize(body)
 
 	for _, tok := range toks {
 		if tok.Kind == tokenKindHLBegin || tok.Kind == tokenKindEOL {
-			format.WriteString("%s")
-			args = append(args, buf.String())
+			ret.WriteString(buf.String())
 
 			buf.Reset()
 		} else if tok.Kind == tokenKindHLEnd {
-			format.WriteString("%s")
-			str := log.ColorYellow.Sprintf("%s", buf.String())
-			args = append(args, str)
+			ret.WriteString(highlight(buf.String()))
 
 			buf.Reset()
 		} else {
@@ -105,47 +161,90 @@ func formatFTSSnippet(s string) (string, error) {
 		}
 	}
 
-	return fmt.Sprintf(format.String(), args...), nil
+	return ret.String(), nil
 }
 
-// escapePhrase escapes the user-supplied FTS keywords by wrapping each term around
-// double quotations so that they are treated as 'strings' as defined by SQLite FTS5.
-func escapePhrase(s string) (string, error) {
-	var b strings.Builder
+// formatFTSSnippet turns the matched snippet from a full text search
+// into a format suitable for CLI output
+func formatFTSSnippet(s string) (string, error) {
+	return renderFTSSnippet(s, func(term string) string {
+		return log.ColorYellow.Sprintf("%s", term)
+	})
+}
 
-	terms := strings.Fields(s)
+// plainFTSSnippet turns the matched snippet from a full text search
+// into plain text without highlights
+func plainFTSSnippet(s string) (string, error) {
+	return renderFTSSnippet(s, func(term string) string {
+		return term
+	})
+}
 
-	for idx, term := range terms {
-		if _, err := b.WriteString(fmt.Sprintf("\"%s\"", term)); err != nil {
-			return "", errors.Wrap(err, "writing string to builder")
-		}
+// searchParams is the parameters of a search
+type searchParams struct {
+	Query    compiledQuery
+	BookName string
+	Tags     []string
+	Sort     string
+	Limit    int
+	Offset   int
+	Context  int
+}
 
-		if idx != len(term)-1 {
-			if err := b.WriteByte(' '); err != nil {
-				return "", errors.Wrap(err, "writing space to builder")
-			}
-		}
+// getOrderClause returns the ORDER BY clause for the given sort
+func getOrderClause(sort string) string {
+	switch sort {
+	case sortRecent:
+		return "ORDER BY notes.added_on DESC, notes.rowid DESC"
+	case sortOldest:
+		return "ORDER BY notes.added_on ASC, notes.rowid ASC"
+	default:
+		// bm25 returns lower values for better matches
+		return "ORDER BY bm25(note_fts), notes.added_on DESC"
 	}
-
-	return b.String(), nil
 }
 
-func doQuery(ctx context.DnoteCtx, query, bookName string) (*sql.Rows, error) {
+func doQuery(ctx context.DnoteCtx, params searchParams) (*sql.Rows, error) {
 	db := ctx.DB
 
 	sql := `SELECT
 		notes.rowid,
+		notes.uuid,
 		books.label AS book_label,
-		snippet(note_fts, 0, '<dnotehl>', '</dnotehl>', '...', 28)
+		snippet(note_fts, 0, '<dnotehl>', '</dnotehl>', '...', ?)
 	FROM note_fts
 	INNER JOIN notes ON notes.rowid = note_fts.rowid
 	INNER JOIN books ON notes.book_uuid = books.uuid
 	WHERE note_fts MATCH ?`
-	args := []interface{}{query}
+	args := []interface{}{params.Context, params.Query.Match}
+
+	for _, cond := range params.Query.Conditions {
+		sql = fmt.Sprintf("%s AND %s", sql, cond)
+	}
+	args = append(args, params.Query.Args...)
 
-	if bookName != "" {
-		sql = fmt.Sprintf("%s AND books.label = ?", sql)
-		args = append(args, bookName)
+	if params.BookName != "" {
+		cond, bookArgs := database.BookLabelFilter(params.BookName)
+		sql = fmt.Sprintf("%s AND %s", sql, cond)
+		args = append(args, bookArgs...)
+	}
+	if len(params.Tags) > 0 {
+		cond, tagArgs := database.NoteTagsFilter(params.Tags)
+		sql = fmt.Sprintf("%s AND %s", sql, cond)
+		args = append(args, tagArgs...)
+	}
+
+	sql = fmt.Sprintf("%s %s", sql, getOrderClause(params.Sort))
+
+	if params.Limit > 0 || params.Offset > 0 {
+		// a negative limit means no limit in SQLite
+		limit := -1
+		if params.Limit > 0 {
+			limit = params.Limit
+		}
+
+		sql = fmt.Sprintf("%s LIMIT ? OFFSET ?", sql)
+		args = append(args, limit, params.Offset)
 	}
 
 	rows, err := db.Query(sql, args...)
@@ -155,44 +254,47 @@ func doQuery(ctx context.DnoteCtx, query, bookName string) (*sql.Rows, error) {
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		phrase, err := escapePhrase(args[0])
+		query, err := parseQuery(strings.Join(args, " "))
 		if err != nil {
-			return errors.Wrap(err, "escaping phrase")
+			return errors.Wrap(err, "parsing the query")
 		}
 
-		rows, err := doQuery(ctx, phrase, bookName)
+		rows, err := doQuery(ctx, searchParams{
+			Query:    query,
+			BookName: bookName,
+			Tags:     tags.Normalize(tagFlag),
+			Sort:     sortFlag,
+			Limit:    limitFlag,
+			Offset:   offsetFlag,
+			Context:  contextFlag,
+		})
 		if err != nil {
 			return errors.Wrap(err, "querying notes")
 		}
 		defer rows.Close()
 
-		infos := []noteInfo{}
+		results := []output.FindResult{}
 		for rows.Next() {
-			var info noteInfo
+			var r output.FindResult
 
-			var body string
-			err = rows.Scan(&info.RowID, &info.BookLabel, &body)
+			var snippet string
+			err = rows.Scan(&r.RowID, &r.UUID, &r.BookLabel, &snippet)
 			if err != nil {
 				return errors.Wrap(err, "scanning a row")
 			}
 
-			body, err := formatFTSSnippet(body)
+			r.Highlighted, err = formatFTSSnippet(snippet)
+			if err != nil {
+				return errors.Wrap(err, "formatting a body")
+			}
+			r.Snippet, err = plainFTSSnippet(snippet)
 			if err != nil {
 				return errors.Wrap(err, "formatting a body")
 			}
 
-			info.Body = body
-
-			infos = append(infos, info)
-		}
-
-		for _, info := range infos {
-			bookLabel := log.ColorYellow.Sprintf("(%s)", info.BookLabel)
-			rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
-
-			log.Plainf("%s %s %s\n", bookLabel, rowid, info.Body)
+			results = append(results, r)
 		}
 
-		return nil
+		return output.FindResults(results)
 	}
 }
@@ -0,0 +1,202 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestDoQuery(t *testing.T) {
+	db := database.InitTestDB(t, "../../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	b1UUID := "b1-uuid"
+	b2UUID := "b2-uuid"
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "algo")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b2UUID, "linux")
+
+	d := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).UnixNano()
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, public) VALUES (?, ?, ?, ?, ?)", "n1-uuid", b1UUID, "merge sort is stable", d-1, false)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, public) VALUES (?, ?, ?, ?, ?)", "n2-uuid", b1UUID, "quick sort is not stable", d, true)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, public) VALUES (?, ?, ?, ?, ?)", "n3-uuid", b2UUID, "sort a file with the sort command", d+1, false)
+	database.MustExec(t, "inserting n3 tag", db, "INSERT INTO note_tags (note_uuid, tag) VALUES (?, ?)", "n3-uuid", "cli")
+
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "sort", expected: []string{"n1-uuid", "n2-uuid", "n3-uuid"}},
+		{input: `"sort is"`, expected: []string{"n1-uuid", "n2-uuid"}},
+		{input: "sort -stable", expected: []string{"n3-uuid"}},
+		{input: "merge OR quick", expected: []string{"n1-uuid", "n2-uuid"}},
+		{input: "(merge OR command) stable", expected: []string{"n1-uuid"}},
+		{input: "merg*", expected: []string{"n1-uuid"}},
+		{input: "sort book:algo", expected: []string{"n1-uuid", "n2-uuid"}},
+		{input: "sort -book:algo", expected: []string{"n3-uuid"}},
+		{input: "sort tag:cli", expected: []string{"n3-uuid"}},
+		{input: "sort after:2024-01-01", expected: []string{"n2-uuid", "n3-uuid"}},
+		{input: "sort before:2024-01-01", expected: []string{"n1-uuid"}},
+		{input: "sort public:true", expected: []string{"n2-uuid"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			query, err := parseQuery(tc.input)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "parsing"))
+			}
+
+			rows, err := doQuery(context.DnoteCtx{DB: db}, searchParams{
+				Query:   query,
+				Sort:    sortOldest,
+				Context: 28,
+			})
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "querying"))
+			}
+			defer rows.Close()
+
+			uuids := []string{}
+			for rows.Next() {
+				var rowID int
+				var uuid, bookLabel, snippet string
+				if err := rows.Scan(&rowID, &uuid, &bookLabel, &snippet); err != nil {
+					t.Fatal(errors.Wrap(err, "scanning"))
+				}
+				uuids = append(uuids, uuid)
+			}
+
+			assert.DeepEqual(t, uuids, tc.expected, fmt.Sprintf("result mismatch for %s", tc.input))
+		})
+	}
+}
+
+func TestDoQuery_order(t *testing.T) {
+	db := database.InitTestDB(t, "../../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	bUUID := "b1-uuid"
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", bUUID, "algo")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", bUUID, "a heap is a tree used to implement a priority queue", 1)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", bUUID, "heap heap heap", 3)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", bUUID, "building a heap takes linear time", 2)
+
+	testCases := []struct {
+		sort     string
+		limit    int
+		offset   int
+		expected []string
+	}{
+		{sort: sortRank, expected: []string{"n2-uuid", "n3-uuid", "n1-uuid"}},
+		{sort: sortRecent, expected: []string{"n2-uuid", "n3-uuid", "n1-uuid"}},
+		{sort: sortOldest, expected: []string{"n1-uuid", "n3-uuid", "n2-uuid"}},
+		{sort: sortOldest, limit: 2, expected: []string{"n1-uuid", "n3-uuid"}},
+		{sort: sortOldest, limit: 1, offset: 1, expected: []string{"n3-uuid"}},
+		{sort: sortOldest, offset: 2, expected: []string{"n2-uuid"}},
+		{sort: sortOldest, offset: 3, expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s limit %d offset %d", tc.sort, tc.limit, tc.offset), func(t *testing.T) {
+			query, err := parseQuery("heap")
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "parsing"))
+			}
+
+			rows, err := doQuery(context.DnoteCtx{DB: db}, searchParams{
+				Query:   query,
+				Sort:    tc.sort,
+				Limit:   tc.limit,
+				Offset:  tc.offset,
+				Context: 28,
+			})
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "querying"))
+			}
+			defer rows.Close()
+
+			uuids := []string{}
+			for rows.Next() {
+				var rowID int
+				var uuid, bookLabel, snippet string
+				if err := rows.Scan(&rowID, &uuid, &bookLabel, &snippet); err != nil {
+					t.Fatal(errors.Wrap(err, "scanning"))
+				}
+				uuids = append(uuids, uuid)
+			}
+
+			assert.DeepEqual(t, uuids, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestDoQuery_context(t *testing.T) {
+	db := database.InitTestDB(t, "../../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	bUUID := "b1-uuid"
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", bUUID, "algo")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", bUUID, "one two three heap four five six", 1)
+
+	testCases := []struct {
+		context  int
+		expected string
+	}{
+		{context: 1, expected: "...<dnotehl>heap</dnotehl>..."},
+		{context: 3, expected: "...three <dnotehl>heap</dnotehl> four..."},
+		{context: 28, expected: "one two three <dnotehl>heap</dnotehl> four five six"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("context %d", tc.context), func(t *testing.T) {
+			query, err := parseQuery("heap")
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "parsing"))
+			}
+
+			rows, err := doQuery(context.DnoteCtx{DB: db}, searchParams{
+				Query:   query,
+				Sort:    sortRank,
+				Context: tc.context,
+			})
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "querying"))
+			}
+			defer rows.Close()
+
+			var snippet string
+			for rows.Next() {
+				var rowID int
+				var uuid, bookLabel string
+				if err := rows.Scan(&rowID, &uuid, &bookLabel, &snippet); err != nil {
+					t.Fatal(errors.Wrap(err, "scanning"))
+				}
+			}
+
+			assert.Equal(t, snippet, tc.expected, "snippet mismatch")
+		})
+	}
+}
@@ -0,0 +1,565 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/tags"
+	"github.com/pkg/errors"
+)
+
+// This file implements the query language of the find command. A query is made
+// of search terms and filters:
+//
+//	merge sort            notes containing both terms
+//	"merge sort"          notes containing the exact phrase
+//	merg*                 notes containing a term starting with the prefix
+//	-quick                notes not containing the term
+//	heap OR queue         notes containing either term
+//	(heap OR queue) sort  terms can be grouped with parentheses
+//	book:algorithm        notes in the book
+//	tag:interview         notes with the tag
+//	after:2024-01-01      notes added on or after the date
+//	before:2024-01-01     notes added before the date
+//	public:true           notes that are public, or private if false
+//
+// Filters can be negated with '-' and apply to the whole query regardless of
+// OR groups. They cannot appear inside parentheses.
+
+const (
+	// queryTokenTerm represents a search term
+	queryTokenTerm = iota
+	// queryTokenPhrase represents a quoted phrase
+	queryTokenPhrase
+	// queryTokenFilter represents a key:value filter
+	queryTokenFilter
+	// queryTokenOr represents the OR operator
+	queryTokenOr
+	// queryTokenNot represents a leading '-' negating the next expression
+	queryTokenNot
+	// queryTokenLParen represents an opening parenthesis
+	queryTokenLParen
+	// queryTokenRParen represents a closing parenthesis
+	queryTokenRParen
+	// queryTokenEOF represents the end of the query
+	queryTokenEOF
+)
+
+const (
+	filterKeyBook   = "book"
+	filterKeyTag    = "tag"
+	filterKeyBefore = "before"
+	filterKeyAfter  = "after"
+	filterKeyPublic = "public"
+)
+
+var filterKeys = []string{filterKeyBook, filterKeyTag, filterKeyBefore, filterKeyAfter, filterKeyPublic}
+
+// queryDateLayout is the layout of the dates in the date filters
+const queryDateLayout = "2006-01-02"
+
+type queryToken struct {
+	Kind  int
+	Value string
+	// Prefix is true if a term or a phrase ends with '*'
+	Prefix bool
+	// Key is the key of a filter
+	Key string
+	// Pos is the position of the token in the query, starting from 1
+	Pos int
+}
+
+// queryFilter is a filter parsed from a query
+type queryFilter struct {
+	Key     string
+	Value   string
+	Negated bool
+}
+
+// queryNode is a node of the syntax tree of the search terms
+type queryNode interface{}
+
+type termNode struct {
+	Value  string
+	Prefix bool
+}
+
+type notNode struct {
+	Child queryNode
+}
+
+type andNode struct {
+	Children []queryNode
+}
+
+type orNode struct {
+	Children []queryNode
+}
+
+// compiledQuery is the result of compiling a query. Match is an FTS5 MATCH
+// expression and Conditions are SQL expressions to be joined with AND,
+// whose placeholders are bound to Args in order.
+type compiledQuery struct {
+	Match      string
+	Conditions []string
+	Args       []interface{}
+}
+
+func isFilterKey(s string) bool {
+	for _, k := range filterKeys {
+		if s == k {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isTermBoundary(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// readPhrase reads a quoted phrase starting at the opening quote at idx. It
+// returns the content of the phrase and the index after the closing quote.
+func readPhrase(runes []rune, idx int) (string, int, error) {
+	end := idx + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end == len(runes) {
+		return "", 0, errors.Errorf("unterminated phrase at position %d", idx+1)
+	}
+
+	value := string(runes[idx+1 : end])
+	if strings.TrimSpace(value) == "" {
+		return "", 0, errors.Errorf("empty phrase at position %d", idx+1)
+	}
+
+	return value, end + 1, nil
+}
+
+// lexQuery splits the given query into tokens. Filters found outside of
+// parentheses are returned separately from the rest of the tokens.
+func lexQuery(s string) ([]queryToken, []queryFilter, error) {
+	var toks []queryToken
+	var filters []queryFilter
+
+	runes := []rune(s)
+	depth := 0
+	idx := 0
+
+	for idx < len(runes) {
+		r := runes[idx]
+		pos := idx + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+		case r == '(':
+			depth++
+			toks = append(toks, queryToken{Kind: queryTokenLParen, Pos: pos})
+			idx++
+		case r == ')':
+			depth--
+			toks = append(toks, queryToken{Kind: queryTokenRParen, Pos: pos})
+			idx++
+		case r == '-':
+			if idx+1 == len(runes) || unicode.IsSpace(runes[idx+1]) || runes[idx+1] == ')' {
+				return nil, nil, errors.Errorf("'-' at position %d must be followed by a term", pos)
+			}
+			toks = append(toks, queryToken{Kind: queryTokenNot, Pos: pos})
+			idx++
+		case r == '"':
+			value, next, err := readPhrase(runes, idx)
+			if err != nil {
+				return nil, nil, err
+			}
+			tok := queryToken{Kind: queryTokenPhrase, Value: value, Pos: pos}
+			if next < len(runes) && runes[next] == '*' {
+				tok.Prefix = true
+				next++
+			}
+			toks = append(toks, tok)
+			idx = next
+		default:
+			end := idx
+			for end < len(runes) && !isTermBoundary(runes[end]) {
+				end++
+			}
+			word := string(runes[idx:end])
+			idx = end
+
+			if word == "OR" {
+				toks = append(toks, queryToken{Kind: queryTokenOr, Pos: pos})
+				continue
+			}
+
+			colonIdx := strings.Index(word, ":")
+			if colonIdx > 0 && isFilterKey(strings.ToLower(word[:colonIdx])) {
+				key := strings.ToLower(word[:colonIdx])
+				value := word[colonIdx+1:]
+				if value == "" && idx < len(runes) && runes[idx] == '"' {
+					var err error
+					value, idx, err = readPhrase(runes, idx)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
+				if value == "" {
+					return nil, nil, errors.Errorf("missing value for '%s:' at position %d", key, pos)
+				}
+				if depth > 0 {
+					return nil, nil, errors.Errorf("filter '%s:%s' at position %d cannot be used inside parentheses", key, value, pos)
+				}
+
+				f := queryFilter{Key: key, Value: value}
+				if n := len(toks); n > 0 && toks[n-1].Kind == queryTokenNot && toks[n-1].Pos == pos-1 {
+					f.Negated = true
+					toks = toks[:n-1]
+				}
+				filters = append(filters, f)
+				continue
+			}
+
+			tok := queryToken{Kind: queryTokenTerm, Value: word, Pos: pos}
+			if strings.HasSuffix(word, "*") {
+				tok.Value = strings.TrimRight(word, "*")
+				tok.Prefix = true
+			}
+			if tok.Value == "" {
+				return nil, nil, errors.Errorf("'*' at position %d must follow a term", pos)
+			}
+			toks = append(toks, tok)
+		}
+	}
+
+	toks = append(toks, queryToken{Kind: queryTokenEOF, Pos: len(runes) + 1})
+
+	return toks, filters, nil
+}
+
+// queryParser is a recursive descent parser for the search terms:
+//
+//	or      = and { "OR" and }
+//	and     = unary { unary }
+//	unary   = [ "-" ] primary
+//	primary = term | phrase | "(" or ")"
+type queryParser struct {
+	toks []queryToken
+	idx  int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.toks[p.idx]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.toks[p.idx]
+	if tok.Kind != queryTokenEOF {
+		p.idx++
+	}
+
+	return tok
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []queryNode{first}
+	for p.peek().Kind == queryTokenOr {
+		tok := p.next()
+
+		switch p.peek().Kind {
+		case queryTokenEOF, queryTokenRParen, queryTokenOr:
+			return nil, errors.Errorf("'OR' at position %d must be followed by a term", tok.Pos)
+		}
+
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+
+	return orNode{Children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var children []queryNode
+
+	for {
+		switch p.peek().Kind {
+		case queryTokenEOF, queryTokenRParen, queryTokenOr:
+			if len(children) == 0 {
+				tok := p.peek()
+				if tok.Kind == queryTokenOr {
+					return nil, errors.Errorf("'OR' at position %d must be preceded by a term", tok.Pos)
+				}
+				if tok.Kind == queryTokenRParen {
+					return nil, errors.Errorf("unexpected ')' at position %d", tok.Pos)
+				}
+
+				return nil, errors.New("query does not contain any search term")
+			}
+
+			if len(children) == 1 {
+				return children[0], nil
+			}
+
+			return andNode{Children: children}, nil
+		}
+
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.peek().Kind == queryTokenNot {
+		p.next()
+
+		child, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{Child: child}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+
+	switch tok.Kind {
+	case queryTokenTerm, queryTokenPhrase:
+		return termNode{Value: tok.Value, Prefix: tok.Prefix}, nil
+	case queryTokenLParen:
+		if p.peek().Kind == queryTokenRParen {
+			return nil, errors.Errorf("empty parentheses at position %d", tok.Pos)
+		}
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.Kind != queryTokenRParen {
+			return nil, errors.Errorf("missing ')' for '(' at position %d", tok.Pos)
+		}
+
+		return node, nil
+	case queryTokenNot:
+		return nil, errors.Errorf("unexpected '-' at position %d", tok.Pos)
+	case queryTokenRParen:
+		return nil, errors.Errorf("unexpected ')' at position %d", tok.Pos)
+	default:
+		return nil, errors.Errorf("unexpected token at position %d", tok.Pos)
+	}
+}
+
+// quoteFTSString quotes the given value as an FTS5 string
+func quoteFTSString(s string) string {
+	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(s, `"`, `""`))
+}
+
+// compileMatch compiles the syntax tree into an FTS5 MATCH expression. FTS5
+// only supports NOT as a binary operator, so negated terms are subtracted
+// from the other terms of the same group.
+func compileMatch(node queryNode) (string, error) {
+	switch n := node.(type) {
+	case termNode:
+		ret := quoteFTSString(n.Value)
+		if n.Prefix {
+			ret = ret + "*"
+		}
+
+		return ret, nil
+	case notNode:
+		return "", errors.New("a query cannot consist only of negated terms")
+	case orNode:
+		parts := []string{}
+		for _, child := range n.Children {
+			part, err := compileMatch(child)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+
+		return fmt.Sprintf("(%s)", strings.Join(parts, " OR ")), nil
+	case andNode:
+		var positives, negatives []string
+		for _, child := range n.Children {
+			if c, ok := child.(notNode); ok {
+				part, err := compileMatch(c.Child)
+				if err != nil {
+					return "", err
+				}
+				negatives = append(negatives, part)
+				continue
+			}
+
+			part, err := compileMatch(child)
+			if err != nil {
+				return "", err
+			}
+			positives = append(positives, part)
+		}
+
+		if len(positives) == 0 {
+			return "", errors.New("a query cannot consist only of negated terms")
+		}
+
+		ret := positives[0]
+		if len(positives) > 1 {
+			ret = fmt.Sprintf("(%s)", strings.Join(positives, " AND "))
+		}
+		for _, neg := range negatives {
+			ret = fmt.Sprintf("%s NOT %s", ret, neg)
+		}
+		if len(negatives) > 0 {
+			ret = fmt.Sprintf("(%s)", ret)
+		}
+
+		return ret, nil
+	default:
+		return "", errors.Errorf("unknown node %T", node)
+	}
+}
+
+// parseQueryDate parses the date of a date filter in the local time zone
+func parseQueryDate(key, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(queryDateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date '%s' for '%s:'. Expected a date in the format YYYY-MM-DD", value, key)
+	}
+
+	return t, nil
+}
+
+// compileFilter compiles the filter into an SQL condition and its arguments
+func compileFilter(f queryFilter) (string, []interface{}, error) {
+	var cond string
+	var args []interface{}
+
+	switch f.Key {
+	case filterKeyBook:
+		cond, args = database.BookLabelFilter(f.Value)
+	case filterKeyTag:
+		if err := tags.Validate(f.Value); err != nil {
+			return "", nil, errors.Wrap(err, "invalid tag filter")
+		}
+
+		cond, args = database.NoteTagsFilter(tags.Normalize([]string{f.Value}))
+	case filterKeyBefore, filterKeyAfter:
+		t, err := parseQueryDate(f.Key, f.Value)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if f.Key == filterKeyBefore {
+			cond = "notes.added_on < ?"
+		} else {
+			cond = "notes.added_on >= ?"
+		}
+		args = []interface{}{t.UnixNano()}
+	case filterKeyPublic:
+		var public bool
+		switch strings.ToLower(f.Value) {
+		case "true":
+			public = true
+		case "false":
+			public = false
+		default:
+			return "", nil, errors.Errorf("invalid value '%s' for 'public:'. Expected true or false", f.Value)
+		}
+
+		cond = "notes.public = ?"
+		args = []interface{}{public}
+	default:
+		return "", nil, errors.Errorf("unknown filter '%s:'", f.Key)
+	}
+
+	if f.Negated {
+		cond = fmt.Sprintf("NOT (%s)", cond)
+	}
+
+	return cond, args, nil
+}
+
+// parseQuery parses and compiles the given query
+func parseQuery(s string) (compiledQuery, error) {
+	return compileQuery(s, false)
+}
+
+// compileQuery parses and compiles the given query. If filtersOnly is true,
+// a query made only of filters is allowed and is compiled with an empty Match.
+func compileQuery(s string, filtersOnly bool) (compiledQuery, error) {
+	var ret compiledQuery
+
+	toks, filters, err := lexQuery(s)
+	if err != nil {
+		return ret, err
+	}
+
+	if !filtersOnly || len(toks) > 1 || len(filters) == 0 {
+		p := queryParser{toks: toks}
+		node, err := p.parseOr()
+		if err != nil {
+			return ret, err
+		}
+		if tok := p.peek(); tok.Kind != queryTokenEOF {
+			return ret, errors.Errorf("unexpected ')' at position %d", tok.Pos)
+		}
+
+		ret.Match, err = compileMatch(node)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	for _, f := range filters {
+		cond, args, err := compileFilter(f)
+		if err != nil {
+			return ret, err
+		}
+
+		ret.Conditions = append(ret.Conditions, cond)
+		ret.Args = append(ret.Args, args...)
+	}
+
+	return ret, nil
+}
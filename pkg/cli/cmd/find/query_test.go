@@ -0,0 +1,150 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestParseQuery(t *testing.T) {
+	d := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).UnixNano()
+	tagCond, _ := database.NoteTagsFilter([]string{"go"})
+
+	testCases := []struct {
+		input      string
+		match      string
+		conditions []string
+		args       []interface{}
+	}{
+		{
+			input: "foo",
+			match: `"foo"`,
+		},
+		{
+			input: "foo bar",
+			match: `("foo" AND "bar")`,
+		},
+		{
+			input: `"foo bar" baz`,
+			match: `("foo bar" AND "baz")`,
+		},
+		{
+			input: `merg* "merge so"*`,
+			match: `("merg"* AND "merge so"*)`,
+		},
+		{
+			input: "foo -bar -baz",
+			match: `("foo" NOT "bar" NOT "baz")`,
+		},
+		{
+			input: "foo bar OR baz",
+			match: `(("foo" AND "bar") OR "baz")`,
+		},
+		{
+			input: "(foo OR bar) -(baz OR qux)",
+			match: `(("foo" OR "bar") NOT ("baz" OR "qux"))`,
+		},
+		{
+			input: `or and say"hi"`,
+			match: `("or" AND "and" AND "say" AND "hi")`,
+		},
+		{
+			input: `foo-bar http://example.com`,
+			match: `("foo-bar" AND "http://example.com")`,
+		},
+		{
+			input:      "foo book:algo",
+			match:      `"foo"`,
+			conditions: []string{"books.label = ?"},
+			args:       []interface{}{"algo"},
+		},
+		{
+			input:      `foo -book:algo public:TRUE`,
+			match:      `"foo"`,
+			conditions: []string{"NOT (books.label = ?)", "notes.public = ?"},
+			args:       []interface{}{"algo", true},
+		},
+		{
+			input:      "foo OR bar after:2024-01-01 before:2024-01-01",
+			match:      `("foo" OR "bar")`,
+			conditions: []string{"notes.added_on >= ?", "notes.added_on < ?"},
+			args:       []interface{}{d, d},
+		},
+		{
+			input:      `foo tag:#Go book:"algo"`,
+			match:      `"foo"`,
+			conditions: []string{tagCond, "books.label = ?"},
+			args:       []interface{}{"go", 1, "algo"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := parseQuery(tc.input)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "parsing"))
+			}
+
+			assert.Equal(t, result.Match, tc.match, "match mismatch")
+			assert.DeepEqual(t, result.Conditions, tc.conditions, "conditions mismatch")
+			assert.DeepEqual(t, result.Args, tc.args, "args mismatch")
+		})
+	}
+}
+
+func TestParseQuery_errors(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "", expected: "query does not contain any search term"},
+		{input: "book:algo", expected: "query does not contain any search term"},
+		{input: `foo "bar`, expected: "unterminated phrase at position 5"},
+		{input: `foo ""`, expected: "empty phrase at position 5"},
+		{input: "foo -", expected: "'-' at position 5 must be followed by a term"},
+		{input: "-foo", expected: "a query cannot consist only of negated terms"},
+		{input: "foo OR -bar", expected: "a query cannot consist only of negated terms"},
+		{input: "OR foo", expected: "'OR' at position 1 must be preceded by a term"},
+		{input: "foo OR", expected: "'OR' at position 5 must be followed by a term"},
+		{input: "(foo", expected: "missing ')' for '(' at position 1"},
+		{input: "foo)", expected: "unexpected ')' at position 4"},
+		{input: "foo ()", expected: "empty parentheses at position 5"},
+		{input: "foo *", expected: "'*' at position 5 must follow a term"},
+		{input: "foo book:", expected: "missing value for 'book:' at position 5"},
+		{input: "(foo book:algo)", expected: "filter 'book:algo' at position 6 cannot be used inside parentheses"},
+		{input: "foo before:yesterday", expected: "invalid date 'yesterday' for 'before:'. Expected a date in the format YYYY-MM-DD"},
+		{input: "foo public:yes", expected: "invalid value 'yes' for 'public:'. Expected true or false"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := parseQuery(tc.input)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			assert.Equal(t, err.Error(), tc.expected, "error mismatch")
+		})
+	}
+}
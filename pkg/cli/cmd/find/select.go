@@ -0,0 +1,168 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+)
+
+// matchNotes returns the rowids of the notes matching the query. Unlike in a
+// search, the query can be made only of filters.
+func matchNotes(db *database.DB, query string) ([]int, error) {
+	q, err := compileQuery(query, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the query")
+	}
+
+	var sql string
+	var args []interface{}
+	if q.Match != "" {
+		sql = `SELECT notes.rowid
+		FROM note_fts
+		INNER JOIN notes ON notes.rowid = note_fts.rowid
+		INNER JOIN books ON notes.book_uuid = books.uuid
+		WHERE note_fts MATCH ? AND notes.deleted = false`
+		args = append(args, q.Match)
+	} else {
+		sql = `SELECT notes.rowid
+		FROM notes
+		INNER JOIN books ON notes.book_uuid = books.uuid
+		WHERE notes.deleted = false`
+	}
+
+	for _, cond := range q.Conditions {
+		sql = fmt.Sprintf("%s AND %s", sql, cond)
+	}
+	args = append(args, q.Args...)
+
+	rows, err := db.Query(sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []int{}
+	for rows.Next() {
+		var rowID int
+		if err := rows.Scan(&rowID); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, rowID)
+	}
+
+	return ret, nil
+}
+
+// rangeNotes returns the rowids of the notes in the range
+func rangeNotes(db *database.DB, r utils.IDRange) ([]int, error) {
+	rows, err := db.Query("SELECT rowid FROM notes WHERE rowid BETWEEN ? AND ? AND deleted = false", r.Start, r.End)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []int{}
+	for rows.Next() {
+		var rowID int
+		if err := rows.Scan(&rowID); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, rowID)
+	}
+
+	return ret, nil
+}
+
+// IsBulkArgs checks if the arguments select several notes by ids and ranges of
+// ids. A single argument such as 2023-2024 is the name of a book if the book
+// exists.
+func IsBulkArgs(db *database.DB, args []string) (bool, error) {
+	if !utils.IsBulkIDs(args) {
+		return false, nil
+	}
+	if len(args) > 1 {
+		return true, nil
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM books WHERE label = ? AND deleted = false", args[0]).Scan(&count); err != nil {
+		return false, errors.Wrap(err, "counting books")
+	}
+
+	return count == 0, nil
+}
+
+// SelectNotes returns the notes with the ids in the given ranges and the
+// notes matching the query, ordered by id. The query is ignored if empty.
+// A note given by a single id must exist, while a range may include the ids
+// of removed notes.
+func SelectNotes(db *database.DB, ranges []utils.IDRange, query string) ([]database.NoteInfo, error) {
+	seen := map[int]bool{}
+	rowIDs := []int{}
+	add := func(ids []int) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				rowIDs = append(rowIDs, id)
+			}
+		}
+	}
+
+	for _, r := range ranges {
+		if r.Start == r.End {
+			add([]int{r.Start})
+			continue
+		}
+
+		ids, err := rangeNotes(db, r)
+		if err != nil {
+			return nil, err
+		}
+		add(ids)
+	}
+
+	if query != "" {
+		ids, err := matchNotes(db, query)
+		if err != nil {
+			return nil, err
+		}
+		add(ids)
+	}
+
+	sort.Ints(rowIDs)
+
+	ret := []database.NoteInfo{}
+	for _, id := range rowIDs {
+		info, err := database.GetNoteInfo(db, id)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, info)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,136 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+)
+
+func TestSelectNotes(t *testing.T) {
+	db := database.InitTestDB(t, "../../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algo")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 1, "n1-uuid", "b1-uuid", "merge sort", 1)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 2, "n2-uuid", "b1-uuid", "quick sort", 2)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?, ?)", 3, "n3-uuid", "b1-uuid", "", 3, true)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 4, "n4-uuid", "b1-uuid", "binary heap", 4)
+	database.MustExec(t, "inserting n5", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 5, "n5-uuid", "b1-uuid", "heap sort", 5)
+
+	testCases := []struct {
+		name     string
+		ranges   []utils.IDRange
+		query    string
+		expected []int
+	}{
+		{
+			name:     "ids",
+			ranges:   []utils.IDRange{{Start: 4, End: 4}, {Start: 1, End: 1}},
+			expected: []int{1, 4},
+		},
+		{
+			name:     "range skipping removed notes",
+			ranges:   []utils.IDRange{{Start: 2, End: 10}},
+			expected: []int{2, 4, 5},
+		},
+		{
+			name:     "query",
+			query:    "sort -quick",
+			expected: []int{1, 5},
+		},
+		{
+			name:     "filters only",
+			query:    "public:false -book:linux",
+			expected: []int{1, 2, 4, 5},
+		},
+		{
+			name:     "ids and query",
+			ranges:   []utils.IDRange{{Start: 4, End: 4}, {Start: 5, End: 5}},
+			query:    "merge OR heap",
+			expected: []int{1, 4, 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			notes, err := SelectNotes(db, tc.ranges, tc.query)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			got := []int{}
+			for _, n := range notes {
+				got = append(got, n.RowID)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("nested books", func(t *testing.T) {
+		database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "algo/sorting")
+		database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "algorithms")
+		database.MustExec(t, "inserting n6", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 6, "n6-uuid", "b2-uuid", "radix", 6)
+		database.MustExec(t, "inserting n7", db, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?, ?)", 7, "n7-uuid", "b3-uuid", "radix", 7)
+
+		testCases := []struct {
+			query    string
+			expected []int
+		}{
+			{query: "book:algo", expected: []int{1, 2, 4, 5}},
+			{query: "book:algo/", expected: []int{1, 2, 4, 5, 6}},
+			{query: "radix book:algo/", expected: []int{6}},
+			{query: "book:algo/ -book:algo/sorting/", expected: []int{1, 2, 4, 5}},
+		}
+
+		for _, tc := range testCases {
+			notes, err := SelectNotes(db, nil, tc.query)
+			if err != nil {
+				t.Fatal(errors.Wrapf(err, "executing '%s'", tc.query))
+			}
+
+			got := []int{}
+			for _, n := range notes {
+				got = append(got, n.RowID)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, fmt.Sprintf("result mismatch for '%s'", tc.query))
+		}
+	})
+
+	t.Run("removed note", func(t *testing.T) {
+		_, err := SelectNotes(db, []utils.IDRange{{Start: 3, End: 3}}, "")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assert.Equal(t, err.Error(), "note 3 not found", "error mismatch")
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		if _, err := SelectNotes(db, nil, `"sort`); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
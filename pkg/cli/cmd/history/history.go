@@ -0,0 +1,91 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * List the prior versions of a note
+ dnote history 12
+
+ * See what changed since a revision
+ dnote diff 12 3
+
+ * Restore a revision
+ dnote restore 12 3`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new history command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "history <note id>",
+		Short:   "List the revisions of a note",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+
+		info, err := database.GetNoteInfo(ctx.DB, rowID)
+		if err != nil {
+			return err
+		}
+
+		revisions, err := database.GetNoteRevisions(ctx.DB, info.UUID)
+		if err != nil {
+			return errors.Wrap(err, "getting revisions")
+		}
+
+		items := make([]output.Revision, len(revisions))
+		for i, r := range revisions {
+			items[i] = output.Revision{
+				RowID:    r.RowID,
+				Body:     r.Body,
+				EditedOn: r.EditedOn,
+			}
+		}
+
+		return output.Revisions(info.RowID, items)
+	}
+}
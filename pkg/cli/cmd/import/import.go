@@ -0,0 +1,268 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package importcmd provides the import command. It is not named after the
+// directory because 'import' is a reserved word.
+package importcmd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/importer"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/tags"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/dnote/dnote/pkg/cli/validate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Import a folder of Markdown files
+  dnote import ./notes
+
+  * Import an Obsidian vault
+  dnote import ~/vault --format obsidian
+
+  * Import an Evernote notebook into a book
+  dnote import ~/Downloads/recipes.enex -b cooking
+
+  * See what would be imported without making changes
+  dnote import ~/.nb --dry-run
+`
+
+var formatFlag string
+var bookFlag string
+var dryRunFlag bool
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	if bookFlag != "" {
+		if err := validate.BookName(bookFlag); err != nil {
+			return errors.Wrap(err, "invalid book name")
+		}
+	}
+
+	return nil
+}
+
+// NewCmd returns a new import command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "import <path>",
+		Short:   "Import notes from other note taking tools",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&formatFlag, "format", "f", "", fmt.Sprintf("the format of the source. One of %s. Detected if not set", strings.Join(importer.Names(), ", ")))
+	f.StringVarP(&bookFlag, "book", "b", "", "the book for the notes that do not belong to any folder or notebook")
+	f.BoolVarP(&dryRunFlag, "dry-run", "", false, "report what would be imported without making changes")
+
+	return cmd
+}
+
+// bookSummary is the number of notes to be imported into a book
+type bookSummary struct {
+	Label  string
+	Count  int
+	Exists bool
+}
+
+// summarize groups the notes by book in the order they appear
+func summarize(db *database.DB, notes []importer.Note) ([]bookSummary, error) {
+	ret := []bookSummary{}
+	idx := map[string]int{}
+
+	for _, n := range notes {
+		if i, ok := idx[n.BookLabel]; ok {
+			ret[i].Count++
+			continue
+		}
+
+		var count int
+		if err := db.QueryRow("SELECT count(*) FROM books WHERE label = ?", n.BookLabel).Scan(&count); err != nil {
+			return nil, errors.Wrapf(err, "finding book %s", n.BookLabel)
+		}
+
+		idx[n.BookLabel] = len(ret)
+		ret = append(ret, bookSummary{Label: n.BookLabel, Count: 1, Exists: count > 0})
+	}
+
+	return ret, nil
+}
+
+// validateNotes checks that the notes can be imported
+func validateNotes(notes []importer.Note) error {
+	for _, n := range notes {
+		if err := validate.BookName(n.BookLabel); err != nil {
+			return errors.Wrapf(err, "invalid book name '%s' for %s. Rename the source or use --book", n.BookLabel, n.Source)
+		}
+	}
+
+	return nil
+}
+
+// getBookUUID returns the uuid of the book with the label, creating the book
+// if it does not exist
+func getBookUUID(tx *database.DB, label string) (string, error) {
+	var uuid string
+	err := tx.QueryRow("SELECT uuid FROM books WHERE label = ?", label).Scan(&uuid)
+	if err == nil {
+		return uuid, nil
+	} else if err != sql.ErrNoRows {
+		return "", errors.Wrapf(err, "finding book %s", label)
+	}
+
+	uuid, err = utils.GenerateUUID()
+	if err != nil {
+		return "", errors.Wrap(err, "generating uuid")
+	}
+
+	b := database.NewBook(uuid, label, 0, false, true)
+	if err := b.Insert(tx); err != nil {
+		return "", errors.Wrapf(err, "creating book %s", label)
+	}
+
+	return uuid, nil
+}
+
+// importNotes writes the notes in a single transaction. The books and the
+// notes are marked dirty so that they are uploaded in the next sync.
+func importNotes(db *database.DB, notes []importer.Note) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	bookUUIDs := map[string]string{}
+
+	for _, n := range notes {
+		bookUUID, ok := bookUUIDs[n.BookLabel]
+		if !ok {
+			bookUUID, err = getBookUUID(tx, n.BookLabel)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			bookUUIDs[n.BookLabel] = bookUUID
+		}
+
+		noteUUID, err := utils.GenerateUUID()
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "generating uuid")
+		}
+
+		note := database.NewNote(noteUUID, bookUUID, n.Body, n.AddedOn, n.EditedOn, 0, false, false, true)
+		if err := note.Insert(tx); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "creating the note from %s", n.Source)
+		}
+
+		if err := database.SetNoteTags(tx, noteUUID, tags.Union(n.Tags, tags.Extract(n.Body))); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "setting the tags of the note from %s", n.Source)
+		}
+
+		if err := database.IndexNoteLinks(tx, noteUUID, n.Body); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "indexing the links of the note from %s", n.Source)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	return nil
+}
+
+func printSummary(books []bookSummary) {
+	for _, b := range books {
+		var suffix string
+		if !b.Exists {
+			suffix = " (new book)"
+		}
+
+		log.Plainf("  %s: %d notes%s\n", b.Label, b.Count, suffix)
+	}
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		var imp importer.Importer
+		var err error
+		if formatFlag == "" {
+			var name string
+			name, imp, err = importer.Detect(path)
+			if err != nil {
+				return err
+			}
+
+			log.Debug("detected format: %s\n", name)
+		} else {
+			imp, err = importer.Get(formatFlag)
+			if err != nil {
+				return err
+			}
+		}
+
+		notes, err := imp.Read(path, importer.Options{Book: bookFlag})
+		if err != nil {
+			return errors.Wrap(err, "reading notes")
+		}
+		if err := validateNotes(notes); err != nil {
+			return err
+		}
+
+		books, err := summarize(ctx.DB, notes)
+		if err != nil {
+			return errors.Wrap(err, "summarizing")
+		}
+
+		if dryRunFlag {
+			log.Infof("would import %d notes into %d books\n", len(notes), len(books))
+			printSummary(books)
+			return nil
+		}
+
+		if err := importNotes(ctx.DB, notes); err != nil {
+			return errors.Wrap(err, "importing notes")
+		}
+
+		log.Successf("imported %d notes into %d books\n", len(notes), len(books))
+		printSummary(books)
+
+		return nil
+	}
+}
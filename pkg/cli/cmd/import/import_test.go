@@ -0,0 +1,92 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importcmd
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/importer"
+	"github.com/pkg/errors"
+)
+
+var dbPath = "../../tmp/dnote-test.db"
+
+func TestImportNotes(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, dbPath, nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label, usn, dirty) VALUES (?, ?, ?, ?)", "b1-uuid", "js", 10, false)
+
+	notes := []importer.Note{
+		{BookLabel: "js", Body: "n1 body", AddedOn: 100, EditedOn: 200},
+		{BookLabel: "work/infra", Body: "n2 body", AddedOn: 300},
+		{BookLabel: "work/infra", Body: "n3 body", AddedOn: 400},
+	}
+
+	books, err := summarize(db, notes)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "summarizing"))
+	}
+	assert.DeepEqual(t, books, []bookSummary{
+		{Label: "js", Count: 1, Exists: true},
+		{Label: "work/infra", Count: 2, Exists: false},
+	}, "summary mismatch")
+
+	// execute
+	if err := importNotes(db, notes); err != nil {
+		t.Fatal(errors.Wrap(err, "importing"))
+	}
+
+	// test
+	var bookCount, noteCount int
+	database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &bookCount)
+	database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+	assert.Equal(t, bookCount, 2, "book count mismatch")
+	assert.Equal(t, noteCount, 3, "note count mismatch")
+
+	var b2 database.Book
+	database.MustScan(t, "getting b2", db.QueryRow("SELECT uuid, usn, dirty FROM books WHERE label = ?", "work/infra"), &b2.UUID, &b2.USN, &b2.Dirty)
+	assert.Equal(t, b2.USN, 0, "b2 usn mismatch")
+	assert.Equal(t, b2.Dirty, true, "b2 dirty mismatch")
+
+	var n1 database.Note
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT book_uuid, added_on, edited_on, usn, dirty FROM notes WHERE body = ?", "n1 body"), &n1.BookUUID, &n1.AddedOn, &n1.EditedOn, &n1.USN, &n1.Dirty)
+	assert.Equal(t, n1.BookUUID, "b1-uuid", "n1 book_uuid mismatch")
+	assert.Equal(t, n1.AddedOn, int64(100), "n1 added_on mismatch")
+	assert.Equal(t, n1.EditedOn, int64(200), "n1 edited_on mismatch")
+	assert.Equal(t, n1.USN, 0, "n1 usn mismatch")
+	assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+
+	var infraCount int
+	database.MustScan(t, "counting infra notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND dirty = ?", b2.UUID, true), &infraCount)
+	assert.Equal(t, infraCount, 2, "infra note count mismatch")
+}
+
+func TestValidateNotes(t *testing.T) {
+	if err := validateNotes([]importer.Note{{BookLabel: "js"}, {BookLabel: "work/infra"}}); err != nil {
+		t.Errorf("unexpected error %s", err.Error())
+	}
+
+	if err := validateNotes([]importer.Note{{BookLabel: "123", Source: "123/a.md"}}); err == nil {
+		t.Error("expected an error for a numeric book name")
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/ui"
@@ -62,6 +63,12 @@ func Do(ctx context.DnoteCtx, email, password string) error {
 		return errors.Wrap(err, "requesting session")
 	}
 
+	ctx.SessionKey = signinResp.Key
+	cipherKey, err := unlockCipherKey(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unlocking the encryption key")
+	}
+
 	db := ctx.DB
 	tx, err := db.Begin()
 	if err != nil {
@@ -74,12 +81,55 @@ func Do(ctx context.DnoteCtx, email, password string) error {
 	if err := database.UpsertSystem(tx, consts.SystemSessionKeyExpiry, strconv.FormatInt(signinResp.ExpiresAt, 10)); err != nil {
 		return errors.Wrap(err, "saving session key")
 	}
+	if cipherKey != nil {
+		err = e2ee.Save(tx, cipherKey)
+	} else {
+		err = e2ee.Remove(tx)
+	}
+	if err != nil {
+		return errors.Wrap(err, "saving the encryption key")
+	}
 
 	tx.Commit()
 
 	return nil
 }
 
+// unlockCipherKey returns the key for the end-to-end encryption of the account,
+// or nil if the account does not use it. It uses the key on this device if it
+// is the one of the account, or else asks for the encryption passphrase.
+func unlockCipherKey(ctx context.DnoteCtx) ([]byte, error) {
+	settings, err := client.GetEncryption(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting the encryption settings")
+	}
+	if !settings.Enabled {
+		return nil, nil
+	}
+
+	if ctx.CipherKey != nil && e2ee.CheckKey(settings, ctx.CipherKey) {
+		return ctx.CipherKey, nil
+	}
+
+	if !ui.IsTerminal() {
+		return nil, errors.New("the account uses end-to-end encryption. Run 'dnote login' in a terminal to enter the encryption passphrase")
+	}
+
+	var passphrase string
+	if err := ui.PromptPassword("encryption passphrase", &passphrase); err != nil {
+		return nil, errors.Wrap(err, "getting passphrase input")
+	}
+
+	cipherKey, err := e2ee.Unwrap(settings, passphrase)
+	if err == e2ee.ErrWrongPassphrase {
+		return nil, errors.New("wrong encryption passphrase")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return cipherKey, nil
+}
+
 func getUsername() (string, error) {
 	if usernameFlag != "" {
 		return usernameFlag, nil
@@ -19,11 +19,17 @@
 package login
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/client"
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
+	"github.com/pkg/errors"
 )
 
 func TestGetServerDisplayURL(t *testing.T) {
@@ -72,3 +78,76 @@ func TestGetServerDisplayURL(t *testing.T) {
 		})
 	}
 }
+
+func TestUnlockCipherKey(t *testing.T) {
+	cipherKey := []byte("AES256Key-32Characters1234567890")
+	settings, err := e2ee.Wrap(cipherKey, "phrase1234", 1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "wrapping the key"))
+	}
+
+	testCases := []struct {
+		name     string
+		settings client.EncryptionSettings
+		localKey []byte
+		expected []byte
+		fail     bool
+	}{
+		{
+			name:     "plaintext",
+			settings: client.EncryptionSettings{},
+			expected: nil,
+		},
+		{
+			name:     "local key",
+			settings: settings,
+			localKey: cipherKey,
+			expected: cipherKey,
+		},
+		{
+			// the passphrase cannot be asked outside a terminal
+			name:     "another local key",
+			settings: settings,
+			localKey: []byte("AES256Key-32Charactersabcdefghij"),
+			fail:     true,
+		},
+		{
+			name:     "no local key",
+			settings: settings,
+			fail:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "GET" {
+					t.Fatalf("unexpected request Method: %s Path: %s", r.Method, r.URL.Path)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tc.settings)
+			}))
+			defer ts.Close()
+
+			ctx := context.DnoteCtx{
+				APIEndpoint: ts.URL,
+				SessionKey:  "someSessionKey",
+				CipherKey:   tc.localKey,
+			}
+
+			got, err := unlockCipherKey(ctx)
+			if tc.fail {
+				if err == nil {
+					t.Fatal("should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "key mismatch")
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/pkg/errors"
@@ -76,6 +77,9 @@ func Do(ctx context.DnoteCtx) error {
 	if err := database.DeleteSystem(tx, consts.SystemSessionKeyExpiry); err != nil {
 		return errors.Wrap(err, "deleting session key expiry")
 	}
+	if err := e2ee.Remove(tx); err != nil {
+		return errors.Wrap(err, "deleting the encryption key")
+	}
 
 	tx.Commit()
 
@@ -21,11 +21,11 @@ package ls
 import (
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
-	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -36,6 +36,9 @@ var example = `
 
  * List notes in a book
  dnote ls javascript
+
+ * List a book and the books nested in it
+ dnote ls work/
  `
 
 var deprecationWarning = `and "view" will replace it in the future version.
@@ -58,7 +61,7 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 		Aliases:    []string{"l", "notes"},
 		Short:      "List all notes",
 		Example:    example,
-		RunE:       NewRun(ctx, false),
+		RunE:       NewRun(ctx, false, nil),
 		PreRunE:    preRun,
 		Deprecated: deprecationWarning,
 	}
@@ -66,19 +69,32 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	return cmd
 }
 
-// NewRun returns a new run function for ls
-func NewRun(ctx context.DnoteCtx, nameOnly bool) infra.RunEFunc {
+// NewRun returns a new run function for ls. If tags are given, only the notes
+// having all of them are listed.
+func NewRun(ctx context.DnoteCtx, nameOnly bool, tags []string) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			if err := printBooks(ctx, nameOnly); err != nil {
+		var bookName string
+		if len(args) > 0 {
+			bookName = args[0]
+		}
+
+		if (len(args) == 0 || database.IsBookSubtree(bookName)) && len(tags) > 0 {
+			if err := printTaggedNotes(ctx, tags, bookName); err != nil {
+				return errors.Wrap(err, "viewing tagged notes")
+			}
+
+			return nil
+		}
+
+		if len(args) == 0 || database.IsBookSubtree(bookName) {
+			if err := printBooks(ctx, nameOnly, bookName); err != nil {
 				return errors.Wrap(err, "viewing books")
 			}
 
 			return nil
 		}
 
-		bookName := args[0]
-		if err := printNotes(ctx, bookName); err != nil {
+		if err := printNotes(ctx, bookName, tags); err != nil {
 			return errors.Wrapf(err, "viewing book '%s'", bookName)
 		}
 
@@ -86,87 +102,46 @@ func NewRun(ctx context.DnoteCtx, nameOnly bool) infra.RunEFunc {
 	}
 }
 
-// bookInfo is an information about the book to be printed on screen
-type bookInfo struct {
-	BookLabel string
-	NoteCount int
-}
-
-// noteInfo is an information about the note to be printed on screen
-type noteInfo struct {
-	RowID int
-	Body  string
-}
-
-// getNewlineIdx returns the index of newline character in a string
-func getNewlineIdx(str string) int {
-	var ret int
-
-	ret = strings.Index(str, "\n")
-
-	if ret == -1 {
-		ret = strings.Index(str, "\r\n")
-	}
-
-	return ret
-}
-
-// formatBody returns an excerpt of the given raw note content and a boolean
-// indicating if the returned string has been excertped
-func formatBody(noteBody string) (string, bool) {
-	trimmed := strings.TrimRight(noteBody, "\r\n")
-	newlineIdx := getNewlineIdx(trimmed)
-
-	if newlineIdx > -1 {
-		ret := strings.Trim(trimmed[0:newlineIdx], " ")
-
-		return ret, true
-	}
-
-	return strings.Trim(trimmed, " "), false
-}
-
-func printBookLine(info bookInfo, nameOnly bool) {
-	if nameOnly {
-		fmt.Println(info.BookLabel)
-	} else {
-		log.Printf("%s %s\n", info.BookLabel, log.ColorYellow.Sprintf("(%d)", info.NoteCount))
-	}
-}
-
-func printBooks(ctx context.DnoteCtx, nameOnly bool) error {
+// printBooks prints the books. If subtree is not empty, only the books in the
+// subtree are printed.
+func printBooks(ctx context.DnoteCtx, nameOnly bool, subtree string) error {
 	db := ctx.DB
 
-	rows, err := db.Query(`SELECT books.label, count(notes.uuid) note_count
+	query := `SELECT books.uuid, books.label, count(notes.uuid) note_count
 	FROM books
 	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
-	WHERE books.deleted = false
-	GROUP BY books.uuid
-	ORDER BY books.label ASC;`)
+	WHERE books.deleted = false`
+	args := []interface{}{}
+	if subtree != "" {
+		cond, bookArgs := database.BookLabelFilter(subtree)
+		query = fmt.Sprintf("%s AND %s", query, cond)
+		args = append(args, bookArgs...)
+	}
+
+	rows, err := db.Query(query+" GROUP BY books.uuid ORDER BY books.label ASC;", args...)
 	if err != nil {
 		return errors.Wrap(err, "querying books")
 	}
 	defer rows.Close()
 
-	infos := []bookInfo{}
+	infos := []output.Book{}
 	for rows.Next() {
-		var info bookInfo
-		err = rows.Scan(&info.BookLabel, &info.NoteCount)
+		var info output.Book
+		err = rows.Scan(&info.UUID, &info.Label, &info.NoteCount)
 		if err != nil {
 			return errors.Wrap(err, "scanning a row")
 		}
 
 		infos = append(infos, info)
 	}
-
-	for _, info := range infos {
-		printBookLine(info, nameOnly)
+	if subtree != "" && len(infos) == 0 {
+		return errors.Errorf("book '%s' not found", subtree)
 	}
 
-	return nil
+	return output.BookList(infos, nameOnly)
 }
 
-func printNotes(ctx context.DnoteCtx, bookName string) error {
+func printNotes(ctx context.DnoteCtx, bookName string, tags []string) error {
 	db := ctx.DB
 
 	var bookUUID string
@@ -177,35 +152,72 @@ func printNotes(ctx context.DnoteCtx, bookName string) error {
 		return errors.Wrap(err, "querying the book")
 	}
 
-	rows, err := db.Query(`SELECT rowid, body FROM notes WHERE book_uuid = ? AND deleted = ? ORDER BY added_on ASC;`, bookUUID, false)
+	query := fmt.Sprintf(`SELECT rowid, uuid, body, added_on, edited_on, %s FROM notes WHERE book_uuid = ? AND deleted = ?`, database.NoteTagsColumn)
+	args := []interface{}{bookUUID, false}
+	if len(tags) > 0 {
+		cond, tagArgs := database.NoteTagsFilter(tags)
+		query = fmt.Sprintf("%s AND %s", query, cond)
+		args = append(args, tagArgs...)
+	}
+
+	rows, err := db.Query(query+" ORDER BY added_on ASC;", args...)
 	if err != nil {
 		return errors.Wrap(err, "querying notes")
 	}
 	defer rows.Close()
 
-	infos := []noteInfo{}
+	infos := []output.Note{}
 	for rows.Next() {
-		var info noteInfo
-		err = rows.Scan(&info.RowID, &info.Body)
+		var noteTags string
+		info := output.Note{BookLabel: bookName}
+		err = rows.Scan(&info.RowID, &info.UUID, &info.Body, &info.AddedOn, &info.EditedOn, &noteTags)
 		if err != nil {
 			return errors.Wrap(err, "scanning a row")
 		}
+		info.Tags = database.SplitTags(noteTags)
 
 		infos = append(infos, info)
 	}
 
-	log.Infof("on book %s\n", bookName)
+	return output.NoteList(bookName, infos)
+}
+
+// printTaggedNotes prints the notes that have all of the given tags. If subtree
+// is not empty, only the notes in the books in the subtree are printed.
+func printTaggedNotes(ctx context.DnoteCtx, tags []string, subtree string) error {
+	db := ctx.DB
+
+	cond, args := database.NoteTagsFilter(tags)
+	if subtree != "" {
+		bookCond, bookArgs := database.BookLabelFilter(subtree)
+		cond = fmt.Sprintf("%s AND %s", cond, bookCond)
+		args = append(args, bookArgs...)
+	}
+
+	query := fmt.Sprintf(`SELECT notes.rowid, notes.uuid, books.label, notes.body, notes.added_on, notes.edited_on, %s
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false AND %s
+	ORDER BY books.label ASC, notes.added_on ASC;`, database.NoteTagsColumn, cond)
 
-	for _, info := range infos {
-		body, isExcerpt := formatBody(info.Body)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
 
-		rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
-		if isExcerpt {
-			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
+	infos := []output.Note{}
+	for rows.Next() {
+		var noteTags string
+		var info output.Note
+		err = rows.Scan(&info.RowID, &info.UUID, &info.BookLabel, &info.Body, &info.AddedOn, &info.EditedOn, &noteTags)
+		if err != nil {
+			return errors.Wrap(err, "scanning a row")
 		}
+		info.Tags = database.SplitTags(noteTags)
 
-		log.Plainf("%s %s\n", rowid, body)
+		infos = append(infos, info)
 	}
 
-	return nil
+	return output.NoteList("", infos)
 }
@@ -0,0 +1,98 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package profilecmd
+
+import (
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var apiEndpointFlag, editorFlag, dbFlag string
+
+func newAddCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "add <name>",
+		Short:   "Add a profile",
+		PreRunE: preRunOneArg,
+		RunE:    newAddRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&apiEndpointFlag, "api-endpoint", "", "", "the API endpoint of the server. Defaults to that of the default profile")
+	f.StringVarP(&editorFlag, "editor", "", "", "the editor command. Defaults to that of the default profile")
+	f.StringVarP(&dbFlag, "db", "", "", "the path to the SQLite file. Defaults to a file in the dnote data directory")
+
+	return cmd
+}
+
+// addProfile adds a profile with the given name to the config
+func addProfile(cf config.Config, name string, p config.Profile) (config.Config, error) {
+	if err := config.ValidateProfileName(name); err != nil {
+		return cf, err
+	}
+	if _, ok := cf.Profiles[name]; ok {
+		return cf, errors.Errorf("profile '%s' already exists", name)
+	}
+
+	if p.APIEndpoint == "" {
+		p.APIEndpoint = cf.APIEndpoint
+	}
+
+	profiles := map[string]config.Profile{}
+	for k, v := range cf.Profiles {
+		profiles[k] = v
+	}
+	profiles[name] = p
+	cf.Profiles = profiles
+
+	return cf, nil
+}
+
+func newAddRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		cf, err = addProfile(cf, name, config.Profile{
+			APIEndpoint: apiEndpointFlag,
+			Editor:      editorFlag,
+			DB:          dbFlag,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := config.Write(ctx, cf); err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		log.Successf("added profile %s\n", name)
+		log.Plainf("Run 'dnote profile use %s' to use it by default, or pass '--profile %s'\n", name, name)
+
+		return nil
+	}
+}
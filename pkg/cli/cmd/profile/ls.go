@@ -0,0 +1,73 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package profilecmd
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newLsCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List the profiles",
+		PreRunE: preRunNoArgs,
+		RunE:    newLsRun(ctx),
+	}
+
+	return cmd
+}
+
+func newLsRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		current := ctx.Profile
+		if current == "" {
+			current = consts.DefaultProfileName
+		}
+
+		for _, name := range cf.ProfileNames() {
+			p, err := cf.GetProfile(name)
+			if err != nil {
+				return errors.Wrapf(err, "getting profile %s", name)
+			}
+
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+
+			fmt.Printf("%s %s %s\n", marker, name, log.ColorGray.Sprintf("(%s)", p.APIEndpoint))
+		}
+
+		return nil
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/dnote/dnote/pkg/cli/cmd/find"
+	"github.com/dnote/dnote/pkg/cli/completion"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
@@ -35,6 +37,7 @@ import (
 
 var bookFlag string
 var yesFlag bool
+var queryFlag string
 
 var example = `
   * Delete a note by id
@@ -42,22 +45,33 @@ var example = `
 
   * Delete a book by name
   dnote delete js
+
+  * Choose a note to delete with a fuzzy finder
+  dnote delete
+
+  * Delete several notes by ids and ranges of ids
+  dnote delete 2 5 8-12
+
+  * Delete the notes matching a search query
+  dnote delete --query "tag:draft before:2024-01-01"
 `
 
 // NewCmd returns a new remove command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "remove <note id|book name>",
-		Short:   "Remove a note or a book",
-		Aliases: []string{"rm", "d", "delete"},
-		Example: example,
-		PreRunE: preRun,
-		RunE:    newRun(ctx),
+		Use:               "remove <note id...?|book name?>",
+		Short:             "Remove a note or a book",
+		Aliases:           []string{"rm", "d", "delete"},
+		Example:           example,
+		PreRunE:           preRun,
+		RunE:              newRun(ctx),
+		ValidArgsFunction: completion.BooksOrNotes(ctx, true),
 	}
 
 	f := cmd.Flags()
 	f.StringVarP(&bookFlag, "book", "b", "", "The book name to delete")
 	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+	f.StringVarP(&queryFlag, "query", "q", "", "remove the notes matching the search query. See 'dnote find --help' for the syntax")
 
 	f.MarkDeprecated("book", "Pass the book name as an argument. e.g. `dnote rm book_name`")
 
@@ -65,7 +79,7 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 }
 
 func preRun(cmd *cobra.Command, args []string) error {
-	if len(args) != 1 && len(args) != 2 {
+	if len(args) > 2 && !utils.IsBulkIDs(args) {
 		return errors.New("Incorrect number of argument")
 	}
 
@@ -82,6 +96,19 @@ func maybeConfirm(message string, defaultValue bool) (bool, error) {
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		bulk, err := find.IsBulkArgs(ctx.DB, args)
+		if err != nil {
+			return errors.Wrap(err, "reading the arguments")
+		}
+
+		if queryFlag != "" || bulk {
+			if err := runNotes(ctx, args); err != nil {
+				return errors.Wrap(err, "removing the notes")
+			}
+
+			return nil
+		}
+
 		// DEPRECATED: Remove in 1.0.0
 		if bookFlag != "" {
 			if err := runBook(ctx, bookFlag); err != nil {
@@ -103,6 +130,22 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			return nil
 		}
 
+		if len(args) == 0 {
+			rowID, err := ui.PickNote(ctx, "")
+			if err == ui.ErrPickerCancelled {
+				log.Warnf("aborted by user\n")
+				return nil
+			} else if err != nil {
+				return errors.Wrap(err, "choosing a note")
+			}
+
+			if err := runNote(ctx, strconv.Itoa(rowID)); err != nil {
+				return errors.Wrap(err, "removing the note")
+			}
+
+			return nil
+		}
+
 		target := args[0]
 
 		if utils.IsNumber(target) {
@@ -132,7 +175,9 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 		return err
 	}
 
-	output.NoteInfo(noteInfo)
+	if err := output.NoteInfo(noteInfo); err != nil {
+		return errors.Wrap(err, "printing the note")
+	}
 
 	ok, err := maybeConfirm("remove this note?", false)
 	if err != nil {
@@ -143,12 +188,17 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 		return nil
 	}
 
+	backlinks, err := database.GetRemovalBacklinks(db, []string{noteInfo.UUID})
+	if err != nil {
+		return errors.Wrap(err, "getting backlinks")
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return errors.Wrap(err, "beginning a transaction")
 	}
 
-	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE uuid = ?", true, true, "", noteInfo.UUID); err != nil {
+	if err := database.TrashNote(tx, ctx.Clock, noteInfo.UUID); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "removing the note")
 	}
@@ -160,19 +210,48 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 	}
 
 	log.Successf("removed from %s\n", noteInfo.BookLabel)
+	warnDanglingLinks(backlinks)
 
 	return nil
 }
 
-func runBook(ctx context.DnoteCtx, bookLabel string) error {
+func runNotes(ctx context.DnoteCtx, args []string) error {
+	if queryFlag != "" && len(args) > 0 {
+		return errors.New("--query cannot be used with note ids")
+	}
+
 	db := ctx.DB
 
-	bookUUID, err := database.GetBookUUID(db, bookLabel)
+	ranges, err := utils.ParseIDRanges(args)
 	if err != nil {
-		return errors.Wrap(err, "finding book uuid")
+		return err
 	}
 
-	ok, err := maybeConfirm(fmt.Sprintf("delete book '%s' and all its notes?", bookLabel), false)
+	notes, err := find.SelectNotes(db, ranges, queryFlag)
+	if err != nil {
+		return errors.Wrap(err, "finding notes")
+	}
+	if len(notes) == 0 {
+		return errors.New("no notes found")
+	}
+
+	items := make([]output.Note, len(notes))
+	for i, n := range notes {
+		items[i] = output.Note{
+			RowID:     n.RowID,
+			UUID:      n.UUID,
+			BookLabel: n.BookLabel,
+			Body:      n.Content,
+			AddedOn:   n.AddedOn,
+			EditedOn:  n.EditedOn,
+			Tags:      n.Tags,
+		}
+	}
+	if err := output.NoteList("", items); err != nil {
+		return errors.Wrap(err, "printing the notes")
+	}
+
+	ok, err := maybeConfirm(fmt.Sprintf("remove %d notes?", len(notes)), false)
 	if err != nil {
 		return errors.Wrap(err, "getting confirmation")
 	}
@@ -181,23 +260,70 @@ func runBook(ctx context.DnoteCtx, bookLabel string) error {
 		return nil
 	}
 
+	uuids := make([]string, len(notes))
+	for i, n := range notes {
+		uuids[i] = n.UUID
+	}
+	backlinks, err := database.GetRemovalBacklinks(db, uuids)
+	if err != nil {
+		return errors.Wrap(err, "getting backlinks")
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return errors.Wrap(err, "beginning a transaction")
 	}
 
-	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE book_uuid = ?", true, true, "", bookUUID); err != nil {
+	for _, n := range notes {
+		if err := database.TrashNote(tx, ctx.Clock, n.UUID); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "removing note %d", n.RowID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		tx.Rollback()
-		return errors.Wrap(err, "removing notes in the book")
+		return errors.Wrap(err, "committing transaction")
+	}
+
+	log.Successf("removed %d notes\n", len(notes))
+	warnDanglingLinks(backlinks)
+
+	return nil
+}
+
+func runBook(ctx context.DnoteCtx, bookLabel string) error {
+	db := ctx.DB
+
+	bookUUID, err := database.GetBookUUID(db, bookLabel)
+	if err != nil {
+		return errors.Wrap(err, "finding book uuid")
 	}
 
-	// override the label with a random string
-	uniqLabel, err := utils.GenerateUUID()
+	ok, err := maybeConfirm(fmt.Sprintf("delete book '%s' and all its notes?", bookLabel), false)
 	if err != nil {
-		return errors.Wrap(err, "generating uuid to override with")
+		return errors.Wrap(err, "getting confirmation")
+	}
+	if !ok {
+		log.Warnf("aborted by user\n")
+		return nil
 	}
 
-	if _, err = tx.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ?", true, true, uniqLabel, bookUUID); err != nil {
+	uuids, err := getBookNoteUUIDs(db, bookUUID)
+	if err != nil {
+		return errors.Wrap(err, "getting notes in the book")
+	}
+	backlinks, err := database.GetRemovalBacklinks(db, uuids)
+	if err != nil {
+		return errors.Wrap(err, "getting backlinks")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	if err := database.TrashBook(tx, ctx.Clock, bookUUID); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "removing the book")
 	}
@@ -209,6 +335,38 @@ func runBook(ctx context.DnoteCtx, bookLabel string) error {
 	}
 
 	log.Success("removed book\n")
+	warnDanglingLinks(backlinks)
 
 	return nil
 }
+
+// getBookNoteUUIDs returns the uuids of the notes in the book that are not deleted
+func getBookNoteUUIDs(db *database.DB, bookUUID string) ([]string, error) {
+	rows, err := db.Query("SELECT uuid FROM notes WHERE book_uuid = ? AND deleted = false", bookUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating notes")
+	}
+
+	return ret, nil
+}
+
+// warnDanglingLinks reports the links to the removed notes
+func warnDanglingLinks(backlinks []database.Link) {
+	for _, l := range backlinks {
+		log.Warnf("note %d now has a dangling link [[%s]]\n", l.RowID, l.Target)
+	}
+}
@@ -0,0 +1,126 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package restore
+
+import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/tags"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * Restore the note 12 to the revision 3
+ dnote restore 12 3`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new restore command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "restore <note id> <revision id>",
+		Short:   "Restore a note to a revision",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+// restoreRevision replaces the content of the note with the content of the
+// revision. The replaced content is recorded as a new revision, and the
+// hashtags of the restored content become the tags of the note.
+func restoreRevision(ctx context.DnoteCtx, tx *database.DB, info database.NoteInfo, rev database.NoteRevision) error {
+	if err := database.UpdateNoteContent(tx, ctx.Clock, info.RowID, rev.Body, ctx.RevisionLimit); err != nil {
+		return errors.Wrap(err, "updating the note")
+	}
+
+	newTags := tags.Union(tags.Difference(info.Tags, tags.Extract(info.Content)), tags.Extract(rev.Body))
+	if !tags.Equal(info.Tags, newTags) {
+		if err := database.UpdateNoteTags(tx, ctx.Clock, info.RowID, info.UUID, newTags); err != nil {
+			return errors.Wrap(err, "updating tags")
+		}
+	}
+
+	return nil
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+		revID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.Wrap(err, "invalid revision id")
+		}
+
+		info, err := database.GetNoteInfo(ctx.DB, rowID)
+		if err != nil {
+			return err
+		}
+
+		rev, err := database.GetNoteRevision(ctx.DB, info.UUID, revID)
+		if err != nil {
+			return err
+		}
+
+		if rev.Body == info.Content {
+			return errors.New("the note already has the content of the revision")
+		}
+
+		tx, err := ctx.DB.Begin()
+		if err != nil {
+			return errors.Wrap(err, "beginning a transaction")
+		}
+
+		if err := restoreRevision(ctx, tx, info, rev); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		noteInfo, err := database.GetNoteInfo(tx, rowID)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "getting note info")
+		}
+
+		if err := tx.Commit(); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "committing a transaction")
+		}
+
+		log.Successf("restored the revision %d\n", rev.RowID)
+		return output.NoteInfo(noteInfo)
+	}
+}
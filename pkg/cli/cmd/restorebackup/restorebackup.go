@@ -0,0 +1,107 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package restorebackup
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/completion"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/migrate"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Restore a backup in the backups directory
+  dnote restore-backup dnote-20240311T093000.000000.db
+
+  * Restore a backup elsewhere without a prompt
+  dnote restore-backup ~/backups/dnote.db -y`
+
+var yesFlag bool
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new restore-backup command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore-backup <file>",
+		Short: "Replace the local database with a backup",
+		Long: `Replace the local database with a backup taken by 'dnote backup'. The file is
+either a path or the name of a backup in the backups directory. The current
+database is backed up before it is replaced, and a backup taken by an older
+version of dnote is migrated the next time dnote runs.`,
+		Example:           example,
+		PreRunE:           preRun,
+		RunE:              newRun(ctx),
+		ValidArgsFunction: completion.Backups(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		path, err := backup.Resolve(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		if !yesFlag {
+			ok, err := ui.Confirm(fmt.Sprintf("replace the local database with %s?", path), false)
+			if err != nil {
+				return errors.Wrap(err, "getting confirmation")
+			}
+			if !ok {
+				log.Warnf("aborted by user\n")
+				return nil
+			}
+		}
+
+		current, err := backup.Restore(ctx, path, len(migrate.LocalSequence))
+		if current != "" {
+			log.Infof("backed up the replaced database to %s\n", current)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := backup.Rotate(ctx, ctx.BackupLimit); err != nil {
+			return errors.Wrap(err, "rotating the backups")
+		}
+
+		log.Successf("restored %s\n", path)
+
+		return nil
+	}
+}
@@ -0,0 +1,205 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package reviewcmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/completion"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/review"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * Review the notes due today
+  dnote review
+
+  * Review the notes in a book and the books nested in it
+  dnote review -b work/
+
+  * Review up to 5 notes a day
+  dnote review --limit 5`
+
+var bookFlag string
+var limitFlag int
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+	if limitFlag < 1 {
+		return errors.New("--limit must be at least 1")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new review command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "review",
+		Short:   "Review the notes that are due one at a time",
+		Long:    "Review the notes that are due one at a time. After each note, grade how well you recalled it, and the note is scheduled to come back after an interval that grows as you remember it.",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&bookFlag, "book", "b", "", "the book to review notes in. A name ending with '/' such as 'work/' includes the nested books")
+	f.IntVarP(&limitFlag, "limit", "", 20, "the maximum number of notes to review a day")
+	cmd.RegisterFlagCompletionFunc("book", completion.BookFlag(ctx))
+
+	return cmd
+}
+
+// errQuit is returned when the user quits the review
+var errQuit = errors.New("quit")
+
+// errSkip is returned when the user skips a note
+var errSkip = errors.New("skip")
+
+// readGrade prompts the user for a grade until a valid one is given
+func readGrade(r *bufio.Reader) (review.Grade, error) {
+	for {
+		log.Askf("(1) again (2) hard (3) good (4) easy (s) skip (q) quit", false)
+
+		input, err := r.ReadString('\n')
+		if err != nil && !(err == io.EOF && input != "") {
+			if err == io.EOF {
+				fmt.Println()
+				return 0, errQuit
+			}
+
+			return 0, errors.Wrap(err, "reading stdin")
+		}
+
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "s", "skip":
+			return 0, errSkip
+		case "q", "quit":
+			return 0, errQuit
+		}
+
+		g, err := review.ParseGrade(input)
+		if err != nil {
+			log.Warnf("%s\n", err.Error())
+			continue
+		}
+
+		return g, nil
+	}
+}
+
+// grade schedules the next review of the note
+func grade(ctx context.DnoteCtx, n database.DueNote, g review.Grade) (database.NoteReview, error) {
+	r, ok, err := database.GetNoteReview(ctx.DB, n.UUID)
+	if err != nil {
+		return r, errors.Wrap(err, "getting the review")
+	}
+	if !ok {
+		r = review.New(n.UUID)
+	}
+
+	r = review.Schedule(r, g, ctx.Clock.Now())
+	if err := r.Upsert(ctx.DB); err != nil {
+		return r, errors.Wrap(err, "saving the review")
+	}
+
+	return r, nil
+}
+
+func printNote(n database.DueNote, idx, total int) {
+	status := "due"
+	if n.New {
+		status = "new"
+	}
+
+	fmt.Println()
+	log.Infof("(%d/%d) note %s in %s %s\n", idx+1, total, log.ColorYellow.Sprintf("%d", n.RowID), n.BookLabel, log.ColorGray.Sprintf("(%s)", status))
+	fmt.Printf("\n%s\n\n", strings.TrimSpace(n.Body))
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		now := ctx.Clock.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+		reviewed, err := database.CountReviewedSince(ctx.DB, today.UnixNano())
+		if err != nil {
+			return errors.Wrap(err, "counting the notes reviewed today")
+		}
+		if reviewed >= limitFlag {
+			log.Infof("you have reviewed %d notes today, reaching the daily limit of %d\n", reviewed, limitFlag)
+			return nil
+		}
+
+		notes, err := database.GetDueNotes(ctx.DB, now.UnixNano(), bookFlag, limitFlag-reviewed)
+		if err != nil {
+			return errors.Wrap(err, "getting the due notes")
+		}
+		if len(notes) == 0 {
+			log.Info("no notes are due for review\n")
+			return nil
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+
+		var count int
+		for i, n := range notes {
+			printNote(n, i, len(notes))
+
+			g, err := readGrade(reader)
+			if err == errSkip {
+				continue
+			} else if err == errQuit {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			r, err := grade(ctx, n, g)
+			if err != nil {
+				return errors.Wrapf(err, "grading note %d", n.RowID)
+			}
+			count++
+
+			days := "days"
+			if r.Interval == 1 {
+				days = "day"
+			}
+			log.Plainf("%s next review in %d %s\n", log.ColorGray.Sprint(g.String()), r.Interval, days)
+		}
+
+		fmt.Println()
+		log.Successf("reviewed %d notes\n", count)
+
+		return nil
+	}
+}
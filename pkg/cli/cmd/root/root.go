@@ -19,17 +19,65 @@
 package root
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+var outputFlag string
+var profileFlag string
+
 var root = &cobra.Command{
-	Use:           "dnote",
-	Short:         "Dnote - a simple command line notebook",
-	SilenceErrors: true,
-	SilenceUsage:  true,
-	CompletionOptions: cobra.CompletionOptions{
-		DisableDefaultCmd: true,
-	},
+	Use:               "dnote",
+	Short:             "Dnote - a simple command line notebook",
+	SilenceErrors:     true,
+	SilenceUsage:      true,
+	PersistentPreRunE: persistentPreRun,
+}
+
+func init() {
+	root.PersistentFlags().StringVarP(&outputFlag, "output", "", output.FormatText, fmt.Sprintf("the output format. One of %s", strings.Join(output.Formats, ", ")))
+	root.PersistentFlags().StringVarP(&profileFlag, "profile", "", "", fmt.Sprintf("the profile to use. Overrides %s", consts.ProfileEnvName))
+}
+
+// GetProfileFlag returns the value of the profile flag in the given command line
+// arguments. The flag is read ahead of the command line parsing because the
+// context is set up for the profile before the commands are registered.
+func GetProfileFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if arg == "--profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, "--profile=") {
+			return strings.TrimPrefix(arg, "--profile=")
+		}
+	}
+
+	return ""
+}
+
+// persistentPreRun sets up the output format. With any format other than text,
+// messages are printed to the standard error so that the standard output only
+// contains the formatted data.
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	if err := output.SetFormat(outputFlag); err != nil {
+		return err
+	}
+
+	if outputFlag != output.FormatText {
+		log.SetOutput(color.Error)
+	}
+
+	return nil
 }
 
 // Register adds a new command
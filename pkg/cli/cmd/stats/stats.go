@@ -0,0 +1,95 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package statscmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/stats"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * See the report with the activity by week
+  dnote stats
+
+  * See the activity of the last 30 days
+  dnote stats --period day --count 30
+
+  * Print the report as JSON
+  dnote stats --output json`
+
+var periodFlag string
+var countFlag int
+var topFlag int
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+	if err := stats.ValidatePeriod(periodFlag); err != nil {
+		return err
+	}
+	if countFlag < 1 {
+		return errors.New("--count must be at least 1")
+	}
+	if topFlag < 0 {
+		return errors.New("--top cannot be negative")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new stats command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "stats",
+		Short:   "See a report on the notes and the books",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&periodFlag, "period", "", stats.PeriodWeek, fmt.Sprintf("the period to group the activity by. One of %s", strings.Join(stats.Periods, ", ")))
+	f.IntVarP(&countFlag, "count", "", 12, "the number of periods of activity to show")
+	f.IntVarP(&topFlag, "top", "", 5, "the number of the largest books to show")
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		report, err := stats.New(ctx.DB, ctx.Clock.Now(), stats.Options{
+			Period:      periodFlag,
+			PeriodCount: countFlag,
+			TopBooks:    topFlag,
+		})
+		if err != nil {
+			return errors.Wrap(err, "computing the report")
+		}
+
+		return output.Stats(report)
+	}
+}
@@ -0,0 +1,201 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dnote/dnote/pkg/cli/attachments"
+	"github.com/dnote/dnote/pkg/cli/client"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+)
+
+// sendAttachments sends the attachments added locally to the server, uploading
+// the blobs that the server does not have
+func sendAttachments(ctx context.DnoteCtx, tx *database.DB) error {
+	list, err := database.GetDirtyAttachments(tx)
+	if err != nil {
+		return errors.Wrap(err, "getting dirty attachments")
+	}
+
+	for _, a := range list {
+		ok, err := client.HasBlob(ctx, a.Hash)
+		if err != nil {
+			return errors.Wrapf(err, "checking the blob of attachment %s", a.UUID)
+		}
+
+		if !ok {
+			if err := uploadBlob(ctx, a.Hash); err != nil {
+				return errors.Wrapf(err, "uploading the blob of attachment %s", a.UUID)
+			}
+		}
+
+		_, err = client.CreateAttachment(ctx, client.CreateAttachmentPayload{
+			UUID:     a.UUID,
+			NoteUUID: a.NoteUUID,
+			Name:     a.Name,
+			Hash:     a.Hash,
+			Size:     a.Size,
+			AddedOn:  a.AddedOn,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "creating attachment %s", a.UUID)
+		}
+
+		if err := database.MarkAttachmentClean(tx, a.UUID); err != nil {
+			return errors.Wrapf(err, "marking attachment %s clean", a.UUID)
+		}
+	}
+
+	return nil
+}
+
+func uploadBlob(ctx context.DnoteCtx, hash string) error {
+	f, err := attachments.Open(ctx, hash)
+	if err != nil {
+		return errors.Wrap(err, "opening the blob")
+	}
+	defer f.Close()
+
+	return client.UploadBlob(ctx, hash, f)
+}
+
+// receiveAttachments saves the attachments that are in the server but not in the
+// client, for the notes that exist in the client
+func receiveAttachments(ctx context.DnoteCtx, tx *database.DB) error {
+	resp, err := client.GetAttachments(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting attachments from the server")
+	}
+
+	for _, ra := range resp.Attachments {
+		ok, err := database.AttachmentExists(tx, ra.UUID)
+		if err != nil {
+			return errors.Wrapf(err, "checking attachment %s", ra.UUID)
+		}
+		if ok {
+			continue
+		}
+
+		var noteCount int
+		if err := tx.QueryRow("SELECT count(*) FROM notes WHERE uuid = ?", ra.NoteUUID).Scan(&noteCount); err != nil {
+			return errors.Wrapf(err, "checking the note of attachment %s", ra.UUID)
+		}
+		if noteCount == 0 {
+			log.Debug("skipping attachment %s of a note that does not exist locally\n", ra.UUID)
+			continue
+		}
+
+		a := database.Attachment{
+			UUID:     ra.UUID,
+			NoteUUID: ra.NoteUUID,
+			Name:     ra.Name,
+			Hash:     ra.Hash,
+			Size:     ra.Size,
+			AddedOn:  ra.AddedOn,
+			Dirty:    false,
+		}
+		if err := a.Insert(tx); err != nil {
+			return errors.Wrapf(err, "inserting attachment %s", ra.UUID)
+		}
+	}
+
+	return nil
+}
+
+// downloadMissingBlobs downloads the blobs of the attachments that are not in
+// the local storage
+func downloadMissingBlobs(ctx context.DnoteCtx, tx *database.DB) error {
+	hashes, err := database.GetAttachmentHashes(tx)
+	if err != nil {
+		return errors.Wrap(err, "getting the hashes of the attachments")
+	}
+
+	for _, hash := range hashes {
+		ok, err := attachments.Exists(ctx, hash)
+		if err != nil {
+			return errors.Wrapf(err, "checking blob %s", hash)
+		}
+		if ok {
+			continue
+		}
+
+		if err := downloadBlob(ctx, hash); err != nil {
+			return errors.Wrapf(err, "downloading blob %s", hash)
+		}
+	}
+
+	return nil
+}
+
+func downloadBlob(ctx context.DnoteCtx, hash string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(client.DownloadBlob(ctx, hash, pw))
+	}()
+
+	got, _, err := attachments.Put(ctx, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return errors.Wrap(err, "storing the blob")
+	}
+	if got != hash {
+		return errors.Errorf("hash mismatch. got: %s", got)
+	}
+
+	return nil
+}
+
+// syncAttachments transfers the attachments and their blobs that are missing
+// on either side. It must run after the notes are synced so that attachments
+// refer to notes that exist on both sides. With the end-to-end encryption, the
+// attachments are only received, because they cannot be encrypted. Those added
+// locally stay dirty and are not sent.
+func syncAttachments(ctx context.DnoteCtx, tx *database.DB) error {
+	log.Info("syncing attachments.")
+
+	var held []database.Attachment
+	if ctx.CipherKey != nil {
+		list, err := database.GetDirtyAttachments(tx)
+		if err != nil {
+			return errors.Wrap(err, "getting dirty attachments")
+		}
+		held = list
+	} else if err := sendAttachments(ctx, tx); err != nil {
+		return errors.Wrap(err, "sending attachments")
+	}
+	if err := receiveAttachments(ctx, tx); err != nil {
+		return errors.Wrap(err, "receiving attachments")
+	}
+	if err := downloadMissingBlobs(ctx, tx); err != nil {
+		return errors.Wrap(err, "downloading blobs")
+	}
+
+	fmt.Println(" done.")
+
+	if len(held) > 0 {
+		log.Warnf("%d attachment(s) not sent because attachments cannot be encrypted end-to-end\n", len(held))
+	}
+
+	return nil
+}
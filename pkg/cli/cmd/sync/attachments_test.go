@@ -0,0 +1,254 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/attachments"
+	"github.com/dnote/dnote/pkg/cli/client"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/testutils"
+	"github.com/pkg/errors"
+)
+
+func hashOf(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func TestSyncAttachments(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+
+	db := ctx.DB
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 1, false, false)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", 10, "n1-body", 1541108743, false, false)
+	// not yet in the server
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", 0, "n2-body", 1541108743, false, true)
+
+	localHash, _, err := attachments.Put(ctx, strings.NewReader("local content"))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "putting the local blob"))
+	}
+
+	// should be sent
+	a1 := database.Attachment{UUID: "a1-uuid", NoteUUID: "n1-uuid", Name: "a1.txt", Hash: localHash, Size: 13, AddedOn: 1, Dirty: true}
+	// should be kept until its note is sent
+	a2 := database.Attachment{UUID: "a2-uuid", NoteUUID: "n2-uuid", Name: "a2.txt", Hash: localHash, Size: 13, AddedOn: 2, Dirty: true}
+	for _, a := range []database.Attachment{a1, a2} {
+		if err := a.Insert(db); err != nil {
+			t.Fatal(errors.Wrap(err, "inserting attachment"))
+		}
+	}
+
+	serverHash := hashOf("server content")
+	serverBlobs := map[string]string{
+		serverHash: "server content",
+	}
+	serverAttachments := []client.RespAttachment{
+		// should be received
+		{UUID: "a3-uuid", NoteUUID: "n1-uuid", Name: "a3.txt", Hash: serverHash, Size: 14, AddedOn: 3},
+		// should be ignored because the note does not exist locally
+		{UUID: "a4-uuid", NoteUUID: "n4-uuid", Name: "a4.txt", Hash: serverHash, Size: 14, AddedOn: 4},
+	}
+	var createdUUIDs []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v3/blobs/") {
+			hash := strings.TrimPrefix(r.URL.Path, "/v3/blobs/")
+			content, ok := serverBlobs[hash]
+
+			switch r.Method {
+			case "HEAD":
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+				}
+				return
+			case "GET":
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write([]byte(content))
+				return
+			case "PUT":
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(errors.Wrap(err, "reading the blob in the test server"))
+				}
+				serverBlobs[hash] = string(b)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte("{}"))
+				return
+			}
+		}
+
+		if r.URL.Path == "/v3/attachments" && r.Method == "POST" {
+			var payload client.CreateAttachmentPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding payload in the test server"))
+			}
+			createdUUIDs = append(createdUUIDs, payload.UUID)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{}"))
+			return
+		}
+
+		if r.URL.Path == "/v3/attachments" && r.Method == "GET" {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(client.GetAttachmentsResp{Attachments: serverAttachments}); err != nil {
+				t.Fatal(errors.Wrap(err, "encoding the response in the test server"))
+			}
+			return
+		}
+
+		t.Fatalf("unrecognized endpoint reached Method: %s Path: %s", r.Method, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if err := syncAttachments(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	tx.Commit()
+
+	// test
+	assert.DeepEqual(t, createdUUIDs, []string{"a1-uuid"}, "createdUUIDs mismatch")
+	assert.Equal(t, serverBlobs[localHash], "local content", "uploaded blob mismatch")
+
+	var a1Dirty, a2Dirty bool
+	database.MustScan(t, "getting a1", db.QueryRow("SELECT dirty FROM attachments WHERE uuid = ?", "a1-uuid"), &a1Dirty)
+	database.MustScan(t, "getting a2", db.QueryRow("SELECT dirty FROM attachments WHERE uuid = ?", "a2-uuid"), &a2Dirty)
+	assert.Equal(t, a1Dirty, false, "a1 dirty mismatch")
+	assert.Equal(t, a2Dirty, true, "a2 dirty mismatch")
+
+	var a3 database.Attachment
+	database.MustScan(t, "getting a3", db.QueryRow("SELECT note_uuid, name, hash, dirty FROM attachments WHERE uuid = ?", "a3-uuid"), &a3.NoteUUID, &a3.Name, &a3.Hash, &a3.Dirty)
+	assert.Equal(t, a3.NoteUUID, "n1-uuid", "a3 NoteUUID mismatch")
+	assert.Equal(t, a3.Name, "a3.txt", "a3 Name mismatch")
+	assert.Equal(t, a3.Hash, serverHash, "a3 Hash mismatch")
+	assert.Equal(t, a3.Dirty, false, "a3 Dirty mismatch")
+
+	var a4Count int
+	database.MustScan(t, "counting a4", db.QueryRow("SELECT count(*) FROM attachments WHERE uuid = ?", "a4-uuid"), &a4Count)
+	assert.Equal(t, a4Count, 0, "a4 should not be received")
+
+	f, err := attachments.Open(ctx, serverHash)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the downloaded blob"))
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the downloaded blob"))
+	}
+	assert.Equal(t, string(b), "server content", "downloaded blob mismatch")
+}
+
+func TestSyncAttachments_encrypted(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+	ctx.CipherKey = testCipherKey
+
+	db := ctx.DB
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 1, false, false)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", 10, "n1-body", 1541108743, false, false)
+
+	localHash, _, err := attachments.Put(ctx, strings.NewReader("local content"))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "putting the local blob"))
+	}
+
+	a1 := database.Attachment{UUID: "a1-uuid", NoteUUID: "n1-uuid", Name: "a1.txt", Hash: localHash, Size: 13, AddedOn: 1, Dirty: true}
+	if err := a1.Insert(db); err != nil {
+		t.Fatal(errors.Wrap(err, "inserting attachment"))
+	}
+
+	serverHash := hashOf("server content")
+	serverAttachments := []client.RespAttachment{
+		{UUID: "a2-uuid", NoteUUID: "n1-uuid", Name: "a2.txt", Hash: serverHash, Size: 14, AddedOn: 2},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/blobs/"+serverHash && r.Method == "GET" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("server content"))
+			return
+		}
+
+		if r.URL.Path == "/v3/attachments" && r.Method == "GET" {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(client.GetAttachmentsResp{Attachments: serverAttachments}); err != nil {
+				t.Fatal(errors.Wrap(err, "encoding the response in the test server"))
+			}
+			return
+		}
+
+		t.Fatalf("unexpected request Method: %s Path: %s", r.Method, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if err := syncAttachments(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	tx.Commit()
+
+	// test
+	var a1Dirty bool
+	database.MustScan(t, "getting a1", db.QueryRow("SELECT dirty FROM attachments WHERE uuid = ?", "a1-uuid"), &a1Dirty)
+	assert.Equal(t, a1Dirty, true, "a1 should not be sent")
+
+	var a2Count int
+	database.MustScan(t, "counting a2", db.QueryRow("SELECT count(*) FROM attachments WHERE uuid = ?", "a2-uuid"), &a2Count)
+	assert.Equal(t, a2Count, 1, "a2 should be received")
+}
@@ -0,0 +1,238 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"database/sql"
+
+	"github.com/dnote/dnote/pkg/cli/client"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+)
+
+// getCipherKey returns the key to encrypt the synced data with, or nil if the
+// account syncs in plaintext. If setup is true, it sets up the end-to-end
+// encryption of the account unless it already has one.
+func getCipherKey(ctx context.DnoteCtx, setup bool) ([]byte, error) {
+	settings, err := client.GetEncryption(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting the encryption settings")
+	}
+
+	if !settings.Enabled {
+		if ctx.CipherKey != nil {
+			return nil, errors.New("the server does not have the encryption key of this account. Run 'dnote login' again")
+		}
+		if !setup {
+			return nil, nil
+		}
+
+		if !ui.IsTerminal() {
+			return nil, errors.New("the password and the passphrase are required to set up the encryption. Run 'dnote sync --encrypt' in a terminal")
+		}
+
+		password, passphrase, err := readSetupInput()
+		if err != nil {
+			return nil, err
+		}
+
+		return setupEncryption(ctx, password, passphrase)
+	}
+
+	if ctx.CipherKey == nil {
+		return nil, errors.New("the account uses end-to-end encryption. Run 'dnote login' to unlock the encryption key")
+	}
+	if !e2ee.CheckKey(settings, ctx.CipherKey) {
+		return nil, errors.New("the encryption key does not match that of the account. Run 'dnote login' again")
+	}
+
+	return ctx.CipherKey, nil
+}
+
+// readSetupInput reads the password of the account, which authorizes the setup,
+// and a new encryption passphrase, asking for it twice
+func readSetupInput() (string, string, error) {
+	var password, passphrase, confirmation string
+	if err := ui.PromptPassword("password", &password); err != nil {
+		return "", "", errors.Wrap(err, "getting password input")
+	}
+
+	log.Plain("Choose a passphrase to encrypt the notes with. It is never sent to the server, and the notes cannot be recovered without it.\n")
+	if err := ui.PromptPassword("new passphrase", &passphrase); err != nil {
+		return "", "", errors.Wrap(err, "getting the passphrase")
+	}
+	if err := ui.PromptPassword("confirm the passphrase", &confirmation); err != nil {
+		return "", "", errors.Wrap(err, "getting the confirmation")
+	}
+	if passphrase != confirmation {
+		return "", "", errors.New("the passphrases do not match")
+	}
+
+	return password, passphrase, nil
+}
+
+// setupEncryption generates the cipher key of the account, stores it in the
+// server encrypted with the passphrase, and saves it locally. The password of
+// the account authorizes the setup. The passphrase must differ from it because
+// the server receives the password.
+func setupEncryption(ctx context.DnoteCtx, password, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("the passphrase is empty")
+	}
+	if passphrase == password {
+		return nil, errors.New("the passphrase must differ from the password of the account")
+	}
+
+	cipherKey, settings, err := e2ee.Setup(passphrase, consts.EncryptionIteration)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating the encryption key")
+	}
+
+	_, err = client.UpdateEncryption(ctx, password, settings)
+	if errors.Cause(err) == client.ErrInvalidLogin {
+		return nil, errors.New("wrong password")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "saving the encryption settings in the server")
+	}
+
+	// Save the key right away, so that it is not lost if the sync fails. Until a
+	// sync completes, the books and notes in the server can still be in plaintext.
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning a transaction")
+	}
+	if err := e2ee.Save(tx, cipherKey); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := database.UpsertSystem(tx, consts.SystemEncryptionPending, "true"); err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "marking the encryption pending")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing the transaction")
+	}
+
+	return cipherKey, nil
+}
+
+// isEncryptionPending tells if the books and notes have not all been sent to
+// the server encrypted since the end-to-end encryption was set up
+func isEncryptionPending(tx *database.DB) (bool, error) {
+	var ret bool
+	err := database.GetSystem(tx, consts.SystemEncryptionPending, &ret)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "finding the pending encryption")
+	}
+
+	return ret, nil
+}
+
+// markAllDirty marks all books and notes dirty, so that they are sent to the
+// server again, encrypted
+func markAllDirty(tx *database.DB) error {
+	if _, err := tx.Exec("UPDATE books SET dirty = ? WHERE NOT deleted", true); err != nil {
+		return errors.Wrap(err, "marking books dirty")
+	}
+	if _, err := tx.Exec("UPDATE notes SET dirty = ? WHERE NOT deleted", true); err != nil {
+		return errors.Wrap(err, "marking notes dirty")
+	}
+
+	return nil
+}
+
+// decryptFragNote decrypts the content and the tags of a note in a sync fragment
+func decryptFragNote(cipherKey []byte, n client.SyncFragNote) (client.SyncFragNote, error) {
+	// A deleted note has no content
+	if !n.Encrypted || n.Deleted {
+		return n, nil
+	}
+	if cipherKey == nil {
+		return n, errors.New("the note is encrypted. Run 'dnote login' to unlock the encryption key")
+	}
+
+	body, tags, err := e2ee.DecryptNote(cipherKey, n.Body)
+	if err != nil {
+		return n, err
+	}
+
+	n.Body = body
+	n.Tags = tags
+	n.Encrypted = false
+
+	return n, nil
+}
+
+// decryptFragBook decrypts the label of a book in a sync fragment
+func decryptFragBook(cipherKey []byte, b client.SyncFragBook) (client.SyncFragBook, error) {
+	// A deleted book has no label
+	if !b.Encrypted || b.Deleted {
+		return b, nil
+	}
+	if cipherKey == nil {
+		return b, errors.New("the book is encrypted. Run 'dnote login' to unlock the encryption key")
+	}
+
+	label, err := e2ee.DecryptLabel(cipherKey, b.Label)
+	if err != nil {
+		return b, err
+	}
+
+	b.Label = label
+	b.Encrypted = false
+
+	return b, nil
+}
+
+// encryptNote returns the content and the tags of a note to send to the server,
+// and whether they are encrypted. The tags are encrypted with the content.
+func encryptNote(cipherKey []byte, body string, tags []string) (string, []string, bool, error) {
+	if cipherKey == nil {
+		return body, tags, false, nil
+	}
+
+	content, err := e2ee.EncryptNote(cipherKey, body, tags)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	return content, []string{}, true, nil
+}
+
+// encryptLabel returns the label of a book to send to the server, and whether it
+// is encrypted
+func encryptLabel(cipherKey []byte, label string) (string, bool, error) {
+	if cipherKey == nil {
+		return label, false, nil
+	}
+
+	ret, err := e2ee.EncryptLabel(cipherKey, label)
+	if err != nil {
+		return "", false, err
+	}
+
+	return ret, true, nil
+}
@@ -0,0 +1,462 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/client"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
+	"github.com/dnote/dnote/pkg/cli/testutils"
+	"github.com/pkg/errors"
+)
+
+var testCipherKey = []byte("AES256Key-32Characters1234567890")
+
+func mustEncryptNote(t *testing.T, body string, tags []string) string {
+	ret, err := e2ee.EncryptNote(testCipherKey, body, tags)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting note"))
+	}
+
+	return ret
+}
+
+func mustEncryptLabel(t *testing.T, label string) string {
+	ret, err := e2ee.EncryptLabel(testCipherKey, label)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting label"))
+	}
+
+	return ret
+}
+
+func TestProcessFragments_encrypted(t *testing.T) {
+	fragments := []client.SyncFragment{
+		{
+			FragMaxUSN:  3,
+			UserMaxUSN:  3,
+			CurrentTime: 1550436136,
+			Notes: []client.SyncFragNote{
+				{UUID: "n1-uuid", Body: mustEncryptNote(t, "n1 body", []string{"go"}), Encrypted: true, Tags: []string{}},
+				{UUID: "n2-uuid", Body: "", Encrypted: true, Deleted: true},
+				{UUID: "n3-uuid", Body: "n3 body", Tags: []string{"js"}},
+			},
+			Books: []client.SyncFragBook{
+				{UUID: "b1-uuid", Label: mustEncryptLabel(t, "golang"), Encrypted: true},
+				{UUID: "b2-uuid", Label: "", Encrypted: true, Deleted: true},
+			},
+			ExpungedNotes: []string{},
+			ExpungedBooks: []string{},
+		},
+	}
+
+	t.Run("with key", func(t *testing.T) {
+		sl, err := processFragments(fragments, testCipherKey)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, sl.Notes, map[string]client.SyncFragNote{
+			"n1-uuid": {UUID: "n1-uuid", Body: "n1 body", Tags: []string{"go"}},
+			"n2-uuid": {UUID: "n2-uuid", Body: "", Encrypted: true, Deleted: true},
+			"n3-uuid": {UUID: "n3-uuid", Body: "n3 body", Tags: []string{"js"}},
+		}, "notes mismatch")
+		assert.DeepEqual(t, sl.Books, map[string]client.SyncFragBook{
+			"b1-uuid": {UUID: "b1-uuid", Label: "golang"},
+			"b2-uuid": {UUID: "b2-uuid", Label: "", Encrypted: true, Deleted: true},
+		}, "books mismatch")
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		if _, err := processFragments(fragments, nil); err == nil {
+			t.Error("should fail without the key")
+		}
+	})
+}
+
+func TestSendNotes_encrypted(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+	ctx.CipherKey = testCipherKey
+
+	db := ctx.DB
+
+	database.MustExec(t, "inserting last max usn", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastMaxUSN, 0)
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 1, false, false)
+	// should be created
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", 0, "n1-body", 1541108743, false, true)
+	// should be updated
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", 11, "n2-body", 1541108743, false, true)
+	if err := database.SetNoteTags(db, "n2-uuid", []string{"go", "sql"}); err != nil {
+		t.Fatal(errors.Wrap(err, "setting tags of n2"))
+	}
+
+	type sentNote struct {
+		Body string
+		Tags []string
+	}
+	sent := map[string]sentNote{}
+
+	// fire up a test server. It decrypts the payload for test purposes.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Content   string   `json:"content"`
+			Tags      []string `json:"tags"`
+			Encrypted bool     `json:"encrypted"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload in the test server"))
+		}
+
+		assert.Equal(t, payload.Encrypted, true, "Encrypted mismatch")
+		assert.DeepEqual(t, payload.Tags, []string{}, "tags should be sent in the encrypted content")
+
+		body, tags, err := e2ee.DecryptNote(testCipherKey, payload.Content)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "decrypting in the test server"))
+		}
+
+		var uuid string
+		if r.URL.Path == "/v3/notes" && r.Method == "POST" {
+			uuid = "server-n1-uuid"
+		} else if r.URL.Path == "/v3/notes/n2-uuid" && r.Method == "PATCH" {
+			uuid = "n2-uuid"
+		} else {
+			t.Fatalf("unrecognized endpoint reached Method: %s Path: %s", r.Method, r.URL.Path)
+		}
+		sent[uuid] = sentNote{Body: body, Tags: tags}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.CreateNoteResp{
+			Result: client.RespNote{UUID: uuid},
+		})
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if _, err := sendNotes(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	tx.Commit()
+
+	// test
+	assert.DeepEqual(t, sent, map[string]sentNote{
+		"server-n1-uuid": {Body: "n1-body", Tags: []string{}},
+		"n2-uuid":        {Body: "n2-body", Tags: []string{"go", "sql"}},
+	}, "sent notes mismatch")
+
+	var n1Body string
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT body FROM notes WHERE uuid = ?", "server-n1-uuid"), &n1Body)
+	assert.Equal(t, n1Body, "n1-body", "the local note should stay in plaintext")
+}
+
+func TestSendBooks_encrypted(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+	ctx.CipherKey = testCipherKey
+
+	db := ctx.DB
+
+	database.MustExec(t, "inserting last max usn", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastMaxUSN, 0)
+	// should be created
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 0, false, true)
+	// should be updated
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "b2-label", 11, false, true)
+
+	var sentLabels []string
+
+	// fire up a test server. It decrypts the payload for test purposes.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !(r.URL.Path == "/v3/books" && r.Method == "POST") && !(r.URL.Path == "/v3/books/b2-uuid" && r.Method == "PATCH") {
+			t.Fatalf("unrecognized endpoint reached Method: %s Path: %s", r.Method, r.URL.Path)
+		}
+
+		var payload client.CreateBookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload in the test server"))
+		}
+
+		assert.Equal(t, payload.Encrypted, true, "Encrypted mismatch")
+
+		label, err := e2ee.DecryptLabel(testCipherKey, payload.Name)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "decrypting in the test server"))
+		}
+		sentLabels = append(sentLabels, label)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.CreateBookResp{
+			Book: client.RespBook{UUID: "server-" + label},
+		})
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if _, err := sendBooks(ctx, tx, false); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	tx.Commit()
+
+	// test
+	sort.Strings(sentLabels)
+	assert.DeepEqual(t, sentLabels, []string{"b1-label", "b2-label"}, "sent labels mismatch")
+}
+
+func TestSetupEncryption(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+
+	var stored client.EncryptionSettings
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/encryption" || r.Method != "PUT" {
+			t.Fatalf("unrecognized endpoint reached Method: %s Path: %s", r.Method, r.URL.Path)
+		}
+
+		var payload client.UpdateEncryptionPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload in the test server"))
+		}
+		if payload.Password != "pass1234" {
+			http.Error(w, "Invalid currnet password.", http.StatusUnauthorized)
+			return
+		}
+
+		stored = client.EncryptionSettings{
+			Enabled:      true,
+			Salt:         payload.Salt,
+			Iteration:    payload.Iteration,
+			CipherKeyEnc: payload.CipherKeyEnc,
+			KeyCheck:     payload.KeyCheck,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	failCases := []struct {
+		name       string
+		password   string
+		passphrase string
+	}{
+		{
+			name:       "wrong password",
+			password:   "pass5678",
+			passphrase: "phrase1234",
+		},
+		{
+			name:       "passphrase same as password",
+			password:   "pass1234",
+			passphrase: "pass1234",
+		},
+		{
+			name:       "empty passphrase",
+			password:   "pass1234",
+			passphrase: "",
+		},
+	}
+
+	for _, tc := range failCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := setupEncryption(ctx, tc.password, tc.passphrase); err == nil {
+				t.Fatal("should fail")
+			}
+
+			key, err := e2ee.Load(ctx.DB)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "loading the key"))
+			}
+			assert.DeepEqual(t, key, []byte(nil), "no key should be saved")
+
+			pending, err := isEncryptionPending(ctx.DB)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "checking the pending encryption"))
+			}
+			assert.Equal(t, pending, false, "the encryption should not be pending")
+		})
+	}
+
+	t.Run("passphrase", func(t *testing.T) {
+		cipherKey, err := setupEncryption(ctx, "pass1234", "phrase1234")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		key, err := e2ee.Load(ctx.DB)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "loading the key"))
+		}
+		assert.DeepEqual(t, key, cipherKey, "saved key mismatch")
+
+		pending, err := isEncryptionPending(ctx.DB)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "checking the pending encryption"))
+		}
+		assert.Equal(t, pending, true, "the encryption should be pending until a sync completes")
+
+		serverKey, err := e2ee.Unwrap(stored, "phrase1234")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "unwrapping the key in the server"))
+		}
+		assert.DeepEqual(t, serverKey, cipherKey, "key in the server mismatch")
+
+		if _, err := e2ee.Unwrap(stored, "pass1234"); err != e2ee.ErrWrongPassphrase {
+			t.Fatal("the password of the account should not decrypt the key")
+		}
+	})
+}
+
+func TestGetCipherKey(t *testing.T) {
+	settings, err := e2ee.Wrap(testCipherKey, "phrase1234", 1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "wrapping the key"))
+	}
+
+	testCases := []struct {
+		name      string
+		settings  client.EncryptionSettings
+		cipherKey []byte
+		expected  []byte
+		fail      bool
+	}{
+		{
+			name:      "plaintext",
+			settings:  client.EncryptionSettings{},
+			cipherKey: nil,
+			expected:  nil,
+		},
+		{
+			name:      "encrypted",
+			settings:  settings,
+			cipherKey: testCipherKey,
+			expected:  testCipherKey,
+		},
+		{
+			name:      "encrypted without key",
+			settings:  settings,
+			cipherKey: nil,
+			fail:      true,
+		},
+		{
+			name:      "encrypted with another key",
+			settings:  settings,
+			cipherKey: []byte("AES256Key-32Charactersabcdefghij"),
+			fail:      true,
+		},
+		{
+			name:      "key without encryption",
+			settings:  client.EncryptionSettings{},
+			cipherKey: testCipherKey,
+			fail:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tc.settings)
+			}))
+			defer ts.Close()
+
+			ctx := context.DnoteCtx{
+				APIEndpoint: ts.URL,
+				SessionKey:  "someSessionKey",
+				CipherKey:   tc.cipherKey,
+			}
+
+			got, err := getCipherKey(ctx, false)
+			if tc.fail {
+				if err == nil {
+					t.Fatal("should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "key mismatch")
+		})
+	}
+}
+
+func TestMarkAllDirty(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, dbPath, nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 1, false, false)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "b2-label", 2, true, false)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", 10, "n1-body", 1541108743, false, false)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", 11, "", 1541108743, true, false)
+
+	// execute
+	if err := markAllDirty(db); err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	var b1Dirty, b2Dirty, n1Dirty, n2Dirty bool
+	database.MustScan(t, "getting b1", db.QueryRow("SELECT dirty FROM books WHERE uuid = ?", "b1-uuid"), &b1Dirty)
+	database.MustScan(t, "getting b2", db.QueryRow("SELECT dirty FROM books WHERE uuid = ?", "b2-uuid"), &b2Dirty)
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT dirty FROM notes WHERE uuid = ?", "n1-uuid"), &n1Dirty)
+	database.MustScan(t, "getting n2", db.QueryRow("SELECT dirty FROM notes WHERE uuid = ?", "n2-uuid"), &n2Dirty)
+
+	assert.Equal(t, b1Dirty, true, "b1 dirty mismatch")
+	assert.Equal(t, b2Dirty, false, "b2 dirty mismatch")
+	assert.Equal(t, n1Dirty, true, "n1 dirty mismatch")
+	assert.Equal(t, n2Dirty, false, "n2 dirty mismatch")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/migrate"
+	"github.com/dnote/dnote/pkg/cli/tags"
 	"github.com/dnote/dnote/pkg/cli/upgrade"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -42,7 +43,7 @@ const (
 var example = `
   dnote sync`
 
-var isFullSync bool
+var isFullSync, isEncrypt bool
 
 // NewCmd returns a new sync command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
@@ -56,6 +57,7 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 
 	f := cmd.Flags()
 	f.BoolVarP(&isFullSync, "full", "f", false, "perform a full sync instead of incrementally syncing only the changed data.")
+	f.BoolVar(&isEncrypt, "encrypt", false, "set up the end-to-end encryption of the account, and encrypt all notes and books in the server.")
 
 	return cmd
 }
@@ -95,8 +97,8 @@ func (l syncList) getLength() int {
 }
 
 // processFragments categorizes items in sync fragments into a sync list. It also decrypts any
-// encrypted data in sync fragments.
-func processFragments(fragments []client.SyncFragment) (syncList, error) {
+// encrypted data in sync fragments with the given cipher key.
+func processFragments(fragments []client.SyncFragment, cipherKey []byte) (syncList, error) {
 	notes := map[string]client.SyncFragNote{}
 	books := map[string]client.SyncFragBook{}
 	expungedNotes := map[string]bool{}
@@ -106,10 +108,20 @@ func processFragments(fragments []client.SyncFragment) (syncList, error) {
 
 	for _, fragment := range fragments {
 		for _, note := range fragment.Notes {
-			notes[note.UUID] = note
+			n, err := decryptFragNote(cipherKey, note)
+			if err != nil {
+				return syncList{}, errors.Wrapf(err, "decrypting note %s", note.UUID)
+			}
+
+			notes[note.UUID] = n
 		}
 		for _, book := range fragment.Books {
-			books[book.UUID] = book
+			b, err := decryptFragBook(cipherKey, book)
+			if err != nil {
+				return syncList{}, errors.Wrapf(err, "decrypting book %s", book.UUID)
+			}
+
+			books[book.UUID] = b
 		}
 		for _, uuid := range fragment.ExpungedBooks {
 			expungedBooks[uuid] = true
@@ -146,7 +158,7 @@ func getSyncList(ctx context.DnoteCtx, afterUSN int) (syncList, error) {
 		return syncList{}, errors.Wrap(err, "getting sync fragments")
 	}
 
-	ret, err := processFragments(fragments)
+	ret, err := processFragments(fragments, ctx.CipherKey)
 	if err != nil {
 		return syncList{}, errors.Wrap(err, "making sync list")
 	}
@@ -283,6 +295,14 @@ func mergeNote(tx *database.DB, serverNote client.SyncFragNote, localNote databa
 			return errors.Wrapf(err, "updating local note %s", serverNote.UUID)
 		}
 
+		if err := mergeNoteTags(tx, serverNote, localNote, serverNote.Body); err != nil {
+			return errors.Wrapf(err, "merging tags of note %s", serverNote.UUID)
+		}
+
+		if err := database.IndexNoteLinks(tx, serverNote.UUID, serverNote.Body); err != nil {
+			return errors.Wrapf(err, "indexing links of note %s", serverNote.UUID)
+		}
+
 		return nil
 	}
 
@@ -296,6 +316,64 @@ func mergeNote(tx *database.DB, serverNote client.SyncFragNote, localNote databa
 		return errors.Wrapf(err, "updating local note %s", serverNote.UUID)
 	}
 
+	if err := mergeNoteTags(tx, serverNote, localNote, mr.body); err != nil {
+		return errors.Wrapf(err, "merging tags of note %s", serverNote.UUID)
+	}
+
+	if err := database.IndexNoteLinks(tx, serverNote.UUID, mr.body); err != nil {
+		return errors.Wrapf(err, "indexing links of note %s", serverNote.UUID)
+	}
+
+	return nil
+}
+
+// mergeNoteTags updates the tags of the local note with the tags from the server.
+// The tags of a dirty local note are kept so that they can be uploaded later.
+// The hashtags in the merged body are always kept.
+func mergeNoteTags(tx *database.DB, serverNote client.SyncFragNote, localNote database.Note, body string) error {
+	localTags, err := database.GetNoteTags(tx, serverNote.UUID)
+	if err != nil {
+		return errors.Wrap(err, "getting local tags")
+	}
+
+	var base []string
+	if serverNote.Tags == nil {
+		// the server does not support tags. Only update the hashtags.
+		base = tags.Difference(localTags, tags.Extract(localNote.Body))
+	} else if localNote.Dirty && !localNote.Deleted {
+		base = tags.Union(localTags, serverNote.Tags)
+	} else {
+		base = serverNote.Tags
+	}
+
+	merged := tags.Union(base, tags.Extract(body))
+	if tags.Equal(localTags, merged) {
+		return nil
+	}
+
+	if err := database.SetNoteTags(tx, serverNote.UUID, merged); err != nil {
+		return errors.Wrap(err, "setting tags")
+	}
+
+	return nil
+}
+
+// insertServerNote inserts a note that exists in the server but not in the client
+func insertServerNote(tx *database.DB, n client.SyncFragNote) error {
+	note := database.NewNote(n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, false)
+
+	if err := note.Insert(tx); err != nil {
+		return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
+	}
+
+	if err := database.SetNoteTags(tx, n.UUID, tags.Union(n.Tags, tags.Extract(n.Body))); err != nil {
+		return errors.Wrapf(err, "setting tags of note with uuid %s", n.UUID)
+	}
+
+	if err := database.IndexNoteLinks(tx, n.UUID, n.Body); err != nil {
+		return errors.Wrapf(err, "indexing links of note with uuid %s", n.UUID)
+	}
+
 	return nil
 }
 
@@ -309,10 +387,8 @@ func stepSyncNote(tx *database.DB, n client.SyncFragNote) error {
 
 	// if note exists in the server and does not exist in the client, insert the note.
 	if err == sql.ErrNoRows {
-		note := database.NewNote(n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, false)
-
-		if err := note.Insert(tx); err != nil {
-			return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
+		if err := insertServerNote(tx, n); err != nil {
+			return err
 		}
 	} else {
 		if err := mergeNote(tx, n, localNote); err != nil {
@@ -333,10 +409,8 @@ func fullSyncNote(tx *database.DB, n client.SyncFragNote) error {
 
 	// if note exists in the server and does not exist in the client, insert the note.
 	if err == sql.ErrNoRows {
-		note := database.NewNote(n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, false)
-
-		if err := note.Insert(tx); err != nil {
-			return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
+		if err := insertServerNote(tx, n); err != nil {
+			return err
 		}
 	} else if n.USN > localNote.USN {
 		if err := mergeNote(tx, n, localNote); err != nil {
@@ -629,10 +703,13 @@ func stepSync(ctx context.DnoteCtx, tx *database.DB, afterUSN int) error {
 	return nil
 }
 
-func sendBooks(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
+// sendBooks sends either the dirty books that are deleted or those that are not.
+// The deletions are sent after the notes, because the server deletes the notes
+// remaining in a deleted book, such as those moved out of a merged book.
+func sendBooks(ctx context.DnoteCtx, tx *database.DB, deleted bool) (bool, error) {
 	isBehind := false
 
-	rows, err := tx.Query("SELECT uuid, label, usn, deleted FROM books WHERE dirty")
+	rows, err := tx.Query("SELECT uuid, label, usn, deleted FROM books WHERE dirty AND deleted = ?", deleted)
 	if err != nil {
 		return isBehind, errors.Wrap(err, "getting syncable books")
 	}
@@ -659,7 +736,12 @@ func sendBooks(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 				continue
 			} else {
-				resp, err := client.CreateBook(ctx, book.Label)
+				label, encrypted, err := encryptLabel(ctx.CipherKey, book.Label)
+				if err != nil {
+					return isBehind, errors.Wrap(err, "encrypting a book")
+				}
+
+				resp, err := client.CreateBook(ctx, label, encrypted)
 				if err != nil {
 					return isBehind, errors.Wrap(err, "creating a book")
 				}
@@ -697,7 +779,12 @@ func sendBooks(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 				respUSN = resp.Book.USN
 			} else {
-				resp, err := client.UpdateBook(ctx, book.Label, book.UUID)
+				label, encrypted, err := encryptLabel(ctx.CipherKey, book.Label)
+				if err != nil {
+					return isBehind, errors.Wrap(err, "encrypting a book")
+				}
+
+				resp, err := client.UpdateBook(ctx, label, book.UUID, encrypted)
 				if err != nil {
 					return isBehind, errors.Wrap(err, "updating a book")
 				}
@@ -751,6 +838,11 @@ func sendNotes(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 		log.Debug("sending note %s\n", note.UUID)
 
+		noteTags, err := database.GetNoteTags(tx, note.UUID)
+		if err != nil {
+			return isBehind, errors.Wrap(err, "getting note tags")
+		}
+
 		var respUSN int
 
 		// if new, create it in the server, or else, update.
@@ -764,7 +856,12 @@ func sendNotes(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 				continue
 			} else {
-				resp, err := client.CreateNote(ctx, note.BookUUID, note.Body)
+				body, tags, encrypted, err := encryptNote(ctx.CipherKey, note.Body, noteTags)
+				if err != nil {
+					return isBehind, errors.Wrap(err, "encrypting a note")
+				}
+
+				resp, err := client.CreateNote(ctx, note.BookUUID, body, tags, encrypted)
 				if err != nil {
 					return isBehind, errors.Wrap(err, "creating a note")
 				}
@@ -797,7 +894,12 @@ func sendNotes(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 				respUSN = resp.Result.USN
 			} else {
-				resp, err := client.UpdateNote(ctx, note.UUID, note.BookUUID, note.Body, note.Public)
+				body, tags, encrypted, err := encryptNote(ctx.CipherKey, note.Body, noteTags)
+				if err != nil {
+					return isBehind, errors.Wrap(err, "encrypting a note")
+				}
+
+				resp, err := client.UpdateNote(ctx, note.UUID, note.BookUUID, body, note.Public, tags, encrypted)
 				if err != nil {
 					return isBehind, errors.Wrap(err, "updating a note")
 				}
@@ -841,7 +943,7 @@ func sendChanges(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 
 	fmt.Printf(" (total %d).", delta)
 
-	behind1, err := sendBooks(ctx, tx)
+	behind1, err := sendBooks(ctx, tx, false)
 	if err != nil {
 		return behind1, errors.Wrap(err, "sending books")
 	}
@@ -851,9 +953,14 @@ func sendChanges(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 		return behind2, errors.Wrap(err, "sending notes")
 	}
 
+	behind3, err := sendBooks(ctx, tx, true)
+	if err != nil {
+		return behind3, errors.Wrap(err, "sending deleted books")
+	}
+
 	fmt.Println(" done.")
 
-	isBehind := behind1 || behind2
+	isBehind := behind1 || behind2 || behind3
 
 	return isBehind, nil
 }
@@ -895,6 +1002,12 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			return errors.Wrap(err, "running remote migrations")
 		}
 
+		cipherKey, err := getCipherKey(ctx, isEncrypt)
+		if err != nil {
+			return errors.Wrap(err, "getting the encryption key")
+		}
+		ctx.CipherKey = cipherKey
+
 		tx, err := ctx.DB.Begin()
 		if err != nil {
 			return errors.Wrap(err, "beginning a transaction")
@@ -932,6 +1045,20 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			return errors.Wrap(syncErr, "syncing changes from the server")
 		}
 
+		// Send everything again, now that the changes from the server are merged.
+		// Keep doing so until a sync completes after the encryption was set up.
+		encryptionPending, err := isEncryptionPending(tx)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "checking the pending encryption")
+		}
+		if isEncrypt || encryptionPending {
+			if err := markAllDirty(tx); err != nil {
+				tx.Rollback()
+				return errors.Wrap(err, "marking all books and notes to be encrypted")
+			}
+		}
+
 		isBehind, err := sendChanges(ctx, tx)
 		if err != nil {
 			tx.Rollback()
@@ -955,6 +1082,29 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			}
 		}
 
+		if err := syncAttachments(ctx, tx); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "syncing attachments")
+		}
+
+		if err := database.PruneNoteRevisions(tx, ctx.RevisionLimit); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "pruning note revisions")
+		}
+
+		// The removals have been synced and can no longer be restored
+		if err := database.EmptyTrash(tx); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "emptying the trash")
+		}
+
+		if encryptionPending {
+			if err := database.DeleteSystem(tx, consts.SystemEncryptionPending); err != nil {
+				tx.Rollback()
+				return errors.Wrap(err, "clearing the pending encryption")
+			}
+		}
+
 		tx.Commit()
 
 		log.Success("success\n")
@@ -68,7 +68,7 @@ func TestProcessFragments(t *testing.T) {
 	}
 
 	// exec
-	sl, err := processFragments(fragments)
+	sl, err := processFragments(fragments, nil)
 	if err != nil {
 		t.Fatalf(errors.Wrap(err, "executing").Error())
 	}
@@ -1958,10 +1958,14 @@ func TestSendBooks(t *testing.T) {
 		t.Fatalf(errors.Wrap(err, "beginning a transaction").Error())
 	}
 
-	if _, err := sendBooks(ctx, tx); err != nil {
+	if _, err := sendBooks(ctx, tx, false); err != nil {
 		tx.Rollback()
 		t.Fatalf(errors.Wrap(err, "executing").Error())
 	}
+	if _, err := sendBooks(ctx, tx, true); err != nil {
+		tx.Rollback()
+		t.Fatalf(errors.Wrap(err, "executing for deleted books").Error())
+	}
 
 	tx.Commit()
 
@@ -2113,7 +2117,7 @@ func TestSendBooks_isBehind(t *testing.T) {
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("beginning a transaction for test case %d", idx)).Error())
 				}
 
-				isBehind, err := sendBooks(ctx, tx)
+				isBehind, err := sendBooks(ctx, tx, false)
 				if err != nil {
 					tx.Rollback()
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("executing for test case %d", idx)).Error())
@@ -2161,7 +2165,7 @@ func TestSendBooks_isBehind(t *testing.T) {
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("beginning a transaction for test case %d", idx)).Error())
 				}
 
-				isBehind, err := sendBooks(ctx, tx)
+				isBehind, err := sendBooks(ctx, tx, true)
 				if err != nil {
 					tx.Rollback()
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("executing for test case %d", idx)).Error())
@@ -2209,7 +2213,7 @@ func TestSendBooks_isBehind(t *testing.T) {
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("beginning a transaction for test case %d", idx)).Error())
 				}
 
-				isBehind, err := sendBooks(ctx, tx)
+				isBehind, err := sendBooks(ctx, tx, false)
 				if err != nil {
 					tx.Rollback()
 					t.Fatalf(errors.Wrap(err, fmt.Sprintf("executing for test case %d", idx)).Error())
@@ -2224,6 +2228,52 @@ func TestSendBooks_isBehind(t *testing.T) {
 	})
 }
 
+func TestSendChanges_mergedBook(t *testing.T) {
+	// set up
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	testutils.Login(t, &ctx)
+
+	db := ctx.DB
+
+	database.MustExec(t, "inserting last max usn", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastMaxUSN, 0)
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 1, false, false)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "b2-label", 2, false, false)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, body, added_on, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", 3, "n1 body", 1541108743, false, false)
+
+	if _, err := database.MergeBook(db, ctx.Clock, "b1-uuid", "b2-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "merging the book"))
+	}
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	ctx.APIEndpoint = ts.URL
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if _, err := sendChanges(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	tx.Commit()
+
+	// test
+	// the note must be moved before the server deletes the notes in the merged book
+	assert.DeepEqual(t, requests, []string{"PATCH /v3/notes/n1-uuid", "DELETE /v3/books/b1-uuid"}, "requests mismatch")
+}
+
 // TestSendNotes tests that notes are put to correct 'buckets' by running a test server and recording the
 // uuid from the incoming data.
 func TestSendNotes(t *testing.T) {
@@ -2857,6 +2907,102 @@ n1 body edited
 	}
 }
 
+func TestMergeNoteTags(t *testing.T) {
+	testCases := []struct {
+		name         string
+		clientDirty  bool
+		clientBody   string
+		clientTags   []string
+		serverBody   string
+		serverTags   []string
+		expectedTags []string
+	}{
+		{
+			name:         "not dirty",
+			clientDirty:  false,
+			clientBody:   "n1 body",
+			clientTags:   []string{"a", "b"},
+			serverBody:   "n1 body",
+			serverTags:   []string{"b", "c"},
+			expectedTags: []string{"b", "c"},
+		},
+		{
+			name:         "dirty",
+			clientDirty:  true,
+			clientBody:   "n1 body",
+			clientTags:   []string{"a", "b"},
+			serverBody:   "n1 body",
+			serverTags:   []string{"b", "c"},
+			expectedTags: []string{"a", "b", "c"},
+		},
+		{
+			name:         "server without tags support",
+			clientDirty:  false,
+			clientBody:   "n1 body #old",
+			clientTags:   []string{"a", "old"},
+			serverBody:   "n1 body #new",
+			serverTags:   nil,
+			expectedTags: []string{"a", "new"},
+		},
+		{
+			name:         "hashtags in the server body",
+			clientDirty:  false,
+			clientBody:   "n1 body",
+			clientTags:   []string{},
+			serverBody:   "n1 body #go",
+			serverTags:   []string{},
+			expectedTags: []string{"go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// set up
+			db := database.InitTestDB(t, "../../tmp/.dnote", nil)
+			defer database.TeardownTestDB(t, db)
+
+			b1UUID := "b1-uuid"
+			database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, dirty) VALUES (?, ?, ?, ?)", b1UUID, "b1-label", 5, false)
+			n1UUID := testutils.MustGenerateUUID(t)
+			database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, usn, added_on, edited_on, body, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", n1UUID, b1UUID, 1, 1541232118, 1541219320, tc.clientBody, false, tc.clientDirty)
+			if err := database.SetNoteTags(db, n1UUID, tc.clientTags); err != nil {
+				t.Fatal(errors.Wrap(err, "setting tags"))
+			}
+
+			// execute
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "beginning a transaction"))
+			}
+
+			fragNote := client.SyncFragNote{
+				UUID:     n1UUID,
+				BookUUID: b1UUID,
+				USN:      21,
+				AddedOn:  1541232118,
+				EditedOn: 1541219321,
+				Body:     tc.serverBody,
+				Tags:     tc.serverTags,
+			}
+			localNote := database.Note{UUID: n1UUID, BookUUID: b1UUID, USN: 1, Body: tc.clientBody, Dirty: tc.clientDirty}
+
+			if err := mergeNote(tx, fragNote, localNote); err != nil {
+				tx.Rollback()
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			tx.Commit()
+
+			// test
+			tags, err := database.GetNoteTags(db, n1UUID)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "getting tags"))
+			}
+			assert.DeepEqual(t, tags, tc.expectedTags, "tags mismatch")
+		})
+	}
+}
+
 func TestCheckBookPristine(t *testing.T) {
 	// set up
 	db := database.InitTestDB(t, "../../tmp/.dnote", nil)
@@ -0,0 +1,68 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package trash
+
+import (
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newLsCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List the removed notes and books",
+		PreRunE: preRunNoArgs,
+		RunE:    newLsRun(ctx),
+	}
+
+	return cmd
+}
+
+func newLsRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := database.PurgeTrash(ctx.DB, ctx.Clock, ctx.TrashRetentionDays); err != nil {
+			return errors.Wrap(err, "purging the trash")
+		}
+
+		items, err := database.GetTrashItems(ctx.DB)
+		if err != nil {
+			return errors.Wrap(err, "getting the trash")
+		}
+
+		ret := make([]output.TrashItem, len(items))
+		for i, item := range items {
+			ret[i] = output.TrashItem{
+				RowID:     item.RowID,
+				Kind:      item.Kind,
+				UUID:      item.UUID,
+				Label:     item.Label,
+				Body:      item.Body,
+				NoteCount: item.NoteCount,
+				RemovedOn: item.RemovedOn,
+			}
+		}
+
+		return output.TrashList(ret)
+	}
+}
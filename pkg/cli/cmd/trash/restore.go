@@ -0,0 +1,90 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package trash
+
+import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func preRunRestore(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func newRestoreCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "restore <id>",
+		Short:   "Restore a removed note or book",
+		PreRunE: preRunRestore,
+		RunE:    newRestoreRun(ctx),
+	}
+
+	return cmd
+}
+
+func newRestoreRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid id")
+		}
+
+		if err := database.PurgeTrash(ctx.DB, ctx.Clock, ctx.TrashRetentionDays); err != nil {
+			return errors.Wrap(err, "purging the trash")
+		}
+
+		item, err := database.GetTrashItem(ctx.DB, rowID)
+		if err != nil {
+			return err
+		}
+
+		tx, err := ctx.DB.Begin()
+		if err != nil {
+			return errors.Wrap(err, "beginning a transaction")
+		}
+
+		if err := database.RestoreTrashItem(tx, ctx.Clock, item); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "restoring the item")
+		}
+
+		if err := tx.Commit(); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "committing a transaction")
+		}
+
+		if item.Kind == database.TrashKindBook {
+			log.Successf("restored book %s\n", item.Label)
+		} else {
+			log.Successf("restored to %s\n", item.Label)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package trash
+
+import (
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+  * List the removed notes and books
+  dnote trash ls
+
+  * Restore a removed note or book by its id in the trash
+  dnote trash restore 3
+
+  * Permanently delete the removed notes and books
+  dnote trash empty`
+
+func preRunNoArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new trash command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "trash",
+		Short:   "Manage removed notes and books",
+		Example: example,
+	}
+
+	cmd.AddCommand(newLsCmd(ctx))
+	cmd.AddCommand(newRestoreCmd(ctx))
+	cmd.AddCommand(newEmptyCmd(ctx))
+
+	return cmd
+}
@@ -19,13 +19,20 @@
 package view
 
 import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/completion"
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/dnote/dnote/pkg/cli/cmd/cat"
 	"github.com/dnote/dnote/pkg/cli/cmd/ls"
+	"github.com/dnote/dnote/pkg/cli/tags"
 	"github.com/dnote/dnote/pkg/cli/utils"
 )
 
@@ -36,12 +43,25 @@ var example = `
  * List notes in a book
  dnote view javascript
 
+ * List a book and the books nested in it
+ dnote view work/
+
+ * List notes tagged with "es6" in all books or in a book
+ dnote view --tag es6
+ dnote view javascript --tag es6
+
  * View a particular note in a book
  dnote view javascript 0
+
+ * Choose a note to view with a fuzzy finder, optionally within a book
+ dnote view --pick
+ dnote view javascript --pick
  `
 
 var nameOnly bool
 var contentOnly bool
+var tagFlag []string
+var pickFlag bool
 
 func preRun(cmd *cobra.Command, args []string) error {
 	if len(args) > 2 {
@@ -54,17 +74,20 @@ func preRun(cmd *cobra.Command, args []string) error {
 // NewCmd returns a new view command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "view <book name?> <note index?>",
-		Aliases: []string{"v"},
-		Short:   "List books, notes or view a content",
-		Example: example,
-		RunE:    newRun(ctx),
-		PreRunE: preRun,
+		Use:               "view <book name?> <note index?>",
+		Aliases:           []string{"v"},
+		Short:             "List books, notes or view a content",
+		Example:           example,
+		RunE:              newRun(ctx),
+		PreRunE:           preRun,
+		ValidArgsFunction: completion.BooksOrNotes(ctx, false),
 	}
 
 	f := cmd.Flags()
 	f.BoolVarP(&nameOnly, "name-only", "", false, "print book names only")
 	f.BoolVarP(&contentOnly, "content-only", "", false, "print the note content only")
+	f.StringSliceVarP(&tagFlag, "tag", "t", []string{}, "list only the notes with the tag. Can be repeated to require all tags")
+	f.BoolVarP(&pickFlag, "pick", "p", false, "choose a note to view with a fuzzy finder")
 
 	return cmd
 }
@@ -73,17 +96,30 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
 		var run infra.RunEFunc
 
+		noteTags := tags.Normalize(tagFlag)
+		if len(noteTags) > 0 && nameOnly {
+			return errors.New("--name-only flag is invalid with --tag")
+		}
+
+		if pickFlag {
+			return runPick(ctx, cmd, args)
+		}
+
 		if len(args) == 0 {
-			run = ls.NewRun(ctx, nameOnly)
+			run = ls.NewRun(ctx, nameOnly, noteTags)
 		} else if len(args) == 1 {
-			if nameOnly {
+			if nameOnly && !database.IsBookSubtree(args[0]) {
 				return errors.New("--name-only flag is only valid when viewing books")
 			}
 
 			if utils.IsNumber(args[0]) {
+				if len(noteTags) > 0 {
+					return errors.New("--tag flag is only valid when listing notes")
+				}
+
 				run = cat.NewRun(ctx, contentOnly)
 			} else {
-				run = ls.NewRun(ctx, false)
+				run = ls.NewRun(ctx, nameOnly, noteTags)
 			}
 		} else if len(args) == 2 {
 			// DEPRECATED: passing book name to view command is deprecated
@@ -95,3 +131,28 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 		return run(cmd, args)
 	}
 }
+
+// runPick lets the user choose a note, optionally within the book given in
+// the arguments, and prints it
+func runPick(ctx context.DnoteCtx, cmd *cobra.Command, args []string) error {
+	if nameOnly || len(tagFlag) > 0 {
+		return errors.New("--pick flag is invalid with --name-only and --tag")
+	}
+
+	var bookLabel string
+	if len(args) == 1 && !utils.IsNumber(args[0]) {
+		bookLabel = args[0]
+	} else if len(args) > 0 {
+		return errors.New("--pick flag only accepts a book name")
+	}
+
+	rowID, err := ui.PickNote(ctx, bookLabel)
+	if err == ui.ErrPickerCancelled {
+		log.Warnf("aborted by user\n")
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "choosing a note")
+	}
+
+	return cat.NewRun(ctx, contentOnly)(cmd, []string{strconv.Itoa(rowID)})
+}
@@ -0,0 +1,190 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package completion provides the functions to complete the arguments and
+// flags of the commands with the data in the local database
+package completion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/backup"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// excerptLength is the maximum number of characters in the excerpt of a note
+// shown next to its id
+const excerptLength = 50
+
+// Func is a function completing the arguments or the value of a flag
+type Func func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// BookLabels returns the labels of the books starting with the given prefix
+func BookLabels(db *database.DB, prefix string) ([]string, error) {
+	rows, err := db.Query("SELECT label FROM books WHERE deleted = false ORDER BY label ASC")
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var label string
+		if err := rows.Scan(&label); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		if strings.HasPrefix(label, prefix) {
+			ret = append(ret, label)
+		}
+	}
+
+	return ret, nil
+}
+
+// excerpt returns the first non-empty line of a note body, shortened to fit
+// next to the note id
+func excerpt(body string) string {
+	var line string
+	for _, l := range strings.Split(body, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			line = l
+			break
+		}
+	}
+
+	r := []rune(line)
+	if len(r) > excerptLength {
+		return string(r[:excerptLength-3]) + "..."
+	}
+
+	return line
+}
+
+// NoteIDs returns the ids of the notes starting with the given prefix, each
+// followed by a tab and an excerpt of the note as its description
+func NoteIDs(db *database.DB, prefix string) ([]string, error) {
+	rows, err := db.Query(`SELECT notes.rowid, books.label, notes.body
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false
+	ORDER BY notes.rowid ASC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var rowID int
+		var label, body string
+		if err := rows.Scan(&rowID, &label, &body); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		id := fmt.Sprintf("%d", rowID)
+		if strings.HasPrefix(id, prefix) {
+			ret = append(ret, fmt.Sprintf("%s\t(%s) %s", id, label, excerpt(body)))
+		}
+	}
+
+	return ret, nil
+}
+
+// complete runs the given queries and returns their results for a shell
+// completion. Files are not completed.
+func complete(queries ...func() ([]string, error)) ([]string, cobra.ShellCompDirective) {
+	ret := []string{}
+	for _, q := range queries {
+		items, err := q()
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		ret = append(ret, items...)
+	}
+
+	return ret, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Books returns a function completing the first argument with book labels
+func Books(ctx context.DnoteCtx) Func {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return complete(func() ([]string, error) { return BookLabels(ctx.DB, toComplete) })
+	}
+}
+
+// BooksOrNotes returns a function completing the first argument with book
+// labels and note ids, and the rest of the arguments with note ids if the
+// command accepts several notes
+func BooksOrNotes(ctx context.DnoteCtx, multipleNotes bool) Func {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return complete(
+				func() ([]string, error) { return BookLabels(ctx.DB, toComplete) },
+				func() ([]string, error) { return NoteIDs(ctx.DB, toComplete) },
+			)
+		}
+		if multipleNotes {
+			return complete(func() ([]string, error) { return NoteIDs(ctx.DB, toComplete) })
+		}
+
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Backups returns a function completing the first argument with the names of
+// the backups. Files are completed as well for the backups kept elsewhere.
+func Backups(ctx context.DnoteCtx) Func {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		names, err := backup.List(ctx)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		ret := []string{}
+		for _, name := range names {
+			if strings.HasPrefix(name, toComplete) {
+				ret = append(ret, name)
+			}
+		}
+
+		return ret, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// BookFlag returns a function completing the value of a flag with book labels
+func BookFlag(ctx context.DnoteCtx) Func {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return complete(func() ([]string, error) { return BookLabels(ctx.DB, toComplete) })
+	}
+}
@@ -0,0 +1,96 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package completion
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func setupNotes(t *testing.T, db *database.DB) {
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "work/infra")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "work")
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b4-uuid", "wiki", true)
+
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "\n# Closures\nfunctions with their scope", 1)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "", 2, true)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b2-uuid", "Rolling back a deployment in the staging cluster after a failed release", 3)
+}
+
+func TestBookLabels(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	setupNotes(t, db)
+
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{prefix: "", expected: []string{"js", "work", "work/infra"}},
+		{prefix: "w", expected: []string{"work", "work/infra"}},
+		{prefix: "work/", expected: []string{"work/infra"}},
+		{prefix: "1", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			got, err := BookLabels(db, tc.prefix)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "labels mismatch")
+		})
+	}
+}
+
+func TestNoteIDs(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	setupNotes(t, db)
+
+	got, err := NoteIDs(db, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.DeepEqual(t, got, []string{
+		"1\t(js) # Closures",
+		"3\t(work/infra) Rolling back a deployment in the staging cluste...",
+	}, "ids mismatch")
+
+	got, err = NoteIDs(db, "3")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, len(got), 1, "count mismatch")
+
+	got, err = NoteIDs(db, "js")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.DeepEqual(t, got, []string{}, "a book label should not match note ids")
+}
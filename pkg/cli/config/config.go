@@ -21,6 +21,8 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"sort"
 
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
@@ -35,6 +37,81 @@ type Config struct {
 	Editor             string `yaml:"editor"`
 	APIEndpoint        string `yaml:"apiEndpoint"`
 	EnableUpgradeCheck bool   `yaml:"enableUpgradeCheck"`
+	RevisionLimit      int    `yaml:"revisionLimit"`
+	TrashRetentionDays int    `yaml:"trashRetentionDays"`
+	BackupLimit        int    `yaml:"backupLimit"`
+	// Profiles are the profiles other than the default profile, by names
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// Profile is the name of the profile used if none is given by the flag
+	// or the environment. It is empty for the default profile.
+	Profile string `yaml:"profile,omitempty"`
+}
+
+// Profile is a named set of settings for keeping a separate notebook, possibly
+// synced with a different server
+type Profile struct {
+	// APIEndpoint defaults to the API endpoint of the default profile if empty
+	APIEndpoint string `yaml:"apiEndpoint,omitempty"`
+	// Editor defaults to the editor of the default profile if empty
+	Editor string `yaml:"editor,omitempty"`
+	// DB is the path to the SQLite file. It defaults to a file in the
+	// directory of the profile in the dnote data directory if empty.
+	DB string `yaml:"db,omitempty"`
+}
+
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateProfileName validates the name of a new profile
+func ValidateProfileName(name string) error {
+	if !profileNameRegex.MatchString(name) {
+		return errors.Errorf("invalid profile name '%s'. Use letters, numbers, '-' and '_'", name)
+	}
+	if name == consts.DefaultProfileName {
+		return errors.Errorf("'%s' is reserved for the default profile", name)
+	}
+
+	return nil
+}
+
+// IsDefaultProfile checks if the given profile name refers to the default profile
+func IsDefaultProfile(name string) bool {
+	return name == "" || name == consts.DefaultProfileName
+}
+
+// GetProfile returns the profile with the given name. The default profile is
+// made of the top level settings.
+func (c Config) GetProfile(name string) (Profile, error) {
+	if IsDefaultProfile(name) {
+		return Profile{
+			APIEndpoint: c.APIEndpoint,
+			Editor:      c.Editor,
+		}, nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, errors.Errorf("profile '%s' not found. Run 'dnote profile ls' to see the profiles", name)
+	}
+	if p.APIEndpoint == "" {
+		p.APIEndpoint = c.APIEndpoint
+	}
+	if p.Editor == "" {
+		p.Editor = c.Editor
+	}
+
+	return p, nil
+}
+
+// ProfileNames returns the names of all profiles, starting with the default
+// profile and followed by the others in alphabetical order
+func (c Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append([]string{consts.DefaultProfileName}, names...)
 }
 
 func checkLegacyPath(ctx context.DnoteCtx) (string, bool) {
@@ -79,6 +156,18 @@ func Read(ctx context.DnoteCtx) (Config, error) {
 	return ret, nil
 }
 
+// Parse parses the content of a config file. Unlike Read, it fails on
+// unknown keys, so that mistyped keys are caught when the user edits the file.
+func Parse(b []byte) (Config, error) {
+	var ret Config
+
+	if err := yaml.UnmarshalStrict(b, &ret); err != nil {
+		return ret, errors.Wrap(err, "unmarshalling config")
+	}
+
+	return ret, nil
+}
+
 // Write writes the config to the config file
 func Write(ctx context.DnoteCtx, cf Config) error {
 	path := GetPath(ctx)
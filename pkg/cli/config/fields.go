@@ -0,0 +1,364 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+// Field is a setting in the config file that can be read and changed by its key
+type Field struct {
+	Key string
+	// Env is the environment variable that overrides the setting
+	Env         string
+	Description string
+	validate    func(string) error
+	get         func(Config) string
+	// set sets a value that has been validated
+	set func(*Config, string)
+	// profileValue returns the value of the setting in a profile. It is nil
+	// if the setting cannot be set for each profile.
+	profileValue func(*Profile) *string
+	// defaultValue returns the value used when the setting is unset. It is nil
+	// if the setting cannot be unset.
+	defaultValue func() string
+}
+
+// Fields are the settings that can be read and changed by their keys
+var Fields = []Field{
+	{
+		Key:          "editor",
+		Env:          "DNOTE_EDITOR",
+		Description:  "the command to launch a text editor",
+		validate:     validateEditor,
+		get:          func(c Config) string { return c.Editor },
+		set:          func(c *Config, v string) { c.Editor = v },
+		profileValue: func(p *Profile) *string { return &p.Editor },
+		defaultValue: DefaultEditor,
+	},
+	{
+		Key:          "apiEndpoint",
+		Env:          "DNOTE_API_ENDPOINT",
+		Description:  "the URL of the API of the Dnote server",
+		validate:     validateURL,
+		get:          func(c Config) string { return c.APIEndpoint },
+		set:          func(c *Config, v string) { c.APIEndpoint = v },
+		profileValue: func(p *Profile) *string { return &p.APIEndpoint },
+	},
+	{
+		Key:          "enableUpgradeCheck",
+		Env:          "DNOTE_ENABLE_UPGRADE_CHECK",
+		Description:  "whether to check for a new version",
+		validate:     validateBool,
+		get:          func(c Config) string { return strconv.FormatBool(c.EnableUpgradeCheck) },
+		set:          func(c *Config, v string) { c.EnableUpgradeCheck, _ = strconv.ParseBool(v) },
+		defaultValue: func() string { return "true" },
+	},
+	{
+		Key:          "revisionLimit",
+		Env:          "DNOTE_REVISION_LIMIT",
+		Description:  "the number of revisions kept for each note",
+		validate:     validateNonNegativeInt,
+		get:          func(c Config) string { return strconv.Itoa(c.RevisionLimit) },
+		set:          func(c *Config, v string) { c.RevisionLimit, _ = strconv.Atoi(v) },
+		defaultValue: func() string { return strconv.Itoa(consts.DefaultRevisionLimit) },
+	},
+	{
+		Key:          "trashRetentionDays",
+		Env:          "DNOTE_TRASH_RETENTION_DAYS",
+		Description:  "the number of days for which removed items are kept in the trash. 0 keeps them forever",
+		validate:     validateNonNegativeInt,
+		get:          func(c Config) string { return strconv.Itoa(c.TrashRetentionDays) },
+		set:          func(c *Config, v string) { c.TrashRetentionDays, _ = strconv.Atoi(v) },
+		defaultValue: func() string { return strconv.Itoa(consts.DefaultTrashRetentionDays) },
+	},
+	{
+		Key:          "backupLimit",
+		Env:          "DNOTE_BACKUP_LIMIT",
+		Description:  "the number of backups kept for each database. 0 keeps all backups",
+		validate:     validateNonNegativeInt,
+		get:          func(c Config) string { return strconv.Itoa(c.BackupLimit) },
+		set:          func(c *Config, v string) { c.BackupLimit, _ = strconv.Atoi(v) },
+		defaultValue: func() string { return strconv.Itoa(consts.DefaultBackupLimit) },
+	},
+}
+
+func validateEditor(v string) error {
+	if strings.TrimSpace(v) == "" {
+		return errors.New("editor cannot be empty")
+	}
+
+	return nil
+}
+
+func validateURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		return errors.Errorf("'%s' is not a valid URL", v)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("'%s' is not a valid URL. It must start with http:// or https://", v)
+	}
+	if u.Host == "" {
+		return errors.Errorf("'%s' is not a valid URL. It has no host", v)
+	}
+
+	return nil
+}
+
+func validateBool(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return errors.Errorf("'%s' is not a boolean. Use true or false", v)
+	}
+
+	return nil
+}
+
+func validateNonNegativeInt(v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return errors.Errorf("'%s' is not a non-negative integer", v)
+	}
+
+	return nil
+}
+
+// GetField returns the field with the given key
+func GetField(key string) (Field, error) {
+	for _, f := range Fields {
+		if f.Key == key {
+			return f, nil
+		}
+	}
+
+	keys := []string{}
+	for _, f := range Fields {
+		keys = append(keys, f.Key)
+	}
+
+	return Field{}, errors.Errorf("unknown key '%s'. Use one of %s", key, strings.Join(keys, ", "))
+}
+
+// Validate validates a value for the field
+func (f Field) Validate(v string) error {
+	if err := f.validate(v); err != nil {
+		return errors.Wrapf(err, "invalid %s", f.Key)
+	}
+
+	return nil
+}
+
+// CanUnset checks if the field can be unset in the given profile
+func (f Field) CanUnset(profile string) bool {
+	if IsDefaultProfile(profile) {
+		return f.defaultValue != nil
+	}
+
+	return f.profileValue != nil
+}
+
+// Set validates and sets the value of the field in the given profile. If the
+// field cannot be set for each profile, the top level setting is set.
+func (c *Config) Set(profile, key, value string) error {
+	f, err := GetField(key)
+	if err != nil {
+		return err
+	}
+	if err := f.Validate(value); err != nil {
+		return err
+	}
+
+	if IsDefaultProfile(profile) || f.profileValue == nil {
+		f.set(c, value)
+		return nil
+	}
+
+	return c.setProfileValue(profile, f, value)
+}
+
+// Unset unsets the field in the given profile. A field of a profile falls back
+// to the value of the default profile, and a top level setting falls back to
+// its default value.
+func (c *Config) Unset(profile, key string) error {
+	f, err := GetField(key)
+	if err != nil {
+		return err
+	}
+
+	if IsDefaultProfile(profile) || f.profileValue == nil {
+		if f.defaultValue == nil {
+			return errors.Errorf("%s cannot be unset", f.Key)
+		}
+
+		f.set(c, f.defaultValue())
+		return nil
+	}
+
+	return c.setProfileValue(profile, f, "")
+}
+
+func (c *Config) setProfileValue(profile string, f Field, value string) error {
+	p, ok := c.Profiles[profile]
+	if !ok {
+		return errors.Errorf("profile '%s' not found", profile)
+	}
+
+	*f.profileValue(&p) = value
+
+	profiles := map[string]Profile{}
+	for k, v := range c.Profiles {
+		profiles[k] = v
+	}
+	profiles[profile] = p
+	c.Profiles = profiles
+
+	return nil
+}
+
+// Validate validates the values in the config, including those of the profiles
+func (c Config) Validate() error {
+	for _, f := range Fields {
+		if err := f.Validate(f.get(c)); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range c.ProfileNames()[1:] {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+
+		p := c.Profiles[name]
+		for _, f := range Fields {
+			if f.profileValue == nil {
+				continue
+			}
+
+			v := *f.profileValue(&p)
+			if v == "" {
+				continue
+			}
+			if err := f.Validate(v); err != nil {
+				return errors.Wrapf(err, "profile %s", name)
+			}
+		}
+	}
+
+	if c.Profile != "" {
+		if _, err := c.GetProfile(c.Profile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Value is the value of a setting in use and where it came from
+type Value struct {
+	Key   string
+	Value string
+	// Origin is where the value came from. It is 'file:<path>' for the top
+	// level settings, 'profile:<name>' for the settings of a profile, and
+	// 'env:<name>' for environment variables.
+	Origin string
+}
+
+// Resolve returns the config in use for the profile of the given context and
+// the values of its settings. The settings of the profile take precedence over
+// the top level settings, and the environment variables take precedence over both.
+func Resolve(ctx context.DnoteCtx, cf Config) (Config, []Value, error) {
+	var p Profile
+	if !IsDefaultProfile(ctx.Profile) {
+		var ok bool
+		p, ok = cf.Profiles[ctx.Profile]
+		if !ok {
+			return cf, nil, errors.Errorf("profile '%s' not found. Run 'dnote profile ls' to see the profiles", ctx.Profile)
+		}
+	}
+
+	ret := cf
+	values := []Value{}
+	for _, f := range Fields {
+		v := Value{
+			Key:    f.Key,
+			Value:  f.get(cf),
+			Origin: fmt.Sprintf("file:%s", GetPath(ctx)),
+		}
+
+		if f.profileValue != nil && !IsDefaultProfile(ctx.Profile) {
+			if pv := *f.profileValue(&p); pv != "" {
+				v.Value = pv
+				v.Origin = fmt.Sprintf("profile:%s", ctx.Profile)
+			}
+		}
+
+		if ev, ok := os.LookupEnv(f.Env); ok && ev != "" {
+			if err := f.Validate(ev); err != nil {
+				return cf, nil, errors.Wrapf(err, "reading %s", f.Env)
+			}
+
+			v.Value = ev
+			v.Origin = fmt.Sprintf("env:%s", f.Env)
+		}
+
+		f.set(&ret, v.Value)
+		values = append(values, v)
+	}
+
+	return ret, values, nil
+}
+
+// DefaultEditor returns the system's editor command with appropriate flags,
+// if necessary, to make the command wait until editor is close to exit.
+func DefaultEditor() string {
+	editor := os.Getenv("EDITOR")
+
+	var ret string
+
+	switch editor {
+	case "atom":
+		ret = "atom -w"
+	case "subl":
+		ret = "subl -n -w"
+	case "code":
+		ret = "code -n -w"
+	case "mate":
+		ret = "mate -w"
+	case "vim":
+		ret = "vim"
+	case "nano":
+		ret = "nano"
+	case "emacs":
+		ret = "emacs"
+	case "nvim":
+		ret = "nvim"
+	default:
+		ret = "vi"
+	}
+
+	return ret
+}
@@ -0,0 +1,153 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+)
+
+func TestFieldValidate(t *testing.T) {
+	testCases := []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{key: "editor", value: "vim", valid: true},
+		{key: "editor", value: " ", valid: false},
+		{key: "apiEndpoint", value: "https://dnote.example.com/api", valid: true},
+		{key: "apiEndpoint", value: "http://localhost:3001/api", valid: true},
+		{key: "apiEndpoint", value: "dnote.example.com/api", valid: false},
+		{key: "apiEndpoint", value: "ftp://dnote.example.com", valid: false},
+		{key: "apiEndpoint", value: "https://", valid: false},
+		{key: "enableUpgradeCheck", value: "false", valid: true},
+		{key: "enableUpgradeCheck", value: "no", valid: false},
+		{key: "revisionLimit", value: "0", valid: true},
+		{key: "revisionLimit", value: "-1", valid: false},
+		{key: "trashRetentionDays", value: "seven", valid: false},
+		{key: "backupLimit", value: "0", valid: true},
+		{key: "backupLimit", value: "-3", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s", tc.key, tc.value), func(t *testing.T) {
+			f, err := GetField(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, f.Validate(tc.value) == nil, tc.valid, "validity mismatch")
+		})
+	}
+}
+
+func TestSetUnset(t *testing.T) {
+	cf := Config{
+		Editor:        "vim",
+		APIEndpoint:   "https://api.getdnote.com",
+		RevisionLimit: 10,
+		Profiles: map[string]Profile{
+			"work": {APIEndpoint: "https://dnote.example.com/api"},
+		},
+	}
+
+	t.Run("top level", func(t *testing.T) {
+		c := cf
+		if err := c.Set("", "revisionLimit", "20"); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.RevisionLimit, 20, "revisionLimit mismatch")
+
+		if err := c.Unset("", "revisionLimit"); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.RevisionLimit, 50, "revisionLimit should be reset to the default")
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		c := cf
+		if err := c.Set("work", "editor", "nano"); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.Profiles["work"].Editor, "nano", "profile editor mismatch")
+		assert.Equal(t, c.Editor, "vim", "top level editor should not change")
+		assert.Equal(t, cf.Profiles["work"].Editor, "", "original config should not change")
+
+		if err := c.Unset("work", "apiEndpoint"); err != nil {
+			t.Fatal(err)
+		}
+		p, err := c.GetProfile("work")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, p.APIEndpoint, "https://api.getdnote.com", "profile should fall back to the default API endpoint")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := cf
+		assert.NotEqual(t, c.Set("", "apiEndpoint", "not a url"), nil, "expected a validation error")
+		assert.NotEqual(t, c.Set("", "colour", "red"), nil, "expected an unknown key error")
+		assert.NotEqual(t, c.Unset("", "apiEndpoint"), nil, "apiEndpoint should not be unset")
+	})
+}
+
+func TestResolve(t *testing.T) {
+	cf := Config{
+		Editor:             "vim",
+		APIEndpoint:        "https://api.getdnote.com",
+		EnableUpgradeCheck: true,
+		Profiles: map[string]Profile{
+			"work": {APIEndpoint: "https://dnote.example.com/api"},
+		},
+	}
+	ctx := context.DnoteCtx{
+		Paths:   context.Paths{Config: "/home/user/.config"},
+		Profile: "work",
+	}
+
+	t.Setenv("DNOTE_EDITOR", "nano")
+	t.Setenv("DNOTE_API_ENDPOINT", "")
+
+	got, values, err := Resolve(ctx, cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got.Editor, "nano", "editor mismatch")
+	assert.Equal(t, got.APIEndpoint, "https://dnote.example.com/api", "apiEndpoint mismatch")
+	assert.Equal(t, got.EnableUpgradeCheck, true, "enableUpgradeCheck mismatch")
+
+	origins := map[string]string{}
+	for _, v := range values {
+		origins[v.Key] = v.Origin
+	}
+	assert.Equal(t, origins["editor"], "env:DNOTE_EDITOR", "editor origin mismatch")
+	assert.Equal(t, origins["apiEndpoint"], "profile:work", "apiEndpoint origin mismatch")
+	assert.Equal(t, origins["enableUpgradeCheck"], "file:/home/user/.config/dnote/dnoterc", "enableUpgradeCheck origin mismatch")
+
+	t.Run("invalid env", func(t *testing.T) {
+		t.Setenv("DNOTE_REVISION_LIMIT", "many")
+
+		_, _, err := Resolve(ctx, cf)
+		assert.NotEqual(t, err, nil, "expected an error")
+	})
+}
@@ -19,6 +19,8 @@
 // Package consts provides definitions of constants
 package consts
 
+import "time"
+
 var (
 	// LegacyDnoteDirName is the name of the legacy directory containing dnote files
 	LegacyDnoteDirName = ".dnote"
@@ -32,6 +34,49 @@ var (
 	TmpContentFileExt = "md"
 	// ConfigFilename is the name of the config file
 	ConfigFilename = "dnoterc"
+	// TemplatesDirName is the name of the directory containing note templates
+	// in the directory of the config file
+	TemplatesDirName = "templates"
+	// TemplateFileExt is the extension for the note template files
+	TemplateFileExt = "tmpl"
+	// AttachmentsDirName is the name of the directory containing the blobs of
+	// the attachments in the dnote data directory
+	AttachmentsDirName = "attachments"
+	// MaxAttachmentSize is the maximum size of an attachment in bytes
+	MaxAttachmentSize int64 = 32 << 20
+	// ProfilesDirName is the name of the directory containing the data of the
+	// profiles other than the default profile in the dnote data directory
+	ProfilesDirName = "profiles"
+	// DefaultProfileName is the name of the profile made of the top level settings
+	// in the config file
+	DefaultProfileName = "default"
+	// ProfileEnvName is the name of the environment variable for the profile to use
+	ProfileEnvName = "DNOTE_PROFILE"
+	// BackupsDirName is the name of the directory containing the backups of a
+	// database, next to the database file
+	BackupsDirName = "backups"
+	// DefaultBackupLimit is the default number of backups kept for each database
+	DefaultBackupLimit = 10
+	// DefaultRevisionLimit is the default number of revisions kept for each note
+	DefaultRevisionLimit = 50
+	// DefaultTrashRetentionDays is the default number of days for which removed
+	// items are kept in the trash
+	DefaultTrashRetentionDays = 30
+	// EncryptionKeyEnvName is the name of the environment variable for the key
+	// of an encrypted database, encoded in base64
+	EncryptionKeyEnvName = "DNOTE_ENCRYPTION_KEY"
+	// EncryptionIteration is the number of PBKDF2 iterations used to derive the
+	// key of an encrypted database from its passphrase
+	EncryptionIteration = 100000
+	// AgentDirName is the name of the directory of the key agent in the dnote
+	// cache directory. Only the user can access it.
+	AgentDirName = "agent"
+	// AgentSocketName is the name of the socket of the key agent in its
+	// directory
+	AgentSocketName = "agent.sock"
+	// DefaultUnlockTimeout is the default duration for which the key agent
+	// keeps the key of an unlocked database
+	DefaultUnlockTimeout = 15 * time.Minute
 
 	// SystemSchema is the key for schema in the system table
 	SystemSchema = "schema"
@@ -47,4 +92,9 @@ var (
 	SystemSessionKey = "session_token"
 	// SystemSessionKeyExpiry is the timestamp at which the session key will expire
 	SystemSessionKeyExpiry = "session_token_expiry"
+	// SystemCipherKey is the key for the end-to-end encryption of the synced data
+	SystemCipherKey = "cipher_key"
+	// SystemEncryptionPending is set from the setup of the end-to-end encryption
+	// until all books and notes have been sent to the server encrypted
+	SystemEncryptionPending = "encryption_pending"
 )
@@ -44,6 +44,14 @@ type DnoteCtx struct {
 	Editor             string
 	Clock              clock.Clock
 	EnableUpgradeCheck bool
+	RevisionLimit      int
+	TrashRetentionDays int
+	BackupLimit        int
+	// Profile is the name of the profile in use. It is empty for the default profile.
+	Profile string
+	// CipherKey is the key for the end-to-end encryption of the synced data. It is nil
+	// if the encryption is not set up.
+	CipherKey []byte
 }
 
 // Redact replaces private information from the context with a set of
@@ -57,5 +65,9 @@ func Redact(ctx DnoteCtx) DnoteCtx {
 	}
 	ctx.SessionKey = sessionKey
 
+	if ctx.CipherKey != nil {
+		ctx.CipherKey = []byte("1")
+	}
+
 	return ctx
 }
@@ -0,0 +1,159 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// Attachment is a file attached to a note. Its content is stored as a blob
+// addressed by its hash.
+type Attachment struct {
+	RowID    int
+	UUID     string
+	NoteUUID string
+	Name     string
+	Hash     string
+	Size     int64
+	AddedOn  int64
+	Dirty    bool
+}
+
+// Insert inserts a new attachment
+func (a Attachment) Insert(db *DB) error {
+	_, err := db.Exec("INSERT INTO attachments (uuid, note_uuid, name, hash, size, added_on, dirty) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		a.UUID, a.NoteUUID, a.Name, a.Hash, a.Size, a.AddedOn, a.Dirty)
+
+	if err != nil {
+		return errors.Wrapf(err, "inserting attachment with uuid %s", a.UUID)
+	}
+
+	return nil
+}
+
+func scanAttachments(rows *sql.Rows) ([]Attachment, error) {
+	defer rows.Close()
+
+	ret := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.RowID, &a.UUID, &a.NoteUUID, &a.Name, &a.Hash, &a.Size, &a.AddedOn, &a.Dirty); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+
+	return ret, nil
+}
+
+const attachmentColumns = "attachments.rowid, attachments.uuid, attachments.note_uuid, attachments.name, attachments.hash, attachments.size, attachments.added_on, attachments.dirty"
+
+// GetNoteAttachments returns the attachments of the note with the given uuid
+// in the order in which they were added
+func GetNoteAttachments(db *DB, noteUUID string) ([]Attachment, error) {
+	rows, err := db.Query("SELECT "+attachmentColumns+" FROM attachments WHERE note_uuid = ? ORDER BY rowid", noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying attachments")
+	}
+
+	return scanAttachments(rows)
+}
+
+// GetAttachment returns the attachment with the given rowid. It returns
+// sql.ErrNoRows if the attachment does not exist or belongs to a removed note.
+func GetAttachment(db *DB, rowID int) (Attachment, error) {
+	var a Attachment
+
+	err := db.QueryRow(`SELECT `+attachmentColumns+`
+		FROM attachments
+		INNER JOIN notes ON notes.uuid = attachments.note_uuid
+		WHERE attachments.rowid = ? AND notes.deleted = false`, rowID).
+		Scan(&a.RowID, &a.UUID, &a.NoteUUID, &a.Name, &a.Hash, &a.Size, &a.AddedOn, &a.Dirty)
+	if err == sql.ErrNoRows {
+		return a, err
+	} else if err != nil {
+		return a, errors.Wrap(err, "finding the attachment")
+	}
+
+	return a, nil
+}
+
+// AttachmentExists checks if the attachment with the given uuid exists
+func AttachmentExists(db *DB, uuid string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM attachments WHERE uuid = ?", uuid).Scan(&count); err != nil {
+		return false, errors.Wrap(err, "counting attachments")
+	}
+
+	return count > 0, nil
+}
+
+// GetDirtyAttachments returns the attachments that have not been sent to the
+// server and belong to notes that exist in the server
+func GetDirtyAttachments(db *DB) ([]Attachment, error) {
+	rows, err := db.Query(`SELECT ` + attachmentColumns + `
+		FROM attachments
+		INNER JOIN notes ON notes.uuid = attachments.note_uuid
+		WHERE attachments.dirty = true AND notes.usn > 0 AND notes.deleted = false
+		ORDER BY attachments.rowid`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying attachments")
+	}
+
+	return scanAttachments(rows)
+}
+
+// MarkAttachmentClean marks the attachment with the given uuid as sent to the server
+func MarkAttachmentClean(db *DB, uuid string) error {
+	if _, err := db.Exec("UPDATE attachments SET dirty = false WHERE uuid = ?", uuid); err != nil {
+		return errors.Wrap(err, "updating the attachment")
+	}
+
+	return nil
+}
+
+// GetAttachmentHashes returns the distinct hashes of the blobs of all attachments
+func GetAttachmentHashes(db *DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT hash FROM attachments ORDER BY hash")
+	if err != nil {
+		return nil, errors.Wrap(err, "querying hashes")
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+
+	return ret, nil
+}
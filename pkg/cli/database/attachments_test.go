@@ -0,0 +1,83 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestGetDirtyAttachments(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "b1")
+	MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, deleted) VALUES (?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", 1, 10, false)
+	MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, deleted) VALUES (?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2", 2, 0, false)
+	MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, deleted) VALUES (?, ?, ?, ?, ?, ?)", "n3-uuid", "b1-uuid", "", 3, 11, true)
+
+	attachments := []Attachment{
+		{UUID: "a1-uuid", NoteUUID: "n1-uuid", Name: "a1", Hash: "h1", Size: 1, AddedOn: 1, Dirty: true},
+		{UUID: "a2-uuid", NoteUUID: "n1-uuid", Name: "a2", Hash: "h2", Size: 2, AddedOn: 2, Dirty: false},
+		{UUID: "a3-uuid", NoteUUID: "n2-uuid", Name: "a3", Hash: "h3", Size: 3, AddedOn: 3, Dirty: true},
+		{UUID: "a4-uuid", NoteUUID: "n3-uuid", Name: "a4", Hash: "h1", Size: 1, AddedOn: 4, Dirty: true},
+	}
+	for _, a := range attachments {
+		if err := a.Insert(db); err != nil {
+			t.Fatal(errors.Wrap(err, "inserting attachment"))
+		}
+	}
+
+	// execute
+	got, err := GetDirtyAttachments(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	assert.Equal(t, len(got), 1, "length mismatch")
+	assert.Equal(t, got[0].UUID, "a1-uuid", "uuid mismatch")
+
+	if err := MarkAttachmentClean(db, "a1-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "marking the attachment clean"))
+	}
+
+	got, err = GetDirtyAttachments(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing after marking clean"))
+	}
+	assert.Equal(t, len(got), 0, "length mismatch after marking clean")
+
+	hashes, err := GetAttachmentHashes(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting hashes"))
+	}
+	assert.DeepEqual(t, hashes, []string{"h1", "h2", "h3"}, "hashes mismatch")
+
+	n1Attachments, err := GetNoteAttachments(db, "n1-uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting note attachments"))
+	}
+	assert.Equal(t, len(n1Attachments), 2, "n1 attachments length mismatch")
+	assert.Equal(t, n1Attachments[0].Name, "a1", "n1 attachment 0 name mismatch")
+	assert.Equal(t, n1Attachments[1].Name, "a2", "n1 attachment 1 name mismatch")
+}
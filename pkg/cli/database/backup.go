@@ -0,0 +1,156 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// copyTimeout is the maximum duration for which a copy waits for the other
+// connections to release their locks on the databases
+var copyTimeout = 10 * time.Second
+
+// copyRetryInterval is the interval at which a copy is retried while the
+// databases are locked
+var copyRetryInterval = 50 * time.Millisecond
+
+func getSQLDB(d *DB) (*sql.DB, error) {
+	db, ok := d.Conn.(*sql.DB)
+	if !ok || db == nil {
+		return nil, errors.New("not a database connection")
+	}
+
+	return db, nil
+}
+
+func withSQLiteConn(db *sql.DB, f func(*sqlite3.SQLiteConn) error) error {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "getting a connection")
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return errors.New("not a sqlite connection")
+		}
+
+		return f(c)
+	})
+}
+
+// copyDB copies all the pages of the src database into the dest database with
+// the SQLite online backup API. The src database can be used by the other
+// connections during the copy, and dest is a consistent snapshot of it.
+func copyDB(dest, src *DB) error {
+	destDB, err := getSQLDB(dest)
+	if err != nil {
+		return errors.Wrap(err, "destination")
+	}
+	srcDB, err := getSQLDB(src)
+	if err != nil {
+		return errors.Wrap(err, "source")
+	}
+
+	return withSQLiteConn(destDB, func(destConn *sqlite3.SQLiteConn) error {
+		return withSQLiteConn(srcDB, func(srcConn *sqlite3.SQLiteConn) error {
+			b, err := destConn.Backup("main", srcConn, "main")
+			if err != nil {
+				return errors.Wrap(err, "starting the backup")
+			}
+
+			deadline := time.Now().Add(copyTimeout)
+			for {
+				// Step returns false without an error while the databases are locked
+				done, err := b.Step(-1)
+				if err != nil {
+					b.Close()
+					return errors.Wrap(err, "copying the pages")
+				}
+				if done {
+					break
+				}
+				if time.Now().After(deadline) {
+					b.Close()
+					return errors.New("timed out waiting for the database to be unlocked")
+				}
+
+				time.Sleep(copyRetryInterval)
+			}
+
+			if err := b.Finish(); err != nil {
+				return errors.Wrap(err, "finishing the backup")
+			}
+
+			return nil
+		})
+	})
+}
+
+// BackupTo writes a consistent snapshot of the database to a new SQLite file
+// at the given path while the database may be in use. The snapshot of an
+// encrypted database is encrypted with the same key.
+func (d *DB) BackupTo(path string) error {
+	if d.enc != nil {
+		content, err := serialize(d)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writeEncrypted(path, d.enc.header, d.enc.key, content); err != nil {
+			return errors.Wrapf(err, "writing the database to %s", path)
+		}
+
+		return nil
+	}
+
+	dest, err := Open(path)
+	if err != nil {
+		return errors.Wrap(err, "opening the backup")
+	}
+	defer dest.Close()
+
+	if err := copyDB(dest, d); err != nil {
+		return errors.Wrapf(err, "copying the database to %s", path)
+	}
+
+	return nil
+}
+
+// RestoreFrom replaces the content of the database with that of the SQLite
+// file at the given path, which can be encrypted with the key of the database
+func (d *DB) RestoreFrom(path string) error {
+	src, err := d.OpenCopy(path)
+	if err != nil {
+		return errors.Wrap(err, "opening the backup")
+	}
+	defer src.Close()
+
+	if err := copyDB(d, src); err != nil {
+		return errors.Wrapf(err, "copying the database from %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestBackupTo(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+	path := "../tmp/dnote-test-backup.db"
+	defer os.Remove(path)
+
+	// execute
+	if err := db.BackupTo(path); err != nil {
+		t.Fatal(errors.Wrap(err, "backing up"))
+	}
+
+	// test
+	MustExec(t, "inserting b2 after the backup", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+
+	backup, err := Open(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the backup"))
+	}
+	defer backup.Close()
+
+	var label string
+	var count int
+	MustScan(t, "counting books", backup.QueryRow("SELECT count(*) FROM books"), &count)
+	MustScan(t, "getting the label", backup.QueryRow("SELECT label FROM books"), &label)
+	assert.Equal(t, count, 1, "book count mismatch")
+	assert.Equal(t, label, "js", "label mismatch")
+}
+
+func TestRestoreFrom(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+	path := "../tmp/dnote-test-backup.db"
+	defer os.Remove(path)
+	if err := db.BackupTo(path); err != nil {
+		t.Fatal(errors.Wrap(err, "backing up"))
+	}
+
+	MustExec(t, "removing b1", db, "DELETE FROM books WHERE uuid = ?", "b1-uuid")
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+
+	// execute
+	if err := db.RestoreFrom(path); err != nil {
+		t.Fatal(errors.Wrap(err, "restoring"))
+	}
+
+	// test
+	var label string
+	var count int
+	MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &count)
+	MustScan(t, "getting the label", db.QueryRow("SELECT label FROM books"), &label)
+	assert.Equal(t, count, 1, "book count mismatch")
+	assert.Equal(t, label, "js", "label mismatch")
+}
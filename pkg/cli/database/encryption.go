@@ -0,0 +1,783 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/crypt"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// The whole database file is encrypted rather than the note bodies and the
+// book labels alone. Their text is also stored in the search index, the
+// revisions, the trash and the links, which would stay in plaintext, and the
+// search and the lookups by label cannot run on encrypted columns. Decrypting
+// the file into an in-memory database keeps all of them working unchanged. It
+// is why the changes are written back to the file and the writes of the
+// processes are serialized with a lock file.
+
+// encryptedMagic starts the files of the encrypted databases. It is followed
+// by a line with the header in JSON and by the encrypted SQLite database.
+const encryptedMagic = "DNOTE ENCRYPTED DATABASE\n"
+
+// encryptionVersion is the version of the format of the encrypted databases
+const encryptionVersion = 1
+
+// saltSize is the size of the salt used to derive the key in bytes
+const saltSize = 16
+
+// lockTimeout is the maximum duration for which a write to an encrypted
+// database waits for another process to finish writing
+var lockTimeout = 30 * time.Second
+
+// lockRetryInterval is the interval at which the lock is retried
+var lockRetryInterval = 50 * time.Millisecond
+
+// ErrWrongKey is returned when a key does not decrypt a database
+var ErrWrongKey = errors.New("wrong passphrase")
+
+// EncryptionHeader has the parameters to derive the key of an encrypted
+// database from its passphrase. It is stored in plaintext in the file.
+type EncryptionHeader struct {
+	Version   int    `json:"version"`
+	Salt      []byte `json:"salt"`
+	Iteration int    `json:"iteration"`
+}
+
+// NewEncryptionHeader returns a header with a random salt
+func NewEncryptionHeader(iteration int) (EncryptionHeader, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return EncryptionHeader{}, errors.Wrap(err, "generating the salt")
+	}
+
+	return EncryptionHeader{
+		Version:   encryptionVersion,
+		Salt:      salt,
+		Iteration: iteration,
+	}, nil
+}
+
+// DeriveKey derives the key of the database from the passphrase
+func (h EncryptionHeader) DeriveKey(passphrase string) ([]byte, error) {
+	key, _, err := crypt.MakeKeys([]byte(passphrase), h.Salt, h.Iteration)
+	if err != nil {
+		return nil, errors.Wrap(err, "deriving the key")
+	}
+
+	return key, nil
+}
+
+// encryption is the state of an encrypted database. The content is decrypted
+// into an in-memory database, which is encrypted and written back to the file
+// after each write.
+type encryption struct {
+	header EncryptionHeader
+	key    []byte
+	// pin keeps the in-memory database alive while the other connections of
+	// the pool are closed
+	pin *sql.Conn
+	// lock is held during a write so that the other processes do not
+	// overwrite the changes. locks counts the nested holders.
+	lock  *fileLock
+	locks int
+	// hash is the hash of the content that was last read from or written to
+	// the file
+	hash [sha256.Size]byte
+	// fileHash is the hash of the file when it was last read or written. The
+	// file has been changed by another process if it differs.
+	fileHash [sha256.Size]byte
+}
+
+// ReadEncryptionHeader returns the header of the database file at the given
+// path, and false if the file is not encrypted or does not exist
+func ReadEncryptionHeader(path string) (EncryptionHeader, bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return EncryptionHeader{}, false, nil
+	} else if err != nil {
+		return EncryptionHeader{}, false, errors.Wrapf(err, "opening %s", path)
+	}
+	defer f.Close()
+
+	header, _, ok, err := readEncryptedFile(f)
+	if err != nil {
+		return EncryptionHeader{}, false, errors.Wrapf(err, "reading %s", path)
+	}
+
+	return header, ok, nil
+}
+
+// readEncryptedFile returns the header and the encrypted content of an
+// encrypted database, and false if the reader does not have one
+func readEncryptedFile(r io.Reader) (EncryptionHeader, string, bool, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(encryptedMagic))
+	if err == io.EOF || (err == nil && string(magic) != encryptedMagic) {
+		return EncryptionHeader{}, "", false, nil
+	} else if err != nil {
+		return EncryptionHeader{}, "", false, errors.Wrap(err, "reading the magic")
+	}
+	if _, err := br.Discard(len(encryptedMagic)); err != nil {
+		return EncryptionHeader{}, "", false, errors.Wrap(err, "reading the magic")
+	}
+
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		return EncryptionHeader{}, "", false, errors.Wrap(err, "reading the header")
+	}
+	var header EncryptionHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return EncryptionHeader{}, "", false, errors.Wrap(err, "decoding the header")
+	}
+	if header.Version != encryptionVersion {
+		return EncryptionHeader{}, "", false, errors.Errorf("unsupported encryption version %d", header.Version)
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return EncryptionHeader{}, "", false, errors.Wrap(err, "reading the content")
+	}
+
+	return header, string(data), true, nil
+}
+
+// readEncrypted decrypts the encrypted database at the given path and returns
+// its header and its SQLite content
+func readEncrypted(path string, key []byte) (EncryptionHeader, []byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return EncryptionHeader{}, nil, errors.Wrapf(err, "reading %s", path)
+	}
+
+	return decryptFile(path, b, key)
+}
+
+// decryptFile decrypts the content b of the encrypted database at the given
+// path and returns its header and its SQLite content
+func decryptFile(path string, b, key []byte) (EncryptionHeader, []byte, error) {
+	header, data, ok, err := readEncryptedFile(bytes.NewReader(b))
+	if err != nil {
+		return EncryptionHeader{}, nil, errors.Wrapf(err, "reading %s", path)
+	}
+	if !ok {
+		return EncryptionHeader{}, nil, errors.Errorf("%s is not encrypted", path)
+	}
+
+	content, err := crypt.AesGcmDecrypt(key, data)
+	if err != nil {
+		return EncryptionHeader{}, nil, ErrWrongKey
+	}
+
+	return header, content, nil
+}
+
+// writeEncrypted encrypts the SQLite content and writes it to the file at the
+// given path, and returns the hash of the file. The file is replaced at once
+// so that it is never left half written.
+func writeEncrypted(path string, header EncryptionHeader, key, content []byte) ([sha256.Size]byte, error) {
+	data, err := crypt.AesGcmEncrypt(key, content)
+	if err != nil {
+		return [sha256.Size]byte{}, errors.Wrap(err, "encrypting")
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return [sha256.Size]byte{}, errors.Wrap(err, "encoding the header")
+	}
+
+	var b bytes.Buffer
+	b.WriteString(encryptedMagic)
+	b.Write(h)
+	b.WriteString("\n")
+	b.WriteString(data)
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".dnote-*.tmp")
+	if err != nil {
+		return [sha256.Size]byte{}, errors.Wrap(err, "creating a temporary file")
+	}
+	tmpPath := f.Name()
+
+	write := func() error {
+		if err := f.Chmod(0600); err != nil {
+			return errors.Wrap(err, "setting the permissions")
+		}
+		if _, err := f.Write(b.Bytes()); err != nil {
+			return errors.Wrap(err, "writing")
+		}
+
+		return f.Sync()
+	}
+	if err := write(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return [sha256.Size]byte{}, errors.Wrapf(err, "writing %s", tmpPath)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return [sha256.Size]byte{}, errors.Wrapf(err, "closing %s", tmpPath)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return [sha256.Size]byte{}, errors.Wrapf(err, "replacing %s", path)
+	}
+
+	return sha256.Sum256(b.Bytes()), nil
+}
+
+// openMemory opens a new in-memory database shared by the connections of the
+// pool, and a connection that keeps it alive
+func openMemory() (*sql.DB, *sql.Conn, error) {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, nil, errors.Wrap(err, "generating the name")
+	}
+
+	db, err := sql.Open("sqlite3", "file:/dnote-"+hex.EncodeToString(b)+"?vfs=memdb")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "opening the in-memory database")
+	}
+
+	pin, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, nil, errors.Wrap(err, "connecting to the in-memory database")
+	}
+
+	return db, pin, nil
+}
+
+// serialize returns the SQLite content of the database
+func serialize(d *DB) ([]byte, error) {
+	db, err := getSQLDB(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []byte
+	err = withSQLiteConn(db, func(c *sqlite3.SQLiteConn) error {
+		b, err := c.Serialize("main")
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+
+		ret = b
+		return nil
+	})
+
+	return ret, err
+}
+
+// deserialize replaces the content of the database with the given SQLite content
+func deserialize(d *DB, content []byte) error {
+	src, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return errors.Wrap(err, "opening a temporary database")
+	}
+	defer src.Close()
+	// Every connection to :memory: has its own database
+	src.SetMaxOpenConns(1)
+
+	err = withSQLiteConn(src, func(c *sqlite3.SQLiteConn) error {
+		return c.Deserialize(content, "main")
+	})
+	if err != nil {
+		return errors.Wrap(err, "deserializing")
+	}
+
+	return copyDB(d, &DB{Conn: src})
+}
+
+func openEncrypted(path string, key []byte) (*DB, error) {
+	conn, pin, err := openMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		Conn:     conn,
+		Filepath: path,
+		enc: &encryption{
+			key: key,
+			pin: pin,
+		},
+	}
+	if err := db.reload(); err != nil {
+		db.closeMemory()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenEncrypted decrypts the encrypted database at the given path into memory
+// with the key. Each write is written to the file when it is committed, and
+// reads the changes that the other processes have written since. The writes
+// of the processes are done one at a time.
+func OpenEncrypted(path string, key []byte) (*DB, error) {
+	return openEncrypted(path, key)
+}
+
+// OpenCopy opens the database at the given path, such as a backup, decrypting
+// it in memory with the key of the database if it is encrypted. The changes to
+// an encrypted copy are not saved.
+func (d *DB) OpenCopy(path string) (*DB, error) {
+	_, encrypted, err := ReadEncryptionHeader(path)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return Open(path)
+	}
+
+	if d.enc == nil {
+		return nil, errors.Errorf("%s is encrypted but the database is not. Enable the encryption to open it", path)
+	}
+
+	db, err := openEncrypted(path, d.enc.key)
+	if err == ErrWrongKey {
+		return nil, errors.Errorf("%s was encrypted with another passphrase", path)
+	} else if err != nil {
+		return nil, err
+	}
+	db.Filepath = ""
+
+	return db, nil
+}
+
+// Encrypted returns true if the database is encrypted
+func (d *DB) Encrypted() bool {
+	return d.enc != nil
+}
+
+// EncryptionKey returns the key of an encrypted database
+func (d *DB) EncryptionKey() []byte {
+	if d.enc == nil {
+		return nil
+	}
+
+	return d.enc.key
+}
+
+// reload replaces the content of an encrypted database with the file if
+// another process has written it since it was last read or written
+func (d *DB) reload() error {
+	b, err := os.ReadFile(d.Filepath)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", d.Filepath)
+	}
+
+	fileHash := sha256.Sum256(b)
+	if fileHash == d.enc.fileHash {
+		return nil
+	}
+
+	header, content, err := decryptFile(d.Filepath, b, d.enc.key)
+	if err != nil {
+		return err
+	}
+	if err := deserialize(d, content); err != nil {
+		return err
+	}
+
+	// The content is hashed after the copy, which can change the bytes
+	// of the file without changing the data
+	saved, err := serialize(d)
+	if err != nil {
+		return err
+	}
+
+	d.enc.header = header
+	d.enc.hash = sha256.Sum256(saved)
+	d.enc.fileHash = fileHash
+
+	return nil
+}
+
+// persisted returns true if the database is encrypted and written to a file
+func (d *DB) persisted() bool {
+	return d.enc != nil && d.Filepath != ""
+}
+
+// acquire locks the file of an encrypted database for a write, and reloads
+// it with the changes of the other processes if reload is true. The lock can
+// be acquired again by the same database.
+func (d *DB) acquire(reload bool) error {
+	if d.enc.locks > 0 {
+		d.enc.locks++
+		return nil
+	}
+
+	l, err := acquireLock(d.Filepath)
+	if err != nil {
+		return err
+	}
+	if reload {
+		if err := d.reload(); err != nil {
+			l.release()
+			if err == ErrWrongKey {
+				return errors.Errorf("%s was encrypted again with another passphrase", d.Filepath)
+			}
+
+			return errors.Wrap(err, "reading the changes of the other processes")
+		}
+	}
+
+	d.enc.lock = l
+	d.enc.locks = 1
+
+	return nil
+}
+
+// release releases the lock acquired for a write
+func (d *DB) release() error {
+	if d.enc.locks == 0 {
+		return nil
+	}
+
+	d.enc.locks--
+	if d.enc.locks > 0 {
+		return nil
+	}
+
+	l := d.enc.lock
+	d.enc.lock = nil
+
+	return l.release()
+}
+
+// Save encrypts the content of an encrypted database and writes it to the file
+// if it has changed since it was last written. It does nothing if the database
+// is not encrypted.
+func (d *DB) Save() error {
+	if !d.persisted() {
+		return nil
+	}
+
+	content, err := serialize(d)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(content)
+	if hash == d.enc.hash {
+		return nil
+	}
+
+	// The content is not reloaded so that the changes are not lost
+	if err := d.acquire(false); err != nil {
+		return err
+	}
+	defer d.release()
+
+	fileHash, err := writeEncrypted(d.Filepath, d.enc.header, d.enc.key, content)
+	if err != nil {
+		return errors.Wrap(err, "saving the encrypted database")
+	}
+	d.enc.hash = hash
+	d.enc.fileHash = fileHash
+
+	return nil
+}
+
+// closeMemory closes the in-memory database of an encrypted database
+func (d *DB) closeMemory() error {
+	pinErr := d.enc.pin.Close()
+
+	db, err := getSQLDB(d)
+	if err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	return pinErr
+}
+
+// closeEncrypted saves and closes an encrypted database
+func (d *DB) closeEncrypted() error {
+	saveErr := d.Save()
+	closeErr := d.closeMemory()
+	// A transaction was not ended
+	if d.enc.lock != nil {
+		d.enc.lock.release()
+		d.enc.lock = nil
+		d.enc.locks = 0
+	}
+
+	if saveErr != nil {
+		return saveErr
+	}
+
+	return closeErr
+}
+
+// Encrypt encrypts the database file with the key derived from the header and
+// keeps working on the decrypted content in memory
+func (d *DB) Encrypt(header EncryptionHeader, key []byte) error {
+	if d.enc != nil {
+		return errors.New("the database is already encrypted")
+	}
+	file, err := getSQLDB(d)
+	if err != nil {
+		return err
+	}
+
+	conn, pin, err := openMemory()
+	if err != nil {
+		return err
+	}
+
+	db := &DB{
+		Conn:     conn,
+		Filepath: d.Filepath,
+		enc: &encryption{
+			header: header,
+			key:    key,
+			pin:    pin,
+		},
+	}
+	if err := db.acquire(false); err != nil {
+		db.closeMemory()
+		return err
+	}
+	if err := copyDB(db, d); err != nil {
+		db.closeMemory()
+		db.release()
+		return errors.Wrap(err, "copying the database into memory")
+	}
+	if err := db.Save(); err != nil {
+		db.closeMemory()
+		db.release()
+		return err
+	}
+	if err := db.release(); err != nil {
+		db.closeMemory()
+		return errors.Wrap(err, "releasing the lock")
+	}
+
+	*d = *db
+	if err := file.Close(); err != nil {
+		return errors.Wrap(err, "closing the database file")
+	}
+
+	return nil
+}
+
+// Decrypt writes the content of an encrypted database back to the file in
+// plaintext and keeps working on the file
+func (d *DB) Decrypt() error {
+	if d.enc == nil {
+		return errors.New("the database is not encrypted")
+	}
+
+	if err := d.acquire(true); err != nil {
+		return err
+	}
+	if err := d.writePlaintext(); err != nil {
+		d.release()
+		return err
+	}
+	lockErr := d.release()
+
+	file, err := Open(d.Filepath)
+	if err != nil {
+		return errors.Wrap(err, "opening the decrypted database")
+	}
+
+	closeErr := d.closeMemory()
+	*d = *file
+
+	if lockErr != nil {
+		return errors.Wrap(lockErr, "releasing the lock")
+	}
+
+	return closeErr
+}
+
+// writePlaintext replaces the file of an encrypted database with its content
+// in plaintext
+func (d *DB) writePlaintext() error {
+	tmpPath := d.Filepath + ".tmp"
+	os.Remove(tmpPath)
+
+	tmp, err := Open(tmpPath)
+	if err != nil {
+		return errors.Wrap(err, "opening a temporary database")
+	}
+	if err := copyDB(tmp, d); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "copying the database to the file")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "closing the temporary database")
+	}
+	if err := os.Rename(tmpPath, d.Filepath); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrapf(err, "replacing %s", d.Filepath)
+	}
+
+	return nil
+}
+
+// EncryptFile encrypts the plaintext SQLite file at the given path, such as a
+// backup, with the key of the encrypted database
+func (d *DB) EncryptFile(path string) error {
+	if d.enc == nil {
+		return errors.New("the database is not encrypted")
+	}
+
+	src, err := Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s", path)
+	}
+	content, err := serialize(src)
+	if cerr := src.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", path)
+	}
+
+	_, err = writeEncrypted(path, d.enc.header, d.enc.key, content)
+	return err
+}
+
+// DecryptFile writes the file at the given path, encrypted with the key of the
+// encrypted database, back in plaintext
+func (d *DB) DecryptFile(path string) error {
+	if d.enc == nil {
+		return errors.New("the database is not encrypted")
+	}
+
+	_, content, err := readEncrypted(path, d.enc.key)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrapf(err, "writing %s", tmpPath)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrapf(err, "replacing %s", path)
+	}
+
+	return nil
+}
+
+// fileLock is a lock file next to a database, containing the id of the
+// process holding it
+type fileLock struct {
+	path string
+}
+
+func acquireLock(dbPath string) (*fileLock, error) {
+	path := dbPath + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, errors.Wrapf(err, "writing %s", path)
+			}
+
+			return &fileLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrapf(err, "creating %s", path)
+		}
+
+		if isStaleLock(path) {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("another dnote process has been writing to the database for %s. If no other process is running, remove %s", lockTimeout, path)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func (l *fileLock) release() error {
+	return os.Remove(l.path)
+}
+
+// isStaleLock returns true if the process that created the lock file at the
+// given path is no longer running
+func isStaleLock(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	// The id may not be written yet
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return false
+	}
+
+	return !processExists(pid)
+}
+
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+
+	// On Windows, FindProcess fails if the process does not exist
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,368 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func mustEncrypt(t *testing.T, db *DB, passphrase string) []byte {
+	header, err := NewEncryptionHeader(1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "making the header"))
+	}
+	key, err := header.DeriveKey(passphrase)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "deriving the key"))
+	}
+
+	if err := db.Encrypt(header, key); err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting"))
+	}
+
+	return key
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(errors.Wrapf(err, "reading %s", path))
+	}
+
+	return b
+}
+
+func TestEncrypt(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "secretbook")
+	MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "secret note body", 1)
+
+	// execute
+	key := mustEncrypt(t, db, "pass")
+
+	// test
+	assert.Equal(t, db.Encrypted(), true, "encrypted mismatch")
+	assert.DeepEqual(t, db.EncryptionKey(), key, "key mismatch")
+
+	b := mustReadFile(t, db.Filepath)
+	assert.Equal(t, strings.HasPrefix(string(b), encryptedMagic), true, "the file should start with the magic")
+	assert.Equal(t, bytes.Contains(b, []byte("secret")), false, "the file should not contain the plaintext")
+
+	header, ok, err := ReadEncryptionHeader(db.Filepath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the header"))
+	}
+	assert.Equal(t, ok, true, "the header should be found")
+	assert.Equal(t, header.Iteration, 1000, "iteration mismatch")
+
+	var body string
+	MustScan(t, "searching", db.QueryRow("SELECT notes.body FROM note_fts INNER JOIN notes ON notes.rowid = note_fts.rowid WHERE note_fts MATCH ?", "secret"), &body)
+	assert.Equal(t, body, "secret note body", "body mismatch")
+
+	if err := db.Encrypt(header, key); err == nil {
+		t.Fatal("encrypting an encrypted database should fail")
+	}
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	// set up
+	path := "../tmp/dnote-test.db"
+	db := InitTestDB(t, path, nil)
+	key := mustEncrypt(t, db, "pass")
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	if err := db.Close(); err != nil {
+		t.Fatal(errors.Wrap(err, "closing"))
+	}
+	defer os.Remove(path)
+
+	t.Run("wrong key", func(t *testing.T) {
+		header, _, err := ReadEncryptionHeader(path)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the header"))
+		}
+		wrongKey, err := header.DeriveKey("wrong")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "deriving the key"))
+		}
+
+		_, err = OpenEncrypted(path, wrongKey)
+		assert.Equal(t, err, ErrWrongKey, "error mismatch")
+
+		if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+			t.Fatal("the lock should be released")
+		}
+	})
+
+	t.Run("correct key", func(t *testing.T) {
+		db, err := OpenEncrypted(path, key)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "opening"))
+		}
+
+		var label string
+		MustScan(t, "getting the label", db.QueryRow("SELECT label FROM books"), &label)
+		assert.Equal(t, label, "js", "label mismatch")
+
+		MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+		if err := db.Close(); err != nil {
+			t.Fatal(errors.Wrap(err, "closing"))
+		}
+
+		db, err = OpenEncrypted(path, key)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reopening"))
+		}
+		defer db.Close()
+
+		var count int
+		MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &count)
+		assert.Equal(t, count, 2, "the changes should be saved on close")
+	})
+}
+
+func TestOpenEncrypted_concurrent(t *testing.T) {
+	// set up
+	path := "../tmp/dnote-test.db"
+	db := InitTestDB(t, path, nil)
+	key := mustEncrypt(t, db, "pass")
+	defer TeardownTestDB(t, db)
+
+	other, err := OpenEncrypted(path, key)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the database in use"))
+	}
+	defer other.Close()
+
+	// execute
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	MustExec(t, "inserting b2", other, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+	MustExec(t, "inserting b3", tx, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "go")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(errors.Wrap(err, "committing"))
+	}
+
+	// test
+	copy, err := db.OpenCopy(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the file"))
+	}
+	defer copy.Close()
+
+	var count int
+	MustScan(t, "counting books", copy.QueryRow("SELECT count(*) FROM books"), &count)
+	assert.Equal(t, count, 3, "the writes of both processes should be saved")
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatal("the lock should be released after the writes")
+	}
+}
+
+func TestOpenEncrypted_lock(t *testing.T) {
+	// set up
+	path := "../tmp/dnote-test.db"
+	db := InitTestDB(t, path, nil)
+	mustEncrypt(t, db, "pass")
+	defer TeardownTestDB(t, db)
+
+	timeout := lockTimeout
+	lockTimeout = 100 * time.Millisecond
+	defer func() { lockTimeout = timeout }()
+
+	// A running process is writing
+	if err := os.WriteFile(path+".lock", []byte(fmt.Sprint(os.Getpid())), 0600); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the lock"))
+	}
+	defer os.Remove(path + ".lock")
+
+	// test
+	var count int
+	MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &count)
+	assert.Equal(t, count, 0, "reading should not wait for the lock")
+
+	if _, err := db.Exec("INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js"); err == nil {
+		t.Fatal("writing while another process writes should fail after the timeout")
+	}
+	if _, err := db.Begin(); err == nil {
+		t.Fatal("beginning a transaction while another process writes should fail after the timeout")
+	}
+
+	t.Run("stale lock", func(t *testing.T) {
+		// The id of a process that cannot exist
+		if err := os.WriteFile(path+".lock", []byte(fmt.Sprint(1<<30)), 0600); err != nil {
+			t.Fatal(errors.Wrap(err, "writing the lock"))
+		}
+
+		MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	})
+}
+
+func TestCommit_encrypted(t *testing.T) {
+	// set up
+	path := "../tmp/dnote-test.db"
+	db := InitTestDB(t, path, nil)
+	mustEncrypt(t, db, "pass")
+	defer TeardownTestDB(t, db)
+
+	before := mustReadFile(t, path)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+	MustExec(t, "inserting b1", tx, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	assert.DeepEqual(t, mustReadFile(t, path), before, "an uncommitted transaction should not be written")
+
+	// execute
+	if err := tx.Commit(); err != nil {
+		t.Fatal(errors.Wrap(err, "committing"))
+	}
+
+	// test
+	copy, err := db.OpenCopy(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the file"))
+	}
+	defer copy.Close()
+
+	var label string
+	MustScan(t, "getting the label", copy.QueryRow("SELECT label FROM books"), &label)
+	assert.Equal(t, label, "js", "a committed transaction should be written before the database is closed")
+
+	t.Run("rollback", func(t *testing.T) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "beginning a transaction"))
+		}
+		MustExec(t, "inserting b2", tx, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+		tx.Rollback()
+
+		if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+			t.Fatal("the lock should be released after a rollback")
+		}
+	})
+}
+
+func TestSave(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	mustEncrypt(t, db, "pass")
+
+	before := mustReadFile(t, db.Filepath)
+
+	// execute
+	if err := db.Save(); err != nil {
+		t.Fatal(errors.Wrap(err, "saving"))
+	}
+
+	// test
+	assert.DeepEqual(t, mustReadFile(t, db.Filepath), before, "an unchanged database should not be written")
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	if err := db.Save(); err != nil {
+		t.Fatal(errors.Wrap(err, "saving"))
+	}
+	assert.NotEqual(t, string(mustReadFile(t, db.Filepath)), string(before), "a changed database should be written")
+}
+
+func TestDecrypt(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	mustEncrypt(t, db, "pass")
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+	// execute
+	if err := db.Decrypt(); err != nil {
+		t.Fatal(errors.Wrap(err, "decrypting"))
+	}
+
+	// test
+	assert.Equal(t, db.Encrypted(), false, "encrypted mismatch")
+	if _, err := os.Stat(db.Filepath + ".lock"); !os.IsNotExist(err) {
+		t.Fatal("the lock should be released")
+	}
+
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+
+	file, err := Open(db.Filepath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the file"))
+	}
+	defer file.Close()
+
+	var count int
+	MustScan(t, "counting books", file.QueryRow("SELECT count(*) FROM books"), &count)
+	assert.Equal(t, count, 2, "book count mismatch")
+}
+
+func TestBackupTo_encrypted(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	mustEncrypt(t, db, "pass")
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "secretbook")
+
+	path := "../tmp/dnote-test-backup.db"
+	defer os.Remove(path)
+
+	// execute
+	if err := db.BackupTo(path); err != nil {
+		t.Fatal(errors.Wrap(err, "backing up"))
+	}
+
+	// test
+	b := mustReadFile(t, path)
+	assert.Equal(t, strings.HasPrefix(string(b), encryptedMagic), true, "the backup should be encrypted")
+	assert.Equal(t, bytes.Contains(b, []byte("secret")), false, "the backup should not contain the plaintext")
+
+	MustExec(t, "removing b1", db, "DELETE FROM books")
+	if err := db.RestoreFrom(path); err != nil {
+		t.Fatal(errors.Wrap(err, "restoring"))
+	}
+
+	var label string
+	MustScan(t, "getting the label", db.QueryRow("SELECT label FROM books"), &label)
+	assert.Equal(t, label, "secretbook", "label mismatch")
+
+	t.Run("plaintext database", func(t *testing.T) {
+		plain := InitTestDB(t, "../tmp/dnote-test-plain.db", nil)
+		defer TeardownTestDB(t, plain)
+
+		if _, err := plain.OpenCopy(path); err == nil {
+			t.Fatal("opening an encrypted backup without a key should fail")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/links"
+	"github.com/pkg/errors"
+)
+
+// Link is a link between notes. For a link from a note, the fields other than
+// Target describe the linked note and are empty if the link is dangling. For a
+// backlink, they describe the note that links.
+type Link struct {
+	Target    string
+	RowID     int
+	UUID      string
+	BookLabel string
+	Title     string
+	// Ambiguous is true if the target matches more than one note
+	Ambiguous bool
+}
+
+// Dangling checks if the link does not lead to a note
+func (l Link) Dangling() bool {
+	return l.UUID == ""
+}
+
+// IndexNoteLinks replaces the links of the note with the given uuid with the
+// links found in the given body
+func IndexNoteLinks(db *DB, noteUUID, body string) error {
+	if _, err := db.Exec("DELETE FROM note_links WHERE note_uuid = ?", noteUUID); err != nil {
+		return errors.Wrap(err, "deleting links")
+	}
+
+	for _, target := range links.Extract(body) {
+		if _, err := db.Exec("INSERT OR IGNORE INTO note_links (note_uuid, target) VALUES (?, ?)", noteUUID, target); err != nil {
+			return errors.Wrapf(err, "inserting link '%s'", target)
+		}
+	}
+
+	return nil
+}
+
+// queryLinkedNotes returns the notes that are not deleted and match the given
+// condition, as links to the given target
+func queryLinkedNotes(db *DB, target, cond string, args ...interface{}) ([]Link, error) {
+	rows, err := db.Query(`SELECT notes.rowid, notes.uuid, books.label, notes.body
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.deleted = false AND `+cond+`
+		ORDER BY notes.rowid`, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	var ret []Link
+	for rows.Next() {
+		l := Link{Target: target}
+		var body string
+		if err := rows.Scan(&l.RowID, &l.UUID, &l.BookLabel, &body); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		l.Title = links.Title(body)
+		ret = append(ret, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating notes")
+	}
+
+	return ret, nil
+}
+
+// ResolveLink finds the note that the given link target leads to. A uuid
+// prefix matches the notes whose uuid starts with it, and a book and a title
+// match the notes in the book whose title is the same regardless of the case.
+// The returned link is dangling if no note or more than one note matches.
+func ResolveLink(db *DB, target string) (Link, error) {
+	var matches []Link
+	var err error
+
+	if links.IsUUIDPrefix(target) {
+		matches, err = queryLinkedNotes(db, target, "substr(notes.uuid, 1, ?) = ?", len(target), target)
+		if err != nil {
+			return Link{}, errors.Wrap(err, "finding notes by uuid")
+		}
+	} else if book, title, ok := links.SplitBookTitle(target); ok {
+		notes, err := queryLinkedNotes(db, target, "books.label = ?", book)
+		if err != nil {
+			return Link{}, errors.Wrap(err, "finding notes by title")
+		}
+
+		for _, n := range notes {
+			if strings.EqualFold(n.Title, title) {
+				matches = append(matches, n)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Link{Target: target}, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return Link{Target: target, Ambiguous: true}, nil
+	}
+}
+
+// getLinkTargets returns the targets of the links from the note with the given
+// uuid in the order they appear in the note
+func getLinkTargets(db *DB, noteUUID string) ([]string, error) {
+	rows, err := db.Query("SELECT target FROM note_links WHERE note_uuid = ? ORDER BY rowid", noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying links")
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating links")
+	}
+
+	return ret, nil
+}
+
+// GetNoteLinks returns the resolved links from the note with the given uuid
+func GetNoteLinks(db *DB, noteUUID string) ([]Link, error) {
+	targets, err := getLinkTargets(db, noteUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Link{}
+	for _, target := range targets {
+		l, err := ResolveLink(db, target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving link '%s'", target)
+		}
+
+		ret = append(ret, l)
+	}
+
+	return ret, nil
+}
+
+// GetBacklinks returns the links to the note with the given uuid from the other
+// notes that are not deleted
+func GetBacklinks(db *DB, noteUUID string) ([]Link, error) {
+	var label, body string
+	err := db.QueryRow(`SELECT books.label, notes.body
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.uuid = ?`, noteUUID).Scan(&label, &body)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf("note %s not found", noteUUID)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "querying the note")
+	}
+	title := links.Title(body)
+
+	rows, err := db.Query(`SELECT note_links.target, notes.rowid, notes.uuid, books.label, notes.body
+		FROM note_links
+		INNER JOIN notes ON notes.uuid = note_links.note_uuid
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.deleted = false AND notes.uuid != ?
+		ORDER BY notes.rowid, note_links.rowid`, noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying links")
+	}
+	defer rows.Close()
+
+	var candidates []Link
+	for rows.Next() {
+		var l Link
+		var sourceBody string
+		if err := rows.Scan(&l.Target, &l.RowID, &l.UUID, &l.BookLabel, &sourceBody); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		if links.IsUUIDPrefix(l.Target) {
+			if !strings.HasPrefix(noteUUID, l.Target) {
+				continue
+			}
+		} else if b, t, ok := links.SplitBookTitle(l.Target); !ok || b != label || !strings.EqualFold(t, title) {
+			continue
+		}
+
+		l.Title = links.Title(sourceBody)
+		candidates = append(candidates, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating links")
+	}
+
+	// Leave out the links that match other notes as well
+	ret := []Link{}
+	for _, l := range candidates {
+		resolved, err := ResolveLink(db, l.Target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving link '%s'", l.Target)
+		}
+		if resolved.UUID == noteUUID {
+			ret = append(ret, l)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetRemovalBacklinks returns the backlinks to the given notes from the notes
+// that are not among them. They are the links that become dangling when the
+// given notes are removed.
+func GetRemovalBacklinks(db *DB, noteUUIDs []string) ([]Link, error) {
+	removed := map[string]bool{}
+	for _, uuid := range noteUUIDs {
+		removed[uuid] = true
+	}
+
+	ret := []Link{}
+	for _, uuid := range noteUUIDs {
+		backlinks, err := GetBacklinks(db, uuid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting backlinks to note %s", uuid)
+		}
+
+		for _, l := range backlinks {
+			if !removed[l.UUID] {
+				ret = append(ret, l)
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,184 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+// setupLinkedNotes inserts notes that link to each other
+func setupLinkedNotes(t *testing.T, db *DB) {
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "golang")
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "work/infra")
+
+	notes := []struct {
+		uuid     string
+		bookUUID string
+		body     string
+		deleted  bool
+	}{
+		{"aaaa1111-0000", "b1-uuid", "# Channels\nsee [[work/infra/deploy steps]] and [[bbbb2222]]", false},
+		{"aaaa2222-0000", "b1-uuid", "Goroutines\n[[golang/channels]] [[ABCD]] [[aaaa]]", false},
+		{"bbbb2222-0000", "b2-uuid", "Deploy steps\n[[aaaa1111]]", false},
+		{"cccc3333-0000", "b2-uuid", "", true},
+		{"dddd4444-0000", "b2-uuid", "Rollback\n[[cccc3333]] [[golang/Channels]]", false},
+	}
+
+	for _, n := range notes {
+		MustExec(t, "inserting a note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", n.uuid, n.bookUUID, n.body, 1, n.deleted)
+
+		if err := IndexNoteLinks(db, n.uuid, n.body); err != nil {
+			t.Fatal(errors.Wrap(err, "indexing links"))
+		}
+	}
+}
+
+func TestIndexNoteLinks(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting a link", db, "INSERT INTO note_links (note_uuid, target) VALUES (?, ?)", "n1-uuid", "old/link")
+	MustExec(t, "inserting a link", db, "INSERT INTO note_links (note_uuid, target) VALUES (?, ?)", "n2-uuid", "other/link")
+
+	// execute
+	if err := IndexNoteLinks(db, "n1-uuid", "[[js/closures]] [[ABCD12]] [[js/closures]]"); err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	targets, err := getLinkTargets(db, "n1-uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting targets"))
+	}
+	assert.DeepEqual(t, targets, []string{"js/closures", "abcd12"}, "n1 targets mismatch")
+
+	targets, err = getLinkTargets(db, "n2-uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting targets"))
+	}
+	assert.DeepEqual(t, targets, []string{"other/link"}, "n2 targets mismatch")
+}
+
+func TestResolveLink(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	setupLinkedNotes(t, db)
+
+	testCases := []struct {
+		target    string
+		uuid      string
+		ambiguous bool
+	}{
+		{target: "aaaa1111", uuid: "aaaa1111-0000"},
+		{target: "aaaa", ambiguous: true},
+		{target: "golang/channels", uuid: "aaaa1111-0000"},
+		{target: "work/infra/Deploy Steps", uuid: "bbbb2222-0000"},
+		{target: "work/Deploy steps", uuid: ""},
+		{target: "cccc3333", uuid: ""},
+		{target: "eeee", uuid: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			l, err := ResolveLink(db, tc.target)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.Equal(t, l.Target, tc.target, "Target mismatch")
+			assert.Equal(t, l.UUID, tc.uuid, "UUID mismatch")
+			assert.Equal(t, l.Ambiguous, tc.ambiguous, "Ambiguous mismatch")
+		})
+	}
+}
+
+func TestGetNoteLinks(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	setupLinkedNotes(t, db)
+
+	// execute
+	result, err := GetNoteLinks(db, "aaaa2222-0000")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	assert.Equal(t, len(result), 3, "length mismatch")
+	assert.Equal(t, result[0].Target, "golang/channels", "result[0] Target mismatch")
+	assert.Equal(t, result[0].UUID, "aaaa1111-0000", "result[0] UUID mismatch")
+	assert.Equal(t, result[0].BookLabel, "golang", "result[0] BookLabel mismatch")
+	assert.Equal(t, result[0].Title, "Channels", "result[0] Title mismatch")
+	assert.Equal(t, result[1].Target, "abcd", "result[1] Target mismatch")
+	assert.Equal(t, result[1].Dangling(), true, "result[1] should be dangling")
+	assert.Equal(t, result[2].Target, "aaaa", "result[2] Target mismatch")
+	assert.Equal(t, result[2].Dangling(), true, "result[2] should be dangling")
+	assert.Equal(t, result[2].Ambiguous, true, "result[2] should be ambiguous")
+}
+
+func TestGetBacklinks(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	setupLinkedNotes(t, db)
+
+	t.Run("linked note", func(t *testing.T) {
+		result, err := GetBacklinks(db, "aaaa1111-0000")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, len(result), 3, "length mismatch")
+		assert.Equal(t, result[0].UUID, "aaaa2222-0000", "result[0] UUID mismatch")
+		assert.Equal(t, result[0].Target, "golang/channels", "result[0] Target mismatch")
+		assert.Equal(t, result[0].Title, "Goroutines", "result[0] Title mismatch")
+		assert.Equal(t, result[1].UUID, "bbbb2222-0000", "result[1] UUID mismatch")
+		assert.Equal(t, result[1].Target, "aaaa1111", "result[1] Target mismatch")
+		assert.Equal(t, result[1].BookLabel, "work/infra", "result[1] BookLabel mismatch")
+		assert.Equal(t, result[2].UUID, "dddd4444-0000", "result[2] UUID mismatch")
+		assert.Equal(t, result[2].Target, "golang/Channels", "result[2] Target mismatch")
+	})
+
+	t.Run("ambiguous links", func(t *testing.T) {
+		result, err := GetBacklinks(db, "aaaa2222-0000")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, len(result), 0, "length mismatch")
+	})
+
+	t.Run("deleted note", func(t *testing.T) {
+		result, err := GetBacklinks(db, "cccc3333-0000")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, len(result), 0, "length mismatch")
+	})
+}
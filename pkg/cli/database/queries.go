@@ -20,7 +20,11 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/dnote/dnote/pkg/clock"
 	"github.com/pkg/errors"
 )
@@ -89,6 +93,9 @@ type NoteInfo struct {
 	Content   string
 	AddedOn   int64
 	EditedOn  int64
+	Tags      []string
+	// Links is the resolved links from the note. GetNoteInfo does not set it.
+	Links []Link
 }
 
 // GetNoteInfo returns a NoteInfo for the note with the given noteRowID
@@ -106,9 +113,83 @@ func GetNoteInfo(db *DB, noteRowID int) (NoteInfo, error) {
 		return ret, errors.Wrap(err, "querying the note")
 	}
 
+	tags, err := GetNoteTags(db, ret.UUID)
+	if err != nil {
+		return ret, errors.Wrap(err, "getting tags")
+	}
+	ret.Tags = tags
+
+	return ret, nil
+}
+
+// GetNoteTags returns the tags of the note with the given uuid in alphabetical order
+func GetNoteTags(db *DB, noteUUID string) ([]string, error) {
+	rows, err := db.Query("SELECT tag FROM note_tags WHERE note_uuid = ? ORDER BY tag", noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tags")
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, tag)
+	}
+
 	return ret, nil
 }
 
+// NoteTagsColumn is an SQL expression that evaluates to the comma separated
+// tags of the note in the row of the notes table
+const NoteTagsColumn = `COALESCE((SELECT group_concat(tag, ',') FROM
+		(SELECT tag FROM note_tags WHERE note_tags.note_uuid = notes.uuid ORDER BY tag)), '')`
+
+// SplitTags splits the value of NoteTagsColumn into a list of tags
+func SplitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(s, ",")
+}
+
+// NoteTagsFilter returns an SQL condition and its arguments for selecting the
+// notes that have all of the given tags
+func NoteTagsFilter(tags []string) (string, []interface{}) {
+	placeholders := make([]string, len(tags))
+	args := make([]interface{}, 0, len(tags)+1)
+	for i, t := range tags {
+		placeholders[i] = "?"
+		args = append(args, t)
+	}
+	args = append(args, len(tags))
+
+	cond := fmt.Sprintf(`notes.uuid IN (SELECT note_uuid FROM note_tags
+		WHERE tag IN (%s) GROUP BY note_uuid HAVING count(*) = ?)`, strings.Join(placeholders, ", "))
+
+	return cond, args
+}
+
+// SetNoteTags replaces the tags of the note with the given uuid. The tags are
+// expected to be normalized.
+func SetNoteTags(db *DB, noteUUID string, tags []string) error {
+	if _, err := db.Exec("DELETE FROM note_tags WHERE note_uuid = ?", noteUUID); err != nil {
+		return errors.Wrap(err, "deleting tags")
+	}
+
+	for _, tag := range tags {
+		if _, err := db.Exec("INSERT OR IGNORE INTO note_tags (note_uuid, tag) VALUES (?, ?)", noteUUID, tag); err != nil {
+			return errors.Wrapf(err, "inserting tag '%s'", tag)
+		}
+	}
+
+	return nil
+}
+
 // BookInfo is a basic information about a book
 type BookInfo struct {
 	RowID int
@@ -146,9 +227,100 @@ func GetBookUUID(db *DB, label string) (string, error) {
 	return ret, nil
 }
 
-// UpdateBookName updates a book name
+// IsBookSubtree checks if the given book label ends with a slash, which
+// selects the book and all of the books nested in it
+func IsBookSubtree(label string) bool {
+	return strings.HasSuffix(label, "/")
+}
+
+// BookLabelFilter returns an SQL condition and its arguments for selecting the
+// books with the given label. If the label is a subtree such as 'work/', the
+// book 'work' and all of its descendants are selected.
+func BookLabelFilter(label string) (string, []interface{}) {
+	if !IsBookSubtree(label) {
+		return "books.label = ?", []interface{}{label}
+	}
+
+	parent := strings.TrimRight(label, "/")
+	prefix := parent + "/"
+
+	return "(books.label = ? OR substr(books.label, 1, ?) = ?)", []interface{}{parent, utf8.RuneCountInString(prefix), prefix}
+}
+
+// getBookRenames returns the new labels of the book and of the books nested in
+// it by their uuids, when the book is renamed from the label to the name
+func getBookRenames(db *DB, uuid, label, name string) (map[string]string, error) {
+	oldPrefix := label + "/"
+	rows, err := db.Query(`SELECT uuid, label FROM books
+		WHERE uuid != ? AND deleted = false AND substr(label, 1, ?) = ?`,
+		uuid, utf8.RuneCountInString(oldPrefix), oldPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying the nested books")
+	}
+	defer rows.Close()
+
+	ret := map[string]string{uuid: name}
+	for rows.Next() {
+		var u, l string
+		if err := rows.Scan(&u, &l); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret[u] = name + "/" + strings.TrimPrefix(l, oldPrefix)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating the nested books")
+	}
+
+	return ret, nil
+}
+
+// UpdateBookName updates a book name. The books nested in the book are renamed
+// along with it. It fails without renaming any book if a new name is taken by
+// another book.
 func UpdateBookName(db *DB, uuid string, name string) error {
-	_, err := db.Exec(`UPDATE books
+	var label string
+	err := db.QueryRow("SELECT label FROM books WHERE uuid = ?", uuid).Scan(&label)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("book %s not found", uuid)
+	} else if err != nil {
+		return errors.Wrap(err, "querying the book")
+	}
+
+	if name == label {
+		return nil
+	}
+
+	renames, err := getBookRenames(db, uuid, label, name)
+	if err != nil {
+		return err
+	}
+	for _, newLabel := range renames {
+		var other string
+		err := db.QueryRow("SELECT uuid FROM books WHERE label = ?", newLabel).Scan(&other)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "querying the book")
+		}
+
+		if _, ok := renames[other]; !ok {
+			return errors.Errorf("book '%s' already exists", newLabel)
+		}
+	}
+
+	// rename the descendants first so that the new name is not taken for one
+	// of them when it is nested in the old name
+	oldPrefix := label + "/"
+	_, err = db.Exec(`UPDATE books
+		SET label = ? || substr(label, ?), dirty = ?
+		WHERE uuid != ? AND deleted = false AND substr(label, 1, ?) = ?`,
+		name+"/", utf8.RuneCountInString(oldPrefix)+1, true, uuid, utf8.RuneCountInString(oldPrefix), oldPrefix)
+	if err != nil {
+		return errors.Wrap(err, "renaming the nested books")
+	}
+
+	_, err = db.Exec(`UPDATE books
 		SET label = ?, dirty = ?
 		WHERE uuid = ?`, name, true, uuid)
 	if err != nil {
@@ -158,6 +330,40 @@ func UpdateBookName(db *DB, uuid string, name string) error {
 	return nil
 }
 
+// MergeBook moves all notes in the source book to the destination book and
+// removes the source book. The notes and the source book are marked dirty so
+// that the merge is synced. It returns the number of notes moved.
+func MergeBook(db *DB, c clock.Clock, srcUUID, dstUUID string) (int, error) {
+	if srcUUID == dstUUID {
+		return 0, errors.New("cannot merge a book into itself")
+	}
+
+	ts := c.Now().UnixNano()
+
+	res, err := db.Exec(`UPDATE notes
+		SET book_uuid = ?, edited_on = ?, dirty = ?
+		WHERE book_uuid = ? AND deleted = false`, dstUUID, ts, true, srcUUID)
+	if err != nil {
+		return 0, errors.Wrap(err, "moving notes")
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "counting moved notes")
+	}
+
+	// override the label with a random string so that it can be reused
+	uniqLabel, err := utils.GenerateUUID()
+	if err != nil {
+		return 0, errors.Wrap(err, "generating uuid to override with")
+	}
+
+	if _, err = db.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ?", true, true, uniqLabel, srcUUID); err != nil {
+		return 0, errors.Wrap(err, "removing the source book")
+	}
+
+	return int(count), nil
+}
+
 // GetActiveNote gets the note which has the given rowid and is not deleted
 func GetActiveNote(db *DB, rowid int) (Note, error) {
 	var ret Note
@@ -195,8 +401,10 @@ func GetActiveNote(db *DB, rowid int) (Note, error) {
 	return ret, nil
 }
 
-// UpdateNoteContent updates the note content and marks the note as dirty
-func UpdateNoteContent(db *DB, c clock.Clock, rowID int, content string) error {
+// UpdateNoteContent updates the note content and its links, and marks the note
+// as dirty. The prior content is recorded as a revision by a trigger on the
+// notes table, and the revisions beyond the given limit are pruned.
+func UpdateNoteContent(db *DB, c clock.Clock, rowID int, content string, revisionLimit int) error {
 	ts := c.Now().UnixNano()
 
 	_, err := db.Exec(`UPDATE notes
@@ -206,6 +414,17 @@ func UpdateNoteContent(db *DB, c clock.Clock, rowID int, content string) error {
 		return errors.Wrap(err, "updating the note")
 	}
 
+	var uuid string
+	if err := db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", rowID).Scan(&uuid); err != nil {
+		return errors.Wrap(err, "finding the note uuid")
+	}
+	if err := IndexNoteLinks(db, uuid, content); err != nil {
+		return errors.Wrap(err, "indexing links")
+	}
+	if err := PruneNoteRevisions(db, revisionLimit); err != nil {
+		return errors.Wrap(err, "pruning revisions")
+	}
+
 	return nil
 }
 
@@ -222,3 +441,92 @@ func UpdateNoteBook(db *DB, c clock.Clock, rowID int, bookUUID string) error {
 
 	return nil
 }
+
+// UpdateNoteTags replaces the tags of the note and marks the note as dirty
+func UpdateNoteTags(db *DB, c clock.Clock, rowID int, noteUUID string, tags []string) error {
+	ts := c.Now().UnixNano()
+
+	if err := SetNoteTags(db, noteUUID, tags); err != nil {
+		return errors.Wrap(err, "setting tags")
+	}
+
+	_, err := db.Exec(`UPDATE notes
+			SET edited_on = ?, dirty = ?
+			WHERE rowid = ?`, ts, true, rowID)
+	if err != nil {
+		return errors.Wrap(err, "updating the note")
+	}
+
+	return nil
+}
+
+// NoteRevision is a prior content of a note
+type NoteRevision struct {
+	RowID    int
+	NoteUUID string
+	Body     string
+	EditedOn int64
+}
+
+// GetNoteRevisions returns the revisions of the note with the given uuid, the
+// most recent first
+func GetNoteRevisions(db *DB, noteUUID string) ([]NoteRevision, error) {
+	rows, err := db.Query(`SELECT rowid, note_uuid, body, edited_on
+		FROM note_revisions
+		WHERE note_uuid = ?
+		ORDER BY edited_on DESC, rowid DESC`, noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying revisions")
+	}
+	defer rows.Close()
+
+	ret := []NoteRevision{}
+	for rows.Next() {
+		var r NoteRevision
+		if err := rows.Scan(&r.RowID, &r.NoteUUID, &r.Body, &r.EditedOn); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// GetNoteRevision returns the revision with the given rowid of the note with
+// the given uuid
+func GetNoteRevision(db *DB, noteUUID string, rowID int) (NoteRevision, error) {
+	var ret NoteRevision
+
+	err := db.QueryRow(`SELECT rowid, note_uuid, body, edited_on
+		FROM note_revisions
+		WHERE note_uuid = ? AND rowid = ?`, noteUUID, rowID).
+		Scan(&ret.RowID, &ret.NoteUUID, &ret.Body, &ret.EditedOn)
+	if err == sql.ErrNoRows {
+		return ret, errors.Errorf("revision %d not found", rowID)
+	} else if err != nil {
+		return ret, errors.Wrap(err, "querying the revision")
+	}
+
+	return ret, nil
+}
+
+// PruneNoteRevisions deletes the revisions of all notes except the given number
+// of most recent ones for each note
+func PruneNoteRevisions(db *DB, limit int) error {
+	if limit < 0 {
+		limit = 0
+	}
+
+	_, err := db.Exec(`DELETE FROM note_revisions WHERE rowid IN (
+			SELECT rowid FROM (
+				SELECT rowid, row_number() OVER (PARTITION BY note_uuid ORDER BY edited_on DESC, rowid DESC) AS n
+				FROM note_revisions
+			) WHERE n > ?
+		)`, limit)
+	if err != nil {
+		return errors.Wrap(err, "deleting revisions")
+	}
+
+	return nil
+}
@@ -306,7 +306,7 @@ func TestUpdateNoteContent(t *testing.T) {
 	now := time.Date(2017, time.March, 14, 21, 15, 0, 0, time.UTC)
 	c.SetNow(now)
 
-	err := UpdateNoteContent(db, c, rowid, "n1 content updated")
+	err := UpdateNoteContent(db, c, rowid, "n1 content updated [[js/closures]]", 50)
 	if err != nil {
 		t.Fatal(errors.Wrap(err, "executing"))
 	}
@@ -317,9 +317,117 @@ func TestUpdateNoteContent(t *testing.T) {
 
 	MustScan(t, "getting the note record", db.QueryRow("SELECT body, edited_on, dirty FROM notes WHERE rowid = ?", rowid), &content, &editedOn, &dirty)
 
-	assert.Equal(t, content, "n1 content updated", "content mismatch")
+	assert.Equal(t, content, "n1 content updated [[js/closures]]", "content mismatch")
 	assert.Equal(t, int64(editedOn), now.UnixNano(), "editedOn mismatch")
 	assert.Equal(t, dirty, true, "dirty mismatch")
+
+	targets, err := getLinkTargets(db, uuid)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting link targets"))
+	}
+	assert.DeepEqual(t, targets, []string{"js/closures"}, "link targets mismatch")
+
+	revisions, err := GetNoteRevisions(db, uuid)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting revisions"))
+	}
+	assert.Equal(t, len(revisions), 1, "revision count mismatch")
+	assert.Equal(t, revisions[0].Body, "n1 content", "revision body mismatch")
+	assert.Equal(t, revisions[0].EditedOn, int64(1542058875), "revision edited_on mismatch")
+
+	// the revisions beyond the limit are pruned
+	if err := UpdateNoteContent(db, c, rowid, "n1 content updated again", 1); err != nil {
+		t.Fatal(errors.Wrap(err, "executing with a limit"))
+	}
+
+	revisions, err = GetNoteRevisions(db, uuid)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting revisions after the second update"))
+	}
+	assert.Equal(t, len(revisions), 1, "revision count after the second update mismatch")
+	assert.Equal(t, revisions[0].Body, "n1 content updated [[js/closures]]", "revision body after the second update mismatch")
+}
+
+func TestGetNoteRevision(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting r1", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n1-uuid", "n1 v1", 1)
+	MustExec(t, "inserting r2", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n2-uuid", "n2 v1", 2)
+
+	var r1RowID, r2RowID int
+	MustScan(t, "getting r1 rowid", db.QueryRow("SELECT rowid FROM note_revisions WHERE body = ?", "n1 v1"), &r1RowID)
+	MustScan(t, "getting r2 rowid", db.QueryRow("SELECT rowid FROM note_revisions WHERE body = ?", "n2 v1"), &r2RowID)
+
+	got, err := GetNoteRevision(db, "n1-uuid", r1RowID)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, got.Body, "n1 v1", "body mismatch")
+	assert.Equal(t, got.EditedOn, int64(1), "edited_on mismatch")
+
+	// a revision of another note should not be found
+	if _, err := GetNoteRevision(db, "n1-uuid", r2RowID); err == nil {
+		t.Error("expected an error for a revision of another note")
+	}
+}
+
+func TestPruneNoteRevisions(t *testing.T) {
+	testCases := []struct {
+		limit      int
+		n1Expected []string
+		n2Expected []string
+	}{
+		{
+			limit:      3,
+			n1Expected: []string{"n1 v3", "n1 v2", "n1 v1"},
+			n2Expected: []string{"n2 v1"},
+		},
+		{
+			limit:      2,
+			n1Expected: []string{"n1 v3", "n1 v2"},
+			n2Expected: []string{"n2 v1"},
+		},
+		{
+			limit:      0,
+			n1Expected: []string{},
+			n2Expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("limit %d", tc.limit), func(t *testing.T) {
+			// set up
+			db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+			defer TeardownTestDB(t, db)
+
+			MustExec(t, "inserting n1 v1", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n1-uuid", "n1 v1", 1)
+			MustExec(t, "inserting n1 v3", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n1-uuid", "n1 v3", 3)
+			MustExec(t, "inserting n1 v2", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n1-uuid", "n1 v2", 2)
+			MustExec(t, "inserting n2 v1", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n2-uuid", "n2 v1", 1)
+
+			// execute
+			if err := PruneNoteRevisions(db, tc.limit); err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			// test
+			for uuid, expected := range map[string][]string{"n1-uuid": tc.n1Expected, "n2-uuid": tc.n2Expected} {
+				revisions, err := GetNoteRevisions(db, uuid)
+				if err != nil {
+					t.Fatal(errors.Wrap(err, "getting revisions"))
+				}
+
+				got := []string{}
+				for _, r := range revisions {
+					got = append(got, r.Body)
+				}
+
+				assert.DeepEqual(t, got, expected, fmt.Sprintf("%s revisions mismatch", uuid))
+			}
+		})
+	}
 }
 
 func TestUpdateNoteBook(t *testing.T) {
@@ -382,3 +490,200 @@ func TestUpdateBookName(t *testing.T) {
 	assert.Equal(t, b1.USN, 8, "USN mismatch")
 	assert.Equal(t, b1.Deleted, false, "Deleted mismatch")
 }
+
+func TestUpdateBookName_Nested(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "work", 1, false, false)
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "work/infra", 2, false, false)
+	MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b3-uuid", "work/infra/k8s", 3, false, false)
+	MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b4-uuid", "workshop", 4, false, false)
+	MustExec(t, "inserting b5", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b5-uuid", "work-old", 5, false, false)
+
+	// execute
+	if err := UpdateBookName(db, "b1-uuid", "job/work"); err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	testCases := []struct {
+		uuid          string
+		expectedLabel string
+		expectedDirty bool
+	}{
+		{uuid: "b1-uuid", expectedLabel: "job/work", expectedDirty: true},
+		{uuid: "b2-uuid", expectedLabel: "job/work/infra", expectedDirty: true},
+		{uuid: "b3-uuid", expectedLabel: "job/work/infra/k8s", expectedDirty: true},
+		{uuid: "b4-uuid", expectedLabel: "workshop", expectedDirty: false},
+		{uuid: "b5-uuid", expectedLabel: "work-old", expectedDirty: false},
+	}
+
+	for _, tc := range testCases {
+		var b Book
+		MustScan(t, "getting the book", db.QueryRow("SELECT label, dirty FROM books WHERE uuid = ?", tc.uuid), &b.Label, &b.Dirty)
+		assert.Equal(t, b.Label, tc.expectedLabel, fmt.Sprintf("Label mismatch for %s", tc.uuid))
+		assert.Equal(t, b.Dirty, tc.expectedDirty, fmt.Sprintf("Dirty mismatch for %s", tc.uuid))
+	}
+}
+
+func TestUpdateBookName_Unchanged(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "work", 1, false, false)
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "work/infra", 2, false, false)
+
+	// execute
+	if err := UpdateBookName(db, "b1-uuid", "work"); err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	var dirtyCount int
+	MustScan(t, "counting dirty books", db.QueryRow("SELECT count(*) FROM books WHERE dirty"), &dirtyCount)
+	assert.Equal(t, dirtyCount, 0, "no book should be marked dirty")
+}
+
+func TestUpdateBookName_Conflict(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "work", 1, false, false)
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "work/infra", 2, false, false)
+	MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b3-uuid", "job/infra", 3, false, false)
+
+	// execute
+	err := UpdateBookName(db, "b1-uuid", "job")
+
+	// test
+	if err == nil {
+		t.Fatal("renaming a nested book to a taken name should fail")
+	}
+	assert.Equal(t, err.Error(), "book 'job/infra' already exists", "error mismatch")
+
+	var b1Label, b2Label string
+	MustScan(t, "getting b1", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "b1-uuid"), &b1Label)
+	MustScan(t, "getting b2", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "b2-uuid"), &b2Label)
+	assert.Equal(t, b1Label, "work", "b1 label mismatch")
+	assert.Equal(t, b2Label, "work/infra", "b2 label mismatch")
+
+	t.Run("into a descendant", func(t *testing.T) {
+		// work/infra is renamed to work/infra/infra before work is renamed
+		if err := UpdateBookName(db, "b1-uuid", "work/infra"); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		MustScan(t, "getting b1", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "b1-uuid"), &b1Label)
+		MustScan(t, "getting b2", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "b2-uuid"), &b2Label)
+		assert.Equal(t, b1Label, "work/infra", "b1 label mismatch")
+		assert.Equal(t, b2Label, "work/infra/infra", "b2 label mismatch")
+	})
+}
+
+func TestBookLabelFilter(t *testing.T) {
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	for _, label := range []string{"work", "work/infra", "work/infra/k8s", "workshop", "work-old", "日本/語"} {
+		MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", label+"-uuid", label)
+	}
+
+	testCases := []struct {
+		label    string
+		expected []string
+	}{
+		{label: "work", expected: []string{"work"}},
+		{label: "work/", expected: []string{"work", "work/infra", "work/infra/k8s"}},
+		{label: "work/infra/", expected: []string{"work/infra", "work/infra/k8s"}},
+		{label: "日本/", expected: []string{"日本/語"}},
+		{label: "missing/", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			cond, args := BookLabelFilter(tc.label)
+			rows, err := db.Query(fmt.Sprintf("SELECT label FROM books WHERE %s ORDER BY label", cond), args...)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "querying"))
+			}
+			defer rows.Close()
+
+			got := []string{}
+			for rows.Next() {
+				var label string
+				if err := rows.Scan(&label); err != nil {
+					t.Fatal(errors.Wrap(err, "scanning"))
+				}
+				got = append(got, label)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "labels mismatch")
+		})
+	}
+}
+
+func TestMergeBook(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	b1UUID := "b1-uuid"
+	b2UUID := "b2-uuid"
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", b1UUID, "b1-label", 8, false, false)
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", b2UUID, "b2-label", 9, false, false)
+	MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", "n1-uuid", b1UUID, "n1 content", 1542058875, 0, 1, false, false, false)
+	MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", "n2-uuid", b1UUID, "", 1542058875, 0, 2, false, true, false)
+	MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", "n3-uuid", b2UUID, "n3 content", 1542058875, 0, 3, false, false, false)
+
+	// execute
+	c := clock.NewMock()
+	now := time.Date(2017, time.March, 14, 21, 15, 0, 0, time.UTC)
+	c.SetNow(now)
+
+	count, err := MergeBook(db, c, b1UUID, b2UUID)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// test
+	assert.Equal(t, count, 1, "count mismatch")
+
+	var n1, n2, n3 Note
+	MustScan(t, "getting n1", db.QueryRow("SELECT book_uuid, edited_on, dirty FROM notes WHERE uuid = ?", "n1-uuid"), &n1.BookUUID, &n1.EditedOn, &n1.Dirty)
+	MustScan(t, "getting n2", db.QueryRow("SELECT book_uuid, edited_on, dirty FROM notes WHERE uuid = ?", "n2-uuid"), &n2.BookUUID, &n2.EditedOn, &n2.Dirty)
+	MustScan(t, "getting n3", db.QueryRow("SELECT book_uuid, edited_on, dirty FROM notes WHERE uuid = ?", "n3-uuid"), &n3.BookUUID, &n3.EditedOn, &n3.Dirty)
+
+	assert.Equal(t, n1.BookUUID, b2UUID, "n1 BookUUID mismatch")
+	assert.Equal(t, n1.EditedOn, now.UnixNano(), "n1 EditedOn mismatch")
+	assert.Equal(t, n1.Dirty, true, "n1 Dirty mismatch")
+	assert.Equal(t, n2.BookUUID, b1UUID, "n2 BookUUID mismatch")
+	assert.Equal(t, n2.Dirty, false, "n2 Dirty mismatch")
+	assert.Equal(t, n3.BookUUID, b2UUID, "n3 BookUUID mismatch")
+	assert.Equal(t, n3.Dirty, false, "n3 Dirty mismatch")
+
+	var b1, b2 Book
+	MustScan(t, "getting b1", db.QueryRow("SELECT label, dirty, deleted FROM books WHERE uuid = ?", b1UUID), &b1.Label, &b1.Dirty, &b1.Deleted)
+	MustScan(t, "getting b2", db.QueryRow("SELECT label, dirty, deleted FROM books WHERE uuid = ?", b2UUID), &b2.Label, &b2.Dirty, &b2.Deleted)
+
+	assert.NotEqual(t, b1.Label, "b1-label", "b1 Label should have been overridden")
+	assert.Equal(t, b1.Dirty, true, "b1 Dirty mismatch")
+	assert.Equal(t, b1.Deleted, true, "b1 Deleted mismatch")
+	assert.Equal(t, b2.Label, "b2-label", "b2 Label mismatch")
+	assert.Equal(t, b2.Dirty, false, "b2 Dirty mismatch")
+	assert.Equal(t, b2.Deleted, false, "b2 Deleted mismatch")
+}
+
+func TestMergeBook_Self(t *testing.T) {
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 8, false, false)
+
+	if _, err := MergeBook(db, clock.NewMock(), "b1-uuid", "b1-uuid"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,139 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// NoteReview is the scheduling state of a note in the review
+type NoteReview struct {
+	NoteUUID string
+	// Ease is the factor by which the interval grows after a successful review
+	Ease float64
+	// Interval is the number of days until the next review
+	Interval int
+	// Repetitions is the number of successful reviews in a row
+	Repetitions int
+	// Lapses is the number of times the note was forgotten
+	Lapses     int
+	DueOn      int64
+	ReviewedOn int64
+}
+
+// Upsert inserts the review, or updates it if the note already has one
+func (r NoteReview) Upsert(db *DB) error {
+	_, err := db.Exec(`INSERT INTO note_reviews (note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(note_uuid) DO UPDATE SET
+		ease = excluded.ease,
+		interval = excluded.interval,
+		repetitions = excluded.repetitions,
+		lapses = excluded.lapses,
+		due_on = excluded.due_on,
+		reviewed_on = excluded.reviewed_on`,
+		r.NoteUUID, r.Ease, r.Interval, r.Repetitions, r.Lapses, r.DueOn, r.ReviewedOn)
+	if err != nil {
+		return errors.Wrapf(err, "upserting the review of note %s", r.NoteUUID)
+	}
+
+	return nil
+}
+
+// GetNoteReview returns the review of the note with the given uuid. The second
+// return value is false if the note has never been reviewed.
+func GetNoteReview(db *DB, noteUUID string) (NoteReview, bool, error) {
+	var r NoteReview
+	err := db.QueryRow(`SELECT note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on
+	FROM note_reviews WHERE note_uuid = ?`, noteUUID).
+		Scan(&r.NoteUUID, &r.Ease, &r.Interval, &r.Repetitions, &r.Lapses, &r.DueOn, &r.ReviewedOn)
+	if err == sql.ErrNoRows {
+		return r, false, nil
+	} else if err != nil {
+		return r, false, errors.Wrap(err, "querying the review")
+	}
+
+	return r, true, nil
+}
+
+// DueNote is a note due for a review
+type DueNote struct {
+	RowID     int
+	UUID      string
+	BookLabel string
+	Body      string
+	// New is true if the note has never been reviewed
+	New bool
+}
+
+// GetDueNotes returns up to limit notes that are due for a review at the given
+// time, optionally in the books with the given label. The notes that have been
+// reviewed come first in the order of their due time, followed by the new notes
+// in the order in which they were added.
+func GetDueNotes(db *DB, now int64, bookLabel string, limit int) ([]DueNote, error) {
+	query := `SELECT notes.rowid, notes.uuid, books.label, notes.body, note_reviews.note_uuid IS NULL
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	LEFT JOIN note_reviews ON note_reviews.note_uuid = notes.uuid
+	WHERE notes.deleted = false AND (note_reviews.note_uuid IS NULL OR note_reviews.due_on <= ?)`
+	args := []interface{}{now}
+
+	if bookLabel != "" {
+		cond, bookArgs := BookLabelFilter(bookLabel)
+		query = fmt.Sprintf("%s AND %s", query, cond)
+		args = append(args, bookArgs...)
+	}
+
+	query += " ORDER BY note_reviews.note_uuid IS NULL, note_reviews.due_on, notes.added_on LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying due notes")
+	}
+	defer rows.Close()
+
+	ret := []DueNote{}
+	for rows.Next() {
+		var n DueNote
+		if err := rows.Scan(&n.RowID, &n.UUID, &n.BookLabel, &n.Body, &n.New); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+
+	return ret, nil
+}
+
+// CountReviewedSince counts the notes reviewed at or after the given time
+func CountReviewedSince(db *DB, since int64) (int, error) {
+	var ret int
+	if err := db.QueryRow("SELECT count(*) FROM note_reviews WHERE reviewed_on >= ?", since).Scan(&ret); err != nil {
+		return 0, errors.Wrap(err, "counting reviewed notes")
+	}
+
+	return ret, nil
+}
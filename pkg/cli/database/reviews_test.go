@@ -0,0 +1,137 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestNoteReviewUpsert(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	r := NoteReview{NoteUUID: "n1-uuid", Ease: 2.5, Interval: 1, Repetitions: 1, DueOn: 10, ReviewedOn: 1}
+
+	// execute
+	if err := r.Upsert(db); err != nil {
+		t.Fatal(errors.Wrap(err, "inserting"))
+	}
+	r.Interval = 6
+	r.Repetitions = 2
+	if err := r.Upsert(db); err != nil {
+		t.Fatal(errors.Wrap(err, "updating"))
+	}
+
+	// test
+	got, ok, err := GetNoteReview(db, "n1-uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the review"))
+	}
+	assert.Equal(t, ok, true, "review should exist")
+	assert.DeepEqual(t, got, r, "review mismatch")
+
+	_, ok, err = GetNoteReview(db, "n2-uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the review"))
+	}
+	assert.Equal(t, ok, false, "review should not exist")
+}
+
+func TestGetDueNotes(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "work")
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "work/infra")
+	MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "js")
+
+	notes := []struct {
+		uuid     string
+		bookUUID string
+		addedOn  int64
+		deleted  bool
+	}{
+		{"n1-uuid", "b1-uuid", 1, false},
+		{"n2-uuid", "b2-uuid", 2, false},
+		{"n3-uuid", "b3-uuid", 3, false},
+		{"n4-uuid", "b3-uuid", 4, false},
+		{"n5-uuid", "b3-uuid", 5, true},
+		{"n6-uuid", "b1-uuid", 6, false},
+	}
+	for _, n := range notes {
+		MustExec(t, "inserting a note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", n.uuid, n.bookUUID, n.uuid, n.addedOn, n.deleted)
+	}
+
+	// n3 and n4 are due, n1 is not due yet
+	MustExec(t, "inserting r1", db, "INSERT INTO note_reviews (note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", 2.5, 1, 1, 0, 200, 90)
+	MustExec(t, "inserting r3", db, "INSERT INTO note_reviews (note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on) VALUES (?, ?, ?, ?, ?, ?, ?)", "n3-uuid", 2.5, 1, 1, 0, 80, 10)
+	MustExec(t, "inserting r4", db, "INSERT INTO note_reviews (note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on) VALUES (?, ?, ?, ?, ?, ?, ?)", "n4-uuid", 2.5, 1, 1, 0, 50, 20)
+
+	uuids := func(notes []DueNote) []string {
+		ret := []string{}
+		for _, n := range notes {
+			ret = append(ret, n.UUID)
+		}
+		return ret
+	}
+
+	t.Run("all books", func(t *testing.T) {
+		got, err := GetDueNotes(db, 100, "", 10)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, uuids(got), []string{"n4-uuid", "n3-uuid", "n2-uuid", "n6-uuid"}, "uuids mismatch")
+		assert.Equal(t, got[0].New, false, "n4 should not be new")
+		assert.Equal(t, got[2].New, true, "n2 should be new")
+		assert.Equal(t, got[2].BookLabel, "work/infra", "n2 book label mismatch")
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		got, err := GetDueNotes(db, 100, "", 1)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, uuids(got), []string{"n4-uuid"}, "uuids mismatch")
+	})
+
+	t.Run("book subtree", func(t *testing.T) {
+		got, err := GetDueNotes(db, 100, "work/", 10)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, uuids(got), []string{"n2-uuid", "n6-uuid"}, "uuids mismatch")
+	})
+
+	t.Run("count reviewed", func(t *testing.T) {
+		count, err := CountReviewedSince(db, 20)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, count, 2, "count mismatch")
+	})
+}
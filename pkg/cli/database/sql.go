@@ -49,17 +49,35 @@ type sqlTx interface {
 type DB struct {
 	Conn     SQLCommon
 	Filepath string
+	// enc is set if the database is encrypted
+	enc *encryption
+	// parent is the encrypted database of a transaction, which is saved when
+	// the transaction is committed
+	parent *DB
 }
 
 // Begin begins a transaction
 func (d *DB) Begin() (*DB, error) {
 	if db, ok := d.Conn.(sqlDb); ok && db != nil {
+		if !d.persisted() {
+			tx, err := db.Begin()
+			if err != nil {
+				return nil, err
+			}
+
+			return &DB{Conn: tx}, nil
+		}
+
+		if err := d.acquire(true); err != nil {
+			return nil, err
+		}
 		tx, err := db.Begin()
 		if err != nil {
+			d.release()
 			return nil, err
 		}
 
-		return &DB{Conn: tx}, nil
+		return &DB{Conn: tx, parent: d}, nil
 	}
 
 	return nil, errors.New("can't start transaction")
@@ -68,7 +86,15 @@ func (d *DB) Begin() (*DB, error) {
 // Commit commits a transaction
 func (d *DB) Commit() error {
 	if db, ok := d.Conn.(sqlTx); ok && db != nil {
-		return db.Commit()
+		err := db.Commit()
+		if d.parent != nil {
+			if err == nil {
+				err = d.parent.Save()
+			}
+			d.endWrite()
+		}
+
+		return err
 	}
 
 	return errors.New("invalid transaction")
@@ -77,7 +103,11 @@ func (d *DB) Commit() error {
 // Rollback rolls back a transaction
 func (d *DB) Rollback() error {
 	if db, ok := d.Conn.(sqlTx); ok && db != nil {
-		if err := db.Rollback(); err != nil {
+		err := db.Rollback()
+		if d.parent != nil {
+			d.endWrite()
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -85,9 +115,33 @@ func (d *DB) Rollback() error {
 	return errors.New("invalid transaction")
 }
 
-// Exec executes a sql
+// endWrite releases the lock of the encrypted database of a transaction
+func (d *DB) endWrite() {
+	d.parent.release()
+	d.parent = nil
+}
+
+// Exec executes a sql. On an encrypted database, a statement outside of a
+// transaction is saved at once.
 func (d *DB) Exec(query string, values ...interface{}) (sql.Result, error) {
-	return d.Conn.Exec(query, values...)
+	if !d.persisted() {
+		return d.Conn.Exec(query, values...)
+	}
+
+	if err := d.acquire(true); err != nil {
+		return nil, err
+	}
+	defer d.release()
+
+	ret, err := d.Conn.Exec(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Save(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
 }
 
 // Prepare prepares a sql
@@ -111,6 +165,10 @@ type closer interface {
 
 // Close closes a db connection
 func (d *DB) Close() error {
+	if d.enc != nil {
+		return d.closeEncrypted()
+	}
+
 	if db, ok := d.Conn.(closer); ok {
 		return db.Close()
 	}
@@ -79,7 +79,95 @@ CREATE TABLE actions
 			timestamp integer NOT NULL
 		);
 CREATE UNIQUE INDEX idx_notes_uuid ON notes(uuid);
-CREATE INDEX idx_notes_book_uuid ON notes(book_uuid);`
+CREATE INDEX idx_notes_book_uuid ON notes(book_uuid);
+CREATE TABLE note_tags
+		(
+			note_uuid text NOT NULL,
+			tag text NOT NULL
+		);
+CREATE UNIQUE INDEX idx_note_tags_note_uuid_tag ON note_tags(note_uuid, tag);
+CREATE INDEX idx_note_tags_tag ON note_tags(tag);
+CREATE TRIGGER notes_after_delete_tags AFTER DELETE ON notes BEGIN
+				DELETE FROM note_tags WHERE note_uuid = old.uuid;
+			END;
+CREATE TRIGGER notes_after_update_uuid AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_tags SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;
+CREATE TABLE note_revisions
+		(
+			note_uuid text NOT NULL,
+			body text NOT NULL,
+			edited_on integer NOT NULL
+		);
+CREATE INDEX idx_note_revisions_note_uuid ON note_revisions(note_uuid);
+CREATE TRIGGER notes_after_update_body AFTER UPDATE OF body ON notes
+			WHEN old.body != new.body AND old.body != '' BEGIN
+				INSERT INTO note_revisions (note_uuid, body, edited_on)
+				VALUES (old.uuid, old.body, CASE WHEN old.edited_on = 0 THEN old.added_on ELSE old.edited_on END);
+			END;
+CREATE TRIGGER notes_after_delete_revisions AFTER DELETE ON notes BEGIN
+				DELETE FROM note_revisions WHERE note_uuid = old.uuid;
+			END;
+CREATE TRIGGER notes_after_update_uuid_revisions AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_revisions SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;
+CREATE TABLE trash
+		(
+			kind text NOT NULL,
+			uuid text NOT NULL,
+			label text NOT NULL,
+			body text NOT NULL,
+			parent_id integer,
+			removed_on integer NOT NULL
+		);
+CREATE INDEX idx_trash_parent_id ON trash(parent_id);
+CREATE TABLE note_links
+		(
+			note_uuid text NOT NULL,
+			target text NOT NULL
+		);
+CREATE UNIQUE INDEX idx_note_links_note_uuid_target ON note_links(note_uuid, target);
+CREATE TRIGGER notes_after_delete_links AFTER DELETE ON notes BEGIN
+				DELETE FROM note_links WHERE note_uuid = old.uuid;
+			END;
+CREATE TRIGGER notes_after_update_uuid_links AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_links SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;
+CREATE TABLE attachments
+		(
+			uuid text NOT NULL,
+			note_uuid text NOT NULL,
+			name text NOT NULL,
+			hash text NOT NULL,
+			size integer NOT NULL,
+			added_on integer NOT NULL,
+			dirty bool DEFAULT false
+		);
+CREATE UNIQUE INDEX idx_attachments_uuid ON attachments(uuid);
+CREATE INDEX idx_attachments_note_uuid ON attachments(note_uuid);
+CREATE TRIGGER notes_after_delete_attachments AFTER DELETE ON notes BEGIN
+				DELETE FROM attachments WHERE note_uuid = old.uuid;
+			END;
+CREATE TRIGGER notes_after_update_uuid_attachments AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE attachments SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;
+CREATE TABLE note_reviews
+		(
+			note_uuid text PRIMARY KEY,
+			ease real NOT NULL,
+			interval integer NOT NULL,
+			repetitions integer NOT NULL,
+			lapses integer NOT NULL,
+			due_on integer NOT NULL,
+			reviewed_on integer NOT NULL
+		);
+CREATE INDEX idx_note_reviews_due_on ON note_reviews(due_on);
+CREATE TRIGGER notes_after_delete_reviews AFTER DELETE ON notes BEGIN
+				DELETE FROM note_reviews WHERE note_uuid = old.uuid;
+			END;
+CREATE TRIGGER notes_after_update_uuid_reviews AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_reviews SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`
 
 // MustScan scans the given row and fails a test in case of any errors
 func MustScan(t *testing.T, message string, row *sql.Row, args ...interface{}) {
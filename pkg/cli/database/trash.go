@@ -0,0 +1,334 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TrashKindNote is the kind of the trash items for removed notes
+	TrashKindNote = "note"
+	// TrashKindBook is the kind of the trash items for removed books
+	TrashKindBook = "book"
+)
+
+// TrashItem is a removed note or book that can be restored until the removal
+// is synced. For a note, Label is the label of its book.
+type TrashItem struct {
+	RowID     int
+	Kind      string
+	UUID      string
+	Label     string
+	Body      string
+	RemovedOn int64
+	// NoteCount is the number of notes removed with a book
+	NoteCount int
+}
+
+// insertTrashItem records a removed item and returns the rowid of the record
+func insertTrashItem(db *DB, kind, uuid, label, body string, parentID interface{}, removedOn int64) (int64, error) {
+	res, err := db.Exec(`INSERT INTO trash (kind, uuid, label, body, parent_id, removed_on)
+		VALUES (?, ?, ?, ?, ?, ?)`, kind, uuid, label, body, parentID, removedOn)
+	if err != nil {
+		return 0, errors.Wrap(err, "inserting a trash item")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "getting the trash item id")
+	}
+
+	return id, nil
+}
+
+// TrashNote removes the note with the given uuid and moves its content to the trash
+func TrashNote(db *DB, c clock.Clock, noteUUID string) error {
+	var body, bookLabel string
+	err := db.QueryRow(`SELECT notes.body, books.label
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.uuid = ? AND notes.deleted = false`, noteUUID).Scan(&body, &bookLabel)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("note %s not found", noteUUID)
+	} else if err != nil {
+		return errors.Wrap(err, "querying the note")
+	}
+
+	if _, err := insertTrashItem(db, TrashKindNote, noteUUID, bookLabel, body, nil, c.Now().UnixNano()); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE uuid = ?", true, true, "", noteUUID); err != nil {
+		return errors.Wrap(err, "removing the note")
+	}
+
+	return nil
+}
+
+// TrashBook removes the book with the given uuid and all its notes, and moves
+// their labels and contents to the trash
+func TrashBook(db *DB, c clock.Clock, bookUUID string) error {
+	var label string
+	err := db.QueryRow("SELECT label FROM books WHERE uuid = ? AND deleted = false", bookUUID).Scan(&label)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("book %s not found", bookUUID)
+	} else if err != nil {
+		return errors.Wrap(err, "querying the book")
+	}
+
+	removedOn := c.Now().UnixNano()
+
+	bookID, err := insertTrashItem(db, TrashKindBook, bookUUID, label, "", nil, removedOn)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query("SELECT uuid, body FROM notes WHERE book_uuid = ? AND deleted = false", bookUUID)
+	if err != nil {
+		return errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	notes := map[string]string{}
+	for rows.Next() {
+		var uuid, body string
+		if err := rows.Scan(&uuid, &body); err != nil {
+			return errors.Wrap(err, "scanning a row")
+		}
+
+		notes[uuid] = body
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating notes")
+	}
+
+	for uuid, body := range notes {
+		if _, err := insertTrashItem(db, TrashKindNote, uuid, label, body, bookID, removedOn); err != nil {
+			return err
+		}
+	}
+
+	if _, err = db.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE book_uuid = ?", true, true, "", bookUUID); err != nil {
+		return errors.Wrap(err, "removing notes in the book")
+	}
+
+	// override the label with a random string
+	uniqLabel, err := utils.GenerateUUID()
+	if err != nil {
+		return errors.Wrap(err, "generating uuid to override with")
+	}
+
+	if _, err = db.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ?", true, true, uniqLabel, bookUUID); err != nil {
+		return errors.Wrap(err, "removing the book")
+	}
+
+	return nil
+}
+
+// trashItemColumns are the columns scanned by scanTrashItem
+const trashItemColumns = `trash.rowid, trash.kind, trash.uuid, trash.label, trash.body, trash.removed_on,
+	(SELECT count(*) FROM trash AS t WHERE t.parent_id = trash.rowid)`
+
+func scanTrashItem(scan func(dest ...interface{}) error) (TrashItem, error) {
+	var ret TrashItem
+	err := scan(&ret.RowID, &ret.Kind, &ret.UUID, &ret.Label, &ret.Body, &ret.RemovedOn, &ret.NoteCount)
+
+	return ret, err
+}
+
+// GetTrashItems returns the items in the trash, the most recently removed
+// first. The notes removed with a book are not included.
+func GetTrashItems(db *DB) ([]TrashItem, error) {
+	rows, err := db.Query(`SELECT ` + trashItemColumns + `
+		FROM trash
+		WHERE parent_id IS NULL
+		ORDER BY removed_on DESC, rowid DESC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying the trash")
+	}
+	defer rows.Close()
+
+	ret := []TrashItem{}
+	for rows.Next() {
+		item, err := scanTrashItem(rows.Scan)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, item)
+	}
+
+	return ret, nil
+}
+
+// GetTrashItem returns the item in the trash with the given rowid
+func GetTrashItem(db *DB, rowID int) (TrashItem, error) {
+	item, err := scanTrashItem(db.QueryRow(`SELECT `+trashItemColumns+`
+		FROM trash
+		WHERE rowid = ? AND parent_id IS NULL`, rowID).Scan)
+	if err == sql.ErrNoRows {
+		return item, errors.Errorf("trash item %d not found", rowID)
+	} else if err != nil {
+		return item, errors.Wrap(err, "querying the trash item")
+	}
+
+	return item, nil
+}
+
+// restoreNote restores the content of a removed note and marks it as dirty so
+// that the removal is not synced
+func restoreNote(db *DB, noteUUID, body string, editedOn int64) error {
+	res, err := db.Exec(`UPDATE notes
+		SET deleted = ?, dirty = ?, body = ?, edited_on = ?
+		WHERE uuid = ? AND deleted = ?`, false, true, body, editedOn, noteUUID, true)
+	if err != nil {
+		return errors.Wrap(err, "restoring the note")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "counting restored notes")
+	}
+	if n == 0 {
+		return errors.New("the note no longer exists")
+	}
+
+	return nil
+}
+
+func restoreTrashNote(db *DB, c clock.Clock, item TrashItem) error {
+	var bookDeleted bool
+	err := db.QueryRow(`SELECT books.deleted
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.uuid = ?`, item.UUID).Scan(&bookDeleted)
+	if err == sql.ErrNoRows {
+		return errors.New("the note no longer exists")
+	} else if err != nil {
+		return errors.Wrap(err, "querying the book")
+	}
+	if bookDeleted {
+		return errors.Errorf("the book '%s' was removed. restore the book first", item.Label)
+	}
+
+	return restoreNote(db, item.UUID, item.Body, c.Now().UnixNano())
+}
+
+func restoreTrashBook(db *DB, c clock.Clock, item TrashItem) error {
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM books WHERE label = ? AND deleted = false", item.Label).Scan(&count); err != nil {
+		return errors.Wrap(err, "checking the book label")
+	}
+	if count > 0 {
+		return errors.Errorf("a book named '%s' already exists", item.Label)
+	}
+
+	res, err := db.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ? AND deleted = ?", false, true, item.Label, item.UUID, true)
+	if err != nil {
+		return errors.Wrap(err, "restoring the book")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "counting restored books")
+	}
+	if n == 0 {
+		return errors.New("the book no longer exists")
+	}
+
+	rows, err := db.Query("SELECT uuid, body FROM trash WHERE parent_id = ?", item.RowID)
+	if err != nil {
+		return errors.Wrap(err, "querying notes in the trash")
+	}
+	defer rows.Close()
+
+	notes := map[string]string{}
+	for rows.Next() {
+		var uuid, body string
+		if err := rows.Scan(&uuid, &body); err != nil {
+			return errors.Wrap(err, "scanning a row")
+		}
+
+		notes[uuid] = body
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating notes in the trash")
+	}
+
+	editedOn := c.Now().UnixNano()
+	for uuid, body := range notes {
+		if err := restoreNote(db, uuid, body, editedOn); err != nil {
+			return errors.Wrapf(err, "restoring note %s", uuid)
+		}
+	}
+
+	return nil
+}
+
+// RestoreTrashItem restores the removed note or book, and the notes removed
+// with the book, and deletes the item from the trash. The restored items are
+// marked as dirty so that the removal is not synced.
+func RestoreTrashItem(db *DB, c clock.Clock, item TrashItem) error {
+	var err error
+	if item.Kind == TrashKindBook {
+		err = restoreTrashBook(db, c, item)
+	} else {
+		err = restoreTrashNote(db, c, item)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM trash WHERE rowid = ? OR parent_id = ?", item.RowID, item.RowID); err != nil {
+		return errors.Wrap(err, "deleting the trash item")
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently deletes all items in the trash
+func EmptyTrash(db *DB) error {
+	if _, err := db.Exec("DELETE FROM trash"); err != nil {
+		return errors.Wrap(err, "emptying the trash")
+	}
+
+	return nil
+}
+
+// PurgeTrash permanently deletes the items that have been in the trash for
+// longer than the given number of days. Nothing is deleted if retentionDays
+// is not positive.
+func PurgeTrash(db *DB, c clock.Clock, retentionDays int) error {
+	if retentionDays <= 0 {
+		return nil
+	}
+
+	cutoff := c.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour).UnixNano()
+
+	if _, err := db.Exec("DELETE FROM trash WHERE removed_on < ?", cutoff); err != nil {
+		return errors.Wrap(err, "purging the trash")
+	}
+
+	return nil
+}
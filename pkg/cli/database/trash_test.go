@@ -0,0 +1,268 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/pkg/errors"
+)
+
+// setupTrashTest inserts a book 'js' with the notes n1 and n2, and a book
+// 'linux' with the note n3
+func setupTrashTest(t *testing.T, db *DB) {
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn) VALUES (?, ?, ?)", "b1-uuid", "js", 1)
+	MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn) VALUES (?, ?, ?)", "b2-uuid", "linux", 2)
+	MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn) VALUES (?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1, 11)
+	MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 2, 12)
+	MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3 body", 3, 13)
+}
+
+func mustGetTrashItems(t *testing.T, db *DB) []TrashItem {
+	items, err := GetTrashItems(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting trash items"))
+	}
+
+	return items
+}
+
+func TestTrashNote(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	setupTrashTest(t, db)
+
+	c := clock.NewMock()
+	c.SetNow(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+
+	// execute
+	if err := TrashNote(db, c, "n1-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "trashing the note"))
+	}
+
+	// test
+	var n1 Note
+	MustScan(t, "getting n1", db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE uuid = ?", "n1-uuid"), &n1.Body, &n1.Deleted, &n1.Dirty)
+	assert.Equal(t, n1.Body, "", "n1 body mismatch")
+	assert.Equal(t, n1.Deleted, true, "n1 deleted mismatch")
+	assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+
+	items := mustGetTrashItems(t, db)
+	assert.Equal(t, len(items), 1, "item count mismatch")
+	assert.Equal(t, items[0].Kind, TrashKindNote, "kind mismatch")
+	assert.Equal(t, items[0].UUID, "n1-uuid", "uuid mismatch")
+	assert.Equal(t, items[0].Label, "js", "label mismatch")
+	assert.Equal(t, items[0].Body, "n1 body", "body mismatch")
+	assert.Equal(t, items[0].RemovedOn, c.Now().UnixNano(), "removed_on mismatch")
+
+	// a removed note cannot be removed again
+	if err := TrashNote(db, c, "n1-uuid"); err == nil {
+		t.Error("expected an error for a removed note")
+	}
+}
+
+func TestTrashBook(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	setupTrashTest(t, db)
+
+	c := clock.NewMock()
+
+	// execute
+	if err := TrashBook(db, c, "b1-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "trashing the book"))
+	}
+
+	// test
+	var b1 Book
+	MustScan(t, "getting b1", db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "b1-uuid"), &b1.Label, &b1.Deleted, &b1.Dirty)
+	assert.NotEqual(t, b1.Label, "js", "b1 label should be overridden")
+	assert.Equal(t, b1.Deleted, true, "b1 deleted mismatch")
+	assert.Equal(t, b1.Dirty, true, "b1 dirty mismatch")
+
+	var deletedCount int
+	MustScan(t, "counting deleted notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted AND body = ''"), &deletedCount)
+	assert.Equal(t, deletedCount, 2, "deleted note count mismatch")
+
+	items := mustGetTrashItems(t, db)
+	assert.Equal(t, len(items), 1, "item count mismatch")
+	assert.Equal(t, items[0].Kind, TrashKindBook, "kind mismatch")
+	assert.Equal(t, items[0].Label, "js", "label mismatch")
+	assert.Equal(t, items[0].NoteCount, 2, "note count mismatch")
+}
+
+func TestRestoreTrashItem(t *testing.T) {
+	t.Run("note", func(t *testing.T) {
+		// set up
+		db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+		defer TeardownTestDB(t, db)
+		setupTrashTest(t, db)
+
+		c := clock.NewMock()
+		if err := TrashNote(db, c, "n3-uuid"); err != nil {
+			t.Fatal(errors.Wrap(err, "trashing the note"))
+		}
+		MustExec(t, "marking n3 clean", db, "UPDATE notes SET dirty = ? WHERE uuid = ?", false, "n3-uuid")
+
+		// execute
+		item := mustGetTrashItems(t, db)[0]
+		if err := RestoreTrashItem(db, c, item); err != nil {
+			t.Fatal(errors.Wrap(err, "restoring"))
+		}
+
+		// test
+		var n3 Note
+		MustScan(t, "getting n3", db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE uuid = ?", "n3-uuid"), &n3.Body, &n3.Deleted, &n3.Dirty)
+		assert.Equal(t, n3.Body, "n3 body", "n3 body mismatch")
+		assert.Equal(t, n3.Deleted, false, "n3 deleted mismatch")
+		assert.Equal(t, n3.Dirty, true, "n3 dirty mismatch")
+
+		assert.Equal(t, len(mustGetTrashItems(t, db)), 0, "item count mismatch")
+	})
+
+	t.Run("book", func(t *testing.T) {
+		// set up
+		db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+		defer TeardownTestDB(t, db)
+		setupTrashTest(t, db)
+
+		c := clock.NewMock()
+		if err := TrashNote(db, c, "n1-uuid"); err != nil {
+			t.Fatal(errors.Wrap(err, "trashing the note"))
+		}
+		if err := TrashBook(db, c, "b1-uuid"); err != nil {
+			t.Fatal(errors.Wrap(err, "trashing the book"))
+		}
+
+		items := mustGetTrashItems(t, db)
+		assert.Equal(t, len(items), 2, "item count mismatch")
+
+		// the note cannot be restored before its book
+		var noteItem, bookItem TrashItem
+		for _, item := range items {
+			if item.Kind == TrashKindBook {
+				bookItem = item
+			} else {
+				noteItem = item
+			}
+		}
+		if err := RestoreTrashItem(db, c, noteItem); err == nil {
+			t.Error("expected an error for a note in a removed book")
+		}
+
+		// execute
+		if err := RestoreTrashItem(db, c, bookItem); err != nil {
+			t.Fatal(errors.Wrap(err, "restoring"))
+		}
+
+		// test
+		var b1 Book
+		MustScan(t, "getting b1", db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "b1-uuid"), &b1.Label, &b1.Deleted, &b1.Dirty)
+		assert.Equal(t, b1.Label, "js", "b1 label mismatch")
+		assert.Equal(t, b1.Deleted, false, "b1 deleted mismatch")
+		assert.Equal(t, b1.Dirty, true, "b1 dirty mismatch")
+
+		var n1, n2 Note
+		MustScan(t, "getting n1", db.QueryRow("SELECT body, deleted FROM notes WHERE uuid = ?", "n1-uuid"), &n1.Body, &n1.Deleted)
+		MustScan(t, "getting n2", db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE uuid = ?", "n2-uuid"), &n2.Body, &n2.Deleted, &n2.Dirty)
+		assert.Equal(t, n1.Deleted, true, "n1 was removed before the book and should stay removed")
+		assert.Equal(t, n2.Body, "n2 body", "n2 body mismatch")
+		assert.Equal(t, n2.Deleted, false, "n2 deleted mismatch")
+		assert.Equal(t, n2.Dirty, true, "n2 dirty mismatch")
+
+		// the note removed before the book can now be restored
+		if err := RestoreTrashItem(db, c, noteItem); err != nil {
+			t.Fatal(errors.Wrap(err, "restoring the note"))
+		}
+		MustScan(t, "getting n1", db.QueryRow("SELECT body, deleted FROM notes WHERE uuid = ?", "n1-uuid"), &n1.Body, &n1.Deleted)
+		assert.Equal(t, n1.Body, "n1 body", "n1 body mismatch")
+		assert.Equal(t, n1.Deleted, false, "n1 deleted mismatch")
+
+		var count int
+		MustScan(t, "counting trash", db.QueryRow("SELECT count(*) FROM trash"), &count)
+		assert.Equal(t, count, 0, "trash count mismatch")
+	})
+
+	t.Run("book label taken", func(t *testing.T) {
+		// set up
+		db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+		defer TeardownTestDB(t, db)
+		setupTrashTest(t, db)
+
+		c := clock.NewMock()
+		if err := TrashBook(db, c, "b2-uuid"); err != nil {
+			t.Fatal(errors.Wrap(err, "trashing the book"))
+		}
+		MustExec(t, "inserting a new linux book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "linux")
+
+		// execute
+		err := RestoreTrashItem(db, c, mustGetTrashItems(t, db)[0])
+
+		// test
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assert.Equal(t, err.Error(), "a book named 'linux' already exists", "error mismatch")
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+	setupTrashTest(t, db)
+
+	c := clock.NewMock()
+	now := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	c.SetNow(now.Add(-31 * 24 * time.Hour))
+	if err := TrashBook(db, c, "b1-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "trashing the book"))
+	}
+	c.SetNow(now.Add(-29 * 24 * time.Hour))
+	if err := TrashNote(db, c, "n3-uuid"); err != nil {
+		t.Fatal(errors.Wrap(err, "trashing the note"))
+	}
+	c.SetNow(now)
+
+	// a retention period that is not positive keeps the items
+	if err := PurgeTrash(db, c, 0); err != nil {
+		t.Fatal(errors.Wrap(err, "purging"))
+	}
+	assert.Equal(t, len(mustGetTrashItems(t, db)), 2, "item count mismatch")
+
+	// execute
+	if err := PurgeTrash(db, c, 30); err != nil {
+		t.Fatal(errors.Wrap(err, "purging"))
+	}
+
+	// test
+	items := mustGetTrashItems(t, db)
+	assert.Equal(t, len(items), 1, "item count mismatch")
+	assert.Equal(t, items[0].UUID, "n3-uuid", "uuid mismatch")
+
+	var count int
+	MustScan(t, "counting trash", db.QueryRow("SELECT count(*) FROM trash"), &count)
+	assert.Equal(t, count, 1, "the notes removed with the book should be purged")
+}
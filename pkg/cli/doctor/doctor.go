@@ -0,0 +1,543 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package doctor checks the local database for inconsistencies and repairs them
+package doctor
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/migrate"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// RecoveredBookLabel is the label of the book to which the notes without a
+// book are moved
+const RecoveredBookLabel = "recovered"
+
+// Problem is an inconsistency in the database
+type Problem struct {
+	Message string
+	// Fixable is true if the problem is repaired by the fix of the check
+	Fixable bool
+}
+
+// Check is a check of the database
+type Check struct {
+	Name        string
+	Description string
+	find        func(db *database.DB) ([]Problem, error)
+	// fix repairs the fixable problems found by the check
+	fix func(ctx context.DnoteCtx, tx *database.DB) error
+}
+
+// Result is the result of a check
+type Result struct {
+	Check    Check
+	Problems []Problem
+}
+
+// Checks are the checks run by the doctor, in order
+var Checks = []Check{
+	{
+		Name:        "books",
+		Description: "every note is in a book that exists",
+		find:        findOrphanNotes,
+		fix:         fixOrphanNotes,
+	},
+	{
+		Name:        "labels",
+		Description: "the book labels are unique",
+		find:        findDuplicateLabels,
+		fix:         fixDuplicateLabels,
+	},
+	{
+		Name:        "references",
+		Description: "the tags, revisions, links, attachments and reviews belong to notes that exist",
+		find:        findOrphanRows,
+		fix:         fixOrphanRows,
+	},
+	{
+		Name:        "search",
+		Description: "the search index matches the notes",
+		find:        findFTSProblems,
+		fix:         fixFTS,
+	},
+	{
+		Name:        "schema",
+		Description: "the schema versions in the system table are valid",
+		find:        findSchemaProblems,
+		fix:         fixSchema,
+	},
+	{
+		Name:        "sync",
+		Description: "the notes and books are marked to be synced consistently with their usn",
+		find:        findSyncProblems,
+		fix:         fixSyncProblems,
+	},
+}
+
+// Run runs all checks and returns their results
+func Run(db *database.DB) ([]Result, error) {
+	ret := []Result{}
+	for _, c := range Checks {
+		problems, err := c.find(db)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking %s", c.Name)
+		}
+
+		ret = append(ret, Result{Check: c, Problems: problems})
+	}
+
+	return ret, nil
+}
+
+// CountProblems returns the number of the problems in the results, and the
+// number of those that are fixable
+func CountProblems(results []Result) (int, int) {
+	var total, fixable int
+	for _, r := range results {
+		for _, p := range r.Problems {
+			total++
+			if p.Fixable {
+				fixable++
+			}
+		}
+	}
+
+	return total, fixable
+}
+
+// Fix repairs the fixable problems in the results in a transaction
+func Fix(ctx context.DnoteCtx, results []Result) error {
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	for _, r := range results {
+		if !hasFixable(r.Problems) {
+			continue
+		}
+
+		if err := r.Check.fix(ctx, tx); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "fixing %s", r.Check.Name)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	return nil
+}
+
+func hasFixable(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Fixable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orphanNotesQuery selects the notes in the books that do not exist or have been removed
+var orphanNotesQuery = `SELECT notes.rowid AS note_id, notes.uuid, books.uuid IS NULL
+	FROM notes LEFT JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false AND (books.uuid IS NULL OR books.deleted = true)`
+
+func findOrphanNotes(db *database.DB) ([]Problem, error) {
+	rows, err := db.Query(orphanNotesQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []Problem{}
+	for rows.Next() {
+		var rowID int
+		var uuid string
+		var missing bool
+		if err := rows.Scan(&rowID, &uuid, &missing); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		reason := "a removed book"
+		if missing {
+			reason = "a book that does not exist"
+		}
+
+		ret = append(ret, Problem{
+			Message: fmt.Sprintf("note %d is in %s", rowID, reason),
+			Fixable: true,
+		})
+	}
+
+	return ret, nil
+}
+
+func getRecoveredBookUUID(tx *database.DB) (string, error) {
+	var ret string
+
+	err := tx.QueryRow("SELECT uuid FROM books WHERE label = ? AND deleted = false", RecoveredBookLabel).Scan(&ret)
+	if err == sql.ErrNoRows {
+		ret, err = utils.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		b := database.NewBook(ret, RecoveredBookLabel, 0, false, true)
+		if err := b.Insert(tx); err != nil {
+			return "", errors.Wrapf(err, "creating the %s book", RecoveredBookLabel)
+		}
+	} else if err != nil {
+		return "", errors.Wrapf(err, "getting the uuid of the %s book", RecoveredBookLabel)
+	}
+
+	return ret, nil
+}
+
+// fixOrphanNotes moves the notes without a book to the recovered book. The
+// notes are marked dirty so that the move is synced.
+func fixOrphanNotes(ctx context.DnoteCtx, tx *database.DB) error {
+	bookUUID, err := getRecoveredBookUUID(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE notes SET book_uuid = ?, edited_on = ?, dirty = ?
+		WHERE rowid IN (SELECT note_id FROM (`+orphanNotesQuery+`))`, bookUUID, ctx.Clock.Now().UnixNano(), true)
+	if err != nil {
+		return errors.Wrap(err, "moving notes")
+	}
+
+	return nil
+}
+
+func hasBookLabelIndex(db *database.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = ? AND name = ?", "index", "idx_books_label").Scan(&count)
+	if err != nil {
+		return false, errors.Wrap(err, "querying the index")
+	}
+
+	return count > 0, nil
+}
+
+func findDuplicateLabels(db *database.DB) ([]Problem, error) {
+	rows, err := db.Query(`SELECT label, count(*) FROM books
+		WHERE deleted = false GROUP BY label HAVING count(*) > 1 ORDER BY label`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	ret := []Problem{}
+	for rows.Next() {
+		var label string
+		var count int
+		if err := rows.Scan(&label, &count); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, Problem{
+			Message: fmt.Sprintf("%d books have the label '%s'", count, label),
+			Fixable: true,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+
+	ok, err := hasBookLabelIndex(db)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		ret = append(ret, Problem{
+			Message: "the unique index on the book labels is missing",
+			Fixable: true,
+		})
+	}
+
+	return ret, nil
+}
+
+// fixDuplicateLabels merges the books with the same label into the oldest one
+// and restores the unique index on the labels
+func fixDuplicateLabels(ctx context.DnoteCtx, tx *database.DB) error {
+	rows, err := tx.Query(`SELECT uuid, label FROM books
+		WHERE deleted = false AND label IN
+			(SELECT label FROM books WHERE deleted = false GROUP BY label HAVING count(*) > 1)
+		ORDER BY label, rowid`)
+	if err != nil {
+		return errors.Wrap(err, "querying books")
+	}
+
+	type book struct {
+		uuid  string
+		label string
+	}
+	books := []book{}
+	for rows.Next() {
+		var b book
+		if err := rows.Scan(&b.uuid, &b.label); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "scanning a row")
+		}
+
+		books = append(books, b)
+	}
+	rows.Close()
+
+	dst := map[string]string{}
+	for _, b := range books {
+		dstUUID, ok := dst[b.label]
+		if !ok {
+			dst[b.label] = b.uuid
+			continue
+		}
+
+		if _, err := database.MergeBook(tx, ctx.Clock, b.uuid, dstUUID); err != nil {
+			return errors.Wrapf(err, "merging a book into '%s'", b.label)
+		}
+	}
+
+	if _, err := tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_books_label ON books(label)"); err != nil {
+		return errors.Wrap(err, "creating the index")
+	}
+
+	return nil
+}
+
+// noteTables are the tables with the rows that belong to notes
+var noteTables = []string{"note_tags", "note_revisions", "note_links", "attachments", "note_reviews"}
+
+func findOrphanRows(db *database.DB) ([]Problem, error) {
+	ret := []Problem{}
+	for _, table := range noteTables {
+		var count int
+		query := fmt.Sprintf("SELECT count(*) FROM %s WHERE note_uuid NOT IN (SELECT uuid FROM notes)", table)
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			return nil, errors.Wrapf(err, "counting rows in %s", table)
+		}
+
+		if count > 0 {
+			ret = append(ret, Problem{
+				Message: fmt.Sprintf("%d rows in %s belong to notes that do not exist", count, table),
+				Fixable: true,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+func fixOrphanRows(ctx context.DnoteCtx, tx *database.DB) error {
+	for _, table := range noteTables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE note_uuid NOT IN (SELECT uuid FROM notes)", table)
+		if _, err := tx.Exec(query); err != nil {
+			return errors.Wrapf(err, "deleting rows in %s", table)
+		}
+	}
+
+	return nil
+}
+
+func findFTSProblems(db *database.DB) ([]Problem, error) {
+	// The rank of 1 checks the index against the notes as well
+	_, err := db.Exec("INSERT INTO note_fts(note_fts, rank) VALUES('integrity-check', 1)")
+	if err == nil {
+		return []Problem{}, nil
+	}
+
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrCorrupt {
+		return []Problem{{
+			Message: "the search index does not match the notes",
+			Fixable: true,
+		}}, nil
+	}
+
+	return nil, errors.Wrap(err, "checking the search index")
+}
+
+func fixFTS(ctx context.DnoteCtx, tx *database.DB) error {
+	if _, err := tx.Exec("INSERT INTO note_fts(note_fts) VALUES('rebuild')"); err != nil {
+		return errors.Wrap(err, "rebuilding the search index")
+	}
+
+	return nil
+}
+
+type schemaKey struct {
+	key    string
+	latest int
+	// required is true if the key must exist. The remote schema is created by
+	// the first sync.
+	required bool
+}
+
+var schemaKeys = []schemaKey{
+	{key: consts.SystemSchema, latest: len(migrate.LocalSequence), required: true},
+	{key: consts.SystemRemoteSchema, latest: len(migrate.RemoteSequence), required: false},
+}
+
+func findSchemaProblems(db *database.DB) ([]Problem, error) {
+	ret := []Problem{}
+	for _, k := range schemaKeys {
+		rows, err := db.Query("SELECT value FROM system WHERE key = ?", k.key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "querying %s", k.key)
+		}
+
+		values := []string{}
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				rows.Close()
+				return nil, errors.Wrap(err, "scanning a row")
+			}
+
+			values = append(values, v)
+		}
+		rows.Close()
+
+		if len(values) == 0 && k.required {
+			ret = append(ret, Problem{Message: fmt.Sprintf("the system table has no '%s'", k.key)})
+		}
+		if len(values) > 1 {
+			ret = append(ret, Problem{
+				Message: fmt.Sprintf("the system table has %d rows for '%s'", len(values), k.key),
+				Fixable: true,
+			})
+		}
+
+		for _, v := range values {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				ret = append(ret, Problem{Message: fmt.Sprintf("'%s' is not a number: %s", k.key, v)})
+			} else if n < 0 || n > k.latest {
+				ret = append(ret, Problem{Message: fmt.Sprintf("'%s' is %d, out of the range from 0 to %d", k.key, n, k.latest)})
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// fixSchema keeps only the row with the highest version of each schema key.
+// The migrations always update all the rows of a key, and a lower value can
+// only be left by an interrupted initialization.
+func fixSchema(ctx context.DnoteCtx, tx *database.DB) error {
+	for _, k := range schemaKeys {
+		_, err := tx.Exec(`DELETE FROM system WHERE key = ? AND rowid != (
+			SELECT rowid FROM system WHERE key = ? ORDER BY CAST(value AS integer) DESC, rowid LIMIT 1)`, k.key, k.key)
+		if err != nil {
+			return errors.Wrapf(err, "deleting the duplicate rows of %s", k.key)
+		}
+	}
+
+	return nil
+}
+
+// syncTables are the tables with the resources that are synced
+var syncTables = []string{"books", "notes"}
+
+func countRows(db *database.DB, table, cond string, args ...interface{}) (int, error) {
+	var ret int
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", table, cond)
+	if err := db.QueryRow(query, args...).Scan(&ret); err != nil {
+		return 0, errors.Wrapf(err, "counting %s", table)
+	}
+
+	return ret, nil
+}
+
+func findSyncProblems(db *database.DB) ([]Problem, error) {
+	// The usn of the last sync is unknown if the key is missing
+	hasMaxUSN := true
+	var maxUSN int
+	if err := database.GetSystem(db, consts.SystemLastMaxUSN, &maxUSN); errors.Cause(err) == sql.ErrNoRows {
+		hasMaxUSN = false
+	} else if err != nil {
+		return nil, errors.Wrap(err, "getting the last max usn")
+	}
+
+	ret := []Problem{}
+	for _, table := range syncTables {
+		count, err := countRows(db, table, "usn < 0")
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			ret = append(ret, Problem{
+				Message: fmt.Sprintf("%d %s have a negative usn", count, table),
+				Fixable: true,
+			})
+		}
+
+		count, err = countRows(db, table, "usn = 0 AND dirty = false")
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			ret = append(ret, Problem{
+				Message: fmt.Sprintf("%d %s have never been synced but are not marked to be synced", count, table),
+				Fixable: true,
+			})
+		}
+
+		if !hasMaxUSN {
+			continue
+		}
+
+		count, err = countRows(db, table, "usn > ?", maxUSN)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			ret = append(ret, Problem{
+				Message: fmt.Sprintf("%d %s have a usn greater than that of the last sync. Run 'dnote sync --full'", count, table),
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+// fixSyncProblems marks the notes and books that have never been synced to
+// be synced
+func fixSyncProblems(ctx context.DnoteCtx, tx *database.DB) error {
+	for _, table := range syncTables {
+		query := fmt.Sprintf("UPDATE %s SET usn = 0, dirty = true WHERE usn < 0 OR (usn = 0 AND dirty = false)", table)
+		if _, err := tx.Exec(query); err != nil {
+			return errors.Wrapf(err, "marking %s to be synced", table)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package doctor
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+var paths = context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}
+
+func getResult(t *testing.T, db *database.DB, name string) Result {
+	results, err := Run(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "running the checks"))
+	}
+
+	for _, r := range results {
+		if r.Check.Name == name {
+			return r
+		}
+	}
+
+	t.Fatalf("check %s not found", name)
+	return Result{}
+}
+
+func mustFix(t *testing.T, ctx context.DnoteCtx) {
+	results, err := Run(ctx.DB)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "running the checks"))
+	}
+	if err := Fix(ctx, results); err != nil {
+		t.Fatal(errors.Wrap(err, "fixing"))
+	}
+}
+
+func TestRun_healthy(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	database.MustExec(t, "inserting b1", ctx.DB, "INSERT INTO books (uuid, label, usn, dirty) VALUES (?, ?, ?, ?)", "b1-uuid", "js", 3, false)
+	database.MustExec(t, "inserting n1", ctx.DB, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty) VALUES (?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 #es6", 1, 0, true)
+	database.MustExec(t, "inserting a tag", ctx.DB, "INSERT INTO note_tags (note_uuid, tag) VALUES (?, ?)", "n1-uuid", "es6")
+	database.MustExec(t, "inserting the last max usn", ctx.DB, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastMaxUSN, 3)
+
+	results, err := Run(ctx.DB)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "running the checks"))
+	}
+
+	total, fixable := CountProblems(results)
+	assert.Equal(t, total, 0, "total mismatch")
+	assert.Equal(t, fixable, 0, "fixable mismatch")
+	assert.Equal(t, len(results), len(Checks), "result count mismatch")
+}
+
+func TestOrphanNotes(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b1-uuid", "removed-uuid", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", 1)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "missing-uuid", "n2", 2)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "missing-uuid", "", 3, true)
+
+	r := getResult(t, db, "books")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "note 1 is in a removed book", Fixable: true},
+		{Message: "note 2 is in a book that does not exist", Fixable: true},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var bookUUID string
+	database.MustScan(t, "getting the recovered book", db.QueryRow("SELECT uuid FROM books WHERE label = ?", RecoveredBookLabel), &bookUUID)
+
+	var count int
+	database.MustScan(t, "counting the recovered notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND dirty = true", bookUUID), &count)
+	assert.Equal(t, count, 2, "recovered note count mismatch")
+
+	r = getResult(t, db, "books")
+	assert.Equal(t, len(r.Problems), 0, "problems should be fixed")
+}
+
+func TestDuplicateLabels(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "dropping the index", db, "DROP INDEX idx_books_label")
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b2-uuid", "n1", 1)
+
+	r := getResult(t, db, "labels")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "2 books have the label 'js'", Fixable: true},
+		{Message: "the unique index on the book labels is missing", Fixable: true},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var bookUUID string
+	database.MustScan(t, "getting the book of n1", db.QueryRow("SELECT book_uuid FROM notes WHERE uuid = ?", "n1-uuid"), &bookUUID)
+	assert.Equal(t, bookUUID, "b1-uuid", "the note should be moved to the oldest book")
+
+	var deleted bool
+	database.MustScan(t, "getting b2", db.QueryRow("SELECT deleted FROM books WHERE uuid = ?", "b2-uuid"), &deleted)
+	assert.Equal(t, deleted, true, "the duplicate book should be removed")
+
+	r = getResult(t, db, "labels")
+	assert.Equal(t, len(r.Problems), 0, "problems should be fixed")
+}
+
+func TestOrphanRows(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", 1)
+	database.MustExec(t, "inserting a tag", db, "INSERT INTO note_tags (note_uuid, tag) VALUES (?, ?)", "n1-uuid", "es6")
+	database.MustExec(t, "inserting an orphan tag", db, "INSERT INTO note_tags (note_uuid, tag) VALUES (?, ?)", "n2-uuid", "es6")
+	database.MustExec(t, "inserting an orphan revision", db, "INSERT INTO note_revisions (note_uuid, body, edited_on) VALUES (?, ?, ?)", "n2-uuid", "old", 1)
+
+	r := getResult(t, db, "references")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "1 rows in note_tags belong to notes that do not exist", Fixable: true},
+		{Message: "1 rows in note_revisions belong to notes that do not exist", Fixable: true},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var count int
+	database.MustScan(t, "counting tags", db.QueryRow("SELECT count(*) FROM note_tags"), &count)
+	assert.Equal(t, count, 1, "the tag of n1 should remain")
+
+	r = getResult(t, db, "references")
+	assert.Equal(t, len(r.Problems), 0, "problems should be fixed")
+}
+
+func TestFTS(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "closures", 1)
+
+	r := getResult(t, db, "search")
+	assert.Equal(t, len(r.Problems), 0, "the index should be healthy")
+
+	database.MustExec(t, "removing n1 from the index", db, "INSERT INTO note_fts (note_fts, rowid, body) VALUES ('delete', 1, 'closures')")
+
+	r = getResult(t, db, "search")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "the search index does not match the notes", Fixable: true},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var count int
+	database.MustScan(t, "searching", db.QueryRow("SELECT count(*) FROM note_fts WHERE note_fts MATCH ?", "closures"), &count)
+	assert.Equal(t, count, 1, "the note should be found after the rebuild")
+}
+
+func TestSchema(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting a duplicate schema", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemSchema, 3)
+	database.MustExec(t, "updating the remote schema", db, "UPDATE system SET value = ? WHERE key = ?", "x", consts.SystemRemoteSchema)
+
+	r := getResult(t, db, "schema")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "the system table has 2 rows for 'schema'", Fixable: true},
+		{Message: "'remote_schema' is not a number: x", Fixable: false},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var schema int
+	database.MustScan(t, "getting the schema", db.QueryRow("SELECT value FROM system WHERE key = ?", consts.SystemSchema), &schema)
+	assert.Equal(t, schema, 12, "the highest schema should be kept")
+
+	r = getResult(t, db, "schema")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "'remote_schema' is not a number: x", Fixable: false},
+	}, "remaining problems mismatch")
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.InitTestCtx(t, paths, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting the last max usn", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastMaxUSN, 5)
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, dirty) VALUES (?, ?, ?, ?)", "b1-uuid", "js", 0, false)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, dirty) VALUES (?, ?, ?, ?)", "b2-uuid", "css", 8, false)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty) VALUES (?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", 1, -1, false)
+
+	r := getResult(t, db, "sync")
+	assert.DeepEqual(t, r.Problems, []Problem{
+		{Message: "1 books have never been synced but are not marked to be synced", Fixable: true},
+		{Message: "1 books have a usn greater than that of the last sync. Run 'dnote sync --full'", Fixable: false},
+		{Message: "1 notes have a negative usn", Fixable: true},
+	}, "problems mismatch")
+
+	mustFix(t, ctx)
+
+	var usn int
+	var dirty bool
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT usn, dirty FROM notes WHERE uuid = ?", "n1-uuid"), &usn, &dirty)
+	assert.Equal(t, usn, 0, "usn mismatch")
+	assert.Equal(t, dirty, true, "dirty mismatch")
+
+	r = getResult(t, db, "sync")
+	assert.Equal(t, len(r.Problems), 1, "only the unfixable problem should remain")
+}
@@ -0,0 +1,237 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package e2ee encrypts the data synced with the server end-to-end. The data is
+// encrypted with a random cipher key. The server stores the cipher key encrypted
+// with a key derived from an encryption passphrase, and a key check value that
+// lets the clients verify that they hold the same cipher key. The passphrase is
+// not the password of the account, and never leaves the clients, so that the
+// server cannot derive the key.
+package e2ee
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"github.com/dnote/dnote/pkg/cli/client"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/crypt"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+// ErrWrongPassphrase is an error for a passphrase that does not decrypt the cipher key
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// keyCheckMessage is the message authenticated with the cipher key to make the key check value
+const keyCheckMessage = "dnote key check"
+
+func randomBytes(n int) ([]byte, error) {
+	ret := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, ret); err != nil {
+		return nil, errors.Wrap(err, "reading random bytes")
+	}
+
+	return ret, nil
+}
+
+// MakeKeyCheck returns the key check value of the given cipher key
+func MakeKeyCheck(cipherKey []byte) string {
+	mac := hmac.New(sha256.New, cipherKey)
+	mac.Write([]byte(keyCheckMessage))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckKey tells if the given cipher key is the one of the given settings
+func CheckKey(settings client.EncryptionSettings, cipherKey []byte) bool {
+	return hmac.Equal([]byte(MakeKeyCheck(cipherKey)), []byte(settings.KeyCheck))
+}
+
+func deriveKey(passphrase, salt string, iteration int) ([]byte, error) {
+	key, _, err := crypt.MakeKeys([]byte(passphrase), []byte(salt), iteration)
+	if err != nil {
+		return nil, errors.Wrap(err, "deriving the key from the passphrase")
+	}
+
+	return key, nil
+}
+
+// Setup generates a new cipher key, and returns it with the settings to store
+// in the server
+func Setup(passphrase string, iteration int) ([]byte, client.EncryptionSettings, error) {
+	cipherKey, err := randomBytes(32)
+	if err != nil {
+		return nil, client.EncryptionSettings{}, errors.Wrap(err, "generating the cipher key")
+	}
+
+	settings, err := Wrap(cipherKey, passphrase, iteration)
+	if err != nil {
+		return nil, client.EncryptionSettings{}, err
+	}
+
+	return cipherKey, settings, nil
+}
+
+// Wrap encrypts the cipher key with a key derived from the passphrase and a new
+// salt, and returns the settings to store in the server
+func Wrap(cipherKey []byte, passphrase string, iteration int) (client.EncryptionSettings, error) {
+	salt, err := randomBytes(16)
+	if err != nil {
+		return client.EncryptionSettings{}, errors.Wrap(err, "generating the salt")
+	}
+
+	ret := client.EncryptionSettings{
+		Enabled:   true,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		Iteration: iteration,
+		KeyCheck:  MakeKeyCheck(cipherKey),
+	}
+
+	key, err := deriveKey(passphrase, ret.Salt, iteration)
+	if err != nil {
+		return client.EncryptionSettings{}, err
+	}
+
+	ret.CipherKeyEnc, err = crypt.AesGcmEncrypt(key, cipherKey)
+	if err != nil {
+		return client.EncryptionSettings{}, errors.Wrap(err, "encrypting the cipher key")
+	}
+
+	return ret, nil
+}
+
+// Unwrap decrypts the cipher key in the given settings with the passphrase. It
+// returns ErrWrongPassphrase if the passphrase does not decrypt it.
+func Unwrap(settings client.EncryptionSettings, passphrase string) ([]byte, error) {
+	key, err := deriveKey(passphrase, settings.Salt, settings.Iteration)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherKey, err := crypt.AesGcmDecrypt(key, settings.CipherKeyEnc)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if !CheckKey(settings, cipherKey) {
+		return nil, errors.New("the cipher key does not match the key check value")
+	}
+
+	return cipherKey, nil
+}
+
+// noteContent is the content of a note encrypted as a whole, so that the
+// server cannot read the tags either
+type noteContent struct {
+	Body string   `json:"body"`
+	Tags []string `json:"tags"`
+}
+
+// EncryptNote encrypts the body and the tags of a note
+func EncryptNote(cipherKey []byte, body string, tags []string) (string, error) {
+	b, err := json.Marshal(noteContent{Body: body, Tags: tags})
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling the content")
+	}
+
+	ret, err := crypt.AesGcmEncrypt(cipherKey, b)
+	if err != nil {
+		return "", errors.Wrap(err, "encrypting the content")
+	}
+
+	return ret, nil
+}
+
+// DecryptNote decrypts the body and the tags of a note encrypted by EncryptNote
+func DecryptNote(cipherKey []byte, data string) (string, []string, error) {
+	b, err := crypt.AesGcmDecrypt(cipherKey, data)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "decrypting the content")
+	}
+
+	var c noteContent
+	if err := json.Unmarshal(b, &c); err != nil {
+		return "", nil, errors.Wrap(err, "unmarshaling the content")
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+
+	return c.Body, c.Tags, nil
+}
+
+// EncryptLabel encrypts the label of a book
+func EncryptLabel(cipherKey []byte, label string) (string, error) {
+	ret, err := crypt.AesGcmEncrypt(cipherKey, []byte(label))
+	if err != nil {
+		return "", errors.Wrap(err, "encrypting the label")
+	}
+
+	return ret, nil
+}
+
+// DecryptLabel decrypts the label of a book encrypted by EncryptLabel
+func DecryptLabel(cipherKey []byte, data string) (string, error) {
+	b, err := crypt.AesGcmDecrypt(cipherKey, data)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypting the label")
+	}
+
+	return string(b), nil
+}
+
+// Load returns the cipher key stored in the database, or nil if there is none
+func Load(db *database.DB) ([]byte, error) {
+	var val string
+	err := database.GetSystem(db, consts.SystemCipherKey, &val)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "finding the cipher key")
+	}
+
+	ret, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding the cipher key")
+	}
+
+	return ret, nil
+}
+
+// Save stores the cipher key in the database
+func Save(db *database.DB, cipherKey []byte) error {
+	if err := database.UpsertSystem(db, consts.SystemCipherKey, base64.StdEncoding.EncodeToString(cipherKey)); err != nil {
+		return errors.Wrap(err, "saving the cipher key")
+	}
+
+	return nil
+}
+
+// Remove deletes the cipher key from the database
+func Remove(db *database.DB) error {
+	if err := database.DeleteSystem(db, consts.SystemCipherKey); err != nil {
+		return errors.Wrap(err, "deleting the cipher key")
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package e2ee
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestUnwrap(t *testing.T) {
+	cipherKey, settings, err := Setup("pass1234", 1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "setting up"))
+	}
+
+	assert.Equal(t, len(cipherKey), 32, "key length mismatch")
+	assert.Equal(t, settings.Enabled, true, "Enabled mismatch")
+	assert.Equal(t, settings.Iteration, 1000, "Iteration mismatch")
+	assert.Equal(t, settings.KeyCheck, MakeKeyCheck(cipherKey), "KeyCheck mismatch")
+
+	t.Run("passphrase", func(t *testing.T) {
+		got, err := Unwrap(settings, "pass1234")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, cipherKey, "key mismatch")
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		_, err := Unwrap(settings, "pass5678")
+		assert.Equal(t, err, ErrWrongPassphrase, "error mismatch")
+	})
+
+	t.Run("new passphrase", func(t *testing.T) {
+		newSettings, err := Wrap(cipherKey, "pass5678", 1000)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "wrapping"))
+		}
+		assert.NotEqual(t, newSettings.Salt, settings.Salt, "the salt should be new")
+		assert.Equal(t, newSettings.KeyCheck, settings.KeyCheck, "KeyCheck mismatch")
+
+		got, err := Unwrap(newSettings, "pass5678")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, cipherKey, "key mismatch")
+	})
+
+	t.Run("check", func(t *testing.T) {
+		assert.Equal(t, CheckKey(settings, cipherKey), true, "the key should match")
+		assert.Equal(t, CheckKey(settings, []byte("AES256Key-32Characters1234567890")), false, "another key should not match")
+	})
+}
+
+func TestEncryptNote(t *testing.T) {
+	key := []byte("AES256Key-32Characters1234567890")
+
+	testCases := []struct {
+		body         string
+		tags         []string
+		expectedTags []string
+	}{
+		{
+			body:         "Booleans have toString()",
+			tags:         []string{"es6", "types"},
+			expectedTags: []string{"es6", "types"},
+		},
+		{
+			body:         "",
+			tags:         nil,
+			expectedTags: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.body, func(t *testing.T) {
+			data, err := EncryptNote(key, tc.body, tc.tags)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "encrypting"))
+			}
+
+			body, tags, err := DecryptNote(key, data)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "decrypting"))
+			}
+
+			assert.Equal(t, body, tc.body, "body mismatch")
+			assert.DeepEqual(t, tags, tc.expectedTags, "tags mismatch")
+
+			if _, _, err := DecryptNote([]byte("AES256Key-32Charactersabcdefghij"), data); err == nil {
+				t.Error("decrypting with another key should fail")
+			}
+		})
+	}
+}
+
+func TestEncryptLabel(t *testing.T) {
+	key := []byte("AES256Key-32Characters1234567890")
+
+	data, err := EncryptLabel(key, "js")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting"))
+	}
+	assert.NotEqual(t, data, "js", "the label should be encrypted")
+
+	label, err := DecryptLabel(key, data)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "decrypting"))
+	}
+	assert.Equal(t, label, "js", "label mismatch")
+}
+
+func TestSave(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	got, err := Load(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "loading before saving"))
+	}
+	assert.DeepEqual(t, got, []byte(nil), "key mismatch before saving")
+
+	key := []byte("AES256Key-32Characters1234567890")
+	if err := Save(db, key); err != nil {
+		t.Fatal(errors.Wrap(err, "saving"))
+	}
+
+	got, err = Load(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "loading after saving"))
+	}
+	assert.DeepEqual(t, got, key, "key mismatch after saving")
+
+	if err := Remove(db); err != nil {
+		t.Fatal(errors.Wrap(err, "removing"))
+	}
+
+	got, err = Load(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "loading after removing"))
+	}
+	assert.DeepEqual(t, got, []byte(nil), "key mismatch after removing")
+}
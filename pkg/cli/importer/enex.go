@@ -0,0 +1,173 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// enexTimeLayout is the layout of the timestamps in ENEX files
+const enexTimeLayout = "20060102T150405Z"
+
+type enexExport struct {
+	Notes []enexNote `xml:"note"`
+}
+
+type enexNote struct {
+	Title   string `xml:"title"`
+	Content string `xml:"content"`
+	Created string `xml:"created"`
+	Updated string `xml:"updated"`
+}
+
+// enexImporter reads an Evernote export file. The notes in the file are
+// imported into a book named after the file, which is the name of the
+// notebook in Evernote.
+type enexImporter struct{}
+
+// Detect implements Importer
+func (enexImporter) Detect(path string) bool {
+	return isFile(path) && strings.ToLower(filepath.Ext(path)) == ".enex"
+}
+
+// Read implements Importer
+func (enexImporter) Read(path string, opts Options) ([]Note, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening %s", path)
+	}
+	defer f.Close()
+
+	var e enexExport
+	dec := xml.NewDecoder(f)
+	dec.Strict = false
+	if err := dec.Decode(&e); err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", path)
+	}
+
+	book := getDefaultBook(path, opts)
+	ret := []Note{}
+
+	for i, en := range e.Notes {
+		content, err := enmlToText(en.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting the content of note %d", i+1)
+		}
+
+		body := strings.TrimSpace(strings.TrimSpace(en.Title) + "\n\n" + content)
+		if body == "" {
+			continue
+		}
+
+		n := Note{
+			BookLabel: book,
+			Body:      body,
+			Source:    fmt.Sprintf("%s#%d", path, i+1),
+		}
+		if t, err := time.Parse(enexTimeLayout, en.Created); err == nil {
+			n.AddedOn = t.UnixNano()
+		}
+		if t, err := time.Parse(enexTimeLayout, en.Updated); err == nil {
+			n.EditedOn = t.UnixNano()
+		}
+		if n.AddedOn == 0 {
+			n.AddedOn = n.EditedOn
+		}
+		if n.AddedOn == 0 {
+			return nil, errors.Errorf("note %d has no creation time", i+1)
+		}
+
+		ret = append(ret, n)
+	}
+
+	return ret, nil
+}
+
+// enmlBlockElements is the set of ENML elements that start on a new line
+var enmlBlockElements = map[string]bool{
+	"div": true, "p": true, "br": true, "li": true, "tr": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "en-todo": true,
+}
+
+var blankLinesReg = regexp.MustCompile(`\n{3,}`)
+
+// ensureNewline starts a new line in the buffer unless it is empty or
+// already at the start of a line
+func ensureNewline(buf *strings.Builder) {
+	s := buf.String()
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		buf.WriteString("\n")
+	}
+}
+
+// enmlToText converts the ENML content of an Evernote note into plain text
+func enmlToText(content string) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var buf strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "reading a token")
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if name == "br" {
+				buf.WriteString("\n")
+			} else if enmlBlockElements[name] {
+				ensureNewline(&buf)
+			}
+			if name == "li" {
+				buf.WriteString("- ")
+			}
+		case xml.EndElement:
+			if t.Name.Local != "br" && enmlBlockElements[t.Name.Local] {
+				ensureNewline(&buf)
+			}
+		case xml.CharData:
+			buf.Write(t)
+		}
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+
+	s := blankLinesReg.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s), nil
+}
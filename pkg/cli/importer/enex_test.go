@@ -0,0 +1,97 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestEnmlToText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "divs",
+			input:    `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd"><en-note><div>foo</div><div>bar</div></en-note>`,
+			expected: "foo\nbar",
+		},
+		{
+			name:     "blank line",
+			input:    `<en-note><div>foo</div><div><br/></div><div>bar &amp; baz</div></en-note>`,
+			expected: "foo\n\nbar & baz",
+		},
+		{
+			name:     "list",
+			input:    `<en-note><ul><li>one</li><li>two</li></ul></en-note>`,
+			expected: "- one\n- two",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := enmlToText(tc.input)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "converting"))
+			}
+
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestEnexRead(t *testing.T) {
+	path := filepath.Join(testDir, "My Recipes.enex")
+	defer os.RemoveAll(testDir)
+
+	mustWriteFile(t, path, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20240101T000000Z" application="Evernote" version="10">
+  <note>
+    <title>Pancakes</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd"><en-note><div>flour</div><div>milk</div></en-note>]]></content>
+    <created>20230102T030405Z</created>
+    <updated>20230203T040506Z</updated>
+  </note>
+  <note>
+    <title></title>
+    <content><![CDATA[<en-note></en-note>]]></content>
+    <created>20230102T030405Z</created>
+  </note>
+</en-export>
+`)
+
+	notes, err := enexImporter{}.Read(path, Options{})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading"))
+	}
+
+	assert.Equal(t, len(notes), 1, "length mismatch")
+	assert.Equal(t, notes[0].BookLabel, "My-Recipes", "book mismatch")
+	assert.Equal(t, notes[0].Body, "Pancakes\n\nflour\nmilk", "body mismatch")
+	assert.Equal(t, notes[0].AddedOn, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano(), "added_on mismatch")
+	assert.Equal(t, notes[0].EditedOn, time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC).UnixNano(), "edited_on mismatch")
+}
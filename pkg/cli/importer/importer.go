@@ -0,0 +1,135 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package importer reads notes from the formats of other note taking tools
+package importer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Note is a note read from an external source
+type Note struct {
+	// BookLabel is the label of the book that the note belongs to
+	BookLabel string
+	// Body is the content of the note
+	Body string
+	// AddedOn is the unix timestamp in nanoseconds at which the note was created
+	AddedOn int64
+	// EditedOn is the unix timestamp in nanoseconds at which the note was last
+	// edited. It is 0 if unknown.
+	EditedOn int64
+	// Tags is the list of tags of the note. Hashtags in the body are not
+	// included.
+	Tags []string
+	// Source is the location of the note in the source, used for reporting
+	Source string
+}
+
+// Options is a set of options for reading notes
+type Options struct {
+	// Book is the label of the book for the notes that do not belong to any
+	// folder or notebook in the source. If empty, the name of the source is used.
+	Book string
+}
+
+// Importer reads notes from a source
+type Importer interface {
+	// Detect reports whether the source at the path is in the format of the importer
+	Detect(path string) bool
+	// Read reads all notes from the source at the path
+	Read(path string, opts Options) ([]Note, error)
+}
+
+type entry struct {
+	name     string
+	importer Importer
+}
+
+// registry is the list of the supported importers. When detecting the format
+// of a source, importers are tried in this order, so that the more specific
+// formats come before the generic ones.
+var registry = []entry{
+	{name: "obsidian", importer: obsidianImporter},
+	{name: "nb", importer: nbImporter},
+	{name: "enex", importer: enexImporter{}},
+	{name: "jrnl", importer: jrnlImporter{}},
+	{name: "markdown", importer: markdownImporter},
+}
+
+// Register adds an importer with the given name. It is tried before the
+// built-in importers when detecting the format of a source.
+func Register(name string, i Importer) {
+	registry = append([]entry{{name: name, importer: i}}, registry...)
+}
+
+// Names returns the names of all supported formats
+func Names() []string {
+	ret := []string{}
+	for _, e := range registry {
+		ret = append(ret, e.name)
+	}
+
+	return ret
+}
+
+// Get returns the importer for the format with the given name
+func Get(name string) (Importer, error) {
+	for _, e := range registry {
+		if e.name == name {
+			return e.importer, nil
+		}
+	}
+
+	return nil, errors.Errorf("unsupported format '%s'. Use one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Detect returns the name and the importer for the format of the source at the path
+func Detect(path string) (string, Importer, error) {
+	for _, e := range registry {
+		if e.importer.Detect(path) {
+			return e.name, e.importer, nil
+		}
+	}
+
+	return "", nil, errors.Errorf("could not detect the format of %s. Specify one with --format", path)
+}
+
+// ToBookLabel turns the name of a folder or a notebook into a book label
+// by replacing whitespaces with dashes
+func ToBookLabel(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// getDefaultBook returns the book label for the notes at the top level of the source
+func getDefaultBook(path string, opts Options) string {
+	if opts.Book != "" {
+		return opts.Book
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+
+	base := filepath.Base(abs)
+	return ToBookLabel(strings.TrimSuffix(base, filepath.Ext(base)))
+}
@@ -0,0 +1,104 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// jrnlTimeLayout is the layout of the date and the time of a jrnl entry
+const jrnlTimeLayout = "2006-01-02 15:04"
+
+// jrnlExport is the output of 'jrnl --export json'
+type jrnlExport struct {
+	Entries []jrnlEntry `json:"entries"`
+}
+
+type jrnlEntry struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Date  string `json:"date"`
+	Time  string `json:"time"`
+}
+
+// jrnlImporter reads the JSON export of a jrnl journal
+type jrnlImporter struct{}
+
+// Detect implements Importer
+func (jrnlImporter) Detect(path string) bool {
+	if !isFile(path) || strings.ToLower(filepath.Ext(path)) != ".json" {
+		return false
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var e jrnlExport
+	if err := json.Unmarshal(b, &e); err != nil {
+		return false
+	}
+
+	return e.Entries != nil
+}
+
+// Read implements Importer
+func (jrnlImporter) Read(path string, opts Options) ([]Note, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+
+	var e jrnlExport
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", path)
+	}
+
+	book := getDefaultBook(path, opts)
+	ret := []Note{}
+
+	for i, entry := range e.Entries {
+		body := strings.TrimSpace(strings.TrimSpace(entry.Title) + "\n" + strings.TrimSpace(entry.Body))
+		if body == "" {
+			continue
+		}
+
+		t, err := time.ParseInLocation(jrnlTimeLayout, fmt.Sprintf("%s %s", entry.Date, entry.Time), time.Local)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing the time of entry %d", i+1)
+		}
+
+		ret = append(ret, Note{
+			BookLabel: book,
+			Body:      body,
+			AddedOn:   t.UnixNano(),
+			Source:    fmt.Sprintf("%s#%d", path, i+1),
+		})
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,53 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestJrnlRead(t *testing.T) {
+	path := filepath.Join(testDir, "journal.json")
+	defer os.RemoveAll(testDir)
+
+	mustWriteFile(t, path, `{
+  "tags": {"@work": 1},
+  "entries": [
+    {"title": "Deployed the new API.", "body": "Rollback plan was not needed.", "date": "2023-04-05", "time": "09:30", "tags": ["@work"], "starred": false},
+    {"title": "Short entry.", "body": "", "date": "2023-04-06", "time": "18:00", "tags": [], "starred": true}
+  ]
+}`)
+
+	notes, err := jrnlImporter{}.Read(path, Options{Book: "diary"})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading"))
+	}
+
+	assert.Equal(t, len(notes), 2, "length mismatch")
+	assert.Equal(t, notes[0].BookLabel, "diary", "n1 book mismatch")
+	assert.Equal(t, notes[0].Body, "Deployed the new API.\nRollback plan was not needed.", "n1 body mismatch")
+	assert.Equal(t, notes[0].AddedOn, time.Date(2023, 4, 5, 9, 30, 0, 0, time.Local).UnixNano(), "n1 added_on mismatch")
+	assert.Equal(t, notes[1].Body, "Short entry.", "n2 body mismatch")
+}
@@ -0,0 +1,331 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/tags"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// dirImporter reads notes from a directory of text files. Each directory
+// becomes a book whose label is the path of the directory relative to the root.
+type dirImporter struct {
+	// extensions is the list of file extensions to read as notes
+	extensions []string
+	// detect reports whether the directory is in the format of the importer
+	detect func(path string) bool
+}
+
+// markdownImporter reads a folder of Markdown files, such as the one written
+// by 'dnote export'
+var markdownImporter = dirImporter{
+	extensions: []string{".md", ".markdown"},
+	detect: func(path string) bool {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		if info.IsDir() {
+			return true
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".md" || ext == ".markdown"
+	},
+}
+
+// obsidianImporter reads an Obsidian vault
+var obsidianImporter = dirImporter{
+	extensions: []string{".md"},
+	detect: func(path string) bool {
+		return isDir(filepath.Join(path, ".obsidian"))
+	},
+}
+
+// nbImporter reads the home directory of nb, in which each notebook is a
+// directory with an .index file
+var nbImporter = dirImporter{
+	extensions: []string{".md", ".markdown", ".txt"},
+	detect: func(path string) bool {
+		if isFile(filepath.Join(path, ".index")) {
+			return true
+		}
+
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return false
+		}
+		for _, e := range entries {
+			if e.IsDir() && isFile(filepath.Join(path, e.Name(), ".index")) {
+				return true
+			}
+		}
+
+		return false
+	},
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+func (d dirImporter) hasExtension(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+
+	for _, e := range d.extensions {
+		if ext == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Detect implements Importer
+func (d dirImporter) Detect(path string) bool {
+	return d.detect(path)
+}
+
+// Read implements Importer
+func (d dirImporter) Read(root string, opts Options) ([]Note, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", root)
+	}
+	if !info.IsDir() {
+		n, ok, err := readNoteFile(root, info, getDefaultBook(filepath.Dir(root), opts))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return []Note{}, nil
+		}
+
+		return []Note{n}, nil
+	}
+
+	defaultBook := getDefaultBook(root, opts)
+	ret := []Note{}
+
+	err = filepath.WalkDir(root, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != root && isHidden(de.Name()) {
+			if de.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+		if de.IsDir() || !d.hasExtension(de.Name()) {
+			return nil
+		}
+
+		info, err := de.Info()
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", path)
+		}
+
+		n, ok, err := readNoteFile(path, info, getDirBook(root, path, defaultBook))
+		if err != nil {
+			return err
+		}
+		if ok {
+			ret = append(ret, n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "walking %s", root)
+	}
+
+	return ret, nil
+}
+
+// getDirBook returns the book label for the file at the path based on the
+// directory it is in
+func getDirBook(root, path, defaultBook string) string {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return defaultBook
+	}
+
+	var parts []string
+	for _, seg := range strings.Split(filepath.ToSlash(rel), "/") {
+		parts = append(parts, ToBookLabel(seg))
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// readNoteFile reads a note from the file at the path. It returns false if
+// the file has no content.
+func readNoteFile(path string, info os.FileInfo, book string) (Note, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Note{}, false, errors.Wrapf(err, "reading %s", path)
+	}
+
+	body, fm := splitFrontMatter(string(b))
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return Note{}, false, nil
+	}
+
+	n := Note{
+		BookLabel: book,
+		Body:      body,
+		AddedOn:   info.ModTime().UnixNano(),
+		Source:    path,
+	}
+
+	if ts := fm.getTimestamp("added_on", "created", "date"); ts != 0 {
+		n.AddedOn = ts
+	}
+	if ts := fm.getTimestamp("edited_on", "updated", "modified"); ts != 0 {
+		n.EditedOn = ts
+	}
+	n.Tags = fm.getTags("tags")
+
+	return n, true, nil
+}
+
+// frontMatter is the YAML metadata at the top of a Markdown file
+type frontMatter map[interface{}]interface{}
+
+// splitFrontMatter separates the YAML front matter from the rest of the
+// content. If the content has no valid front matter, it is returned as is.
+func splitFrontMatter(content string) (string, frontMatter) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return content, nil
+	}
+
+	rest := normalized[len("---\n"):]
+
+	var raw, body string
+	if strings.HasPrefix(rest, "---\n") {
+		body = rest[len("---\n"):]
+	} else if idx := strings.Index(rest, "\n---\n"); idx != -1 {
+		raw = rest[:idx]
+		body = rest[idx+len("\n---\n"):]
+	} else if strings.HasSuffix(rest, "\n---") {
+		raw = strings.TrimSuffix(rest, "\n---")
+	} else {
+		return content, nil
+	}
+
+	fm := frontMatter{}
+	if err := yaml.Unmarshal([]byte(raw), &fm); err != nil {
+		return content, nil
+	}
+
+	return body, fm
+}
+
+// timeLayouts is the list of layouts tried when parsing dates in front matter
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// tagSepReg matches the separators of the tags given as a single string
+var tagSepReg = regexp.MustCompile(`[\s,]+`)
+
+// getTags returns the tags in the key, given either as a list or as a string
+// separated by commas or whitespaces. Invalid tags are skipped.
+func (fm frontMatter) getTags(key string) []string {
+	var candidates []string
+
+	switch v := fm[key].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				candidates = append(candidates, s)
+			}
+		}
+	case string:
+		candidates = tagSepReg.Split(v, -1)
+	}
+
+	var ret []string
+	for _, c := range candidates {
+		if tags.Validate(c) == nil {
+			ret = append(ret, c)
+		}
+	}
+
+	return tags.Normalize(ret)
+}
+
+// getTimestamp returns the timestamp in nanoseconds in the first of the keys
+// that holds a valid value. Integers are treated as unix timestamps in
+// nanoseconds, as written by 'dnote export'. It returns 0 if none is found.
+func (fm frontMatter) getTimestamp(keys ...string) int64 {
+	for _, k := range keys {
+		switch v := fm[k].(type) {
+		case int:
+			if v > 0 {
+				return int64(v)
+			}
+		case int64:
+			if v > 0 {
+				return v
+			}
+		case time.Time:
+			return v.UnixNano()
+		case string:
+			for _, layout := range timeLayouts {
+				t, err := time.ParseInLocation(layout, v, time.Local)
+				if err == nil {
+					return t.UnixNano()
+				}
+			}
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,189 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+var testDir = "../tmp/importer"
+
+func mustWriteFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the directory"))
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the file"))
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		expectedBody string
+		expectedFM   bool
+	}{
+		{
+			name:         "no front matter",
+			input:        "foo\nbar",
+			expectedBody: "foo\nbar",
+			expectedFM:   false,
+		},
+		{
+			name:         "front matter",
+			input:        "---\nuuid: abc\n---\nfoo\n",
+			expectedBody: "foo\n",
+			expectedFM:   true,
+		},
+		{
+			name:         "empty front matter",
+			input:        "---\n---\nfoo",
+			expectedBody: "foo",
+			expectedFM:   true,
+		},
+		{
+			name:         "unclosed",
+			input:        "---\nfoo\nbar",
+			expectedBody: "---\nfoo\nbar",
+			expectedFM:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, fm := splitFrontMatter(tc.input)
+			assert.Equal(t, body, tc.expectedBody, "body mismatch")
+			assert.Equal(t, fm != nil, tc.expectedFM, "front matter mismatch")
+		})
+	}
+}
+
+func TestFrontMatterGetTimestamp(t *testing.T) {
+	_, fm := splitFrontMatter("---\nadded_on: 1515199943000000000\ncreated: 2024-01-02 10:30\nupdated: 2024-01-03\n---\nfoo")
+
+	assert.Equal(t, fm.getTimestamp("added_on", "created"), int64(1515199943000000000), "added_on mismatch")
+	assert.Equal(t, fm.getTimestamp("created"), time.Date(2024, 1, 2, 10, 30, 0, 0, time.Local).UnixNano(), "created mismatch")
+	assert.Equal(t, fm.getTimestamp("updated"), time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local).UnixNano(), "updated mismatch")
+	assert.Equal(t, fm.getTimestamp("modified"), int64(0), "missing key mismatch")
+}
+
+func TestFrontMatterGetTags(t *testing.T) {
+	_, fm := splitFrontMatter("---\ntags: [Go, \"#sql\", \"bad tag\"]\nkeywords: go, sql  cli\n---\nfoo")
+
+	assert.DeepEqual(t, fm.getTags("tags"), []string{"go", "sql"}, "list mismatch")
+	assert.DeepEqual(t, fm.getTags("keywords"), []string{"cli", "go", "sql"}, "string mismatch")
+	assert.DeepEqual(t, fm.getTags("missing"), []string{}, "missing key mismatch")
+}
+
+func TestMarkdownRead(t *testing.T) {
+	root := filepath.Join(testDir, "my notes")
+	defer os.RemoveAll(testDir)
+
+	mustWriteFile(t, filepath.Join(root, "top.md"), "top note")
+	mustWriteFile(t, filepath.Join(root, "js", "a.md"), "---\nadded_on: 100\nedited_on: 200\n---\nnote a\n")
+	mustWriteFile(t, filepath.Join(root, "work", "infra ops", "b.markdown"), "note b")
+	mustWriteFile(t, filepath.Join(root, "js", "empty.md"), "  \n")
+	mustWriteFile(t, filepath.Join(root, "js", "image.png"), "png")
+	mustWriteFile(t, filepath.Join(root, ".trash", "c.md"), "note c")
+
+	mtime := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(root, "top.md"), mtime, mtime); err != nil {
+		t.Fatal(errors.Wrap(err, "setting mtime"))
+	}
+
+	notes, err := markdownImporter.Read(root, Options{})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading"))
+	}
+
+	assert.Equal(t, len(notes), 3, "length mismatch")
+
+	assert.Equal(t, notes[0].BookLabel, "js", "n1 book mismatch")
+	assert.Equal(t, notes[0].Body, "note a", "n1 body mismatch")
+	assert.Equal(t, notes[0].AddedOn, int64(100), "n1 added_on mismatch")
+	assert.Equal(t, notes[0].EditedOn, int64(200), "n1 edited_on mismatch")
+
+	assert.Equal(t, notes[1].BookLabel, "my-notes", "n2 book mismatch")
+	assert.Equal(t, notes[1].Body, "top note", "n2 body mismatch")
+	assert.Equal(t, notes[1].AddedOn, mtime.UnixNano(), "n2 added_on mismatch")
+	assert.Equal(t, notes[1].EditedOn, int64(0), "n2 edited_on mismatch")
+
+	assert.Equal(t, notes[2].BookLabel, "work/infra-ops", "n3 book mismatch")
+	assert.Equal(t, notes[2].Body, "note b", "n3 body mismatch")
+}
+
+func TestMarkdownRead_book(t *testing.T) {
+	root := filepath.Join(testDir, "vault")
+	defer os.RemoveAll(testDir)
+
+	mustWriteFile(t, filepath.Join(root, "top.md"), "top note")
+
+	notes, err := markdownImporter.Read(root, Options{Book: "inbox"})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading"))
+	}
+
+	assert.Equal(t, len(notes), 1, "length mismatch")
+	assert.Equal(t, notes[0].BookLabel, "inbox", "book mismatch")
+}
+
+func TestDetect(t *testing.T) {
+	defer os.RemoveAll(testDir)
+
+	mustWriteFile(t, filepath.Join(testDir, "vault", ".obsidian", "app.json"), "{}")
+	mustWriteFile(t, filepath.Join(testDir, "nb", "home", ".index"), "a.md")
+	mustWriteFile(t, filepath.Join(testDir, "md", "a.md"), "a")
+	mustWriteFile(t, filepath.Join(testDir, "notebook.enex"), "<en-export></en-export>")
+	mustWriteFile(t, filepath.Join(testDir, "journal.json"), `{"tags": {}, "entries": []}`)
+	mustWriteFile(t, filepath.Join(testDir, "other.json"), `{"foo": 1}`)
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "vault", expected: "obsidian"},
+		{path: "nb", expected: "nb"},
+		{path: "md", expected: "markdown"},
+		{path: "notebook.enex", expected: "enex"},
+		{path: "journal.json", expected: "jrnl"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			name, _, err := Detect(filepath.Join(testDir, tc.path))
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "detecting"))
+			}
+
+			assert.Equal(t, name, tc.expected, "format mismatch")
+		})
+	}
+
+	if _, _, err := Detect(filepath.Join(testDir, "other.json")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,113 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package infra
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/dnote/dnote/pkg/cli/agent"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+)
+
+// maxPassphraseAttempts is the number of times the passphrase is asked
+const maxPassphraseAttempts = 3
+
+// openDB opens the database at the given path. An encrypted database is
+// unlocked with the key in the environment, the key kept by the agent, or a
+// passphrase read from the terminal, in that order. The key of a passphrase is
+// then kept by the agent for the rest of the session.
+func openDB(paths context.Paths, dbPath string) (*database.DB, error) {
+	header, encrypted, err := database.ReadEncryptionHeader(dbPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the encryption header")
+	}
+	if !encrypted {
+		return database.Open(dbPath)
+	}
+
+	if v := os.Getenv(consts.EncryptionKeyEnvName); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", consts.EncryptionKeyEnvName)
+		}
+
+		db, err := database.OpenEncrypted(dbPath, key)
+		if err == database.ErrWrongKey {
+			return nil, errors.Errorf("%s does not unlock %s", consts.EncryptionKeyEnvName, dbPath)
+		}
+
+		return db, err
+	}
+
+	socketPath := agent.GetSocketPath(paths)
+	key, err := agent.GetKey(socketPath, dbPath)
+	if err != nil {
+		log.Debug("getting the key from the agent: %s\n", err.Error())
+	}
+	if len(key) > 0 {
+		db, err := database.OpenEncrypted(dbPath, key)
+		if err != database.ErrWrongKey {
+			return db, err
+		}
+
+		// The database was encrypted again with another passphrase
+		if err := agent.RemoveKey(socketPath, dbPath); err != nil {
+			log.Debug("removing the key from the agent: %s\n", err.Error())
+		}
+	}
+
+	if !ui.IsTerminal() {
+		return nil, errors.Errorf("the database is encrypted. Run 'dnote encryption unlock' in a terminal, or set %s", consts.EncryptionKeyEnvName)
+	}
+
+	for i := 0; i < maxPassphraseAttempts; i++ {
+		var passphrase string
+		if err := ui.PromptPassword(fmt.Sprintf("passphrase for %s", dbPath), &passphrase); err != nil {
+			return nil, errors.Wrap(err, "getting the passphrase")
+		}
+
+		key, err := header.DeriveKey(passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		db, err := database.OpenEncrypted(dbPath, key)
+		if err == database.ErrWrongKey {
+			log.Errorf("%s\n", err.Error())
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if err := agent.AddKey(socketPath, dbPath, key, consts.DefaultUnlockTimeout); err != nil {
+			log.Warnf("the key could not be kept for the session: %s\n", err.Error())
+		}
+
+		return db, nil
+	}
+
+	return nil, database.ErrWrongKey
+}
@@ -0,0 +1,90 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package infra
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestOpenDB(t *testing.T) {
+	paths := context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}
+	dbPath := "../tmp/dnote-test.db"
+
+	// set up
+	db := database.InitTestDB(t, dbPath, nil)
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+
+	header, err := database.NewEncryptionHeader(1000)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "making the header"))
+	}
+	key, err := header.DeriveKey("pass")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "deriving the key"))
+	}
+	if err := db.Encrypt(header, key); err != nil {
+		t.Fatal(errors.Wrap(err, "encrypting"))
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(errors.Wrap(err, "closing"))
+	}
+	defer os.Remove(dbPath)
+
+	t.Run("key in the environment", func(t *testing.T) {
+		t.Setenv(consts.EncryptionKeyEnvName, base64.StdEncoding.EncodeToString(key))
+
+		db, err := openDB(paths, dbPath)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "opening"))
+		}
+		defer db.Close()
+
+		var label string
+		database.MustScan(t, "getting the label", db.QueryRow("SELECT label FROM books"), &label)
+		assert.Equal(t, label, "js", "label mismatch")
+	})
+
+	t.Run("wrong key in the environment", func(t *testing.T) {
+		wrongKey, err := header.DeriveKey("wrong")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "deriving the key"))
+		}
+		t.Setenv(consts.EncryptionKeyEnvName, base64.StdEncoding.EncodeToString(wrongKey))
+
+		if _, err := openDB(paths, dbPath); err == nil {
+			t.Fatal("opening with a wrong key should fail")
+		}
+	})
+
+	t.Run("no key without a terminal", func(t *testing.T) {
+		t.Setenv(consts.EncryptionKeyEnvName, "")
+
+		if _, err := openDB(paths, dbPath); err == nil {
+			t.Fatal("opening without a key should fail")
+		}
+	})
+}
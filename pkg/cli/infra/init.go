@@ -33,6 +33,7 @@ import (
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/dirs"
+	"github.com/dnote/dnote/pkg/cli/e2ee"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/migrate"
 	"github.com/dnote/dnote/pkg/cli/utils"
@@ -67,35 +68,114 @@ func getDBPath(paths context.Paths) string {
 	return fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName)
 }
 
-func newCtx(versionTag string) (context.DnoteCtx, error) {
-	dnoteDir := getLegacyDnotePath(dirs.Home)
-	paths := context.Paths{
+// getProfileName returns the name of the profile to use, given by the flag,
+// the environment or the config file in the order of precedence. It returns an
+// empty string for the default profile.
+func getProfileName(profileFlag string, cf config.Config) string {
+	name := profileFlag
+	if name == "" {
+		name = os.Getenv(consts.ProfileEnvName)
+	}
+	if name == "" {
+		name = cf.Profile
+	}
+
+	if config.IsDefaultProfile(name) {
+		return ""
+	}
+
+	return name
+}
+
+// getProfileDBPath returns the path to the SQLite file of the given profile
+// that is not the default profile
+func getProfileDBPath(paths context.Paths, name string, p config.Profile) string {
+	if p.DB != "" {
+		return p.DB
+	}
+
+	return filepath.Join(paths.Data, consts.DnoteDirName, consts.ProfilesDirName, name, consts.DnoteDBFileName)
+}
+
+// readProfileConfig reads the config file for choosing a profile. The config
+// file does not exist before the first run.
+func readProfileConfig(paths context.Paths) (config.Config, error) {
+	path := config.GetPath(context.DnoteCtx{Paths: paths})
+	ok, err := utils.FileExists(path)
+	if err != nil {
+		return config.Config{}, errors.Wrap(err, "checking if config exists")
+	}
+	if !ok {
+		return config.Config{}, nil
+	}
+
+	return config.Read(context.DnoteCtx{Paths: paths})
+}
+
+// GetPaths returns the paths to the dnote directories
+func GetPaths() context.Paths {
+	return context.Paths{
 		Home:        dirs.Home,
 		Config:      dirs.ConfigHome,
 		Data:        dirs.DataHome,
 		Cache:       dirs.CacheHome,
-		LegacyDnote: dnoteDir,
+		LegacyDnote: getLegacyDnotePath(dirs.Home),
+	}
+}
+
+func newCtx(versionTag, profileFlag string) (context.DnoteCtx, error) {
+	paths := GetPaths()
+
+	cf, err := readProfileConfig(paths)
+	if err != nil {
+		return context.DnoteCtx{}, errors.Wrap(err, "reading config")
+	}
+
+	profile := getProfileName(profileFlag, cf)
+
+	var dbPath string
+	if profile == "" {
+		dbPath = getDBPath(paths)
+	} else {
+		p, err := cf.GetProfile(profile)
+		if err != nil {
+			return context.DnoteCtx{}, err
+		}
+
+		dbPath = getProfileDBPath(paths, profile, p)
+		if err := initDir(filepath.Dir(dbPath)); err != nil {
+			return context.DnoteCtx{}, errors.Wrap(err, "initializing the profile dir")
+		}
 	}
 
-	dbPath := getDBPath(paths)
+	// The backups taken before the migrations are rotated with the limit in use
+	resolved, _, err := config.Resolve(context.DnoteCtx{Paths: paths, Profile: profile}, cf)
+	if err != nil {
+		return context.DnoteCtx{}, errors.Wrap(err, "resolving config")
+	}
 
-	db, err := database.Open(dbPath)
+	db, err := openDB(paths, dbPath)
 	if err != nil {
 		return context.DnoteCtx{}, errors.Wrap(err, "conntecting to db")
 	}
 
 	ctx := context.DnoteCtx{
-		Paths:   paths,
-		Version: versionTag,
-		DB:      db,
+		Paths:       paths,
+		Version:     versionTag,
+		DB:          db,
+		Clock:       clock.New(),
+		BackupLimit: resolved.BackupLimit,
+		Profile:     profile,
 	}
 
 	return ctx, nil
 }
 
-// Init initializes the Dnote environment and returns a new dnote context
-func Init(apiEndpoint, versionTag string) (*context.DnoteCtx, error) {
-	ctx, err := newCtx(versionTag)
+// Init initializes the Dnote environment and returns a new dnote context for
+// the profile with the given name. If the name is empty, the profile is chosen
+// by the environment or the config file.
+func Init(apiEndpoint, versionTag, profile string) (*context.DnoteCtx, error) {
+	ctx, err := newCtx(versionTag, profile)
 	if err != nil {
 		return nil, errors.Wrap(err, "initializing a context")
 	}
@@ -111,8 +191,11 @@ func Init(apiEndpoint, versionTag string) (*context.DnoteCtx, error) {
 		return nil, errors.Wrap(err, "initializing system data")
 	}
 
-	if err := migrate.Legacy(ctx); err != nil {
-		return nil, errors.Wrap(err, "running legacy migration")
+	// Only the default profile can have the data of the legacy versions
+	if ctx.Profile == "" {
+		if err := migrate.Legacy(ctx); err != nil {
+			return nil, errors.Wrap(err, "running legacy migration")
+		}
 	}
 	if err := migrate.Run(ctx, migrate.LocalSequence, migrate.LocalMode); err != nil {
 		return nil, errors.Wrap(err, "running migration")
@@ -143,12 +226,21 @@ func SetupCtx(ctx context.DnoteCtx) (context.DnoteCtx, error) {
 	if err != nil && err != sql.ErrNoRows {
 		return ctx, errors.Wrap(err, "finding sesison key expiry")
 	}
+	cipherKey, err := e2ee.Load(db)
+	if err != nil {
+		return ctx, errors.Wrap(err, "finding cipher key")
+	}
 
 	cf, err := config.Read(ctx)
 	if err != nil {
 		return ctx, errors.Wrap(err, "reading config")
 	}
 
+	cf, _, err = config.Resolve(ctx, cf)
+	if err != nil {
+		return ctx, errors.Wrap(err, "resolving config")
+	}
+
 	ret := context.DnoteCtx{
 		Paths:              ctx.Paths,
 		Version:            ctx.Version,
@@ -159,6 +251,11 @@ func SetupCtx(ctx context.DnoteCtx) (context.DnoteCtx, error) {
 		Editor:             cf.Editor,
 		Clock:              clock.New(),
 		EnableUpgradeCheck: cf.EnableUpgradeCheck,
+		RevisionLimit:      cf.RevisionLimit,
+		TrashRetentionDays: cf.TrashRetentionDays,
+		BackupLimit:        cf.BackupLimit,
+		Profile:            ctx.Profile,
+		CipherKey:          cipherKey,
 	}
 
 	return ret, nil
@@ -279,37 +376,6 @@ func InitSystem(ctx context.DnoteCtx) error {
 	return nil
 }
 
-// getEditorCommand returns the system's editor command with appropriate flags,
-// if necessary, to make the command wait until editor is close to exit.
-func getEditorCommand() string {
-	editor := os.Getenv("EDITOR")
-
-	var ret string
-
-	switch editor {
-	case "atom":
-		ret = "atom -w"
-	case "subl":
-		ret = "subl -n -w"
-	case "code":
-		ret = "code -n -w"
-	case "mate":
-		ret = "mate -w"
-	case "vim":
-		ret = "vim"
-	case "nano":
-		ret = "nano"
-	case "emacs":
-		ret = "emacs"
-	case "nvim":
-		ret = "nvim"
-	default:
-		ret = "vi"
-	}
-
-	return ret
-}
-
 func initDir(path string) error {
 	ok, err := utils.FileExists(path)
 	if err != nil {
@@ -352,12 +418,15 @@ func initConfigFile(ctx context.DnoteCtx, apiEndpoint string) error {
 		return nil
 	}
 
-	editor := getEditorCommand()
+	editor := config.DefaultEditor()
 
 	cf := config.Config{
 		Editor:             editor,
 		APIEndpoint:        apiEndpoint,
 		EnableUpgradeCheck: true,
+		RevisionLimit:      consts.DefaultRevisionLimit,
+		TrashRetentionDays: consts.DefaultTrashRetentionDays,
+		BackupLimit:        consts.DefaultBackupLimit,
 	}
 
 	if err := config.Write(ctx, cf); err != nil {
@@ -19,9 +19,13 @@
 package infra
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/pkg/errors"
 )
@@ -91,3 +95,47 @@ func TestInitSystemKV_existing(t *testing.T) {
 		db.QueryRow("SELECT value FROM system WHERE key = ?", "testKey"), &val)
 	assert.Equal(t, val, "testVal", "system value should not have been updated")
 }
+
+func TestGetProfileName(t *testing.T) {
+	cf := config.Config{
+		Profile: "work",
+	}
+
+	testCases := []struct {
+		flag     string
+		env      string
+		cf       config.Config
+		expected string
+	}{
+		{flag: "", env: "", cf: config.Config{}, expected: ""},
+		{flag: "", env: "", cf: cf, expected: "work"},
+		{flag: "", env: "personal", cf: cf, expected: "personal"},
+		{flag: "club", env: "personal", cf: cf, expected: "club"},
+		{flag: "default", env: "personal", cf: cf, expected: ""},
+		{flag: "", env: "default", cf: cf, expected: ""},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			t.Setenv(consts.ProfileEnvName, tc.env)
+
+			assert.Equal(t, getProfileName(tc.flag, tc.cf), tc.expected, "profile name mismatch")
+		})
+	}
+}
+
+func TestGetProfileDBPath(t *testing.T) {
+	paths := context.Paths{
+		Data: "/home/user/.local/share",
+	}
+
+	t.Run("default path", func(t *testing.T) {
+		got := getProfileDBPath(paths, "work", config.Profile{})
+		assert.Equal(t, got, "/home/user/.local/share/dnote/profiles/work/dnote.db", "path mismatch")
+	})
+
+	t.Run("custom path", func(t *testing.T) {
+		got := getProfileDBPath(paths, "work", config.Profile{DB: "/tmp/work.db"})
+		assert.Equal(t, got, "/tmp/work.db", "path mismatch")
+	})
+}
@@ -0,0 +1,100 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package links parses the wiki-style links between notes
+package links
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MinUUIDPrefixLength is the minimum length of a uuid prefix that links to a note
+const MinUUIDPrefixLength = 4
+
+// linkRegex matches a link such as [[3f2a9c]] or [[golang/Channels]]
+var linkRegex = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// uuidPrefixRegex matches the characters that can appear in a uuid
+var uuidPrefixRegex = regexp.MustCompile(`^[0-9a-f-]+$`)
+
+// IsUUIDPrefix checks if the given link target is a prefix of a note uuid
+func IsUUIDPrefix(target string) bool {
+	return len(target) >= MinUUIDPrefixLength && uuidPrefixRegex.MatchString(target)
+}
+
+// SplitBookTitle splits the given link target into the name of a book and the
+// title of a note. The title follows the last slash, so that the book can be a
+// nested book. ok is false if the target does not name a book and a title.
+func SplitBookTitle(target string) (book, title string, ok bool) {
+	idx := strings.LastIndex(target, "/")
+	if idx == -1 {
+		return "", "", false
+	}
+
+	book = strings.TrimSpace(target[:idx])
+	title = strings.TrimSpace(target[idx+1:])
+	if book == "" || title == "" {
+		return "", "", false
+	}
+
+	return book, title, true
+}
+
+// Title returns the title of a note, which is the first non-empty line of the
+// body without the leading heading markers
+func Title(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
+
+// Extract returns the targets of the links found in the given note body in the
+// order they appear. uuid prefixes are lowercased and the spaces around the
+// slash between a book and a title are removed. Duplicates and targets that
+// are neither a uuid prefix nor a book and a title are dropped.
+func Extract(body string) []string {
+	seen := map[string]bool{}
+	ret := []string{}
+
+	for _, match := range linkRegex.FindAllStringSubmatch(body, -1) {
+		target := strings.TrimSpace(match[1])
+
+		if lower := strings.ToLower(target); IsUUIDPrefix(lower) {
+			target = lower
+		} else if book, title, ok := SplitBookTitle(target); ok {
+			target = book + "/" + title
+		} else {
+			continue
+		}
+
+		if seen[target] {
+			continue
+		}
+
+		seen[target] = true
+		ret = append(ret, target)
+	}
+
+	return ret
+}
@@ -0,0 +1,103 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package links
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestExtract(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "no links",
+			expected: []string{},
+		},
+		{
+			input:    "see [[3F2A9C]] and [[golang/Channels]]",
+			expected: []string{"3f2a9c", "golang/Channels"},
+		},
+		{
+			input:    "[[ work/infra / Deploy steps ]] and [[3f2a9c]] again [[3f2a9c]]",
+			expected: []string{"work/infra/Deploy steps", "3f2a9c"},
+		},
+		{
+			input:    "[[abc]] [[word]] [[/title]] [[book/]] [[]] [[a\nb/c]] [ [x/y] ]",
+			expected: []string{},
+		},
+		{
+			input:    "[[[js/closures]]]",
+			expected: []string{"js/closures"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, Extract(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestSplitBookTitle(t *testing.T) {
+	testCases := []struct {
+		input string
+		book  string
+		title string
+		ok    bool
+	}{
+		{input: "golang/Channels", book: "golang", title: "Channels", ok: true},
+		{input: "work/infra/Deploy steps", book: "work/infra", title: "Deploy steps", ok: true},
+		{input: "3f2a9c", ok: false},
+		{input: "golang/", ok: false},
+		{input: "/Channels", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			book, title, ok := SplitBookTitle(tc.input)
+
+			assert.Equal(t, book, tc.book, "book mismatch")
+			assert.Equal(t, title, tc.title, "title mismatch")
+			assert.Equal(t, ok, tc.ok, "ok mismatch")
+		})
+	}
+}
+
+func TestTitle(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Channels\nbody", expected: "Channels"},
+		{input: "\n\n## Deploy steps \n1. build", expected: "Deploy steps"},
+		{input: "#\nfoo", expected: "foo"},
+		{input: "", expected: ""},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			assert.Equal(t, Title(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
@@ -20,8 +20,10 @@ package log
 
 import (
 	"fmt"
-	"github.com/fatih/color"
+	"io"
 	"os"
+
+	"github.com/fatih/color"
 )
 
 var (
@@ -39,54 +41,63 @@ var (
 
 var indent = "  "
 
+// output is the writer to which messages are printed
+var output io.Writer = color.Output
+
+// SetOutput sets the writer to which messages are printed. It is used to keep
+// messages out of the standard output when the output is read by programs.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // Info prints information
 func Info(msg string) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorBlue.Sprint("•"), msg)
+	fmt.Fprintf(output, "%s%s %s", indent, ColorBlue.Sprint("•"), msg)
 }
 
 // Infof prints information with optional format verbs
 func Infof(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorBlue.Sprint("•"), fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s", indent, ColorBlue.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Success prints a success message
 func Success(msg string) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorGreen.Sprint("✔"), msg)
+	fmt.Fprintf(output, "%s%s %s", indent, ColorGreen.Sprint("✔"), msg)
 }
 
 // Successf prints a success message with optional format verbs
 func Successf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorGreen.Sprint("✔"), fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s", indent, ColorGreen.Sprint("✔"), fmt.Sprintf(msg, v...))
 }
 
 // Plain prints a plain message without any prefix symbol
 func Plain(msg string) {
-	fmt.Printf("%s%s", indent, msg)
+	fmt.Fprintf(output, "%s%s", indent, msg)
 }
 
 // Plainf prints a plain message without any prefix symbol. It takes optional format verbs.
 func Plainf(msg string, v ...interface{}) {
-	fmt.Printf("%s%s", indent, fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s", indent, fmt.Sprintf(msg, v...))
 }
 
 // Warnf prints a warning message with optional format verbs
 func Warnf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorRed.Sprint("•"), fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s", indent, ColorRed.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Error prints an error message
 func Error(msg string) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorRed.Sprint("⨯"), msg)
+	fmt.Fprintf(output, "%s%s %s", indent, ColorRed.Sprint("⨯"), msg)
 }
 
 // Errorf prints an error message with optional format verbs
 func Errorf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorRed.Sprintf("⨯"), fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s", indent, ColorRed.Sprintf("⨯"), fmt.Sprintf(msg, v...))
 }
 
 // Printf prints an normal message
 func Printf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorGray.Sprint("•"), fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s", indent, ColorGray.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Askf prints an question with optional format verbs. The leading symbol differs in color depending
@@ -101,12 +112,12 @@ func Askf(msg string, masked bool, v ...interface{}) {
 		symbol = ColorGreen.Sprintf(symbolChar)
 	}
 
-	fmt.Fprintf(color.Output, "%s%s %s: ", indent, symbol, fmt.Sprintf(msg, v...))
+	fmt.Fprintf(output, "%s%s %s: ", indent, symbol, fmt.Sprintf(msg, v...))
 }
 
 // Debug prints to the console if DNOTE_DEBUG is set
 func Debug(msg string, v ...interface{}) {
 	if os.Getenv("DNOTE_DEBUG") == "1" {
-		fmt.Fprintf(color.Output, "%s %s", ColorGray.Sprint("DEBUG:"), fmt.Sprintf(msg, v...))
+		fmt.Fprintf(output, "%s %s", ColorGray.Sprint("DEBUG:"), fmt.Sprintf(msg, v...))
 	}
 }
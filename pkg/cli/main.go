@@ -28,15 +28,35 @@ import (
 
 	// commands
 	"github.com/dnote/dnote/pkg/cli/cmd/add"
+	"github.com/dnote/dnote/pkg/cli/cmd/attach"
+	attachmentcmd "github.com/dnote/dnote/pkg/cli/cmd/attachment"
+	attachmentscmd "github.com/dnote/dnote/pkg/cli/cmd/attachments"
+	backupcmd "github.com/dnote/dnote/pkg/cli/cmd/backup"
+	"github.com/dnote/dnote/pkg/cli/cmd/browse"
 	"github.com/dnote/dnote/pkg/cli/cmd/cat"
+	configcmd "github.com/dnote/dnote/pkg/cli/cmd/config"
+	diffcmd "github.com/dnote/dnote/pkg/cli/cmd/diff"
+	doctorcmd "github.com/dnote/dnote/pkg/cli/cmd/doctor"
 	"github.com/dnote/dnote/pkg/cli/cmd/edit"
+	encryptioncmd "github.com/dnote/dnote/pkg/cli/cmd/encryption"
+	"github.com/dnote/dnote/pkg/cli/cmd/export"
 	"github.com/dnote/dnote/pkg/cli/cmd/find"
+	"github.com/dnote/dnote/pkg/cli/cmd/history"
+	importcmd "github.com/dnote/dnote/pkg/cli/cmd/import"
+	linkscmd "github.com/dnote/dnote/pkg/cli/cmd/links"
 	"github.com/dnote/dnote/pkg/cli/cmd/login"
 	"github.com/dnote/dnote/pkg/cli/cmd/logout"
 	"github.com/dnote/dnote/pkg/cli/cmd/ls"
+	profilecmd "github.com/dnote/dnote/pkg/cli/cmd/profile"
 	"github.com/dnote/dnote/pkg/cli/cmd/remove"
+	"github.com/dnote/dnote/pkg/cli/cmd/restore"
+	"github.com/dnote/dnote/pkg/cli/cmd/restorebackup"
+	reviewcmd "github.com/dnote/dnote/pkg/cli/cmd/review"
 	"github.com/dnote/dnote/pkg/cli/cmd/root"
+	statscmd "github.com/dnote/dnote/pkg/cli/cmd/stats"
 	"github.com/dnote/dnote/pkg/cli/cmd/sync"
+	templatecmd "github.com/dnote/dnote/pkg/cli/cmd/template"
+	"github.com/dnote/dnote/pkg/cli/cmd/trash"
 	"github.com/dnote/dnote/pkg/cli/cmd/version"
 	"github.com/dnote/dnote/pkg/cli/cmd/view"
 )
@@ -46,11 +66,23 @@ var apiEndpoint string
 var versionTag = "master"
 
 func main() {
-	ctx, err := infra.Init(apiEndpoint, versionTag)
+	// Locking and the agent do not use the database, and must not ask for the
+	// passphrase of a locked one
+	if encryptioncmd.IsStandalone(os.Args[1:]) {
+		root.Register(encryptioncmd.NewStandaloneCmd(infra.GetPaths()))
+
+		if err := root.Execute(); err != nil {
+			log.Errorf("%s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, err := infra.Init(apiEndpoint, versionTag, root.GetProfileFlag(os.Args[1:]))
 	if err != nil {
-		panic(errors.Wrap(err, "initializing context"))
+		log.Errorf("%s\n", errors.Wrap(err, "initializing context").Error())
+		os.Exit(1)
 	}
-	defer ctx.DB.Close()
 
 	root.Register(remove.NewCmd(*ctx))
 	root.Register(edit.NewCmd(*ctx))
@@ -63,8 +95,33 @@ func main() {
 	root.Register(cat.NewCmd(*ctx))
 	root.Register(view.NewCmd(*ctx))
 	root.Register(find.NewCmd(*ctx))
+	root.Register(export.NewCmd(*ctx))
+	root.Register(importcmd.NewCmd(*ctx))
+	root.Register(browse.NewCmd(*ctx))
+	root.Register(history.NewCmd(*ctx))
+	root.Register(diffcmd.NewCmd(*ctx))
+	root.Register(restore.NewCmd(*ctx))
+	root.Register(trash.NewCmd(*ctx))
+	root.Register(templatecmd.NewCmd(*ctx))
+	root.Register(linkscmd.NewCmd(*ctx))
+	root.Register(attach.NewCmd(*ctx))
+	root.Register(attachmentscmd.NewCmd(*ctx))
+	root.Register(attachmentcmd.NewCmd(*ctx))
+	root.Register(profilecmd.NewCmd(*ctx))
+	root.Register(configcmd.NewCmd(*ctx))
+	root.Register(statscmd.NewCmd(*ctx))
+	root.Register(reviewcmd.NewCmd(*ctx))
+	root.Register(backupcmd.NewCmd(*ctx))
+	root.Register(restorebackup.NewCmd(*ctx))
+	root.Register(doctorcmd.NewCmd(*ctx))
+	root.Register(encryptioncmd.NewCmd(*ctx))
 
-	if err := root.Execute(); err != nil {
+	err = root.Execute()
+	// An encrypted database is written to its file when it is closed
+	if cerr := ctx.DB.Close(); cerr != nil && err == nil {
+		err = errors.Wrap(cerr, "closing the database")
+	}
+	if err != nil {
 		log.Errorf("%s\n", err.Error())
 		os.Exit(1)
 	}
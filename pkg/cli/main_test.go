@@ -19,10 +19,16 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
@@ -325,6 +331,46 @@ func TestEditBook(t *testing.T) {
 		assert.Equal(t, n1.Dirty, false, "n1 Dirty mismatch")
 		assert.Equal(t, n1.USN, 0, "n1 USN mismatch")
 	})
+
+	t.Run("merge-into flag", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "js", "--merge-into", "linux", "-y")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var activeBookCount, linuxNoteCount int
+		database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books WHERE deleted = false"), &activeBookCount)
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND dirty = true", "linux-book-uuid"), &linuxNoteCount)
+
+		assert.Equalf(t, activeBookCount, 1, "active book count mismatch")
+		assert.Equalf(t, linuxNoteCount, 2, "moved note count mismatch")
+
+		var b1, b2 database.Book
+		database.MustScan(t, "getting b1",
+			db.QueryRow("SELECT label, usn, deleted, dirty FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Label, &b1.USN, &b1.Deleted, &b1.Dirty)
+		database.MustScan(t, "getting b2",
+			db.QueryRow("SELECT label, usn, deleted, dirty FROM books WHERE uuid = ?", "linux-book-uuid"), &b2.Label, &b2.USN, &b2.Deleted, &b2.Dirty)
+
+		assert.NotEqual(t, b1.Label, "js", "b1 Label should have been overridden")
+		assert.Equal(t, b1.USN, 111, "b1 USN mismatch")
+		assert.Equal(t, b1.Deleted, true, "b1 Deleted mismatch")
+		assert.Equal(t, b1.Dirty, true, "b1 Dirty mismatch")
+
+		assert.Equal(t, b2.Label, "linux", "b2 Label mismatch")
+		assert.Equal(t, b2.Deleted, false, "b2 Deleted mismatch")
+		assert.Equal(t, b2.Dirty, false, "b2 Dirty mismatch")
+
+		// the label is free to be reused
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "new note")
+
+		var jsBookCount int
+		database.MustScan(t, "counting js books", db.QueryRow("SELECT count(*) FROM books WHERE label = ? AND deleted = false", "js"), &jsBookCount)
+		assert.Equal(t, jsBookCount, 1, "js book count mismatch")
+	})
 }
 
 func TestRemoveNote(t *testing.T) {
@@ -414,6 +460,73 @@ func TestRemoveNote(t *testing.T) {
 	}
 }
 
+func TestPickNote(t *testing.T) {
+	chooseSecond := func(stdin io.WriteCloser) error {
+		if _, err := io.WriteString(stdin, "2\n"); err != nil {
+			return errors.Wrap(err, "choosing a note")
+		}
+
+		return nil
+	}
+
+	t.Run("remove", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, chooseSecond, binaryName, "remove", "-y")
+
+		// Test
+		var n1Deleted, n2Deleted, n3Deleted bool
+		database.MustScan(t, "getting n1", db.QueryRow("SELECT deleted FROM notes WHERE uuid = ?", "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"), &n1Deleted)
+		database.MustScan(t, "getting n2", db.QueryRow("SELECT deleted FROM notes WHERE uuid = ?", "43827b9a-c2b0-4c06-a290-97991c896653"), &n2Deleted)
+		database.MustScan(t, "getting n3", db.QueryRow("SELECT deleted FROM notes WHERE uuid = ?", "3e065d55-6d47-42f2-a6bf-f5844130b2d2"), &n3Deleted)
+
+		assert.Equal(t, n1Deleted, true, "n1 should be removed because notes are listed by the newest first")
+		assert.Equal(t, n2Deleted, false, "n2 deleted mismatch")
+		assert.Equal(t, n3Deleted, false, "n3 deleted mismatch")
+	})
+
+	t.Run("view in a book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "js", "--pick", "--content-only")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		cmd.Stdin = strings.NewReader("2\n")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		// Test
+		assert.Equal(t, stdout.String(), "1) (js) (1) n1 body\n2) (js) (2) n2 body\nchoose a note (1-2): n2 body", "output mismatch")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, func(stdin io.WriteCloser) error {
+			return stdin.Close()
+		}, binaryName, "remove", "-y")
+
+		// Test
+		var deletedCount int
+		database.MustScan(t, "counting deleted notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted = ?", true), &deletedCount)
+		assert.Equal(t, deletedCount, 0, "no note should be removed")
+	})
+}
+
 func TestRemoveBook(t *testing.T) {
 	testCases := []struct {
 		yesFlag bool
@@ -501,3 +614,1168 @@ func TestRemoveBook(t *testing.T) {
 		})
 	}
 }
+
+func TestViewOutputJSON(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup1(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	// Execute
+	cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "--output", "json")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+
+	// Test
+	var books []struct {
+		UUID      string `json:"uuid"`
+		Label     string `json:"label"`
+		NoteCount int    `json:"note_count"`
+	}
+	testutils.MustUnmarshalJSON(t, stdout.Bytes(), &books)
+
+	assert.Equal(t, len(books), 2, "book count mismatch")
+	assert.Equal(t, books[0].UUID, "js-book-uuid", "b1 uuid mismatch")
+	assert.Equal(t, books[0].Label, "js", "b1 label mismatch")
+	assert.Equal(t, books[0].NoteCount, 1, "b1 note count mismatch")
+	assert.Equal(t, books[1].Label, "linux", "b2 label mismatch")
+	assert.Equal(t, books[1].NoteCount, 0, "b2 note count mismatch")
+}
+
+func TestNoteTags(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "foo #ES6", "-t", "Types,draft", "-t", "types")
+
+		// Test
+		var noteUUID string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT uuid FROM notes"), &noteUUID)
+
+		tags, err := database.GetNoteTags(db, noteUUID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting tags"))
+		}
+		assert.DeepEqual(t, tags, []string{"draft", "es6", "types"}, "tags mismatch")
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup4(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		n2UUID := "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"
+		if err := database.SetNoteTags(db, n2UUID, []string{"draft", "js"}); err != nil {
+			t.Fatal(errors.Wrap(err, "setting tags"))
+		}
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "2", "-t", "dates", "--remove-tag", "draft")
+
+		// Test
+		tags, err := database.GetNoteTags(db, n2UUID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting tags"))
+		}
+		assert.DeepEqual(t, tags, []string{"dates", "js"}, "tags mismatch")
+
+		var body string
+		var dirty bool
+		database.MustScan(t, "getting n2", db.QueryRow("SELECT body, dirty FROM notes WHERE uuid = ?", n2UUID), &body, &dirty)
+		assert.Equal(t, body, "Date object implements mathematical comparisons", "n2 body mismatch")
+		assert.Equal(t, dirty, true, "n2 dirty mismatch")
+	})
+
+	t.Run("view", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup5(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		if err := database.SetNoteTags(db, "43827b9a-c2b0-4c06-a290-97991c896653", []string{"es6", "types"}); err != nil {
+			t.Fatal(errors.Wrap(err, "setting tags"))
+		}
+		if err := database.SetNoteTags(db, "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f", []string{"es6"}); err != nil {
+			t.Fatal(errors.Wrap(err, "setting tags"))
+		}
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "--tag", "ES6", "--tag", "types", "--output", "json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		// Test
+		var notes []struct {
+			UUID      string   `json:"uuid"`
+			BookLabel string   `json:"book_label"`
+			Tags      []string `json:"tags"`
+		}
+		testutils.MustUnmarshalJSON(t, stdout.Bytes(), &notes)
+
+		assert.Equal(t, len(notes), 1, "note count mismatch")
+		assert.Equal(t, notes[0].UUID, "43827b9a-c2b0-4c06-a290-97991c896653", "uuid mismatch")
+		assert.Equal(t, notes[0].BookLabel, "js", "book label mismatch")
+		assert.DeepEqual(t, notes[0].Tags, []string{"es6", "types"}, "tags mismatch")
+	})
+}
+
+func TestNoteRevisions(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup4(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	n2UUID := "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "edit", "2", "-c", "foo bar #dates")
+	testutils.RunDnoteCmd(t, opts, binaryName, "edit", "2", "-c", "baz")
+
+	// Execute
+	cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "history", "2", "--output", "json")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+
+	// Test
+	var revisions []struct {
+		ID      int    `json:"id"`
+		NoteID  int    `json:"note_id"`
+		Content string `json:"content"`
+	}
+	testutils.MustUnmarshalJSON(t, stdout.Bytes(), &revisions)
+
+	assert.Equal(t, len(revisions), 2, "revision count mismatch")
+	assert.Equal(t, revisions[0].Content, "foo bar #dates", "revisions[0] content mismatch")
+	assert.Equal(t, revisions[0].NoteID, 2, "revisions[0] note id mismatch")
+	assert.Equal(t, revisions[1].Content, "Date object implements mathematical comparisons", "revisions[1] content mismatch")
+
+	revID := strconv.Itoa(revisions[0].ID)
+
+	t.Run("diff", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "diff", "2", revID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		lines := strings.Split(stdout.String(), "\n")
+		assert.Equal(t, strings.HasPrefix(lines[0], fmt.Sprintf("--- revision %s", revID)), true, "header mismatch")
+		assert.DeepEqual(t, lines[1:], []string{"+++ note 2", "-foo bar #dates", "+baz", ""}, "diff mismatch")
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		database.MustExec(t, "marking n2 clean", db, "UPDATE notes SET dirty = ? WHERE uuid = ?", false, n2UUID)
+
+		testutils.RunDnoteCmd(t, opts, binaryName, "restore", "2", revID)
+
+		var body string
+		var dirty bool
+		database.MustScan(t, "getting n2", db.QueryRow("SELECT body, dirty FROM notes WHERE uuid = ?", n2UUID), &body, &dirty)
+		assert.Equal(t, body, "foo bar #dates", "n2 body mismatch")
+		assert.Equal(t, dirty, true, "n2 dirty mismatch")
+
+		tags, err := database.GetNoteTags(db, n2UUID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting tags"))
+		}
+		assert.DeepEqual(t, tags, []string{"dates"}, "tags mismatch")
+
+		// the replaced content should be kept as a revision
+		revisions, err := database.GetNoteRevisions(db, n2UUID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting revisions"))
+		}
+		assert.Equal(t, len(revisions), 3, "revision count mismatch")
+		assert.Equal(t, revisions[0].Body, "baz", "revisions[0] body mismatch")
+	})
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("restore a note", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		n1UUID := "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"
+
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1")
+		database.MustExec(t, "marking n1 clean", db, "UPDATE notes SET dirty = ? WHERE uuid = ?", false, n1UUID)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "trash", "ls", "--output", "json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		var items []struct {
+			ID        int    `json:"id"`
+			Kind      string `json:"kind"`
+			UUID      string `json:"uuid"`
+			BookLabel string `json:"book_label"`
+			Content   string `json:"content"`
+		}
+		testutils.MustUnmarshalJSON(t, stdout.Bytes(), &items)
+
+		assert.Equal(t, len(items), 1, "item count mismatch")
+		assert.Equal(t, items[0].Kind, "note", "kind mismatch")
+		assert.Equal(t, items[0].UUID, n1UUID, "uuid mismatch")
+		assert.Equal(t, items[0].BookLabel, "js", "book label mismatch")
+		assert.Equal(t, items[0].Content, "n1 body", "content mismatch")
+
+		testutils.RunDnoteCmd(t, opts, binaryName, "trash", "restore", strconv.Itoa(items[0].ID))
+
+		// Test
+		var n1 database.Note
+		database.MustScan(t, "getting n1", db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE uuid = ?", n1UUID), &n1.Body, &n1.Deleted, &n1.Dirty)
+		assert.Equal(t, n1.Body, "n1 body", "n1 body mismatch")
+		assert.Equal(t, n1.Deleted, false, "n1 deleted mismatch")
+		assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+
+		var trashCount int
+		database.MustScan(t, "counting trash", db.QueryRow("SELECT count(*) FROM trash"), &trashCount)
+		assert.Equal(t, trashCount, 0, "trash count mismatch")
+	})
+
+	t.Run("restore a book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+
+		var itemID int
+		database.MustScan(t, "getting the trash item", db.QueryRow("SELECT rowid FROM trash WHERE kind = ?", "book"), &itemID)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "trash", "restore", strconv.Itoa(itemID))
+
+		// Test
+		var b1 database.Book
+		database.MustScan(t, "getting b1", db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Label, &b1.Deleted, &b1.Dirty)
+		assert.Equal(t, b1.Label, "js", "b1 label mismatch")
+		assert.Equal(t, b1.Deleted, false, "b1 deleted mismatch")
+		assert.Equal(t, b1.Dirty, true, "b1 dirty mismatch")
+
+		var activeCount int
+		database.MustScan(t, "counting active notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND deleted = false AND body != ''", "js-book-uuid"), &activeCount)
+		assert.Equal(t, activeCount, 2, "active note count mismatch")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "trash", "empty", "-y")
+
+		// Test
+		var trashCount int
+		database.MustScan(t, "counting trash", db.QueryRow("SELECT count(*) FROM trash"), &trashCount)
+		assert.Equal(t, trashCount, 0, "trash count mismatch")
+
+		var n1Deleted bool
+		database.MustScan(t, "getting n1", db.QueryRow("SELECT deleted FROM notes WHERE uuid = ?", "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"), &n1Deleted)
+		assert.Equal(t, n1Deleted, true, "n1 deleted mismatch")
+	})
+}
+
+func TestBulkNotes(t *testing.T) {
+	n1UUID := "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f"
+	n2UUID := "43827b9a-c2b0-4c06-a290-97991c896653"
+	n3UUID := "3e065d55-6d47-42f2-a6bf-f5844130b2d2"
+
+	t.Run("remove a range", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1-2")
+
+		// Test
+		var n1, n2, n3 database.Note
+		database.MustScan(t, "getting n1", db.QueryRow("SELECT deleted, dirty FROM notes WHERE uuid = ?", n1UUID), &n1.Deleted, &n1.Dirty)
+		database.MustScan(t, "getting n2", db.QueryRow("SELECT deleted, dirty FROM notes WHERE uuid = ?", n2UUID), &n2.Deleted, &n2.Dirty)
+		database.MustScan(t, "getting n3", db.QueryRow("SELECT deleted, dirty FROM notes WHERE uuid = ?", n3UUID), &n3.Deleted, &n3.Dirty)
+		assert.Equal(t, n1.Deleted, true, "n1 deleted mismatch")
+		assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+		assert.Equal(t, n2.Deleted, true, "n2 deleted mismatch")
+		assert.Equal(t, n2.Dirty, true, "n2 dirty mismatch")
+		assert.Equal(t, n3.Deleted, false, "n3 deleted mismatch")
+		assert.Equal(t, n3.Dirty, false, "n3 dirty mismatch")
+
+		var trashCount int
+		database.MustScan(t, "counting trash", db.QueryRow("SELECT count(*) FROM trash"), &trashCount)
+		assert.Equal(t, trashCount, 2, "trash count mismatch")
+	})
+
+	t.Run("remove by a query", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "--query", "body -book:js")
+
+		// Test
+		var deletedCount int
+		database.MustScan(t, "counting removed notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted"), &deletedCount)
+		assert.Equal(t, deletedCount, 1, "removed note count mismatch")
+
+		var n3Deleted bool
+		database.MustScan(t, "getting n3", db.QueryRow("SELECT deleted FROM notes WHERE uuid = ?", n3UUID), &n3Deleted)
+		assert.Equal(t, n3Deleted, true, "n3 deleted mismatch")
+	})
+
+	t.Run("move ids", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "3", "-b", "linux", "-y")
+
+		// Test
+		var n1, n2, n3 database.Note
+		database.MustScan(t, "getting n1", db.QueryRow("SELECT book_uuid, dirty FROM notes WHERE uuid = ?", n1UUID), &n1.BookUUID, &n1.Dirty)
+		database.MustScan(t, "getting n2", db.QueryRow("SELECT book_uuid, dirty FROM notes WHERE uuid = ?", n2UUID), &n2.BookUUID, &n2.Dirty)
+		database.MustScan(t, "getting n3", db.QueryRow("SELECT book_uuid, dirty FROM notes WHERE uuid = ?", n3UUID), &n3.BookUUID, &n3.Dirty)
+		assert.Equal(t, n1.BookUUID, "linux-book-uuid", "n1 book mismatch")
+		assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+		assert.Equal(t, n2.BookUUID, "js-book-uuid", "n2 book mismatch")
+		assert.Equal(t, n2.Dirty, false, "n2 dirty mismatch")
+		assert.Equal(t, n3.BookUUID, "linux-book-uuid", "n3 book mismatch")
+		assert.Equal(t, n3.Dirty, false, "n3 was already in the book and should not be changed")
+	})
+
+	t.Run("move by a query with confirmation", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, testutils.UserConfirm, binaryName, "edit", "--query", "book:js", "-b", "linux")
+
+		// Test
+		var movedCount int
+		database.MustScan(t, "counting notes in linux", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND dirty", "linux-book-uuid"), &movedCount)
+		assert.Equal(t, movedCount, 2, "moved note count mismatch")
+	})
+
+	t.Run("remove a book named like a range", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "1-2", "-c", "range note")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1-2")
+
+		// Test
+		var bookCount int
+		database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books WHERE label = ? AND deleted = false", "1-2"), &bookCount)
+		assert.Equal(t, bookCount, 0, "the book should be removed")
+
+		var deletedCount int
+		database.MustScan(t, "counting removed notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted AND uuid IN (?, ?)", n1UUID, n2UUID), &deletedCount)
+		assert.Equal(t, deletedCount, 0, "the notes in the range should not be removed")
+	})
+
+	t.Run("ids with a query", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "remove", "-y", "1-3", "--query", "body")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		if err := cmd.Run(); err == nil {
+			t.Fatal("combining ids with a query should fail")
+		}
+
+		var deletedCount int
+		database.MustScan(t, "counting removed notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted"), &deletedCount)
+		assert.Equal(t, deletedCount, 0, "no note should be removed")
+	})
+}
+
+func TestNestedBooks(t *testing.T) {
+	setup := func(t *testing.T) *database.DB {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		for _, label := range []string{"work/infra/k8s", "work", "work-old", "js"} {
+			testutils.RunDnoteCmd(t, opts, binaryName, "add", label, "-c", fmt.Sprintf("deployment notes in %s", label))
+		}
+
+		return db
+	}
+
+	t.Run("view a subtree", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "work/", "--name-only")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		// Test
+		assert.Equal(t, stdout.String(), "work\nwork/infra/k8s\n", "output mismatch")
+	})
+
+	t.Run("find in a subtree", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "find", "deployment", "-b", "work/", "--output", "tsv")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		// Test
+		labels := []string{}
+		for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+			labels = append(labels, strings.Split(line, "\t")[2])
+		}
+		sort.Strings(labels)
+		assert.DeepEqual(t, labels, []string{"work", "work/infra/k8s"}, "book labels mismatch")
+	})
+
+	t.Run("rename a parent", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "work", "-n", "job")
+
+		// Test
+		var labels []string
+		rows, err := db.Query("SELECT label FROM books WHERE deleted = false ORDER BY label")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "querying books"))
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var label string
+			if err := rows.Scan(&label); err != nil {
+				t.Fatal(errors.Wrap(err, "scanning a row"))
+			}
+			labels = append(labels, label)
+		}
+
+		assert.DeepEqual(t, labels, []string{"job", "job/infra/k8s", "js", "work-old"}, "book labels mismatch")
+	})
+}
+
+func TestAddTemplate(t *testing.T) {
+	// Setup
+	// run an arbitrary command to initialize the config file
+	testutils.RunDnoteCmd(t, opts, binaryName, "view")
+	defer testutils.RemoveDir(t, testDir)
+
+	configPath := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.ConfigFilename)
+	cf, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the config"))
+	}
+	// use an editor that leaves the content as it is
+	editor, err := exec.LookPath("true")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "finding the editor"))
+	}
+	var lines []string
+	for _, line := range strings.Split(string(cf), "\n") {
+		if strings.HasPrefix(line, "editor:") {
+			line = fmt.Sprintf("editor: %s", editor)
+		}
+		lines = append(lines, line)
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the config"))
+	}
+
+	templatesDir := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.TemplatesDirName)
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the templates directory"))
+	}
+	tmpl := "# {{prompt \"Title\"}}\n\nbook: {{.Book}}\n"
+	if err := os.WriteFile(fmt.Sprintf("%s/incident.%s", templatesDir, consts.TemplateFileExt), []byte(tmpl), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the template"))
+	}
+
+	// Execute
+	cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "template", "ls")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+	assert.Equal(t, stdout.String(), "incident\n", "template list mismatch")
+
+	cmd, stderr, _, err = testutils.NewDnoteCmd(opts, binaryName, "add", "ops", "--template", "incident")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	cmd.Stdin = strings.NewReader("database is down\n")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+
+	// Test
+	db := database.OpenTestDB(t, testDir)
+
+	var body string
+	database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body FROM notes INNER JOIN books ON books.uuid = notes.book_uuid WHERE books.label = ?", "ops"), &body)
+	assert.Equal(t, body, "# database is down\n\nbook: ops\n", "note body mismatch")
+}
+
+func TestNoteLinks(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "golang", "-c", "# Channels\nsend and receive")
+
+	var n1UUID string
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", 1), &n1UUID)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "linux", "-c", "see [[golang/channels]]")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", fmt.Sprintf("[[%s]] and [[js/missing]]", n1UUID[:8]))
+
+	type link struct {
+		Target   string `json:"target"`
+		ID       int    `json:"id"`
+		Dangling bool   `json:"dangling"`
+	}
+	type linksResult struct {
+		Links     []link `json:"links"`
+		Backlinks []link `json:"backlinks"`
+	}
+
+	getLinks := func(t *testing.T, id string) linksResult {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "links", id, "--output", "json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		var ret linksResult
+		testutils.MustUnmarshalJSON(t, stdout.Bytes(), &ret)
+
+		return ret
+	}
+
+	t.Run("backlinks", func(t *testing.T) {
+		result := getLinks(t, "1")
+
+		assert.DeepEqual(t, result.Links, []link{}, "links mismatch")
+		assert.DeepEqual(t, result.Backlinks, []link{
+			{Target: "golang/channels", ID: 2},
+			{Target: n1UUID[:8], ID: 3},
+		}, "backlinks mismatch")
+	})
+
+	t.Run("links", func(t *testing.T) {
+		result := getLinks(t, "3")
+
+		assert.DeepEqual(t, result.Links, []link{
+			{Target: n1UUID[:8], ID: 1},
+			{Target: "js/missing", Dangling: true},
+		}, "links mismatch")
+	})
+
+	t.Run("view resolves links", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "2")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		assert.Equal(t, strings.Contains(stdout.String(), "(1) (golang) Channels [[golang/channels]]"), true, "resolved link not found in output")
+	})
+
+	t.Run("edit updates links", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "3", "-c", "no links")
+
+		result := getLinks(t, "1")
+		assert.DeepEqual(t, result.Backlinks, []link{{Target: "golang/channels", ID: 2}}, "backlinks mismatch")
+	})
+
+	t.Run("remove a linked note", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "remove", "1", "-y")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		assert.Equal(t, strings.Contains(stdout.String(), "note 2 now has a dangling link [[golang/channels]]"), true, "dangling link warning not found in output")
+
+		result := getLinks(t, "2")
+		assert.DeepEqual(t, result.Links, []link{{Target: "golang/channels", Dangling: true}}, "links mismatch")
+	})
+}
+
+func TestAttachments(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+
+	srcPath := fmt.Sprintf("%s/diagram.txt", testDir)
+	if err := ioutil.WriteFile(srcPath, []byte("foo"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the source file"))
+	}
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "attach", "1", srcPath)
+	// the same content is stored once
+	testutils.RunDnoteCmd(t, opts, binaryName, "attach", "1", srcPath)
+
+	t.Run("attach", func(t *testing.T) {
+		var count, dirtyCount int
+		database.MustScan(t, "counting attachments", db.QueryRow("SELECT count(*) FROM attachments"), &count)
+		database.MustScan(t, "counting dirty attachments", db.QueryRow("SELECT count(*) FROM attachments WHERE dirty"), &dirtyCount)
+		assert.Equal(t, count, 2, "count mismatch")
+		assert.Equal(t, dirtyCount, 2, "dirtyCount mismatch")
+
+		blobDir := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.AttachmentsDirName)
+		b, err := ioutil.ReadFile(fmt.Sprintf("%s/2c/2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", blobDir))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the blob"))
+		}
+		assert.Equal(t, string(b), "foo", "blob mismatch")
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "attachments", "1", "--output", "json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		type attachment struct {
+			ID     int    `json:"id"`
+			NoteID int    `json:"note_id"`
+			Name   string `json:"name"`
+			Size   int64  `json:"size"`
+		}
+		var result []attachment
+		testutils.MustUnmarshalJSON(t, stdout.Bytes(), &result)
+
+		assert.DeepEqual(t, result, []attachment{
+			{ID: 1, NoteID: 1, Name: "diagram.txt", Size: 3},
+			{ID: 2, NoteID: 1, Name: "diagram.txt", Size: 3},
+		}, "result mismatch")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		dstPath := fmt.Sprintf("%s/saved.txt", testDir)
+		testutils.RunDnoteCmd(t, opts, binaryName, "attachment", "get", "1", dstPath)
+
+		b, err := ioutil.ReadFile(dstPath)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the saved file"))
+		}
+		assert.Equal(t, string(b), "foo", "saved content mismatch")
+
+		// an existing file is not overwritten
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "attachment", "get", "1", dstPath)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err == nil {
+			t.Fatal("saving to an existing file should fail")
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "profile", "add", "work", "--api-endpoint", "https://dnote.example.com/api")
+
+	// Execute
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "infra", "-c", "deploy steps", "--profile", "work")
+
+	envOpts := testutils.RunDnoteCmdOptions{
+		Env: append(opts.Env, fmt.Sprintf("%s=work", consts.ProfileEnvName)),
+	}
+	testutils.RunDnoteCmd(t, envOpts, binaryName, "add", "infra", "-c", "rollback steps")
+
+	// Test
+	var noteCount int
+	database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+	assert.Equal(t, noteCount, 1, "default profile note count mismatch")
+
+	workDB, err := database.Open(fmt.Sprintf("%s/%s/%s/work/%s", testDir, consts.DnoteDirName, consts.ProfilesDirName, consts.DnoteDBFileName))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the database of the work profile"))
+	}
+	defer workDB.Close()
+
+	var workNoteCount, workBookCount int
+	database.MustScan(t, "counting work notes", workDB.QueryRow("SELECT count(*) FROM notes"), &workNoteCount)
+	database.MustScan(t, "counting work books", workDB.QueryRow("SELECT count(*) FROM books WHERE label = ?", "infra"), &workBookCount)
+	assert.Equal(t, workNoteCount, 2, "work profile note count mismatch")
+	assert.Equal(t, workBookCount, 1, "work profile book count mismatch")
+
+	t.Run("use", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "profile", "use", "work")
+
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "profile", "ls")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		assert.Equal(t, strings.Contains(stdout.String(), "* work"), true, "work profile should be in use")
+		assert.Equal(t, strings.Contains(stdout.String(), "  default"), true, "default profile should be listed")
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "view", "--profile", "nonexistent")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err == nil {
+			t.Error("expected an error for an unknown profile")
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	// Setup
+	testutils.RunDnoteCmd(t, opts, binaryName, "view")
+	defer testutils.RemoveDir(t, testDir)
+
+	runConfig := func(t *testing.T, o testutils.RunDnoteCmdOptions, arg ...string) (string, error) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(o, binaryName, append([]string{"config"}, arg...)...)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			return stderr.String(), err
+		}
+
+		return stdout.String(), nil
+	}
+
+	t.Run("set and get", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "config", "set", "revisionLimit", "7")
+
+		out, err := runConfig(t, opts, "get", "revisionLimit")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, out))
+		}
+		assert.Equal(t, out, "7\n", "revisionLimit mismatch")
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		if _, err := runConfig(t, opts, "set", "apiEndpoint", "not-a-url"); err == nil {
+			t.Error("expected an error for an invalid URL")
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "config", "unset", "revisionLimit")
+
+		out, err := runConfig(t, opts, "get", "revisionLimit")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, out))
+		}
+		assert.Equal(t, out, fmt.Sprintf("%d\n", consts.DefaultRevisionLimit), "revisionLimit mismatch")
+	})
+
+	t.Run("env override", func(t *testing.T) {
+		envOpts := testutils.RunDnoteCmdOptions{
+			Env: append(opts.Env, "DNOTE_EDITOR=nano"),
+		}
+
+		out, err := runConfig(t, envOpts, "list", "--show-origin")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, out))
+		}
+		assert.Equal(t, strings.Contains(out, "env:DNOTE_EDITOR\teditor=nano\n"), true, "editor should come from the environment")
+		assert.Equal(t, strings.Contains(out, "file:"), true, "other settings should come from the config file")
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	// Setup
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "work/infra", "-c", "deploy steps")
+	defer testutils.RemoveDir(t, testDir)
+
+	complete := func(t *testing.T, arg ...string) string {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, append([]string{"__complete"}, arg...)...)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+		}
+
+		return stdout.String()
+	}
+
+	t.Run("books", func(t *testing.T) {
+		out := complete(t, "add", "w")
+		assert.Equal(t, out, "work/infra\n:4\n", "output mismatch")
+	})
+
+	t.Run("books and notes", func(t *testing.T) {
+		out := complete(t, "edit", "")
+		assert.Equal(t, out, "js\nwork/infra\n1\t(js) closures\n2\t(work/infra) deploy steps\n:4\n", "output mismatch")
+	})
+
+	t.Run("book flag", func(t *testing.T) {
+		out := complete(t, "find", "-b", "j")
+		assert.Equal(t, out, "js\n:4\n", "output mismatch")
+	})
+
+	t.Run("scripts", func(t *testing.T) {
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			testutils.RunDnoteCmd(t, opts, binaryName, "completion", shell)
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	// Setup
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "promises")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "css", "-c", "flexbox")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Execute
+	cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "stats", "--output", "json", "--period", "month", "--count", "1")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+
+	// Test
+	var report struct {
+		NoteCount    int `json:"note_count"`
+		BookCount    int `json:"book_count"`
+		LargestBooks []struct {
+			Label     string `json:"label"`
+			NoteCount int    `json:"note_count"`
+		} `json:"largest_books"`
+		Activity []struct {
+			Added int `json:"added"`
+		} `json:"activity"`
+		DirtyNotes int   `json:"dirty_notes"`
+		LastSyncAt int64 `json:"last_sync_at"`
+	}
+	testutils.MustUnmarshalJSON(t, stdout.Bytes(), &report)
+
+	assert.Equal(t, report.NoteCount, 3, "note count mismatch")
+	assert.Equal(t, report.BookCount, 2, "book count mismatch")
+	assert.Equal(t, report.LargestBooks[0].Label, "js", "largest book mismatch")
+	assert.Equal(t, report.LargestBooks[0].NoteCount, 2, "largest book note count mismatch")
+	assert.Equal(t, len(report.Activity), 1, "activity length mismatch")
+	assert.Equal(t, report.Activity[0].Added, 3, "added count mismatch")
+	assert.Equal(t, report.DirtyNotes, 3, "dirty note count mismatch")
+	assert.Equal(t, report.LastSyncAt, int64(0), "last sync mismatch")
+}
+
+func TestReview(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "promises")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "css", "-c", "flexbox")
+
+	// Execute
+	grades := func(stdin io.WriteCloser) error {
+		if _, err := io.WriteString(stdin, "good\ns\n1\n"); err != nil {
+			return errors.Wrap(err, "writing grades to stdin")
+		}
+
+		return stdin.Close()
+	}
+	testutils.WaitDnoteCmd(t, opts, grades, binaryName, "review", "-b", "js")
+	testutils.WaitDnoteCmd(t, opts, grades, binaryName, "review", "--limit", "2")
+
+	// Test
+	type noteReview struct {
+		interval    int
+		repetitions int
+		lapses      int
+	}
+	getReview := func(body string) (noteReview, bool) {
+		var r noteReview
+		err := db.QueryRow(`SELECT note_reviews.interval, note_reviews.repetitions, note_reviews.lapses
+			FROM note_reviews INNER JOIN notes ON notes.uuid = note_reviews.note_uuid
+			WHERE notes.body = ?`, body).Scan(&r.interval, &r.repetitions, &r.lapses)
+		if err != nil {
+			return r, false
+		}
+
+		return r, true
+	}
+
+	// in the first review, closures is graded good and promises is skipped.
+	// in the second review, promises is graded good and the daily limit is reached.
+	r1, ok := getReview("closures")
+	assert.Equal(t, ok, true, "closures should be reviewed")
+	assert.Equal(t, r1, noteReview{interval: 1, repetitions: 1}, "closures review mismatch")
+
+	r2, ok := getReview("promises")
+	assert.Equal(t, ok, true, "promises should be reviewed")
+	assert.Equal(t, r2, noteReview{interval: 1, repetitions: 1}, "promises review mismatch")
+
+	_, ok = getReview("flexbox")
+	assert.Equal(t, ok, false, "flexbox should not be reviewed")
+}
+
+func TestBackup(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	backupDir := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.BackupsDirName)
+	listBackups := func() []string {
+		entries, err := os.ReadDir(backupDir)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the backup directory"))
+		}
+
+		ret := []string{}
+		for _, e := range entries {
+			ret = append(ret, e.Name())
+		}
+
+		return ret
+	}
+
+	// the first run backs up the database before the migrations
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	assert.Equal(t, len(listBackups()), 1, "backup count mismatch after the migrations")
+
+	// Execute
+	testutils.RunDnoteCmd(t, opts, binaryName, "backup")
+	backups := listBackups()
+	assert.Equal(t, len(backups), 2, "backup count mismatch after the backup")
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "promises")
+	testutils.RunDnoteCmd(t, opts, binaryName, "restore-backup", backups[1], "-y")
+
+	// Test
+	var noteCount int
+	database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+	assert.Equal(t, noteCount, 1, "note count mismatch after the restore")
+	assert.Equal(t, len(listBackups()), 3, "the replaced database should be backed up")
+
+	limitOpts := testutils.RunDnoteCmdOptions{
+		Env: append(opts.Env, "DNOTE_BACKUP_LIMIT=2"),
+	}
+	testutils.RunDnoteCmd(t, limitOpts, binaryName, "backup")
+	assert.Equal(t, len(listBackups()), 2, "backup count mismatch after the rotation")
+}
+
+func TestDoctor(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "doctor")
+
+	database.MustExec(t, "moving the note to a missing book", db, "UPDATE notes SET book_uuid = ?", "missing-uuid")
+
+	// Execute
+	cmd, _, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "doctor")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err == nil {
+		t.Fatal("doctor should fail when it finds problems")
+	}
+	assert.Equal(t, strings.Contains(stdout.String(), "note 1 is in a book that does not exist"), true, "the problem should be reported")
+	assert.Equal(t, strings.Contains(stdout.String(), "found 1 problems"), true, "the error should be printed")
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "doctor", "--fix")
+
+	// Test
+	var label string
+	database.MustScan(t, "getting the book of the note", db.QueryRow(`SELECT books.label FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid`), &label)
+	assert.Equal(t, label, "recovered", "label mismatch")
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "doctor")
+}
+
+func TestEncryption(t *testing.T) {
+	// Setup
+	dbPath := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "secret closures")
+	testutils.RunDnoteCmd(t, opts, binaryName, "backup")
+
+	// Execute
+	testutils.WaitDnoteCmd(t, opts, func(stdin io.WriteCloser) error {
+		_, err := io.WriteString(stdin, "pass\n")
+		return err
+	}, binaryName, "encryption", "enable", "--passphrase-stdin")
+
+	// Test
+	b, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the database"))
+	}
+	assert.Equal(t, strings.Contains(string(b), "secret"), false, "the database should not contain the plaintext")
+
+	header, encrypted, err := database.ReadEncryptionHeader(dbPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the header"))
+	}
+	assert.Equal(t, encrypted, true, "the database should be encrypted")
+
+	backups, err := os.ReadDir(fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.BackupsDirName))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the backup directory"))
+	}
+	for _, e := range backups {
+		_, encrypted, err := database.ReadEncryptionHeader(fmt.Sprintf("%s/%s/%s/%s", testDir, consts.DnoteDirName, consts.BackupsDirName, e.Name()))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the header of a backup"))
+		}
+		assert.Equal(t, encrypted, true, "the backups should be encrypted")
+	}
+
+	// without a key
+	cmd, _, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "find", "closures")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err == nil {
+		t.Fatal("a command should fail without the key")
+	}
+	assert.Equal(t, strings.Contains(stdout.String(), "the database is encrypted"), true, "the error should be printed")
+
+	// with the key in the environment
+	key, err := header.DeriveKey("pass")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "deriving the key"))
+	}
+	keyOpts := testutils.RunDnoteCmdOptions{
+		Env: append(opts.Env, fmt.Sprintf("%s=%s", consts.EncryptionKeyEnvName, base64.StdEncoding.EncodeToString(key))),
+	}
+
+	cmd, _, stdout, err = testutils.NewDnoteCmd(keyOpts, binaryName, "find", "closures", "--output", "tsv")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrap(err, "finding"))
+	}
+	assert.Equal(t, strings.Contains(stdout.String(), "secret closures"), true, "the note should be found")
+
+	cmd, _, stdout, err = testutils.NewDnoteCmd(keyOpts, binaryName, "encryption", "unlock", "--print")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err == nil {
+		t.Fatal("printing the key without a terminal should need --yes")
+	}
+	assert.Equal(t, strings.Contains(stdout.String(), base64.StdEncoding.EncodeToString(key)), false, "the key should not be printed")
+
+	cmd, _, stdout, err = testutils.NewDnoteCmd(keyOpts, binaryName, "encryption", "unlock", "--print", "--yes")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(errors.Wrap(err, "printing the key"))
+	}
+	assert.Equal(t, strings.TrimSpace(stdout.String()), base64.StdEncoding.EncodeToString(key), "key mismatch")
+
+	// locking does not need the key
+	testutils.RunDnoteCmd(t, opts, binaryName, "encryption", "lock")
+
+	testutils.RunDnoteCmd(t, keyOpts, binaryName, "add", "js", "-c", "secret promises")
+	testutils.RunDnoteCmd(t, keyOpts, binaryName, "encryption", "disable", "-y")
+
+	_, encrypted, err = database.ReadEncryptionHeader(dbPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the header"))
+	}
+	assert.Equal(t, encrypted, false, "the database should be decrypted")
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening the database"))
+	}
+	defer db.Close()
+
+	var noteCount int
+	database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+	assert.Equal(t, noteCount, 2, "note count mismatch")
+}
@@ -21,6 +21,7 @@ package migrate
 import (
 	"database/sql"
 
+	"github.com/dnote/dnote/pkg/cli/backup"
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/log"
@@ -49,6 +50,16 @@ var LocalSequence = []migration{
 	lm11,
 	lm12,
 	lm13,
+	lm14,
+	lm15,
+	lm16,
+	lm17,
+	lm18,
+	lm19,
+	lm20,
+	lm21,
+	lm22,
+	lm23,
 }
 
 // RemoteSequence is a list of remote migrations to be run
@@ -131,6 +142,20 @@ func execute(ctx context.DnoteCtx, m migration, schemaKey string) error {
 	return nil
 }
 
+func backupBeforeRun(ctx context.DnoteCtx) error {
+	path, err := backup.Create(ctx)
+	if err != nil {
+		return err
+	}
+	log.Debug("backed up the database to %s\n", path)
+
+	if _, err := backup.Rotate(ctx, ctx.BackupLimit); err != nil {
+		return errors.Wrap(err, "rotating the backups")
+	}
+
+	return nil
+}
+
 // Run performs unrun migrations
 func Run(ctx context.DnoteCtx, migrations []migration, mode int) error {
 	schemaKey, err := getSchemaKey(mode)
@@ -147,6 +172,13 @@ func Run(ctx context.DnoteCtx, migrations []migration, mode int) error {
 
 	toRun := migrations[schema:]
 
+	// Back up the database before changing it, unless it has just been created
+	if schema > 0 && len(toRun) > 0 {
+		if err := backupBeforeRun(ctx); err != nil {
+			return errors.Wrap(err, "backing up the database")
+		}
+	}
+
 	for _, m := range toRun {
 		if err := execute(ctx, m, schemaKey); err != nil {
 			return errors.Wrap(err, "running migration")
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 
 	"github.com/dnote/actions"
 	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/backup"
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
@@ -175,6 +177,20 @@ func TestRun_nonfresh(t *testing.T) {
 			var testRun1, testRun2 string
 			database.MustScan(t, "finding test run 1", db.QueryRow("SELECT name FROM migrate_run_test WHERE name = ?", "v3"), &testRun1)
 			database.MustScan(t, "finding test run 2", db.QueryRow("SELECT name FROM migrate_run_test WHERE name = ?", "v4"), &testRun2)
+
+			backups, err := backup.List(ctx)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "listing backups"))
+			}
+			assert.Equal(t, len(backups), 1, "the database should be backed up before the migrations")
+
+			if tc.mode == LocalMode {
+				backupSchema, err := backup.GetSchema(ctx, filepath.Join(backup.GetDir(ctx), backups[0]))
+				if err != nil {
+					t.Fatal(errors.Wrap(err, "getting the schema of the backup"))
+				}
+				assert.Equal(t, backupSchema, 2, "backup schema mismatch")
+			}
 		}()
 	}
 }
@@ -1150,6 +1166,441 @@ func TestLocalMigration13(t *testing.T) {
 	assert.Equal(t, cf.EnableUpgradeCheck, true, "enableUpgradeCheck mismatch")
 }
 
+func TestLocalMigration14(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body #Go #sqlite see #123", 1, 2, false, false, 20, false)
+	n2UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n2", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n2UUID, b1UUID, "n2 body", 3, 4, false, false, 21, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm14.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	var n1TagCount, n2TagCount int
+	database.MustScan(t, "counting n1 tags", db.QueryRow("SELECT count(*) FROM note_tags WHERE note_uuid = ?", n1UUID), &n1TagCount)
+	database.MustScan(t, "counting n2 tags", db.QueryRow("SELECT count(*) FROM note_tags WHERE note_uuid = ?", n2UUID), &n2TagCount)
+	assert.Equal(t, n1TagCount, 2, "n1TagCount mismatch")
+	assert.Equal(t, n2TagCount, 0, "n2TagCount mismatch")
+
+	var n1Dirty bool
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT dirty FROM notes WHERE uuid = ?", n1UUID), &n1Dirty)
+	assert.Equal(t, n1Dirty, false, "n1Dirty mismatch")
+
+	// the triggers should keep the tags in sync with the notes
+	newUUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "updating n1 uuid", db, "UPDATE notes SET uuid = ? WHERE uuid = ?", newUUID, n1UUID)
+
+	var newTagCount int
+	database.MustScan(t, "counting tags after uuid update", db.QueryRow("SELECT count(*) FROM note_tags WHERE note_uuid = ?", newUUID), &newTagCount)
+	assert.Equal(t, newTagCount, 2, "newTagCount mismatch")
+
+	database.MustExec(t, "deleting n1", db, "DELETE FROM notes WHERE uuid = ?", newUUID)
+
+	var tagCount int
+	database.MustScan(t, "counting tags after delete", db.QueryRow("SELECT count(*) FROM note_tags"), &tagCount)
+	assert.Equal(t, tagCount, 0, "tagCount mismatch")
+}
+
+func TestLocalMigration15(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body", 1, 0, false, false, 20, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm15.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	database.MustExec(t, "updating n1 body", db, "UPDATE notes SET body = ?, edited_on = ? WHERE uuid = ?", "n1 body v2", 2, n1UUID)
+	database.MustExec(t, "updating n1 dirty", db, "UPDATE notes SET dirty = ? WHERE uuid = ?", true, n1UUID)
+	database.MustExec(t, "updating n1 body again", db, "UPDATE notes SET body = ?, edited_on = ? WHERE uuid = ?", "n1 body v3", 3, n1UUID)
+
+	revisions, err := database.GetNoteRevisions(db, n1UUID)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting revisions"))
+	}
+
+	assert.Equal(t, len(revisions), 2, "revision count mismatch")
+	assert.Equal(t, revisions[0].Body, "n1 body v2", "revisions[0] body mismatch")
+	assert.Equal(t, revisions[0].EditedOn, int64(2), "revisions[0] edited_on mismatch")
+	assert.Equal(t, revisions[1].Body, "n1 body", "revisions[1] body mismatch")
+	assert.Equal(t, revisions[1].EditedOn, int64(1), "revisions[1] edited_on mismatch")
+
+	// the triggers should keep the revisions in sync with the notes
+	newUUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "updating n1 uuid", db, "UPDATE notes SET uuid = ? WHERE uuid = ?", newUUID, n1UUID)
+
+	var newRevisionCount int
+	database.MustScan(t, "counting revisions after uuid update", db.QueryRow("SELECT count(*) FROM note_revisions WHERE note_uuid = ?", newUUID), &newRevisionCount)
+	assert.Equal(t, newRevisionCount, 2, "newRevisionCount mismatch")
+
+	database.MustExec(t, "deleting n1", db, "DELETE FROM notes WHERE uuid = ?", newUUID)
+
+	var revisionCount int
+	database.MustScan(t, "counting revisions after delete", db.QueryRow("SELECT count(*) FROM note_revisions"), &revisionCount)
+	assert.Equal(t, revisionCount, 0, "revisionCount mismatch")
+}
+
+func TestLocalMigration16(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	data := []byte("editor: vim\napiEndpoint: https://test.com/api\nenableUpgradeCheck: false")
+
+	path := fmt.Sprintf("%s/%s/dnoterc", ctx.Paths.Config, consts.DnoteDirName)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "Failed to write schema file"))
+	}
+
+	// execute
+	err := lm16.run(ctx, nil)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	// test
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading config"))
+	}
+
+	type config struct {
+		Editor             string `yaml:"editor"`
+		ApiEndpoint        string `yaml:"apiEndpoint"`
+		EnableUpgradeCheck bool   `yaml:"enableUpgradeCheck"`
+		RevisionLimit      int    `yaml:"revisionLimit"`
+	}
+
+	var cf config
+	err = yaml.Unmarshal(b, &cf)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "unmarshalling config"))
+	}
+
+	assert.Equal(t, cf.Editor, "vim", "editor mismatch")
+	assert.Equal(t, cf.ApiEndpoint, "https://test.com/api", "apiEndpoint mismatch")
+	assert.Equal(t, cf.EnableUpgradeCheck, false, "enableUpgradeCheck mismatch")
+	assert.Equal(t, cf.RevisionLimit, 50, "revisionLimit mismatch")
+}
+
+func TestLocalMigration17(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm17.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	database.MustExec(t, "inserting a trash item", db, `INSERT INTO trash (kind, uuid, label, body, parent_id, removed_on)
+		VALUES (?, ?, ?, ?, ?, ?)`, "note", "n1-uuid", "js", "n1 body", nil, 1)
+
+	var count int
+	database.MustScan(t, "counting trash items", db.QueryRow("SELECT count(*) FROM trash WHERE parent_id IS NULL"), &count)
+	assert.Equal(t, count, 1, "count mismatch")
+}
+
+func TestLocalMigration18(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	data := []byte("editor: vim\napiEndpoint: https://test.com/api\nrevisionLimit: 10")
+
+	path := fmt.Sprintf("%s/%s/dnoterc", ctx.Paths.Config, consts.DnoteDirName)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "Failed to write schema file"))
+	}
+
+	// execute
+	err := lm18.run(ctx, nil)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	// test
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading config"))
+	}
+
+	type config struct {
+		Editor             string `yaml:"editor"`
+		RevisionLimit      int    `yaml:"revisionLimit"`
+		TrashRetentionDays int    `yaml:"trashRetentionDays"`
+	}
+
+	var cf config
+	err = yaml.Unmarshal(b, &cf)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "unmarshalling config"))
+	}
+
+	assert.Equal(t, cf.Editor, "vim", "editor mismatch")
+	assert.Equal(t, cf.RevisionLimit, 10, "revisionLimit mismatch")
+	assert.Equal(t, cf.TrashRetentionDays, 30, "trashRetentionDays mismatch")
+}
+
+func TestLocalMigration19(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body [[b1/n2 body]] [[abcd1234]] [[word]]", 1, 2, false, false, 20, false)
+	n2UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n2", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n2UUID, b1UUID, "n2 body", 3, 4, false, false, 21, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm19.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	var n1LinkCount, n2LinkCount int
+	database.MustScan(t, "counting n1 links", db.QueryRow("SELECT count(*) FROM note_links WHERE note_uuid = ?", n1UUID), &n1LinkCount)
+	database.MustScan(t, "counting n2 links", db.QueryRow("SELECT count(*) FROM note_links WHERE note_uuid = ?", n2UUID), &n2LinkCount)
+	assert.Equal(t, n1LinkCount, 2, "n1LinkCount mismatch")
+	assert.Equal(t, n2LinkCount, 0, "n2LinkCount mismatch")
+
+	// the triggers should keep the links in sync with the notes
+	newUUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "updating n1 uuid", db, "UPDATE notes SET uuid = ? WHERE uuid = ?", newUUID, n1UUID)
+
+	var newLinkCount int
+	database.MustScan(t, "counting links after uuid update", db.QueryRow("SELECT count(*) FROM note_links WHERE note_uuid = ?", newUUID), &newLinkCount)
+	assert.Equal(t, newLinkCount, 2, "newLinkCount mismatch")
+
+	database.MustExec(t, "deleting n1", db, "DELETE FROM notes WHERE uuid = ?", newUUID)
+
+	var linkCount int
+	database.MustScan(t, "counting links after delete", db.QueryRow("SELECT count(*) FROM note_links"), &linkCount)
+	assert.Equal(t, linkCount, 0, "linkCount mismatch")
+}
+
+func TestLocalMigration20(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body", 1, 2, false, false, 20, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm20.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	a1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting a1", db, `INSERT INTO attachments
+		(uuid, note_uuid, name, hash, size, added_on, dirty) VALUES
+		(?, ?, ?, ?, ?, ?, ?)`, a1UUID, n1UUID, "a1.png", "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", 3, 1, true)
+
+	// the triggers should keep the attachments in sync with the notes
+	newUUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "updating n1 uuid", db, "UPDATE notes SET uuid = ? WHERE uuid = ?", newUUID, n1UUID)
+
+	var noteUUID string
+	database.MustScan(t, "getting the note uuid of a1", db.QueryRow("SELECT note_uuid FROM attachments WHERE uuid = ?", a1UUID), &noteUUID)
+	assert.Equal(t, noteUUID, newUUID, "noteUUID mismatch")
+
+	database.MustExec(t, "deleting n1", db, "DELETE FROM notes WHERE uuid = ?", newUUID)
+
+	var attachmentCount int
+	database.MustScan(t, "counting attachments after delete", db.QueryRow("SELECT count(*) FROM attachments"), &attachmentCount)
+	assert.Equal(t, attachmentCount, 0, "attachmentCount mismatch")
+}
+
+func TestLocalMigration21(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body", 1, 2, false, false, 20, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm21.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	database.MustExec(t, "inserting a review", db, `INSERT INTO note_reviews
+		(note_uuid, ease, interval, repetitions, lapses, due_on, reviewed_on) VALUES
+		(?, ?, ?, ?, ?, ?, ?)`, n1UUID, 2.5, 1, 1, 0, 2, 1)
+
+	// the triggers should keep the reviews in sync with the notes
+	newUUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "updating n1 uuid", db, "UPDATE notes SET uuid = ? WHERE uuid = ?", newUUID, n1UUID)
+
+	var noteUUID string
+	database.MustScan(t, "getting the note uuid of the review", db.QueryRow("SELECT note_uuid FROM note_reviews"), &noteUUID)
+	assert.Equal(t, noteUUID, newUUID, "noteUUID mismatch")
+
+	database.MustExec(t, "deleting n1", db, "DELETE FROM notes WHERE uuid = ?", newUUID)
+
+	var reviewCount int
+	database.MustScan(t, "counting reviews after delete", db.QueryRow("SELECT count(*) FROM note_reviews"), &reviewCount)
+	assert.Equal(t, reviewCount, 0, "reviewCount mismatch")
+}
+
+func TestLocalMigration22(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	data := []byte("editor: vim\napiEndpoint: https://test.com/api\nrevisionLimit: 10\ntrashRetentionDays: 7")
+
+	path := fmt.Sprintf("%s/%s/dnoterc", ctx.Paths.Config, consts.DnoteDirName)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "Failed to write schema file"))
+	}
+
+	// execute
+	err := lm22.run(ctx, nil)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	// test
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading config"))
+	}
+
+	type config struct {
+		Editor             string `yaml:"editor"`
+		TrashRetentionDays int    `yaml:"trashRetentionDays"`
+		BackupLimit        int    `yaml:"backupLimit"`
+	}
+
+	var cf config
+	err = yaml.Unmarshal(b, &cf)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "unmarshalling config"))
+	}
+
+	assert.Equal(t, cf.Editor, "vim", "editor mismatch")
+	assert.Equal(t, cf.TrashRetentionDays, 7, "trashRetentionDays mismatch")
+	assert.Equal(t, cf.BackupLimit, 10, "backupLimit mismatch")
+}
+
 func TestRemoteMigration1(t *testing.T) {
 	// set up
 	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/remote-1-pre-schema.sql", SkipMigration: true}
@@ -1224,3 +1675,50 @@ func TestRemoteMigration1(t *testing.T) {
 	assert.Equal(t, postCSSBookUUID, newCSSBookUUID, "css book uuid was not updated correctly")
 	assert.Equal(t, postLinuxBookUUID, linuxBookUUID, "linux book uuid changed")
 }
+
+func TestLocalMigration23(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-12-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	b1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", b1UUID, "b1")
+
+	n1UUID := testutils.MustGenerateUUID(t)
+	database.MustExec(t, "inserting n1", db, `INSERT INTO notes
+		(uuid, book_uuid, body, added_on, edited_on, public, dirty, usn, deleted) VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`, n1UUID, b1UUID, "n1 body", 1, 0, false, false, 20, false)
+
+	// Execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	if err := lm15.run(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "running lm15"))
+	}
+	if err := lm23.run(ctx, tx); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// Test
+	database.MustExec(t, "updating n1 body", db, "UPDATE notes SET body = ?, edited_on = ? WHERE uuid = ?", "n1 body v2", 2, n1UUID)
+	// removing the note clears the body
+	database.MustExec(t, "removing n1", db, "UPDATE notes SET body = ?, deleted = ? WHERE uuid = ?", "", true, n1UUID)
+
+	revisions, err := database.GetNoteRevisions(db, n1UUID)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting revisions"))
+	}
+
+	assert.Equal(t, len(revisions), 1, "revision count mismatch")
+	assert.Equal(t, revisions[0].Body, "n1 body", "revisions[0] body mismatch")
+}
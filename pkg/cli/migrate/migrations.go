@@ -28,9 +28,11 @@ import (
 	"github.com/dnote/actions"
 	"github.com/dnote/dnote/pkg/cli/client"
 	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/tags"
 	"github.com/pkg/errors"
 )
 
@@ -39,6 +41,13 @@ type migration struct {
 	run  func(ctx context.DnoteCtx, tx *database.DB) error
 }
 
+// skipConfigMigration checks if a migration of the config file should be
+// skipped. The config file is shared by all profiles and is migrated along
+// with the database of the default profile.
+func skipConfigMigration(ctx context.DnoteCtx) bool {
+	return ctx.Profile != ""
+}
+
 var lm1 = migration{
 	name: "upgrade-edit-note-from-v1-to-v3",
 	run: func(ctx context.DnoteCtx, tx *database.DB) error {
@@ -534,6 +543,10 @@ var lm11 = migration{
 var lm12 = migration{
 	name: "add apiEndpoint to the configuration file",
 	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		if skipConfigMigration(ctx) {
+			return nil
+		}
+
 		cf, err := config.Read(ctx)
 		if err != nil {
 			return errors.Wrap(err, "reading config")
@@ -553,6 +566,10 @@ var lm12 = migration{
 var lm13 = migration{
 	name: "add enableUpgradeCheck to the configuration file",
 	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		if skipConfigMigration(ctx) {
+			return nil
+		}
+
 		cf, err := config.Read(ctx)
 		if err != nil {
 			return errors.Wrap(err, "reading config")
@@ -569,6 +586,327 @@ var lm13 = migration{
 	},
 }
 
+var lm14 = migration{
+	name: "create-note-tags-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS note_tags
+		(
+			note_uuid text NOT NULL,
+			tag text NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_note_tags_note_uuid_tag ON note_tags(note_uuid, tag);
+		CREATE INDEX IF NOT EXISTS idx_note_tags_tag ON note_tags(tag);`)
+		if err != nil {
+			return errors.Wrap(err, "creating note_tags table")
+		}
+
+		// Keep the tags consistent when notes are deleted or assigned new uuids by sync
+		_, err = tx.Exec(`CREATE TRIGGER IF NOT EXISTS notes_after_delete_tags AFTER DELETE ON notes BEGIN
+				DELETE FROM note_tags WHERE note_uuid = old.uuid;
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_update_uuid AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_tags SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers")
+		}
+
+		// Tag the existing notes with the hashtags in their bodies
+		rows, err := tx.Query("SELECT uuid, body FROM notes WHERE deleted = false")
+		if err != nil {
+			return errors.Wrap(err, "querying notes")
+		}
+		defer rows.Close()
+
+		noteTags := map[string][]string{}
+		for rows.Next() {
+			var uuid, body string
+			if err := rows.Scan(&uuid, &body); err != nil {
+				return errors.Wrap(err, "scanning row")
+			}
+
+			if t := tags.Extract(body); len(t) > 0 {
+				noteTags[uuid] = t
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return errors.Wrap(err, "iterating notes")
+		}
+
+		for uuid, t := range noteTags {
+			if err := database.SetNoteTags(tx, uuid, t); err != nil {
+				return errors.Wrapf(err, "setting tags for note %s", uuid)
+			}
+		}
+
+		return nil
+	},
+}
+
+var lm15 = migration{
+	name: "create-note-revisions-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS note_revisions
+		(
+			note_uuid text NOT NULL,
+			body text NOT NULL,
+			edited_on integer NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_note_revisions_note_uuid ON note_revisions(note_uuid);`)
+		if err != nil {
+			return errors.Wrap(err, "creating note_revisions table")
+		}
+
+		// Record the prior body whenever the body of a note is replaced, and
+		// keep the revisions consistent when notes are deleted or assigned new
+		// uuids by sync
+		_, err = tx.Exec(`CREATE TRIGGER IF NOT EXISTS notes_after_update_body AFTER UPDATE OF body ON notes
+			WHEN old.body != new.body AND old.body != '' BEGIN
+				INSERT INTO note_revisions (note_uuid, body, edited_on)
+				VALUES (old.uuid, old.body, CASE WHEN old.edited_on = 0 THEN old.added_on ELSE old.edited_on END);
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_delete_revisions AFTER DELETE ON notes BEGIN
+				DELETE FROM note_revisions WHERE note_uuid = old.uuid;
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_update_uuid_revisions AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_revisions SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers")
+		}
+
+		return nil
+	},
+}
+
+var lm16 = migration{
+	name: "add revisionLimit to the configuration file",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		if skipConfigMigration(ctx) {
+			return nil
+		}
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		cf.RevisionLimit = consts.DefaultRevisionLimit
+
+		err = config.Write(ctx, cf)
+		if err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		return nil
+	},
+}
+
+var lm17 = migration{
+	name: "create-trash-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS trash
+		(
+			kind text NOT NULL,
+			uuid text NOT NULL,
+			label text NOT NULL,
+			body text NOT NULL,
+			parent_id integer,
+			removed_on integer NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_trash_parent_id ON trash(parent_id);`)
+		if err != nil {
+			return errors.Wrap(err, "creating trash table")
+		}
+
+		return nil
+	},
+}
+
+var lm18 = migration{
+	name: "add trashRetentionDays to the configuration file",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		if skipConfigMigration(ctx) {
+			return nil
+		}
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		cf.TrashRetentionDays = consts.DefaultTrashRetentionDays
+
+		err = config.Write(ctx, cf)
+		if err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		return nil
+	},
+}
+
+var lm19 = migration{
+	name: "create-note-links-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS note_links
+		(
+			note_uuid text NOT NULL,
+			target text NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_note_links_note_uuid_target ON note_links(note_uuid, target);`)
+		if err != nil {
+			return errors.Wrap(err, "creating note_links table")
+		}
+
+		// Keep the links consistent when notes are deleted or assigned new
+		// uuids by sync
+		_, err = tx.Exec(`CREATE TRIGGER IF NOT EXISTS notes_after_delete_links AFTER DELETE ON notes BEGIN
+				DELETE FROM note_links WHERE note_uuid = old.uuid;
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_update_uuid_links AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_links SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers")
+		}
+
+		// Index the links in the existing notes
+		rows, err := tx.Query("SELECT uuid, body FROM notes WHERE deleted = false")
+		if err != nil {
+			return errors.Wrap(err, "querying notes")
+		}
+		defer rows.Close()
+
+		bodies := map[string]string{}
+		for rows.Next() {
+			var uuid, body string
+			if err := rows.Scan(&uuid, &body); err != nil {
+				return errors.Wrap(err, "scanning row")
+			}
+
+			bodies[uuid] = body
+		}
+		if err := rows.Err(); err != nil {
+			return errors.Wrap(err, "iterating notes")
+		}
+
+		for uuid, body := range bodies {
+			if err := database.IndexNoteLinks(tx, uuid, body); err != nil {
+				return errors.Wrapf(err, "indexing links of note %s", uuid)
+			}
+		}
+
+		return nil
+	},
+}
+
+var lm20 = migration{
+	name: "create-attachments-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS attachments
+		(
+			uuid text NOT NULL,
+			note_uuid text NOT NULL,
+			name text NOT NULL,
+			hash text NOT NULL,
+			size integer NOT NULL,
+			added_on integer NOT NULL,
+			dirty bool DEFAULT false
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_uuid ON attachments(uuid);
+		CREATE INDEX IF NOT EXISTS idx_attachments_note_uuid ON attachments(note_uuid);`)
+		if err != nil {
+			return errors.Wrap(err, "creating attachments table")
+		}
+
+		_, err = tx.Exec(`CREATE TRIGGER IF NOT EXISTS notes_after_delete_attachments AFTER DELETE ON notes BEGIN
+				DELETE FROM attachments WHERE note_uuid = old.uuid;
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_update_uuid_attachments AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE attachments SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers")
+		}
+
+		return nil
+	},
+}
+
+var lm21 = migration{
+	name: "create-note-reviews-table",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS note_reviews
+		(
+			note_uuid text PRIMARY KEY,
+			ease real NOT NULL,
+			interval integer NOT NULL,
+			repetitions integer NOT NULL,
+			lapses integer NOT NULL,
+			due_on integer NOT NULL,
+			reviewed_on integer NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_note_reviews_due_on ON note_reviews(due_on);`)
+		if err != nil {
+			return errors.Wrap(err, "creating note_reviews table")
+		}
+
+		_, err = tx.Exec(`CREATE TRIGGER IF NOT EXISTS notes_after_delete_reviews AFTER DELETE ON notes BEGIN
+				DELETE FROM note_reviews WHERE note_uuid = old.uuid;
+			END;
+		CREATE TRIGGER IF NOT EXISTS notes_after_update_uuid_reviews AFTER UPDATE OF uuid ON notes BEGIN
+				UPDATE note_reviews SET note_uuid = new.uuid WHERE note_uuid = old.uuid;
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers")
+		}
+
+		return nil
+	},
+}
+
+var lm22 = migration{
+	name: "add backupLimit to the configuration file",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		if skipConfigMigration(ctx) {
+			return nil
+		}
+
+		cf, err := config.Read(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading config")
+		}
+
+		cf.BackupLimit = consts.DefaultBackupLimit
+
+		err = config.Write(ctx, cf)
+		if err != nil {
+			return errors.Wrap(err, "writing config")
+		}
+
+		return nil
+	},
+}
+
+var lm23 = migration{
+	name: "skip-revisions-of-removed-notes",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		// Removing a note clears its body, which is not an edit to record
+		_, err := tx.Exec(`DROP TRIGGER IF EXISTS notes_after_update_body;
+		CREATE TRIGGER notes_after_update_body AFTER UPDATE OF body ON notes
+			WHEN old.body != new.body AND old.body != '' AND new.body != '' BEGIN
+				INSERT INTO note_revisions (note_uuid, body, edited_on)
+				VALUES (old.uuid, old.body, CASE WHEN old.edited_on = 0 THEN old.added_on ELSE old.edited_on END);
+			END;`)
+		if err != nil {
+			return errors.Wrap(err, "replacing the trigger")
+		}
+
+		return nil
+	},
+}
+
 var rm1 = migration{
 	name: "sync-book-uuids-from-server",
 	run: func(ctx context.DnoteCtx, tx *database.DB) error {
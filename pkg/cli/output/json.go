@@ -0,0 +1,354 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/stats"
+	"github.com/pkg/errors"
+)
+
+// The types below define the JSON schema of the output. They are documented in
+// OUTPUT.md and must stay backward compatible.
+
+type noteJSON struct {
+	ID        int        `json:"id"`
+	UUID      string     `json:"uuid"`
+	BookLabel string     `json:"book_label"`
+	Content   string     `json:"content"`
+	AddedOn   int64      `json:"added_on"`
+	EditedOn  int64      `json:"edited_on"`
+	Tags      []string   `json:"tags"`
+	Links     []linkJSON `json:"links,omitempty"`
+}
+
+type bookJSON struct {
+	ID    int    `json:"id"`
+	UUID  string `json:"uuid"`
+	Label string `json:"label"`
+}
+
+type bookListItemJSON struct {
+	UUID      string `json:"uuid"`
+	Label     string `json:"label"`
+	NoteCount int    `json:"note_count"`
+}
+
+type findResultJSON struct {
+	ID        int    `json:"id"`
+	UUID      string `json:"uuid"`
+	BookLabel string `json:"book_label"`
+	Snippet   string `json:"snippet"`
+}
+
+type revisionJSON struct {
+	ID       int    `json:"id"`
+	NoteID   int    `json:"note_id"`
+	Content  string `json:"content"`
+	EditedOn int64  `json:"edited_on"`
+}
+
+type linkJSON struct {
+	Target    string `json:"target"`
+	ID        int    `json:"id"`
+	UUID      string `json:"uuid"`
+	BookLabel string `json:"book_label"`
+	Title     string `json:"title"`
+	Dangling  bool   `json:"dangling"`
+	Ambiguous bool   `json:"ambiguous"`
+}
+
+type linksJSON struct {
+	NoteID    int        `json:"note_id"`
+	Links     []linkJSON `json:"links"`
+	Backlinks []linkJSON `json:"backlinks"`
+}
+
+type attachmentJSON struct {
+	ID      int    `json:"id"`
+	UUID    string `json:"uuid"`
+	NoteID  int    `json:"note_id"`
+	Name    string `json:"name"`
+	Hash    string `json:"hash"`
+	Size    int64  `json:"size"`
+	AddedOn int64  `json:"added_on"`
+}
+
+type trashItemJSON struct {
+	ID        int    `json:"id"`
+	Kind      string `json:"kind"`
+	UUID      string `json:"uuid"`
+	BookLabel string `json:"book_label"`
+	Content   string `json:"content"`
+	NoteCount int    `json:"note_count"`
+	RemovedOn int64  `json:"removed_on"`
+}
+
+type bookCountJSON struct {
+	Label     string `json:"label"`
+	NoteCount int    `json:"note_count"`
+}
+
+type activityJSON struct {
+	Start  string `json:"start"`
+	Added  int    `json:"added"`
+	Edited int    `json:"edited"`
+}
+
+type statsJSON struct {
+	NoteCount    int             `json:"note_count"`
+	BookCount    int             `json:"book_count"`
+	LargestBooks []bookCountJSON `json:"largest_books"`
+	Period       string          `json:"period"`
+	Activity     []activityJSON  `json:"activity"`
+	Daily        []activityJSON  `json:"daily"`
+	DirtyNotes   int             `json:"dirty_notes"`
+	DirtyBooks   int             `json:"dirty_books"`
+	LastSyncAt   int64           `json:"last_sync_at"`
+}
+
+// dateLayout is the layout of the dates in the machine readable output
+const dateLayout = "2006-01-02"
+
+func newActivityJSON(activity []stats.Activity) []activityJSON {
+	ret := []activityJSON{}
+	for _, a := range activity {
+		ret = append(ret, activityJSON{
+			Start:  a.Start.Format(dateLayout),
+			Added:  a.Added,
+			Edited: a.Edited,
+		})
+	}
+
+	return ret
+}
+
+func newNoteJSON(info database.NoteInfo) noteJSON {
+	return noteJSON{
+		ID:        info.RowID,
+		UUID:      info.UUID,
+		BookLabel: info.BookLabel,
+		Content:   info.Content,
+		AddedOn:   info.AddedOn,
+		EditedOn:  info.EditedOn,
+		Tags:      nonNilTags(info.Tags),
+		Links:     newLinksJSON(info.Links),
+	}
+}
+
+func newLinksJSON(links []database.Link) []linkJSON {
+	ret := []linkJSON{}
+	for _, l := range links {
+		ret = append(ret, linkJSON{
+			Target:    l.Target,
+			ID:        l.RowID,
+			UUID:      l.UUID,
+			BookLabel: l.BookLabel,
+			Title:     l.Title,
+			Dangling:  l.Dangling(),
+			Ambiguous: l.Ambiguous,
+		})
+	}
+
+	return ret
+}
+
+// nonNilTags makes sure that tags are encoded as an array rather than null
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// jsonFormatter prints JSON documents. If delimited is true, lists are
+// printed as one compact document per line rather than as an array.
+type jsonFormatter struct {
+	delimited bool
+}
+
+func (f jsonFormatter) encode(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	if !f.delimited {
+		enc.SetIndent("", "  ")
+	}
+
+	if err := enc.Encode(v); err != nil {
+		return errors.Wrap(err, "encoding")
+	}
+
+	return nil
+}
+
+// encodeList prints the items either as an array or one per line
+func (f jsonFormatter) encodeList(w io.Writer, items []interface{}) error {
+	if !f.delimited {
+		return f.encode(w, items)
+	}
+
+	for _, item := range items {
+		if err := f.encode(w, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f jsonFormatter) NoteInfo(w io.Writer, info database.NoteInfo) error {
+	return f.encode(w, newNoteJSON(info))
+}
+
+func (f jsonFormatter) NoteContent(w io.Writer, info database.NoteInfo) error {
+	return f.NoteInfo(w, info)
+}
+
+func (f jsonFormatter) BookInfo(w io.Writer, info database.BookInfo) error {
+	return f.encode(w, bookJSON{
+		ID:    info.RowID,
+		UUID:  info.UUID,
+		Label: info.Name,
+	})
+}
+
+func (f jsonFormatter) BookList(w io.Writer, books []Book, nameOnly bool) error {
+	items := []interface{}{}
+	for _, b := range books {
+		items = append(items, bookListItemJSON{
+			UUID:      b.UUID,
+			Label:     b.Label,
+			NoteCount: b.NoteCount,
+		})
+	}
+
+	return f.encodeList(w, items)
+}
+
+func (f jsonFormatter) NoteList(w io.Writer, bookLabel string, notes []Note) error {
+	items := []interface{}{}
+	for _, n := range notes {
+		items = append(items, noteJSON{
+			ID:        n.RowID,
+			UUID:      n.UUID,
+			BookLabel: n.BookLabel,
+			Content:   n.Body,
+			AddedOn:   n.AddedOn,
+			EditedOn:  n.EditedOn,
+			Tags:      nonNilTags(n.Tags),
+		})
+	}
+
+	return f.encodeList(w, items)
+}
+
+func (f jsonFormatter) FindResults(w io.Writer, results []FindResult) error {
+	items := []interface{}{}
+	for _, r := range results {
+		items = append(items, findResultJSON{
+			ID:        r.RowID,
+			UUID:      r.UUID,
+			BookLabel: r.BookLabel,
+			Snippet:   r.Snippet,
+		})
+	}
+
+	return f.encodeList(w, items)
+}
+
+func (f jsonFormatter) Revisions(w io.Writer, noteRowID int, revisions []Revision) error {
+	items := []interface{}{}
+	for _, r := range revisions {
+		items = append(items, revisionJSON{
+			ID:       r.RowID,
+			NoteID:   noteRowID,
+			Content:  r.Body,
+			EditedOn: r.EditedOn,
+		})
+	}
+
+	return f.encodeList(w, items)
+}
+
+func (f jsonFormatter) TrashList(w io.Writer, items []TrashItem) error {
+	ret := []interface{}{}
+	for _, item := range items {
+		ret = append(ret, trashItemJSON{
+			ID:        item.RowID,
+			Kind:      item.Kind,
+			UUID:      item.UUID,
+			BookLabel: item.Label,
+			Content:   item.Body,
+			NoteCount: item.NoteCount,
+			RemovedOn: item.RemovedOn,
+		})
+	}
+
+	return f.encodeList(w, ret)
+}
+
+func (f jsonFormatter) Links(w io.Writer, noteRowID int, links, backlinks []database.Link) error {
+	return f.encode(w, linksJSON{
+		NoteID:    noteRowID,
+		Links:     newLinksJSON(links),
+		Backlinks: newLinksJSON(backlinks),
+	})
+}
+
+func (f jsonFormatter) Attachments(w io.Writer, noteRowID int, attachments []database.Attachment) error {
+	ret := []interface{}{}
+	for _, a := range attachments {
+		ret = append(ret, attachmentJSON{
+			ID:      a.RowID,
+			UUID:    a.UUID,
+			NoteID:  noteRowID,
+			Name:    a.Name,
+			Hash:    a.Hash,
+			Size:    a.Size,
+			AddedOn: a.AddedOn,
+		})
+	}
+
+	return f.encodeList(w, ret)
+}
+
+func (f jsonFormatter) Stats(w io.Writer, report stats.Report) error {
+	books := []bookCountJSON{}
+	for _, b := range report.LargestBooks {
+		books = append(books, bookCountJSON{
+			Label:     b.Label,
+			NoteCount: b.NoteCount,
+		})
+	}
+
+	return f.encode(w, statsJSON{
+		NoteCount:    report.NoteCount,
+		BookCount:    report.BookCount,
+		LargestBooks: books,
+		Period:       report.Period,
+		Activity:     newActivityJSON(report.Activity),
+		Daily:        newActivityJSON(report.Heatmap),
+		DirtyNotes:   report.DirtyNotes,
+		DirtyBooks:   report.DirtyBooks,
+		LastSyncAt:   report.LastSyncAt,
+	})
+}
@@ -21,35 +21,190 @@
 package output
 
 import (
-	"fmt"
-	"time"
+	"io"
 
 	"github.com/dnote/dnote/pkg/cli/database"
-	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/stats"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
 )
 
-// NoteInfo prints a note information
-func NoteInfo(info database.NoteInfo) {
-	log.Infof("book name: %s\n", info.BookLabel)
-	log.Infof("created at: %s\n", time.Unix(0, info.AddedOn).Format("Jan 2, 2006 3:04pm (MST)"))
-	if info.EditedOn != 0 {
-		log.Infof("updated at: %s\n", time.Unix(0, info.EditedOn).Format("Jan 2, 2006 3:04pm (MST)"))
+const (
+	// FormatText is the human readable output format
+	FormatText = "text"
+	// FormatJSON is the output format that prints JSON documents
+	FormatJSON = "json"
+	// FormatNDJSON is the output format that prints newline delimited JSON
+	FormatNDJSON = "ndjson"
+	// FormatTSV is the output format that prints tab separated values
+	FormatTSV = "tsv"
+)
+
+// Formats is the list of all supported output formats
+var Formats = []string{FormatText, FormatJSON, FormatNDJSON, FormatTSV}
+
+// Book is a book in a list of books
+type Book struct {
+	UUID      string
+	Label     string
+	NoteCount int
+}
+
+// Note is a note in a list of notes
+type Note struct {
+	RowID     int
+	UUID      string
+	BookLabel string
+	Body      string
+	AddedOn   int64
+	EditedOn  int64
+	Tags      []string
+}
+
+// FindResult is a note matching a search
+type FindResult struct {
+	RowID     int
+	UUID      string
+	BookLabel string
+	// Snippet is the matching part of the note content
+	Snippet string
+	// Highlighted is the snippet with the matching terms highlighted for
+	// displaying on the terminal
+	Highlighted string
+}
+
+// Revision is a prior content of a note
+type Revision struct {
+	RowID    int
+	Body     string
+	EditedOn int64
+}
+
+// TrashItem is a removed note or book in the trash
+type TrashItem struct {
+	RowID int
+	Kind  string
+	UUID  string
+	// Label is the label of the book, or the book of the note
+	Label     string
+	Body      string
+	NoteCount int
+	RemovedOn int64
+}
+
+// linkStatus returns "ok" if the link leads to a note, or describes why it
+// does not
+func linkStatus(l database.Link) string {
+	if l.Ambiguous {
+		return "ambiguous"
+	}
+	if l.Dangling() {
+		return "dangling"
+	}
+
+	return "ok"
+}
+
+// Formatter prints data in a certain format
+type Formatter interface {
+	NoteInfo(w io.Writer, info database.NoteInfo) error
+	NoteContent(w io.Writer, info database.NoteInfo) error
+	BookInfo(w io.Writer, info database.BookInfo) error
+	BookList(w io.Writer, books []Book, nameOnly bool) error
+	NoteList(w io.Writer, bookLabel string, notes []Note) error
+	FindResults(w io.Writer, results []FindResult) error
+	Revisions(w io.Writer, noteRowID int, revisions []Revision) error
+	TrashList(w io.Writer, items []TrashItem) error
+	Links(w io.Writer, noteRowID int, links, backlinks []database.Link) error
+	Attachments(w io.Writer, noteRowID int, attachments []database.Attachment) error
+	Stats(w io.Writer, report stats.Report) error
+}
+
+// NewFormatter returns a formatter for the format with the given name
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case FormatText:
+		return textFormatter{}, nil
+	case FormatJSON:
+		return jsonFormatter{}, nil
+	case FormatNDJSON:
+		return jsonFormatter{delimited: true}, nil
+	case FormatTSV:
+		return tsvFormatter{}, nil
+	}
+
+	return nil, errors.Errorf("unsupported output format '%s'", format)
+}
+
+// current is the formatter used by the package level functions
+var current Formatter = textFormatter{}
+
+// stdout is the writer to which the package level functions print
+var stdout io.Writer = color.Output
+
+// SetFormat sets the output format used by the package level functions
+func SetFormat(format string) error {
+	f, err := NewFormatter(format)
+	if err != nil {
+		return err
 	}
-	log.Infof("note id: %d\n", info.RowID)
-	log.Infof("note uuid: %s\n", info.UUID)
 
-	fmt.Printf("\n------------------------content------------------------\n")
-	fmt.Printf("%s", info.Content)
-	fmt.Printf("\n-------------------------------------------------------\n")
+	current = f
+	return nil
+}
+
+// NoteInfo prints a note information
+func NoteInfo(info database.NoteInfo) error {
+	return current.NoteInfo(stdout, info)
+}
+
+// NoteContent prints the content of a note
+func NoteContent(info database.NoteInfo) error {
+	return current.NoteContent(stdout, info)
+}
+
+// BookInfo prints a book information
+func BookInfo(info database.BookInfo) error {
+	return current.BookInfo(stdout, info)
+}
+
+// BookList prints a list of books
+func BookList(books []Book, nameOnly bool) error {
+	return current.BookList(stdout, books, nameOnly)
+}
+
+// NoteList prints a list of notes in a book. If bookLabel is empty, the notes
+// may belong to different books.
+func NoteList(bookLabel string, notes []Note) error {
+	return current.NoteList(stdout, bookLabel, notes)
+}
+
+// FindResults prints the results of a search
+func FindResults(results []FindResult) error {
+	return current.FindResults(stdout, results)
+}
+
+// Revisions prints the revisions of a note
+func Revisions(noteRowID int, revisions []Revision) error {
+	return current.Revisions(stdout, noteRowID, revisions)
+}
+
+// TrashList prints the items in the trash
+func TrashList(items []TrashItem) error {
+	return current.TrashList(stdout, items)
+}
+
+// Links prints the links from a note and the backlinks to it
+func Links(noteRowID int, links, backlinks []database.Link) error {
+	return current.Links(stdout, noteRowID, links, backlinks)
 }
 
-func NoteContent(info database.NoteInfo) {
-	fmt.Printf("%s", info.Content)
+// Attachments prints the attachments of a note
+func Attachments(noteRowID int, attachments []database.Attachment) error {
+	return current.Attachments(stdout, noteRowID, attachments)
 }
 
-// BookInfo prints a note information
-func BookInfo(info database.BookInfo) {
-	log.Infof("book name: %s\n", info.Name)
-	log.Infof("book id: %d\n", info.RowID)
-	log.Infof("book uuid: %s\n", info.UUID)
+// Stats prints a report on the notes and the books
+func Stats(report stats.Report) error {
+	return current.Stats(stdout, report)
 }
@@ -0,0 +1,359 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/stats"
+	"github.com/pkg/errors"
+)
+
+var testNoteInfo = database.NoteInfo{
+	RowID:     1,
+	BookLabel: "js",
+	UUID:      "43827b9a-c2b0-4c06-a290-97991c896653",
+	Content:   "Booleans have toString()\n\tand more",
+	AddedOn:   1515199943000000000,
+	EditedOn:  0,
+	Tags:      []string{"es6", "types"},
+}
+
+var testFindResults = []FindResult{
+	{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Snippet: "foo bar", Highlighted: "foo \x1b[33mbar\x1b[0m"},
+	{RowID: 2, UUID: "n2-uuid", BookLabel: "linux", Snippet: "bar baz", Highlighted: "\x1b[33mbar\x1b[0m baz"},
+}
+
+var testRevisions = []Revision{
+	{RowID: 3, Body: "foo\nbar", EditedOn: 1515199943000000000},
+}
+
+var testLinks = []database.Link{
+	{Target: "golang/channels", RowID: 2, UUID: "n2-uuid", BookLabel: "golang", Title: "Channels"},
+	{Target: "abcd", Ambiguous: true},
+}
+
+func TestNewFormatter(t *testing.T) {
+	for _, format := range Formats {
+		if _, err := NewFormatter(format); err != nil {
+			t.Errorf("unexpected error for %s: %s", format, err.Error())
+		}
+	}
+
+	if _, err := NewFormatter("xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestJSONNoteInfo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonFormatter{delimited: true}).NoteInfo(&buf, testNoteInfo); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting"))
+	}
+
+	expected := `{"id":1,"uuid":"43827b9a-c2b0-4c06-a290-97991c896653","book_label":"js","content":"Booleans have toString()\n\tand more","added_on":1515199943000000000,"edited_on":0,"tags":["es6","types"]}
+`
+	assert.Equal(t, buf.String(), expected, "output mismatch")
+}
+
+func TestJSONFindResults(t *testing.T) {
+	testCases := []struct {
+		name      string
+		formatter jsonFormatter
+		results   []FindResult
+		expected  string
+	}{
+		{
+			name:      "json",
+			formatter: jsonFormatter{},
+			results:   testFindResults,
+			expected: `[
+  {
+    "id": 1,
+    "uuid": "n1-uuid",
+    "book_label": "js",
+    "snippet": "foo bar"
+  },
+  {
+    "id": 2,
+    "uuid": "n2-uuid",
+    "book_label": "linux",
+    "snippet": "bar baz"
+  }
+]
+`,
+		},
+		{
+			name:      "json empty",
+			formatter: jsonFormatter{},
+			results:   []FindResult{},
+			expected:  "[]\n",
+		},
+		{
+			name:      "ndjson",
+			formatter: jsonFormatter{delimited: true},
+			results:   testFindResults,
+			expected: `{"id":1,"uuid":"n1-uuid","book_label":"js","snippet":"foo bar"}
+{"id":2,"uuid":"n2-uuid","book_label":"linux","snippet":"bar baz"}
+`,
+		},
+		{
+			name:      "ndjson empty",
+			formatter: jsonFormatter{delimited: true},
+			results:   []FindResult{},
+			expected:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.formatter.FindResults(&buf, tc.results); err != nil {
+				t.Fatal(errors.Wrap(err, "formatting"))
+			}
+
+			assert.Equal(t, buf.String(), tc.expected, "output mismatch")
+		})
+	}
+}
+
+func TestTSV(t *testing.T) {
+	var f tsvFormatter
+
+	var buf bytes.Buffer
+	if err := f.NoteInfo(&buf, testNoteInfo); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting note info"))
+	}
+	assert.Equal(t, buf.String(), "1\t43827b9a-c2b0-4c06-a290-97991c896653\tjs\t1515199943000000000\t0\tBooleans have toString()\\n\\tand more\tes6,types\n", "note info mismatch")
+
+	buf.Reset()
+	books := []Book{{UUID: "b1-uuid", Label: "js", NoteCount: 3}, {UUID: "b2-uuid", Label: `c\d`, NoteCount: 0}}
+	if err := f.BookList(&buf, books, false); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting book list"))
+	}
+	assert.Equal(t, buf.String(), "b1-uuid\tjs\t3\nb2-uuid\tc\\\\d\t0\n", "book list mismatch")
+
+	buf.Reset()
+	if err := f.FindResults(&buf, testFindResults); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting find results"))
+	}
+	assert.Equal(t, buf.String(), "1\tn1-uuid\tjs\tfoo bar\n2\tn2-uuid\tlinux\tbar baz\n", "find results mismatch")
+
+	buf.Reset()
+	if err := f.Revisions(&buf, 7, testRevisions); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting revisions"))
+	}
+	assert.Equal(t, buf.String(), "3\t7\t1515199943000000000\tfoo\\nbar\n", "revisions mismatch")
+
+	buf.Reset()
+	items := []TrashItem{
+		{RowID: 1, Kind: "book", UUID: "b1-uuid", Label: "js", NoteCount: 2, RemovedOn: 2},
+		{RowID: 2, Kind: "note", UUID: "n1-uuid", Label: "linux", Body: "foo\tbar", RemovedOn: 1},
+	}
+	if err := f.TrashList(&buf, items); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting trash items"))
+	}
+	assert.Equal(t, buf.String(), "1\tbook\tb1-uuid\tjs\t2\t2\t\n2\tnote\tn1-uuid\tlinux\t1\t0\tfoo\\tbar\n", "trash items mismatch")
+}
+
+func TestJSONRevisions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonFormatter{delimited: true}).Revisions(&buf, 7, testRevisions); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting"))
+	}
+
+	expected := `{"id":3,"note_id":7,"content":"foo\nbar","edited_on":1515199943000000000}
+`
+	assert.Equal(t, buf.String(), expected, "output mismatch")
+}
+
+func TestJSONLinks(t *testing.T) {
+	t.Run("note info", func(t *testing.T) {
+		info := testNoteInfo
+		info.Links = testLinks[:1]
+
+		var buf bytes.Buffer
+		if err := (jsonFormatter{delimited: true}).NoteInfo(&buf, info); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		expected := `{"id":1,"uuid":"43827b9a-c2b0-4c06-a290-97991c896653","book_label":"js","content":"Booleans have toString()\n\tand more","added_on":1515199943000000000,"edited_on":0,"tags":["es6","types"],"links":[{"target":"golang/channels","id":2,"uuid":"n2-uuid","book_label":"golang","title":"Channels","dangling":false,"ambiguous":false}]}
+`
+		assert.Equal(t, buf.String(), expected, "output mismatch")
+	})
+
+	t.Run("links", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (jsonFormatter{delimited: true}).Links(&buf, 1, testLinks, nil); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		expected := `{"note_id":1,"links":[{"target":"golang/channels","id":2,"uuid":"n2-uuid","book_label":"golang","title":"Channels","dangling":false,"ambiguous":false},{"target":"abcd","id":0,"uuid":"","book_label":"","title":"","dangling":true,"ambiguous":true}],"backlinks":[]}
+`
+		assert.Equal(t, buf.String(), expected, "output mismatch")
+	})
+}
+
+func TestTSVLinks(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (tsvFormatter{}).Links(&buf, 1, testLinks[1:], testLinks[:1]); err != nil {
+		t.Fatal(errors.Wrap(err, "formatting"))
+	}
+
+	assert.Equal(t, buf.String(), "link\tabcd\tambiguous\t0\t\t\t\nbacklink\tgolang/channels\tok\t2\tn2-uuid\tgolang\tChannels\n", "output mismatch")
+}
+
+func TestAttachments(t *testing.T) {
+	attachments := []database.Attachment{
+		{RowID: 3, UUID: "a1-uuid", NoteUUID: "n1-uuid", Name: "screenshot.png", Hash: "h1", Size: 2048, AddedOn: 1515199943000000000},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (jsonFormatter{delimited: true}).Attachments(&buf, 1, attachments); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		expected := `{"id":3,"uuid":"a1-uuid","note_id":1,"name":"screenshot.png","hash":"h1","size":2048,"added_on":1515199943000000000}
+`
+		assert.Equal(t, buf.String(), expected, "output mismatch")
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (tsvFormatter{}).Attachments(&buf, 1, attachments); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		assert.Equal(t, buf.String(), "3\ta1-uuid\t1\tscreenshot.png\th1\t2048\t1515199943000000000\n", "output mismatch")
+	})
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 1023, expected: "1023 B"},
+		{size: 1024, expected: "1.0 KB"},
+		{size: 1536, expected: "1.5 KB"},
+		{size: 32 << 20, expected: "32.0 MB"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, formatSize(tc.size), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedBody    string
+		expectedExcerpt bool
+	}{
+		{input: "foo", expectedBody: "foo", expectedExcerpt: false},
+		{input: "foo\n", expectedBody: "foo", expectedExcerpt: false},
+		{input: " foo \nbar", expectedBody: "foo", expectedExcerpt: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			body, excerpt := formatBody(tc.input)
+			assert.Equal(t, body, tc.expectedBody, "body mismatch")
+			assert.Equal(t, excerpt, tc.expectedExcerpt, "excerpt mismatch")
+		})
+	}
+}
+
+func TestBookTree(t *testing.T) {
+	books := []Book{
+		{Label: "work", NoteCount: 1},
+		{Label: "work-old", NoteCount: 2},
+		{Label: "work/infra/k8s", NoteCount: 3},
+		{Label: "personal/books", NoteCount: 4},
+		{Label: "work/infra", NoteCount: 5},
+		{Label: "js", NoteCount: 6},
+	}
+
+	// each line is formatted as the depth, the name and the note count if
+	// the line is a book
+	expected := []string{
+		"0 js 6",
+		"0 personal",
+		"1 books 4",
+		"0 work 1",
+		"1 infra 5",
+		"2 k8s 3",
+		"0 work-old 2",
+	}
+
+	got := []string{}
+	for _, l := range bookTree(books) {
+		s := fmt.Sprintf("%d %s", l.Depth, l.Name)
+		if l.Book != nil {
+			s = fmt.Sprintf("%s %d", s, l.Book.NoteCount)
+		}
+
+		got = append(got, s)
+	}
+
+	assert.DeepEqual(t, got, expected, "tree mismatch")
+}
+
+func TestStats(t *testing.T) {
+	report := stats.Report{
+		NoteCount:    3,
+		BookCount:    2,
+		LargestBooks: []stats.BookCount{{Label: "js", NoteCount: 2}},
+		Period:       stats.PeriodMonth,
+		Activity:     []stats.Activity{{Start: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Added: 2, Edited: 1}},
+		Heatmap:      []stats.Activity{{Start: time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), Added: 1}},
+		DirtyNotes:   1,
+		LastSyncAt:   1709251200000000000,
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (jsonFormatter{delimited: true}).Stats(&buf, report); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		expected := `{"note_count":3,"book_count":2,"largest_books":[{"label":"js","note_count":2}],"period":"month","activity":[{"start":"2024-03-01","added":2,"edited":1}],"daily":[{"start":"2024-03-13","added":1,"edited":0}],"dirty_notes":1,"dirty_books":0,"last_sync_at":1709251200000000000}
+`
+		assert.Equal(t, buf.String(), expected, "output mismatch")
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (tsvFormatter{}).Stats(&buf, report); err != nil {
+			t.Fatal(errors.Wrap(err, "formatting"))
+		}
+
+		expected := "note_count\t3\nbook_count\t2\ndirty_notes\t1\ndirty_books\t0\nlast_sync_at\t1709251200000000000\nbook\tjs\t2\nmonth\t2024-03-01\t2\t1\n"
+		assert.Equal(t, buf.String(), expected, "output mismatch")
+	})
+}
@@ -0,0 +1,422 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/stats"
+)
+
+// timeLayout is the layout of the timestamps in the text output
+const timeLayout = "Jan 2, 2006 3:04pm (MST)"
+
+var indent = "  "
+
+// textFormatter prints human readable output with colors
+type textFormatter struct{}
+
+func infof(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s %s", indent, log.ColorBlue.Sprint("•"), fmt.Sprintf(msg, v...))
+}
+
+func plainf(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s", indent, fmt.Sprintf(msg, v...))
+}
+
+func (textFormatter) NoteInfo(w io.Writer, info database.NoteInfo) error {
+	infof(w, "book name: %s\n", info.BookLabel)
+	infof(w, "created at: %s\n", time.Unix(0, info.AddedOn).Format(timeLayout))
+	if info.EditedOn != 0 {
+		infof(w, "updated at: %s\n", time.Unix(0, info.EditedOn).Format(timeLayout))
+	}
+	infof(w, "note id: %d\n", info.RowID)
+	infof(w, "note uuid: %s\n", info.UUID)
+	if len(info.Tags) > 0 {
+		infof(w, "tags: %s\n", strings.Join(info.Tags, ", "))
+	}
+
+	fmt.Fprintf(w, "\n------------------------content------------------------\n")
+	fmt.Fprintf(w, "%s", info.Content)
+	fmt.Fprintf(w, "\n-------------------------------------------------------\n")
+
+	if len(info.Links) > 0 {
+		infof(w, "links:\n")
+		for _, l := range info.Links {
+			writeLink(w, l)
+		}
+	}
+
+	return nil
+}
+
+func (textFormatter) NoteContent(w io.Writer, info database.NoteInfo) error {
+	fmt.Fprintf(w, "%s", info.Content)
+
+	return nil
+}
+
+func (textFormatter) BookInfo(w io.Writer, info database.BookInfo) error {
+	infof(w, "book name: %s\n", info.Name)
+	infof(w, "book id: %d\n", info.RowID)
+	infof(w, "book uuid: %s\n", info.UUID)
+
+	return nil
+}
+
+func (textFormatter) BookList(w io.Writer, books []Book, nameOnly bool) error {
+	if nameOnly {
+		for _, b := range books {
+			fmt.Fprintln(w, b.Label)
+		}
+
+		return nil
+	}
+
+	for _, l := range bookTree(books) {
+		prefix := strings.Repeat(indent, l.Depth+1)
+		if l.Book == nil {
+			fmt.Fprintf(w, "%s%s %s\n", prefix, log.ColorGray.Sprint("•"), l.Name)
+		} else {
+			fmt.Fprintf(w, "%s%s %s %s\n", prefix, log.ColorGray.Sprint("•"), l.Name, log.ColorYellow.Sprintf("(%d)", l.Book.NoteCount))
+		}
+	}
+
+	return nil
+}
+
+// bookTreeLine is a line in the tree of books
+type bookTreeLine struct {
+	Depth int
+	Name  string
+	// Book is nil if the line is a parent that is not a book by itself
+	Book *Book
+}
+
+// bookTree arranges the books into a tree by the parts of their labels
+// separated by slashes, and returns the lines of the tree from the top
+func bookTree(books []Book) []bookTreeLine {
+	sorted := make([]Book, len(books))
+	copy(sorted, books)
+
+	parts := make(map[string][]string, len(books))
+	for _, b := range books {
+		parts[b.Label] = strings.Split(b.Label, "/")
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := parts[sorted[i].Label], parts[sorted[j].Label]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return len(a) < len(b)
+	})
+
+	ret := []bookTreeLine{}
+	var prev []string
+	for i := range sorted {
+		cur := parts[sorted[i].Label]
+
+		common := 0
+		for common < len(prev) && common < len(cur)-1 && prev[common] == cur[common] {
+			common++
+		}
+
+		for depth := common; depth < len(cur)-1; depth++ {
+			ret = append(ret, bookTreeLine{Depth: depth, Name: cur[depth]})
+		}
+		ret = append(ret, bookTreeLine{Depth: len(cur) - 1, Name: cur[len(cur)-1], Book: &sorted[i]})
+
+		prev = cur
+	}
+
+	return ret
+}
+
+// getNewlineIdx returns the index of newline character in a string
+func getNewlineIdx(str string) int {
+	var ret int
+
+	ret = strings.Index(str, "\n")
+
+	if ret == -1 {
+		ret = strings.Index(str, "\r\n")
+	}
+
+	return ret
+}
+
+// formatBody returns an excerpt of the given raw note content and a boolean
+// indicating if the returned string has been excertped
+func formatBody(noteBody string) (string, bool) {
+	trimmed := strings.TrimRight(noteBody, "\r\n")
+	newlineIdx := getNewlineIdx(trimmed)
+
+	if newlineIdx > -1 {
+		ret := strings.Trim(trimmed[0:newlineIdx], " ")
+
+		return ret, true
+	}
+
+	return strings.Trim(trimmed, " "), false
+}
+
+func (textFormatter) NoteList(w io.Writer, bookLabel string, notes []Note) error {
+	if bookLabel != "" {
+		infof(w, "on book %s\n", bookLabel)
+	}
+
+	for _, n := range notes {
+		body, isExcerpt := formatBody(n.Body)
+
+		rowid := log.ColorYellow.Sprintf("(%d)", n.RowID)
+		if isExcerpt {
+			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
+		}
+
+		if bookLabel == "" {
+			plainf(w, "%s %s %s\n", log.ColorYellow.Sprintf("(%s)", n.BookLabel), rowid, body)
+		} else {
+			plainf(w, "%s %s\n", rowid, body)
+		}
+	}
+
+	return nil
+}
+
+func (textFormatter) FindResults(w io.Writer, results []FindResult) error {
+	for _, r := range results {
+		bookLabel := log.ColorYellow.Sprintf("(%s)", r.BookLabel)
+		rowid := log.ColorYellow.Sprintf("(%d)", r.RowID)
+
+		plainf(w, "%s %s %s\n", bookLabel, rowid, r.Highlighted)
+	}
+
+	return nil
+}
+
+func (textFormatter) Revisions(w io.Writer, noteRowID int, revisions []Revision) error {
+	if len(revisions) == 0 {
+		infof(w, "note %d has no revisions\n", noteRowID)
+		return nil
+	}
+
+	infof(w, "revisions of note %d\n", noteRowID)
+
+	for _, r := range revisions {
+		body, isExcerpt := formatBody(r.Body)
+		if isExcerpt {
+			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
+		}
+
+		rev := log.ColorYellow.Sprintf("(%d)", r.RowID)
+		editedOn := log.ColorGray.Sprint(time.Unix(0, r.EditedOn).Format(timeLayout))
+
+		plainf(w, "%s %s %s\n", rev, editedOn, body)
+	}
+
+	return nil
+}
+
+func (textFormatter) TrashList(w io.Writer, items []TrashItem) error {
+	if len(items) == 0 {
+		infof(w, "the trash is empty\n")
+		return nil
+	}
+
+	for _, item := range items {
+		id := log.ColorYellow.Sprintf("(%d)", item.RowID)
+		removedOn := log.ColorGray.Sprintf("removed %s", time.Unix(0, item.RemovedOn).Format(timeLayout))
+
+		if item.Kind == database.TrashKindBook {
+			plainf(w, "%s book %s %s %s\n", id, item.Label, log.ColorYellow.Sprintf("(%d notes)", item.NoteCount), removedOn)
+			continue
+		}
+
+		body, isExcerpt := formatBody(item.Body)
+		if isExcerpt {
+			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
+		}
+
+		plainf(w, "%s note %s %s %s\n", id, log.ColorYellow.Sprintf("(%s)", item.Label), body, removedOn)
+	}
+
+	return nil
+}
+
+// writeLink prints a link with the note it leads to, or with its status if
+// it is dangling
+func writeLink(w io.Writer, l database.Link) {
+	target := log.ColorGray.Sprintf("[[%s]]", l.Target)
+
+	if l.Dangling() {
+		plainf(w, "%s %s\n", log.ColorRed.Sprint(linkStatus(l)), target)
+		return
+	}
+
+	rowid := log.ColorYellow.Sprintf("(%d)", l.RowID)
+	bookLabel := log.ColorYellow.Sprintf("(%s)", l.BookLabel)
+
+	plainf(w, "%s %s %s %s\n", rowid, bookLabel, l.Title, target)
+}
+
+func (textFormatter) Links(w io.Writer, noteRowID int, links, backlinks []database.Link) error {
+	if len(links) == 0 {
+		infof(w, "note %d has no links\n", noteRowID)
+	} else {
+		infof(w, "links from note %d\n", noteRowID)
+		for _, l := range links {
+			writeLink(w, l)
+		}
+	}
+
+	if len(backlinks) == 0 {
+		infof(w, "note %d has no backlinks\n", noteRowID)
+	} else {
+		infof(w, "backlinks to note %d\n", noteRowID)
+		for _, l := range backlinks {
+			writeLink(w, l)
+		}
+	}
+
+	return nil
+}
+
+// formatSize formats the given number of bytes in a human readable unit
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func (textFormatter) Attachments(w io.Writer, noteRowID int, attachments []database.Attachment) error {
+	if len(attachments) == 0 {
+		infof(w, "note %d has no attachments\n", noteRowID)
+		return nil
+	}
+
+	infof(w, "attachments of note %d\n", noteRowID)
+	for _, a := range attachments {
+		rowid := log.ColorYellow.Sprintf("(%d)", a.RowID)
+		size := log.ColorGray.Sprintf("(%s)", formatSize(a.Size))
+
+		plainf(w, "%s %s %s\n", rowid, a.Name, size)
+	}
+
+	return nil
+}
+
+// activityBarWidth is the width of the bar of the busiest period
+const activityBarWidth = 30
+
+// heatmapCells are the cells of the heatmap for each level of activity
+var heatmapCells = []string{
+	log.ColorGray.Sprint("·"),
+	log.ColorGreen.Sprint("░"),
+	log.ColorGreen.Sprint("▒"),
+	log.ColorGreen.Sprint("▓"),
+	log.ColorGreen.Sprint("█"),
+}
+
+var weekdayNames = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// writeHeatmap prints the heatmap with a row for each day of the week and a
+// column for each week
+func writeHeatmap(w io.Writer, heatmap []stats.Activity) {
+	levels := stats.Levels(heatmap)
+
+	for day := 0; day < 7; day++ {
+		var row strings.Builder
+		for i := day; i < len(levels); i += 7 {
+			row.WriteString(heatmapCells[levels[i]])
+		}
+
+		plainf(w, "%s%s %s\n", indent, log.ColorGray.Sprint(weekdayNames[day]), row.String())
+	}
+
+	plainf(w, "%s    %s %s %s\n", indent, log.ColorGray.Sprint("less"), strings.Join(heatmapCells, " "), log.ColorGray.Sprint("more"))
+}
+
+func (textFormatter) Stats(w io.Writer, report stats.Report) error {
+	infof(w, "%d notes in %d books\n", report.NoteCount, report.BookCount)
+	infof(w, "not synced: %d notes, %d books\n", report.DirtyNotes, report.DirtyBooks)
+	if report.LastSyncAt == 0 {
+		infof(w, "last sync: never\n")
+	} else {
+		infof(w, "last sync: %s\n", time.Unix(0, report.LastSyncAt).Format(timeLayout))
+	}
+
+	if len(report.LargestBooks) > 0 {
+		fmt.Fprintln(w)
+		infof(w, "largest books\n")
+
+		width := 0
+		for _, b := range report.LargestBooks {
+			if len(b.Label) > width {
+				width = len(b.Label)
+			}
+		}
+		for _, b := range report.LargestBooks {
+			plainf(w, "%s%-*s %s\n", indent, width, b.Label, log.ColorYellow.Sprintf("%d", b.NoteCount))
+		}
+	}
+
+	fmt.Fprintln(w)
+	infof(w, "notes added and edited by %s\n", report.Period)
+
+	max := 0
+	for _, a := range report.Activity {
+		if a.Total() > max {
+			max = a.Total()
+		}
+	}
+	for _, a := range report.Activity {
+		var bar string
+		if max > 0 {
+			bar = strings.Repeat("█", (a.Total()*activityBarWidth+max-1)/max)
+		}
+
+		counts := fmt.Sprintf("%s %s", log.ColorGreen.Sprintf("+%-4d", a.Added), log.ColorYellow.Sprintf("~%-4d", a.Edited))
+		plainf(w, "%s%s %s %s\n", indent, a.Start.Format("2006-01-02"), counts, log.ColorGreen.Sprint(bar))
+	}
+
+	fmt.Fprintln(w)
+	infof(w, "activity in the last %d weeks\n", stats.HeatmapWeeks)
+	writeHeatmap(w, report.Heatmap)
+
+	return nil
+}
@@ -0,0 +1,171 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/stats"
+	"github.com/pkg/errors"
+)
+
+// tsvEscaper escapes the characters that would break the rows and the columns
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// tsvFormatter prints one row per item with tab separated columns and no
+// header. The columns of each row are documented in OUTPUT.md.
+type tsvFormatter struct{}
+
+func writeRow(w io.Writer, cols ...interface{}) error {
+	vals := make([]string, len(cols))
+	for i, c := range cols {
+		switch v := c.(type) {
+		case string:
+			vals[i] = tsvEscaper.Replace(v)
+		case int:
+			vals[i] = strconv.Itoa(v)
+		case int64:
+			vals[i] = strconv.FormatInt(v, 10)
+		default:
+			vals[i] = tsvEscaper.Replace(fmt.Sprint(v))
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, strings.Join(vals, "\t")); err != nil {
+		return errors.Wrap(err, "writing a row")
+	}
+
+	return nil
+}
+
+func (tsvFormatter) NoteInfo(w io.Writer, info database.NoteInfo) error {
+	return writeRow(w, info.RowID, info.UUID, info.BookLabel, info.AddedOn, info.EditedOn, info.Content, strings.Join(info.Tags, ","))
+}
+
+func (f tsvFormatter) NoteContent(w io.Writer, info database.NoteInfo) error {
+	return f.NoteInfo(w, info)
+}
+
+func (tsvFormatter) BookInfo(w io.Writer, info database.BookInfo) error {
+	return writeRow(w, info.RowID, info.UUID, info.Name)
+}
+
+func (tsvFormatter) BookList(w io.Writer, books []Book, nameOnly bool) error {
+	for _, b := range books {
+		if err := writeRow(w, b.UUID, b.Label, b.NoteCount); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) NoteList(w io.Writer, bookLabel string, notes []Note) error {
+	for _, n := range notes {
+		if err := writeRow(w, n.RowID, n.UUID, n.BookLabel, n.AddedOn, n.EditedOn, n.Body, strings.Join(n.Tags, ",")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) FindResults(w io.Writer, results []FindResult) error {
+	for _, r := range results {
+		if err := writeRow(w, r.RowID, r.UUID, r.BookLabel, r.Snippet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) Revisions(w io.Writer, noteRowID int, revisions []Revision) error {
+	for _, r := range revisions {
+		if err := writeRow(w, r.RowID, noteRowID, r.EditedOn, r.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) TrashList(w io.Writer, items []TrashItem) error {
+	for _, item := range items {
+		if err := writeRow(w, item.RowID, item.Kind, item.UUID, item.Label, item.RemovedOn, item.NoteCount, item.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) Links(w io.Writer, noteRowID int, links, backlinks []database.Link) error {
+	for _, l := range links {
+		if err := writeRow(w, "link", l.Target, linkStatus(l), l.RowID, l.UUID, l.BookLabel, l.Title); err != nil {
+			return err
+		}
+	}
+	for _, l := range backlinks {
+		if err := writeRow(w, "backlink", l.Target, linkStatus(l), l.RowID, l.UUID, l.BookLabel, l.Title); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tsvFormatter) Attachments(w io.Writer, noteRowID int, attachments []database.Attachment) error {
+	for _, a := range attachments {
+		if err := writeRow(w, a.RowID, a.UUID, noteRowID, a.Name, a.Hash, a.Size, a.AddedOn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stats prints a row for each figure, led by its kind
+func (tsvFormatter) Stats(w io.Writer, report stats.Report) error {
+	rows := [][]interface{}{
+		{"note_count", report.NoteCount},
+		{"book_count", report.BookCount},
+		{"dirty_notes", report.DirtyNotes},
+		{"dirty_books", report.DirtyBooks},
+		{"last_sync_at", report.LastSyncAt},
+	}
+	for _, b := range report.LargestBooks {
+		rows = append(rows, []interface{}{"book", b.Label, b.NoteCount})
+	}
+	for _, a := range report.Activity {
+		rows = append(rows, []interface{}{report.Period, a.Start.Format(dateLayout), a.Added, a.Edited})
+	}
+
+	for _, row := range rows {
+		if err := writeRow(w, row...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
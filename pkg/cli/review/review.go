@@ -0,0 +1,139 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package review schedules the reviews of notes with a variant of the SM-2
+// spaced repetition algorithm
+package review
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+// Grade is how well a note was recalled in a review
+type Grade int
+
+const (
+	// GradeAgain means that the note was forgotten
+	GradeAgain Grade = iota + 1
+	// GradeHard means that the note was recalled with difficulty
+	GradeHard
+	// GradeGood means that the note was recalled
+	GradeGood
+	// GradeEasy means that the note was recalled easily
+	GradeEasy
+)
+
+var gradeNames = map[Grade]string{
+	GradeAgain: "again",
+	GradeHard:  "hard",
+	GradeGood:  "good",
+	GradeEasy:  "easy",
+}
+
+func (g Grade) String() string {
+	return gradeNames[g]
+}
+
+// ParseGrade parses a grade from its name, its first letter or its number
+func ParseGrade(s string) (Grade, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for g, name := range gradeNames {
+		if s == name || s == name[:1] || s == string(rune('0'+int(g))) {
+			return g, nil
+		}
+	}
+
+	return 0, errors.Errorf("invalid grade '%s'", s)
+}
+
+const (
+	// DefaultEase is the ease of a note that has never been reviewed
+	DefaultEase = 2.5
+	// MinEase is the lowest ease of a note
+	MinEase = 1.3
+	// easyBonus is the factor by which an easy grade extends the interval
+	easyBonus = 1.3
+	// easyFirstInterval is the interval in days after a new note is graded easy
+	easyFirstInterval = 4
+)
+
+// quality maps a grade to the quality of the response in SM-2, from 0 to 5
+func quality(g Grade) float64 {
+	switch g {
+	case GradeAgain:
+		return 1
+	case GradeHard:
+		return 3
+	case GradeGood:
+		return 4
+	}
+
+	return 5
+}
+
+// New returns the scheduling state of a note that has never been reviewed
+func New(noteUUID string) database.NoteReview {
+	return database.NoteReview{
+		NoteUUID: noteUUID,
+		Ease:     DefaultEase,
+	}
+}
+
+// Schedule returns the scheduling state of a note after it is reviewed at the
+// given time with the given grade. A forgotten note starts over and is due the
+// next day. Otherwise the interval is 1 day, then 6 days, and then grows by the
+// ease of the note. An easy grade extends the interval.
+func Schedule(r database.NoteReview, g Grade, now time.Time) database.NoteReview {
+	q := quality(g)
+	r.Ease = math.Max(MinEase, r.Ease+(0.1-(5-q)*(0.08+(5-q)*0.02)))
+
+	if g == GradeAgain {
+		r.Repetitions = 0
+		r.Lapses++
+		r.Interval = 1
+	} else {
+		switch r.Repetitions {
+		case 0:
+			r.Interval = 1
+			if g == GradeEasy {
+				r.Interval = easyFirstInterval
+			}
+		case 1:
+			r.Interval = 6
+		default:
+			r.Interval = int(math.Round(float64(r.Interval) * r.Ease))
+		}
+
+		if g == GradeEasy && r.Repetitions > 0 {
+			r.Interval = int(math.Round(float64(r.Interval) * easyBonus))
+		}
+
+		r.Repetitions++
+	}
+
+	r.ReviewedOn = now.UnixNano()
+	r.DueOn = now.AddDate(0, 0, r.Interval).UnixNano()
+
+	return r
+}
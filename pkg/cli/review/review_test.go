@@ -0,0 +1,107 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package review
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestParseGrade(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Grade
+	}{
+		{input: "1", expected: GradeAgain},
+		{input: "again", expected: GradeAgain},
+		{input: "h", expected: GradeHard},
+		{input: " 3\n", expected: GradeGood},
+		{input: "Easy", expected: GradeEasy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseGrade(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, got, tc.expected, "grade mismatch")
+		})
+	}
+
+	for _, input := range []string{"", "0", "5", "x"} {
+		if _, err := ParseGrade(input); err == nil {
+			t.Errorf("expected an error for '%s'", input)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2024, time.March, 13, 10, 0, 0, 0, time.UTC)
+
+	t.Run("successful reviews", func(t *testing.T) {
+		r := New("n1-uuid")
+
+		var intervals []int
+		for i := 0; i < 4; i++ {
+			r = Schedule(r, GradeGood, now)
+			intervals = append(intervals, r.Interval)
+		}
+
+		assert.DeepEqual(t, intervals, []int{1, 6, 15, 38}, "intervals mismatch")
+		assert.Equal(t, r.Repetitions, 4, "repetitions mismatch")
+		assert.Equal(t, r.Ease, DefaultEase, "ease should not change for good")
+		assert.Equal(t, r.ReviewedOn, now.UnixNano(), "reviewedOn mismatch")
+		assert.Equal(t, r.DueOn, now.AddDate(0, 0, 38).UnixNano(), "dueOn mismatch")
+	})
+
+	t.Run("lapse", func(t *testing.T) {
+		r := New("n1-uuid")
+		r = Schedule(r, GradeGood, now)
+		r = Schedule(r, GradeGood, now)
+		r = Schedule(r, GradeAgain, now)
+
+		assert.Equal(t, r.Interval, 1, "interval mismatch")
+		assert.Equal(t, r.Repetitions, 0, "repetitions mismatch")
+		assert.Equal(t, r.Lapses, 1, "lapses mismatch")
+		assert.Equal(t, fmt.Sprintf("%.2f", r.Ease), "1.96", "ease mismatch")
+	})
+
+	t.Run("ease bounds", func(t *testing.T) {
+		r := New("n1-uuid")
+		for i := 0; i < 10; i++ {
+			r = Schedule(r, GradeAgain, now)
+		}
+
+		assert.Equal(t, r.Ease, MinEase, "ease should not go below the minimum")
+	})
+
+	t.Run("easy", func(t *testing.T) {
+		r := Schedule(New("n1-uuid"), GradeEasy, now)
+		assert.Equal(t, r.Interval, 4, "first interval mismatch")
+		assert.Equal(t, fmt.Sprintf("%.2f", r.Ease), "2.60", "ease mismatch")
+
+		r = Schedule(r, GradeEasy, now)
+		assert.Equal(t, r.Interval, 8, "second interval mismatch")
+	})
+}
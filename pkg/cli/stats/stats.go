@@ -0,0 +1,296 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package stats computes the reports on the growth of the notes
+package stats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PeriodDay groups the activity by day
+	PeriodDay = "day"
+	// PeriodWeek groups the activity by week, starting on Monday
+	PeriodWeek = "week"
+	// PeriodMonth groups the activity by month
+	PeriodMonth = "month"
+)
+
+// Periods is the list of all supported periods
+var Periods = []string{PeriodDay, PeriodWeek, PeriodMonth}
+
+// HeatmapWeeks is the number of weeks in the activity heatmap
+const HeatmapWeeks = 52
+
+// Options are the options for computing a report
+type Options struct {
+	// Period is the length of each period of the activity
+	Period string
+	// PeriodCount is the number of periods of the activity, ending with the
+	// current period
+	PeriodCount int
+	// TopBooks is the number of the largest books to report
+	TopBooks int
+}
+
+// BookCount is the number of notes in a book
+type BookCount struct {
+	Label     string
+	NoteCount int
+}
+
+// Activity is the number of notes added and edited in a period or a day
+type Activity struct {
+	// Start is the start of the period in the local time
+	Start  time.Time
+	Added  int
+	Edited int
+}
+
+// Total returns the number of notes added or edited
+func (a Activity) Total() int {
+	return a.Added + a.Edited
+}
+
+// Report is a report on the notes and the books
+type Report struct {
+	NoteCount    int
+	BookCount    int
+	LargestBooks []BookCount
+	Period       string
+	// Activity has the activity of each period, from the oldest to the current
+	Activity []Activity
+	// Heatmap has the activity of each day in the heatmap, starting on Monday
+	// HeatmapWeeks weeks ago and ending today
+	Heatmap    []Activity
+	DirtyNotes int
+	DirtyBooks int
+	// LastSyncAt is the unix timestamp of the last sync in nanoseconds. It is
+	// 0 if the notes have never been synced.
+	LastSyncAt int64
+}
+
+// periodStart returns the start of the period containing the given time
+func periodStart(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	switch period {
+	case PeriodWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+
+	return day
+}
+
+// addPeriods returns the start of the period n periods after the given start
+func addPeriods(start time.Time, period string, n int) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7*n)
+	case PeriodMonth:
+		return start.AddDate(0, n, 0)
+	}
+
+	return start.AddDate(0, 0, n)
+}
+
+// newActivity returns the periods of activity with no notes, starting with the
+// given start
+func newActivity(start time.Time, period string, count int) []Activity {
+	ret := make([]Activity, count)
+	for i := range ret {
+		ret[i].Start = addPeriods(start, period, i)
+	}
+
+	return ret
+}
+
+// counter counts the notes added and edited in each period of an activity
+type counter struct {
+	activity []Activity
+	period   string
+	// index is the index of each period in the activity by its start
+	index map[int64]int
+}
+
+func newCounter(activity []Activity, period string) counter {
+	index := map[int64]int{}
+	for i, a := range activity {
+		index[a.Start.Unix()] = i
+	}
+
+	return counter{activity: activity, period: period, index: index}
+}
+
+// record counts a note added or edited at the given time in the period
+// containing it, if any
+func (c counter) record(t time.Time, edited bool) {
+	i, ok := c.index[periodStart(t, c.period).Unix()]
+	if !ok {
+		return
+	}
+
+	if edited {
+		c.activity[i].Edited++
+	} else {
+		c.activity[i].Added++
+	}
+}
+
+func getLargestBooks(db *database.DB, limit int) ([]BookCount, error) {
+	rows, err := db.Query(`SELECT books.label, count(notes.uuid) AS note_count
+	FROM books
+	INNER JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
+	WHERE books.deleted = false
+	GROUP BY books.uuid
+	ORDER BY note_count DESC, books.label ASC
+	LIMIT ?`, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	ret := []BookCount{}
+	for rows.Next() {
+		var b BookCount
+		if err := rows.Scan(&b.Label, &b.NoteCount); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, b)
+	}
+
+	return ret, nil
+}
+
+// countActivity counts the notes added and edited in each period of the
+// activity and in each day of the heatmap
+func countActivity(db *database.DB, loc *time.Location, period string, activity, heatmap []Activity) error {
+	rows, err := db.Query("SELECT added_on, edited_on FROM notes WHERE deleted = false")
+	if err != nil {
+		return errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	counters := []counter{newCounter(activity, period), newCounter(heatmap, PeriodDay)}
+
+	for rows.Next() {
+		var addedOn, editedOn int64
+		if err := rows.Scan(&addedOn, &editedOn); err != nil {
+			return errors.Wrap(err, "scanning a row")
+		}
+
+		for _, c := range counters {
+			c.record(time.Unix(0, addedOn).In(loc), false)
+			if editedOn != 0 {
+				c.record(time.Unix(0, editedOn).In(loc), true)
+			}
+		}
+	}
+
+	return nil
+}
+
+// New computes a report as of the given time. The activity is grouped by
+// periods in the location of the given time.
+func New(db *database.DB, now time.Time, opts Options) (Report, error) {
+	ret := Report{
+		Period: opts.Period,
+	}
+
+	if err := db.QueryRow("SELECT count(*) FROM notes WHERE deleted = false").Scan(&ret.NoteCount); err != nil {
+		return ret, errors.Wrap(err, "counting notes")
+	}
+	if err := db.QueryRow("SELECT count(*) FROM books WHERE deleted = false").Scan(&ret.BookCount); err != nil {
+		return ret, errors.Wrap(err, "counting books")
+	}
+	if err := db.QueryRow("SELECT count(*) FROM notes WHERE dirty").Scan(&ret.DirtyNotes); err != nil {
+		return ret, errors.Wrap(err, "counting dirty notes")
+	}
+	if err := db.QueryRow("SELECT count(*) FROM books WHERE dirty").Scan(&ret.DirtyBooks); err != nil {
+		return ret, errors.Wrap(err, "counting dirty books")
+	}
+
+	var lastSyncAt int64
+	if err := database.GetSystem(db, consts.SystemLastSyncAt, &lastSyncAt); err != nil {
+		return ret, errors.Wrap(err, "getting the last sync time")
+	}
+	ret.LastSyncAt = lastSyncAt * int64(time.Second)
+
+	books, err := getLargestBooks(db, opts.TopBooks)
+	if err != nil {
+		return ret, errors.Wrap(err, "getting the largest books")
+	}
+	ret.LargestBooks = books
+
+	current := periodStart(now, opts.Period)
+	ret.Activity = newActivity(addPeriods(current, opts.Period, 1-opts.PeriodCount), opts.Period, opts.PeriodCount)
+
+	today := periodStart(now, PeriodDay)
+	heatmapStart := periodStart(today, PeriodWeek).AddDate(0, 0, -7*(HeatmapWeeks-1))
+	heatmapDays := int(today.Sub(heatmapStart).Hours()/24+0.5) + 1
+	ret.Heatmap = newActivity(heatmapStart, PeriodDay, heatmapDays)
+
+	if err := countActivity(db, now.Location(), opts.Period, ret.Activity, ret.Heatmap); err != nil {
+		return ret, errors.Wrap(err, "counting the activity")
+	}
+
+	return ret, nil
+}
+
+// Levels returns the level of activity of each day in the heatmap from 0 to
+// 4. 0 means no activity, and the rest are the quartiles of the busiest day.
+func Levels(heatmap []Activity) []int {
+	max := 0
+	for _, a := range heatmap {
+		if a.Total() > max {
+			max = a.Total()
+		}
+	}
+
+	ret := make([]int, len(heatmap))
+	for i, a := range heatmap {
+		if a.Total() == 0 {
+			continue
+		}
+
+		ret[i] = (a.Total()*4 + max - 1) / max
+	}
+
+	return ret
+}
+
+// ValidatePeriod validates the name of a period
+func ValidatePeriod(period string) error {
+	for _, p := range Periods {
+		if p == period {
+			return nil
+		}
+	}
+
+	return errors.Errorf("invalid period '%s'. Use one of %s", period, strings.Join(Periods, ", "))
+}
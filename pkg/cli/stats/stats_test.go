@@ -0,0 +1,97 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// Wednesday
+	ts := time.Date(2024, time.March, 13, 15, 4, 5, 0, time.UTC)
+
+	assert.Equal(t, periodStart(ts, PeriodDay), time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), "day mismatch")
+	assert.Equal(t, periodStart(ts, PeriodWeek), time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), "week mismatch")
+	assert.Equal(t, periodStart(ts, PeriodMonth), time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), "month mismatch")
+
+	// Sunday belongs to the week starting on the prior Monday
+	sunday := time.Date(2024, time.March, 17, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, periodStart(sunday, PeriodWeek), time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), "sunday week mismatch")
+}
+
+func TestLevels(t *testing.T) {
+	heatmap := []Activity{{Added: 0}, {Added: 1}, {Added: 4, Edited: 2}, {Added: 8}, {Edited: 3}}
+
+	assert.DeepEqual(t, Levels(heatmap), []int{0, 1, 3, 4, 2}, "levels mismatch")
+}
+
+func TestNew(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer database.TeardownTestDB(t, db)
+
+	now := time.Date(2024, time.March, 13, 15, 0, 0, 0, time.UTC)
+	day := func(d int) int64 {
+		return time.Date(2024, time.March, d, 10, 0, 0, 0, time.UTC).UnixNano()
+	}
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, dirty) VALUES (?, ?, ?)", "b1-uuid", "js", true)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b3-uuid", "linux", true)
+
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, dirty) VALUES (?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", day(1), day(12), true)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2", day(12), 0)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3", day(13), 0)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, deleted) VALUES (?, ?, ?, ?, ?, ?)", "n4-uuid", "b2-uuid", "", day(13), 0, true)
+
+	database.MustExec(t, "inserting last sync", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastSyncAt, 1709251200)
+
+	// Execute
+	report, err := New(db, now, Options{Period: PeriodWeek, PeriodCount: 3, TopBooks: 1})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// Test
+	assert.Equal(t, report.NoteCount, 3, "NoteCount mismatch")
+	assert.Equal(t, report.BookCount, 2, "BookCount mismatch")
+	assert.Equal(t, report.DirtyNotes, 1, "DirtyNotes mismatch")
+	assert.Equal(t, report.DirtyBooks, 1, "DirtyBooks mismatch")
+	assert.Equal(t, report.LastSyncAt, int64(1709251200)*int64(time.Second), "LastSyncAt mismatch")
+	assert.DeepEqual(t, report.LargestBooks, []BookCount{{Label: "js", NoteCount: 2}}, "LargestBooks mismatch")
+
+	assert.DeepEqual(t, report.Activity, []Activity{
+		{Start: time.Date(2024, time.February, 26, 0, 0, 0, 0, time.UTC), Added: 1},
+		{Start: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), Added: 2, Edited: 1},
+	}, "Activity mismatch")
+
+	assert.Equal(t, len(report.Heatmap), 7*(HeatmapWeeks-1)+3, "heatmap length mismatch")
+	assert.Equal(t, report.Heatmap[0].Start.Weekday(), time.Monday, "heatmap should start on Monday")
+	last := report.Heatmap[len(report.Heatmap)-1]
+	assert.Equal(t, last.Start, time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), "heatmap should end today")
+	assert.Equal(t, last.Added, 1, "today's added count mismatch")
+	assert.Equal(t, report.Heatmap[len(report.Heatmap)-2].Total(), 2, "yesterday's count mismatch")
+}
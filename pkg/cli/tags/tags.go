@@ -0,0 +1,129 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package tags provides functions to parse the tags of notes
+package tags
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// hashtagRegex matches a hashtag at the start of the text or after a whitespace
+var hashtagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+
+// numericRegex matches tags that are made only of digits, such as issue numbers
+var numericRegex = regexp.MustCompile(`^[0-9]+$`)
+
+// Normalize trims, lowercases and deduplicates the given tags. Empty tags and
+// the leading '#' are dropped. The result is sorted.
+func Normalize(tags []string) []string {
+	seen := map[string]bool{}
+	ret := []string{}
+
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#")))
+		if t == "" || seen[t] {
+			continue
+		}
+
+		seen[t] = true
+		ret = append(ret, t)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// Validate returns an error if the given tag is not valid
+func Validate(tag string) error {
+	t := strings.TrimPrefix(strings.TrimSpace(tag), "#")
+
+	if t == "" {
+		return errors.New("tag is empty")
+	}
+	if strings.ContainsAny(t, " \t\n\r,") {
+		return errors.Errorf("tag '%s' cannot contain whitespaces or commas", tag)
+	}
+
+	return nil
+}
+
+// Extract returns the normalized hashtags found in the given note body
+func Extract(body string) []string {
+	var tags []string
+
+	for _, match := range hashtagRegex.FindAllStringSubmatch(body, -1) {
+		t := strings.Trim(match[1], "/-")
+		if t == "" || numericRegex.MatchString(t) {
+			continue
+		}
+
+		tags = append(tags, t)
+	}
+
+	return Normalize(tags)
+}
+
+// Union returns the normalized union of the given lists of tags
+func Union(lists ...[]string) []string {
+	var all []string
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+
+	return Normalize(all)
+}
+
+// Difference returns the tags in a that are not in b
+func Difference(a, b []string) []string {
+	excluded := map[string]bool{}
+	for _, t := range Normalize(b) {
+		excluded[t] = true
+	}
+
+	ret := []string{}
+	for _, t := range Normalize(a) {
+		if !excluded[t] {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret
+}
+
+// Equal checks if the two given lists contain the same tags
+func Equal(a, b []string) bool {
+	na := Normalize(a)
+	nb := Normalize(b)
+
+	if len(na) != len(nb) {
+		return false
+	}
+	for i := range na {
+		if na[i] != nb[i] {
+			return false
+		}
+	}
+
+	return true
+}
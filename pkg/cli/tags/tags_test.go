@@ -0,0 +1,119 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package tags
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		input    []string
+		expected []string
+	}{
+		{
+			input:    nil,
+			expected: []string{},
+		},
+		{
+			input:    []string{"Go", "#go", " sql ", ""},
+			expected: []string{"go", "sql"},
+		},
+		{
+			input:    []string{"b", "a", "c"},
+			expected: []string{"a", "b", "c"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, Normalize(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "no tags",
+			expected: []string{},
+		},
+		{
+			input:    "#Go is great for #cli-tools",
+			expected: []string{"cli-tools", "go"},
+		},
+		{
+			input:    "see issue #123 and foo#bar",
+			expected: []string{},
+		},
+		{
+			input:    "line one\n#lang/go, #go.",
+			expected: []string{"go", "lang/go"},
+		},
+		{
+			input:    "# Heading\n##h2",
+			expected: []string{},
+		},
+		{
+			input:    "#日本語",
+			expected: []string{"日本語"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.DeepEqual(t, Extract(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "go", expected: true},
+		{input: "#go", expected: true},
+		{input: "lang/go", expected: true},
+		{input: "", expected: false},
+		{input: "#", expected: false},
+		{input: "foo bar", expected: false},
+		{input: "foo,bar", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			err := Validate(tc.input)
+			assert.Equal(t, err == nil, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestUnionDifference(t *testing.T) {
+	assert.DeepEqual(t, Union([]string{"b", "a"}, []string{"A", "c"}), []string{"a", "b", "c"}, "union mismatch")
+	assert.DeepEqual(t, Difference([]string{"a", "b", "c"}, []string{"B"}), []string{"a", "c"}, "difference mismatch")
+	assert.Equal(t, Equal([]string{"a", "b"}, []string{"B", "a"}), true, "equal mismatch")
+	assert.Equal(t, Equal([]string{"a"}, []string{"a", "b"}), false, "equal mismatch")
+}
@@ -0,0 +1,138 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+// Data is the data available to a note template
+type Data struct {
+	// Book is the name of the book that the note is added to
+	Book string
+	// Date is the current date in the format YYYY-MM-DD
+	Date string
+	// Time is the current time in the format HH:MM
+	Time string
+	// Now is the current time. It can be formatted in other ways, such as
+	// {{.Now.Format "Jan 2, 2006"}}
+	Now time.Time
+}
+
+// NewData returns the data for a note template for a note in the given book
+func NewData(ctx context.DnoteCtx, book string) Data {
+	now := ctx.Clock.Now().Local()
+
+	return Data{
+		Book: book,
+		Date: now.Format("2006-01-02"),
+		Time: now.Format("15:04"),
+		Now:  now,
+	}
+}
+
+// PromptFunc gets the user input for the given message
+type PromptFunc func(message string) (string, error)
+
+// Dir returns the path to the directory containing the note templates
+func Dir(ctx context.DnoteCtx) string {
+	return filepath.Join(filepath.Dir(config.GetPath(ctx)), consts.TemplatesDirName)
+}
+
+// List returns the names of the note templates in alphabetical order
+func List(ctx context.DnoteCtx) ([]string, error) {
+	files, err := ioutil.ReadDir(Dir(ctx))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading the templates directory")
+	}
+
+	ret := []string{}
+	for _, f := range files {
+		ext := "." + consts.TemplateFileExt
+		if f.IsDir() || filepath.Ext(f.Name()) != ext {
+			continue
+		}
+
+		ret = append(ret, strings.TrimSuffix(f.Name(), ext))
+	}
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// Render renders the note template with the given name. The template can
+// ask for user input with {{prompt "message"}}, for which prompt is called
+// once per message.
+func Render(ctx context.DnoteCtx, name string, data Data, prompt PromptFunc) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", errors.Errorf("invalid template name '%s'", name)
+	}
+
+	path := filepath.Join(Dir(ctx), fmt.Sprintf("%s.%s", name, consts.TemplateFileExt))
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", errors.Errorf("template '%s' not found. Run 'dnote template ls' to see the templates", name)
+	} else if err != nil {
+		return "", errors.Wrap(err, "reading the template")
+	}
+
+	answers := map[string]string{}
+	funcs := template.FuncMap{
+		"prompt": func(message string) (string, error) {
+			if answer, ok := answers[message]; ok {
+				return answer, nil
+			}
+
+			answer, err := prompt(message)
+			if err != nil {
+				return "", err
+			}
+			answers[message] = answer
+
+			return answer, nil
+		},
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing the template '%s'", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "executing the template '%s'", name)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,135 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/pkg/errors"
+)
+
+func setupTemplates(t *testing.T, ctx context.DnoteCtx, files map[string]string) {
+	dir := Dir(ctx)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the templates directory"))
+	}
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(fmt.Sprintf("%s/%s", dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(errors.Wrapf(err, "writing %s", name))
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	t.Run("no directory", func(t *testing.T) {
+		got, err := List(ctx)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, []string{}, "result mismatch")
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		setupTemplates(t, ctx, map[string]string{
+			"meeting.tmpl":  "# Meeting",
+			"incident.tmpl": "# Incident",
+			"notes.txt":     "not a template",
+		})
+
+		got, err := List(ctx)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, []string{"incident", "meeting"}, "result mismatch")
+	})
+}
+
+func TestRender(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{Data: "../tmp", Config: "../tmp", Cache: "../tmp"}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	c := clock.NewMock()
+	c.SetNow(time.Date(2024, time.March, 14, 21, 15, 0, 0, time.Local))
+	ctx.Clock = c
+
+	setupTemplates(t, ctx, map[string]string{
+		"incident.tmpl": `# {{prompt "Title"}} ({{.Date}} {{.Time}})
+Book: {{.Book}}
+Severity: {{prompt "Severity"}}
+Summary of {{prompt "Title"}} on {{.Now.Format "Jan 2"}}
+`,
+		"broken.tmpl":  `{{.Missing}}`,
+		"invalid.tmpl": `{{if}}`,
+	})
+
+	t.Run("success", func(t *testing.T) {
+		var messages []string
+		prompt := func(message string) (string, error) {
+			messages = append(messages, message)
+			return fmt.Sprintf("%s answer", message), nil
+		}
+
+		got, err := Render(ctx, "incident", NewData(ctx, "work/infra"), prompt)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		expected := `# Title answer (2024-03-14 21:15)
+Book: work/infra
+Severity: Severity answer
+Summary of Title answer on Mar 14
+`
+		assert.Equal(t, got, expected, "result mismatch")
+		assert.DeepEqual(t, messages, []string{"Title", "Severity"}, "each message should be prompted once")
+	})
+
+	testCases := []struct {
+		name string
+	}{
+		{name: "missing"},
+		{name: "broken"},
+		{name: "invalid"},
+		{name: "../dnoterc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("error %s", tc.name), func(t *testing.T) {
+			prompt := func(message string) (string, error) {
+				return "", nil
+			}
+
+			if _, err := Render(ctx, tc.name, NewData(ctx, "js"), prompt); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// scoreMatch is the score of a matched character
+	scoreMatch = 1
+	// scoreConsecutive is the bonus for a character matched right after the previous one
+	scoreConsecutive = 4
+	// scoreWordStart is the bonus for a character matched at the start of a word
+	scoreWordStart = 6
+)
+
+func isWordStart(text []rune, idx int) bool {
+	if idx == 0 {
+		return true
+	}
+
+	prev := text[idx-1]
+
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+}
+
+// fuzzyScore matches the pattern against the text as a case insensitive
+// subsequence. It returns false if the text does not contain all characters of
+// the pattern in order. Otherwise, the score is higher if the characters are
+// matched consecutively or at the start of words.
+func fuzzyScore(pattern, text []rune) (int, bool) {
+	score := 0
+	prevIdx := -2
+	pi := 0
+
+	for ti := 0; ti < len(text) && pi < len(pattern); ti++ {
+		if unicode.ToLower(text[ti]) != unicode.ToLower(pattern[pi]) {
+			continue
+		}
+
+		score += scoreMatch
+		if ti == prevIdx+1 {
+			score += scoreConsecutive
+		}
+		if isWordStart(text, ti) {
+			score += scoreWordStart
+		}
+
+		prevIdx = ti
+		pi++
+	}
+
+	if pi < len(pattern) {
+		return 0, false
+	}
+
+	return score, true
+}
+
+// fuzzyFilter returns the indices of the texts matching all whitespace
+// separated terms of the query, ordered by the best match first. Texts with
+// the same score keep their order. An empty query matches all texts.
+func fuzzyFilter(query string, texts []string) []int {
+	terms := strings.Fields(query)
+
+	type match struct {
+		idx   int
+		score int
+	}
+
+	var matches []match
+	for i, text := range texts {
+		runes := []rune(text)
+
+		total := 0
+		ok := true
+		for _, term := range terms {
+			score, matched := fuzzyScore([]rune(term), runes)
+			if !matched {
+				ok = false
+				break
+			}
+
+			total += score
+		}
+
+		if ok {
+			matches = append(matches, match{idx: i, score: total})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	ret := make([]int, len(matches))
+	for i, m := range matches {
+		ret[i] = m.idx
+	}
+
+	return ret
+}
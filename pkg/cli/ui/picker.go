@@ -0,0 +1,299 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+// ErrPickerCancelled is returned when the user exits the picker without
+// choosing an item
+var ErrPickerCancelled = errors.New("cancelled")
+
+// numberedWidth is the maximum width of the items in the numbered prompt
+const numberedWidth = 80
+
+// PickerItem is an item that can be chosen in the picker
+type PickerItem struct {
+	ID    int
+	Label string
+	Text  string
+}
+
+// summary returns the item on a single line
+func (i PickerItem) summary() string {
+	text := strings.Join(strings.Fields(i.Text), " ")
+
+	return fmt.Sprintf("(%s) (%d) %s", i.Label, i.ID, text)
+}
+
+// picker is the state of the interactive picker
+type picker struct {
+	items   []PickerItem
+	texts   []string
+	query   string
+	matches []int
+	list    struct {
+		idx    int
+		offset int
+	}
+}
+
+func newPicker(items []PickerItem) *picker {
+	p := &picker{items: items}
+
+	p.texts = make([]string, len(items))
+	for i, item := range items {
+		p.texts[i] = fmt.Sprintf("%s %s", item.Label, item.Text)
+	}
+
+	p.filter()
+
+	return p
+}
+
+// filter updates the matches for the query
+func (p *picker) filter() {
+	p.matches = fuzzyFilter(p.query, p.texts)
+	p.list.idx = 0
+	p.list.offset = 0
+}
+
+func (p *picker) move(delta int) {
+	p.list.idx += delta
+	if p.list.idx >= len(p.matches) {
+		p.list.idx = len(p.matches) - 1
+	}
+	if p.list.idx < 0 {
+		p.list.idx = 0
+	}
+}
+
+// handleKey updates the state for the given key. It returns true and the
+// chosen item if the user is done, with ok set to false if cancelled.
+func (p *picker) handleKey(k Key, pageSize int) (done bool, item PickerItem, ok bool) {
+	switch k.Code {
+	case KeyEnter:
+		if len(p.matches) == 0 {
+			return false, item, false
+		}
+
+		return true, p.items[p.matches[p.list.idx]], true
+	case KeyEsc, KeyCtrlC:
+		return true, item, false
+	case KeyUp:
+		p.move(-1)
+	case KeyDown:
+		p.move(1)
+	case KeyPageUp:
+		p.move(-pageSize)
+	case KeyPageDown:
+		p.move(pageSize)
+	case KeyBackspace:
+		if r := []rune(p.query); len(r) > 0 {
+			p.query = string(r[:len(r)-1])
+			p.filter()
+		}
+	case KeyCtrlU:
+		p.query = ""
+		p.filter()
+	case KeyRune:
+		p.query += string(k.Rune)
+		p.filter()
+	}
+
+	return false, item, false
+}
+
+// render draws the picker with the prompt at the top and the matches below
+func (p *picker) render(prompt string, width, height int) []string {
+	listHeight := height - 2
+	if listHeight < 1 {
+		return []string{Fit(fmt.Sprintf("%s> %s", prompt, p.query), width)}
+	}
+
+	if p.list.idx < p.list.offset {
+		p.list.offset = p.list.idx
+	}
+	if p.list.idx >= p.list.offset+listHeight {
+		p.list.offset = p.list.idx - listHeight + 1
+	}
+
+	ret := []string{
+		Fit(fmt.Sprintf("%s> %s", prompt, p.query), width),
+		Fit(fmt.Sprintf("  %d/%d", len(p.matches), len(p.items)), width),
+	}
+
+	for i := 0; i < listHeight; i++ {
+		idx := p.list.offset + i
+		if idx >= len(p.matches) {
+			ret = append(ret, Fit("", width))
+			continue
+		}
+
+		item := p.items[p.matches[idx]]
+		if idx == p.list.idx {
+			ret = append(ret, Reverse(Fit("> "+item.summary(), width)))
+		} else {
+			ret = append(ret, Fit("  "+item.summary(), width))
+		}
+	}
+
+	return ret
+}
+
+// pickInteractive runs the full-screen fuzzy finder
+func pickInteractive(prompt string, items []PickerItem) (PickerItem, error) {
+	screen, err := NewScreen()
+	if err != nil {
+		return PickerItem{}, errors.Wrap(err, "initializing the screen")
+	}
+
+	item, pickErr := func() (PickerItem, error) {
+		p := newPicker(items)
+
+		for {
+			width, height, err := screen.Size()
+			if err != nil {
+				return PickerItem{}, err
+			}
+			if err := screen.Draw(p.render(prompt, width, height)); err != nil {
+				return PickerItem{}, errors.Wrap(err, "drawing the screen")
+			}
+
+			keys, err := screen.ReadKeys()
+			if err != nil {
+				return PickerItem{}, err
+			}
+
+			for _, k := range keys {
+				done, item, ok := p.handleKey(k, height-2)
+				if !done {
+					continue
+				}
+				if !ok {
+					return PickerItem{}, ErrPickerCancelled
+				}
+
+				return item, nil
+			}
+		}
+	}()
+
+	if err := screen.Close(); err != nil {
+		return PickerItem{}, errors.Wrap(err, "restoring the terminal")
+	}
+
+	return item, pickErr
+}
+
+// pickNumbered prints the numbered items and reads the number of the chosen
+// item from the input
+func pickNumbered(r io.Reader, w io.Writer, prompt string, items []PickerItem) (PickerItem, error) {
+	for i, item := range items {
+		fmt.Fprintf(w, "%d) %s\n", i+1, strings.TrimRight(Fit(item.summary(), numberedWidth), " "))
+	}
+	fmt.Fprintf(w, "%s (1-%d): ", prompt, len(items))
+
+	input, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return PickerItem{}, errors.Wrap(err, "reading the input")
+	}
+
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return PickerItem{}, ErrPickerCancelled
+	}
+
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(items) {
+		return PickerItem{}, errors.Errorf("invalid choice '%s'", input)
+	}
+
+	return items[n-1], nil
+}
+
+// Pick lets the user choose one of the items and returns its ID. It opens a
+// fuzzy finder if the terminal is interactive and falls back to a numbered
+// prompt otherwise.
+func Pick(prompt string, items []PickerItem) (int, error) {
+	if len(items) == 0 {
+		return 0, errors.New("nothing to choose from")
+	}
+
+	var item PickerItem
+	var err error
+	if IsTerminal() {
+		item, err = pickInteractive(prompt, items)
+	} else {
+		item, err = pickNumbered(os.Stdin, os.Stdout, prompt, items)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return item.ID, nil
+}
+
+// PickNote lets the user choose a note by searching note bodies and book
+// labels, and returns the rowid of the chosen note. If bookLabel is not empty,
+// only the notes in the book can be chosen.
+func PickNote(ctx context.DnoteCtx, bookLabel string) (int, error) {
+	query := `SELECT notes.rowid, books.label, notes.body
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false`
+	var args []interface{}
+	if bookLabel != "" {
+		cond, bookArgs := database.BookLabelFilter(bookLabel)
+		query = fmt.Sprintf("%s AND %s", query, cond)
+		args = append(args, bookArgs...)
+	}
+
+	rows, err := ctx.DB.Query(query+" ORDER BY notes.added_on DESC", args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	items := []PickerItem{}
+	for rows.Next() {
+		var item PickerItem
+		if err := rows.Scan(&item.ID, &item.Label, &item.Text); err != nil {
+			return 0, errors.Wrap(err, "scanning a row")
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return 0, errors.New("no notes found")
+	}
+
+	return Pick("choose a note", items)
+}
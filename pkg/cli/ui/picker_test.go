@@ -0,0 +1,155 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		text    string
+		matched bool
+	}{
+		{pattern: "mgs", text: "merge sort", matched: true},
+		{pattern: "MERGE", text: "merge sort", matched: true},
+		{pattern: "sm", text: "merge sort", matched: false},
+		{pattern: "", text: "merge sort", matched: true},
+		{pattern: "x", text: "", matched: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s", tc.pattern, tc.text), func(t *testing.T) {
+			_, matched := fuzzyScore([]rune(tc.pattern), []rune(tc.text))
+			assert.Equal(t, matched, tc.matched, "result mismatch")
+		})
+	}
+
+	consecutive, _ := fuzzyScore([]rune("sort"), []rune("sort"))
+	scattered, _ := fuzzyScore([]rune("sort"), []rune("saobrct"))
+	assert.Equal(t, consecutive > scattered, true, "consecutive matches should score higher")
+
+	wordStart, _ := fuzzyScore([]rune("s"), []rune("merge sort"))
+	inWord, _ := fuzzyScore([]rune("s"), []rune("mergesort"))
+	assert.Equal(t, wordStart > inWord, true, "matches at the start of words should score higher")
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	texts := []string{"js closures", "css flexbox", "js promises and closures", "algorithms merge sort"}
+
+	testCases := []struct {
+		query    string
+		expected []int
+	}{
+		{query: "", expected: []int{0, 1, 2, 3}},
+		{query: "clos", expected: []int{0, 2}},
+		{query: "js prom", expected: []int{2}},
+		{query: "flx", expected: []int{1}},
+		{query: "zzz", expected: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			assert.DeepEqual(t, fuzzyFilter(tc.query, texts), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestPicker(t *testing.T) {
+	items := []PickerItem{
+		{ID: 3, Label: "js", Text: "closures"},
+		{ID: 7, Label: "css", Text: "flexbox\nand grid"},
+		{ID: 9, Label: "js", Text: "promises"},
+	}
+
+	p := newPicker(items)
+	assert.Equal(t, len(p.matches), 3, "all items should match initially")
+
+	for _, r := range "js" {
+		p.handleKey(Key{Code: KeyRune, Rune: r}, 10)
+	}
+	assert.Equal(t, len(p.matches), 2, "matches mismatch")
+
+	p.handleKey(Key{Code: KeyDown}, 10)
+	p.handleKey(Key{Code: KeyDown}, 10)
+	done, item, ok := p.handleKey(Key{Code: KeyEnter}, 10)
+	assert.Equal(t, done, true, "done mismatch")
+	assert.Equal(t, ok, true, "ok mismatch")
+	assert.Equal(t, item.ID, 9, "chosen item mismatch")
+
+	p.handleKey(Key{Code: KeyCtrlU}, 10)
+	p.handleKey(Key{Code: KeyRune, Rune: 'z'}, 10)
+	done, _, _ = p.handleKey(Key{Code: KeyEnter}, 10)
+	assert.Equal(t, done, false, "enter without matches should do nothing")
+
+	lines := p.render("choose", 40, 5)
+	assert.Equal(t, len(lines), 5, "number of lines mismatch")
+	assert.Equal(t, strings.TrimSpace(lines[0]), "choose> z", "prompt mismatch")
+	assert.Equal(t, strings.TrimSpace(lines[1]), "0/3", "count mismatch")
+
+	done, _, ok = p.handleKey(Key{Code: KeyEsc}, 10)
+	assert.Equal(t, done, true, "esc should be done")
+	assert.Equal(t, ok, false, "esc should cancel")
+}
+
+func TestPickNumbered(t *testing.T) {
+	items := []PickerItem{
+		{ID: 3, Label: "js", Text: "closures"},
+		{ID: 7, Label: "css", Text: "flexbox\nand grid"},
+	}
+
+	testCases := []struct {
+		input    string
+		expected int
+		err      string
+	}{
+		{input: "2\n", expected: 7},
+		{input: "1", expected: 3},
+		{input: "\n", err: ErrPickerCancelled.Error()},
+		{input: "", err: ErrPickerCancelled.Error()},
+		{input: "3\n", err: "invalid choice '3'"},
+		{input: "foo\n", err: "invalid choice 'foo'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q", tc.input), func(t *testing.T) {
+			var w bytes.Buffer
+			item, err := pickNumbered(strings.NewReader(tc.input), &w, "choose a note", items)
+
+			assert.Equal(t, w.String(), "1) (js) (3) closures\n2) (css) (7) flexbox and grid\nchoose a note (1-2): ", "output mismatch")
+			if tc.err != "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				assert.Equal(t, err.Error(), tc.err, "error mismatch")
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, item.ID, tc.expected, "result mismatch")
+		})
+	}
+}
@@ -0,0 +1,331 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// KeyRune represents a printable character
+	KeyRune = iota
+	// KeyEnter represents the enter key
+	KeyEnter
+	// KeyEsc represents the escape key
+	KeyEsc
+	// KeyBackspace represents the backspace key
+	KeyBackspace
+	// KeyTab represents the tab key
+	KeyTab
+	// KeyUp represents the up arrow key or ctrl-p
+	KeyUp
+	// KeyDown represents the down arrow key or ctrl-n
+	KeyDown
+	// KeyLeft represents the left arrow key
+	KeyLeft
+	// KeyRight represents the right arrow key
+	KeyRight
+	// KeyHome represents the home key
+	KeyHome
+	// KeyEnd represents the end key
+	KeyEnd
+	// KeyPageUp represents the page up key
+	KeyPageUp
+	// KeyPageDown represents the page down key
+	KeyPageDown
+	// KeyCtrlC represents ctrl-c
+	KeyCtrlC
+	// KeyCtrlU represents ctrl-u
+	KeyCtrlU
+	// KeyUnknown represents a key that is not supported
+	KeyUnknown
+)
+
+// Key is a key pressed by the user
+type Key struct {
+	Code int
+	// Rune is the character if Code is KeyRune
+	Rune rune
+}
+
+// escapeSequences maps the escape sequences sent by terminals, without the
+// leading escape character, to keys
+var escapeSequences = map[string]int{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"OC":  KeyRight,
+	"OD":  KeyLeft,
+	"OH":  KeyHome,
+	"OF":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"[7~": KeyHome,
+	"[8~": KeyEnd,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+}
+
+// parseEscape parses the escape sequence at the beginning of the given input,
+// which starts right after the escape character. It returns the key and the
+// number of bytes consumed.
+func parseEscape(b []byte) (Key, int) {
+	if len(b) == 0 || (b[0] != '[' && b[0] != 'O') {
+		return Key{Code: KeyEsc}, 0
+	}
+
+	// a sequence ends with a byte in the range of 0x40 to 0x7e
+	end := 1
+	for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+		end++
+	}
+	if end == len(b) {
+		return Key{Code: KeyUnknown}, len(b)
+	}
+
+	seq := string(b[:end+1])
+	if code, ok := escapeSequences[seq]; ok {
+		return Key{Code: code}, end + 1
+	}
+
+	return Key{Code: KeyUnknown}, end + 1
+}
+
+// ParseKeys decodes the keys in the given input read from a terminal in raw mode
+func ParseKeys(b []byte) []Key {
+	var ret []Key
+
+	for i := 0; i < len(b); {
+		c := b[i]
+
+		switch c {
+		case 0x1b:
+			key, n := parseEscape(b[i+1:])
+			ret = append(ret, key)
+			i += n + 1
+			continue
+		case '\r', '\n':
+			ret = append(ret, Key{Code: KeyEnter})
+		case 0x7f, 0x08:
+			ret = append(ret, Key{Code: KeyBackspace})
+		case '\t':
+			ret = append(ret, Key{Code: KeyTab})
+		case 0x03:
+			ret = append(ret, Key{Code: KeyCtrlC})
+		case 0x15:
+			ret = append(ret, Key{Code: KeyCtrlU})
+		case 0x10:
+			ret = append(ret, Key{Code: KeyUp})
+		case 0x0e:
+			ret = append(ret, Key{Code: KeyDown})
+		default:
+			if c < 0x20 {
+				ret = append(ret, Key{Code: KeyUnknown})
+				break
+			}
+
+			r, size := utf8.DecodeRune(b[i:])
+			ret = append(ret, Key{Code: KeyRune, Rune: r})
+			i += size
+			continue
+		}
+
+		i++
+	}
+
+	return ret
+}
+
+// Fit truncates or pads the given string with spaces so that it occupies
+// exactly the given number of columns
+func Fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	runes := []rune(s)
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+
+		return string(runes[:width-1]) + "…"
+	}
+
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// Wrap splits the given text into lines that fit in the given number of columns
+func Wrap(s string, width int) []string {
+	var ret []string
+	if width <= 0 {
+		return ret
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		runes := []rune(strings.ReplaceAll(line, "\t", "    "))
+		if len(runes) == 0 {
+			ret = append(ret, "")
+			continue
+		}
+
+		for len(runes) > width {
+			ret = append(ret, string(runes[:width]))
+			runes = runes[width:]
+		}
+		ret = append(ret, string(runes))
+	}
+
+	return ret
+}
+
+// Reverse renders the given string in reverse video
+func Reverse(s string) string {
+	return "\x1b[7m" + s + "\x1b[0m"
+}
+
+// Bold renders the given string in bold
+func Bold(s string) string {
+	return "\x1b[1m" + s + "\x1b[0m"
+}
+
+// IsTerminal checks if both the standard input and the standard output are terminals
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Screen is a full-screen terminal user interface drawn on the alternate screen
+type Screen struct {
+	in    *os.File
+	out   *bufio.Writer
+	fd    int
+	state *terminal.State
+}
+
+// NewScreen puts the terminal in raw mode and switches to the alternate screen
+func NewScreen() (*Screen, error) {
+	s := &Screen{
+		in:  os.Stdin,
+		out: bufio.NewWriter(os.Stdout),
+		fd:  int(os.Stdin.Fd()),
+	}
+
+	if err := s.Resume(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Resume puts the terminal back in raw mode after Suspend
+func (s *Screen) Resume() error {
+	state, err := terminal.MakeRaw(s.fd)
+	if err != nil {
+		return errors.Wrap(err, "putting the terminal in raw mode")
+	}
+	s.state = state
+
+	// switch to the alternate screen and hide the cursor
+	s.out.WriteString("\x1b[?1049h\x1b[?25l")
+
+	return s.flush()
+}
+
+// Suspend restores the terminal so that other programs, such as an editor,
+// can use it
+func (s *Screen) Suspend() error {
+	// show the cursor and switch back to the main screen
+	s.out.WriteString("\x1b[?25h\x1b[?1049l")
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	if s.state == nil {
+		return nil
+	}
+
+	if err := terminal.Restore(s.fd, s.state); err != nil {
+		return errors.Wrap(err, "restoring the terminal")
+	}
+	s.state = nil
+
+	return nil
+}
+
+// Close restores the terminal
+func (s *Screen) Close() error {
+	return s.Suspend()
+}
+
+// Size returns the width and the height of the terminal
+func (s *Screen) Size() (int, int, error) {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "getting the terminal size")
+	}
+
+	return width, height, nil
+}
+
+// ReadKeys blocks until the user presses keys and returns them
+func (s *Screen) ReadKeys() ([]Key, error) {
+	buf := make([]byte, 256)
+
+	n, err := s.in.Read(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the input")
+	}
+
+	return ParseKeys(buf[:n]), nil
+}
+
+// Draw clears the screen and prints the given lines from the top. The lines
+// must fit in the width of the terminal.
+func (s *Screen) Draw(lines []string) error {
+	s.out.WriteString("\x1b[H")
+	for i, line := range lines {
+		s.out.WriteString("\x1b[2K")
+		s.out.WriteString(line)
+		if i != len(lines)-1 {
+			s.out.WriteString("\r\n")
+		}
+	}
+	s.out.WriteString("\x1b[J")
+
+	return s.flush()
+}
+
+func (s *Screen) flush() error {
+	if err := s.out.Flush(); err != nil {
+		return errors.Wrap(err, "writing to the terminal")
+	}
+
+	return nil
+}
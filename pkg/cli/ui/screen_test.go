@@ -0,0 +1,108 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []Key
+	}{
+		{
+			input:    "ab",
+			expected: []Key{{Code: KeyRune, Rune: 'a'}, {Code: KeyRune, Rune: 'b'}},
+		},
+		{
+			input:    "é日",
+			expected: []Key{{Code: KeyRune, Rune: 'é'}, {Code: KeyRune, Rune: '日'}},
+		},
+		{
+			input:    "\x1b[A\x1b[B\x1bOC\x1b[D",
+			expected: []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}, {Code: KeyLeft}},
+		},
+		{
+			input:    "\x1b[5~\x1b[6~\x1b[1~\x1b[F",
+			expected: []Key{{Code: KeyPageUp}, {Code: KeyPageDown}, {Code: KeyHome}, {Code: KeyEnd}},
+		},
+		{
+			input:    "\x1b",
+			expected: []Key{{Code: KeyEsc}},
+		},
+		{
+			input:    "\x1b[3~x",
+			expected: []Key{{Code: KeyUnknown}, {Code: KeyRune, Rune: 'x'}},
+		},
+		{
+			input:    "\r\t\x7f\x03\x15\x10\x0e\x01",
+			expected: []Key{{Code: KeyEnter}, {Code: KeyTab}, {Code: KeyBackspace}, {Code: KeyCtrlC}, {Code: KeyCtrlU}, {Code: KeyUp}, {Code: KeyDown}, {Code: KeyUnknown}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q", tc.input), func(t *testing.T) {
+			assert.DeepEqual(t, ParseKeys([]byte(tc.input)), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	testCases := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{input: "foo", width: 5, expected: "foo  "},
+		{input: "foo", width: 3, expected: "foo"},
+		{input: "foobar", width: 4, expected: "foo…"},
+		{input: "日本語", width: 2, expected: "日…"},
+		{input: "foo", width: 1, expected: "…"},
+		{input: "foo", width: 0, expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %d", tc.input, tc.width), func(t *testing.T) {
+			assert.Equal(t, Fit(tc.input, tc.width), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	testCases := []struct {
+		input    string
+		width    int
+		expected []string
+	}{
+		{input: "foo", width: 5, expected: []string{"foo"}},
+		{input: "foobar", width: 3, expected: []string{"foo", "bar"}},
+		{input: "foo\r\n\nbarbaz", width: 4, expected: []string{"foo", "", "barb", "az"}},
+		{input: "\tx", width: 10, expected: []string{"    x"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q %d", tc.input, tc.width), func(t *testing.T) {
+			assert.DeepEqual(t, Wrap(tc.input, tc.width), tc.expected, "result mismatch")
+		})
+	}
+}
@@ -20,6 +20,7 @@ package utils
 
 import (
 	"regexp"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -46,3 +47,66 @@ func IsNumber(s string) bool {
 
 	return regexNumber.MatchString(s)
 }
+
+// regexIDRange is a regex that matches an id or a range of ids such as 3-10
+var regexIDRange = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+
+// IDRange is an inclusive range of ids
+type IDRange struct {
+	Start int
+	End   int
+}
+
+// IsIDRange checks if the given string is an id or a range of ids
+func IsIDRange(s string) bool {
+	return regexIDRange.MatchString(s)
+}
+
+// IsBulkIDs checks if the given arguments are several note ids or ranges of
+// ids, rather than a single note id
+func IsBulkIDs(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	for _, arg := range args {
+		if !IsIDRange(arg) {
+			return false
+		}
+	}
+
+	return len(args) > 1 || !IsNumber(args[0])
+}
+
+// ParseIDRanges parses ids such as 3 and ranges of ids such as 3-10. An id
+// is parsed as a range with the same start and end.
+func ParseIDRanges(args []string) ([]IDRange, error) {
+	ret := []IDRange{}
+
+	for _, arg := range args {
+		m := regexIDRange.FindStringSubmatch(arg)
+		if m == nil {
+			return nil, errors.Errorf("invalid id '%s'", arg)
+		}
+
+		start, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid id '%s'", arg)
+		}
+
+		end := start
+		if m[2] != "" {
+			end, err = strconv.Atoi(m[2])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid id '%s'", arg)
+			}
+		}
+
+		if start > end {
+			return nil, errors.Errorf("invalid range '%s'. the start is greater than the end", arg)
+		}
+
+		ret = append(ret, IDRange{Start: start, End: end})
+	}
+
+	return ret, nil
+}
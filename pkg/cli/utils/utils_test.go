@@ -0,0 +1,120 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024, 2025 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestParseIDRanges(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected []IDRange
+		err      string
+	}{
+		{
+			args:     []string{"3"},
+			expected: []IDRange{{Start: 3, End: 3}},
+		},
+		{
+			args:     []string{"1", "3-10", "7-7"},
+			expected: []IDRange{{Start: 1, End: 1}, {Start: 3, End: 10}, {Start: 7, End: 7}},
+		},
+		{
+			args:     []string{},
+			expected: []IDRange{},
+		},
+		{
+			args: []string{"10-3"},
+			err:  "invalid range '10-3'. the start is greater than the end",
+		},
+		{
+			args: []string{"3", "js"},
+			err:  "invalid id 'js'",
+		},
+		{
+			args: []string{"3-"},
+			err:  "invalid id '3-'",
+		},
+		{
+			args: []string{"-3"},
+			err:  "invalid id '-3'",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			got, err := ParseIDRanges(tc.args)
+
+			if tc.err != "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				assert.Equal(t, err.Error(), tc.err, "error mismatch")
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestIsBulkIDs(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected bool
+	}{
+		{
+			args:     []string{},
+			expected: false,
+		},
+		{
+			args:     []string{"3"},
+			expected: false,
+		},
+		{
+			args:     []string{"3-10"},
+			expected: true,
+		},
+		{
+			args:     []string{"1", "3"},
+			expected: true,
+		},
+		{
+			args:     []string{"js", "3"},
+			expected: false,
+		},
+		{
+			args:     []string{"js"},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v", tc.args), func(t *testing.T) {
+			assert.Equal(t, IsBulkIDs(tc.args), tc.expected, "result mismatch")
+		})
+	}
+}
@@ -70,6 +70,18 @@ func TestValidateBookName(t *testing.T) {
 			input:    "0333",
 			expected: ErrBookNameNumeric,
 		},
+		{
+			input:    "2023-2024",
+			expected: nil,
+		},
+		{
+			input:    "2023-",
+			expected: nil,
+		},
+		{
+			input:    "v2023-2024",
+			expected: nil,
+		},
 		{
 			input:    " javascript",
 			expected: ErrBookNameHasSpace,
@@ -141,6 +153,35 @@ func TestValidateBookName(t *testing.T) {
 			expected: ErrBookNameMultiline,
 		},
 
+		// nested books
+		{
+			input:    "work/infra/k8s",
+			expected: nil,
+		},
+		{
+			input:    "work/2024",
+			expected: nil,
+		},
+		{
+			input:    "/work",
+			expected: ErrBookNameEmptyPart,
+		},
+		{
+			input:    "work/",
+			expected: ErrBookNameEmptyPart,
+		},
+		{
+			input:    "work//infra",
+			expected: ErrBookNameEmptyPart,
+		},
+		{
+			input:    "/",
+			expected: ErrBookNameEmptyPart,
+		},
+		{
+			input:    "work/my infra",
+			expected: ErrBookNameHasSpace,
+		},
 		// reserved book names
 		{
 			input:    "trash",
@@ -42,6 +42,10 @@ var ErrBookNameEmpty = errors.New("The book name is empty")
 // ErrBookNameMultiline is an error for a book name that has linebreaks
 var ErrBookNameMultiline = errors.New("The book name contains multiple lines")
 
+// ErrBookNameEmptyPart is an error for a book path that has an empty part
+// between, before or after the slashes
+var ErrBookNameEmptyPart = errors.New("The book name cannot start or end with '/' or contain '//'")
+
 func isReservedName(name string) bool {
 	for _, n := range reservedBookNames {
 		if name == n {
@@ -52,7 +56,8 @@ func isReservedName(name string) bool {
 	return false
 }
 
-// BookName validates a book name
+// BookName validates a book name. Nested books are named with paths separated
+// by slashes.
 func BookName(name string) error {
 	if name == "" {
 		return ErrBookNameEmpty
@@ -74,5 +79,12 @@ func BookName(name string) error {
 		return ErrBookNameMultiline
 	}
 
+	// a book name can be a path of nested books such as 'work/infra/k8s'
+	for _, part := range strings.Split(name, "/") {
+		if part == "" {
+			return ErrBookNameEmptyPart
+		}
+	}
+
 	return nil
 }
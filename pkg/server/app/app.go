@@ -22,6 +22,7 @@ import (
 	"github.com/dnote/dnote/pkg/clock"
 	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/dnote/dnote/pkg/server/mailer"
+	"github.com/dnote/dnote/pkg/server/storage"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
@@ -39,6 +40,8 @@ var (
 	ErrEmptyEmailBackend = errors.New("No EmailBackend was provided")
 	// ErrEmptyHTTP500Page is an error for missing HTTP 500 page content
 	ErrEmptyHTTP500Page = errors.New("No HTTP 500 error page was set")
+	// ErrEmptyBlobStore is an error for missing BlobStore in the app configuration
+	ErrEmptyBlobStore = errors.New("No BlobStore was provided")
 )
 
 // App is an application context
@@ -50,6 +53,7 @@ type App struct {
 	Config         config.Config
 	Files          map[string][]byte
 	HTTP500Page    []byte
+	BlobStore      storage.BlobStore
 }
 
 // Validate validates the app configuration
@@ -72,6 +76,9 @@ func (a *App) Validate() error {
 	if a.HTTP500Page == nil {
 		return ErrEmptyHTTP500Page
 	}
+	if a.BlobStore == nil {
+		return ErrEmptyBlobStore
+	}
 
 	return nil
 }
@@ -0,0 +1,222 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/helpers"
+	"github.com/dnote/dnote/pkg/server/storage"
+	"github.com/pkg/errors"
+)
+
+// MaxAttachmentSize is the maximum size of an attachment in bytes
+const MaxAttachmentSize = 32 << 20
+
+var hashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// blobKey returns the key of the blob with the given hash in the blob store.
+// Blobs are namespaced by users so that a user cannot read the content of
+// another user by guessing its hash.
+func blobKey(user database.User, hash string) string {
+	return fmt.Sprintf("%s/%s/%s", user.UUID, hash[:2], hash)
+}
+
+// PutBlob stores the content of the given reader as the blob with the given
+// hash. It returns the size of the blob. The content is staged in a temporary
+// file and only stored once it matches the hash and the size limit, so that an
+// invalid upload never replaces a valid blob. If the blob already exists, the
+// content is only verified.
+func (a *App) PutBlob(user database.User, hash string, r io.Reader) (int64, error) {
+	if !hashRegex.MatchString(hash) {
+		return 0, ErrInvalidHash
+	}
+
+	key := blobKey(user, hash)
+	exists, err := a.BlobStore.Has(key)
+	if err != nil {
+		return 0, errors.Wrap(err, "checking the blob")
+	}
+
+	var staging io.Writer = ioutil.Discard
+	var tmp *os.File
+	if !exists {
+		tmp, err = ioutil.TempFile("", "dnote-blob-")
+		if err != nil {
+			return 0, errors.Wrap(err, "creating a staging file")
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		staging = tmp
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(staging, h), io.LimitReader(r, MaxAttachmentSize+1))
+	if err != nil {
+		return 0, errors.Wrap(err, "reading the blob")
+	}
+	if n > MaxAttachmentSize {
+		return 0, ErrAttachmentTooLarge
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hash {
+		return 0, ErrBlobHashMismatch
+	}
+
+	if exists {
+		return n, nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "rewinding the staging file")
+	}
+	if err := a.BlobStore.Put(key, tmp); err != nil {
+		return 0, errors.Wrap(err, "storing the blob")
+	}
+
+	return n, nil
+}
+
+// HasBlob checks if the user has the blob with the given hash
+func (a *App) HasBlob(user database.User, hash string) (bool, error) {
+	if !hashRegex.MatchString(hash) {
+		return false, ErrInvalidHash
+	}
+
+	ok, err := a.BlobStore.Has(blobKey(user, hash))
+	if err != nil {
+		return false, errors.Wrap(err, "checking the blob")
+	}
+
+	return ok, nil
+}
+
+// GetBlob opens the blob of the user with the given hash for reading. It
+// returns ErrNotFound if the blob does not exist.
+func (a *App) GetBlob(user database.User, hash string) (io.ReadCloser, error) {
+	if !hashRegex.MatchString(hash) {
+		return nil, ErrInvalidHash
+	}
+
+	rc, err := a.BlobStore.Get(blobKey(user, hash))
+	if err == storage.ErrBlobNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "getting the blob")
+	}
+
+	return rc, nil
+}
+
+// CreateAttachmentParams is the parameters for creating an attachment
+type CreateAttachmentParams struct {
+	UUID     string
+	NoteUUID string
+	Name     string
+	Hash     string
+	Size     int64
+	AddedOn  int64
+}
+
+// CreateAttachment creates an attachment of a note of the user. The blob of the
+// attachment must have been uploaded. Creating an attachment with the uuid of an
+// existing attachment returns the existing attachment, so that clients can
+// safely retry.
+func (a *App) CreateAttachment(user database.User, p CreateAttachmentParams) (database.Attachment, error) {
+	if !helpers.ValidateUUID(p.UUID) || !helpers.ValidateUUID(p.NoteUUID) {
+		return database.Attachment{}, ErrInvalidUUID
+	}
+	if !hashRegex.MatchString(p.Hash) {
+		return database.Attachment{}, ErrInvalidHash
+	}
+	if p.Name == "" {
+		return database.Attachment{}, ErrAttachmentNameRequired
+	}
+	if p.Size > MaxAttachmentSize {
+		return database.Attachment{}, ErrAttachmentTooLarge
+	}
+
+	var existing database.Attachment
+	conn := a.DB.Where("uuid = ? AND user_id = ?", p.UUID, user.ID).First(&existing)
+	if !conn.RecordNotFound() {
+		if err := conn.Error; err != nil {
+			return database.Attachment{}, errors.Wrap(err, "finding the existing attachment")
+		}
+
+		return existing, nil
+	}
+
+	var note database.Note
+	conn = a.DB.Where("uuid = ? AND user_id = ? AND NOT deleted", p.NoteUUID, user.ID).First(&note)
+	if conn.RecordNotFound() {
+		return database.Attachment{}, ErrNotFound
+	} else if err := conn.Error; err != nil {
+		return database.Attachment{}, errors.Wrap(err, "finding the note")
+	}
+
+	ok, err := a.HasBlob(user, p.Hash)
+	if err != nil {
+		return database.Attachment{}, err
+	}
+	if !ok {
+		return database.Attachment{}, ErrBlobMissing
+	}
+
+	addedOn := p.AddedOn
+	if addedOn == 0 {
+		addedOn = a.Clock.Now().UnixNano()
+	}
+
+	attachment := database.Attachment{
+		UUID:     p.UUID,
+		UserID:   user.ID,
+		NoteUUID: note.UUID,
+		Name:     p.Name,
+		Hash:     p.Hash,
+		Size:     p.Size,
+		AddedOn:  addedOn,
+	}
+	if err := a.DB.Create(&attachment).Error; err != nil {
+		return database.Attachment{}, errors.Wrap(err, "inserting the attachment")
+	}
+
+	return attachment, nil
+}
+
+// GetAttachments returns the attachments of the notes of the user that are not deleted
+func (a *App) GetAttachments(user database.User) ([]database.Attachment, error) {
+	ret := []database.Attachment{}
+
+	if err := a.DB.
+		Joins("INNER JOIN notes ON notes.uuid = attachments.note_uuid").
+		Where("attachments.user_id = ? AND NOT notes.deleted", user.ID).
+		Order("attachments.id ASC").
+		Find(&ret).Error; err != nil {
+		return nil, errors.Wrap(err, "finding attachments")
+	}
+
+	return ret, nil
+}
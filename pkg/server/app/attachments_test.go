@@ -0,0 +1,256 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/storage"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+// sha256 of "foo"
+var fooHash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func newTestBlobApp(t *testing.T) (App, func()) {
+	dir, err := ioutil.TempDir("", "dnote-blobs")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+
+	a := NewTest(&App{
+		Clock:     clock.NewMock(),
+		BlobStore: storage.NewFSBlobStore(dir),
+	})
+
+	return a, func() { os.RemoveAll(dir) }
+}
+
+func TestPutBlob(t *testing.T) {
+	testCases := []struct {
+		name        string
+		hash        string
+		content     string
+		expectedErr error
+	}{
+		{
+			name:        "valid",
+			hash:        fooHash,
+			content:     "foo",
+			expectedErr: nil,
+		},
+		{
+			name:        "hash mismatch",
+			hash:        fooHash,
+			content:     "bar",
+			expectedErr: ErrBlobHashMismatch,
+		},
+		{
+			name:        "invalid hash",
+			hash:        "../foo",
+			content:     "foo",
+			expectedErr: ErrInvalidHash,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			user := testutils.SetupUserData()
+			anotherUser := testutils.SetupUserData()
+
+			a, cleanup := newTestBlobApp(t)
+			defer cleanup()
+
+			size, err := a.PutBlob(user, tc.hash, strings.NewReader(tc.content))
+			assert.Equal(t, err, tc.expectedErr, "error mismatch")
+
+			if tc.expectedErr != nil {
+				if tc.expectedErr != ErrInvalidHash {
+					ok, err := a.HasBlob(user, tc.hash)
+					if err != nil {
+						t.Fatal(errors.Wrap(err, "checking the blob"))
+					}
+					assert.Equal(t, ok, false, "invalid blob should be discarded")
+				}
+				return
+			}
+
+			assert.Equal(t, size, int64(len(tc.content)), "size mismatch")
+
+			rc, err := a.GetBlob(user, tc.hash)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "getting the blob"))
+			}
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "reading the blob"))
+			}
+			assert.Equal(t, string(b), tc.content, "content mismatch")
+
+			// blobs are not shared between users
+			ok, err := a.HasBlob(anotherUser, tc.hash)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "checking the blob of another user"))
+			}
+			assert.Equal(t, ok, false, "another user should not have the blob")
+
+			_, err = a.GetBlob(anotherUser, tc.hash)
+			assert.Equal(t, err, ErrNotFound, "another user get error mismatch")
+		})
+	}
+}
+
+func TestPutBlob_existing(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+
+	a, cleanup := newTestBlobApp(t)
+	defer cleanup()
+
+	if _, err := a.PutBlob(user, fooHash, strings.NewReader("foo")); err != nil {
+		t.Fatal(errors.Wrap(err, "putting the blob"))
+	}
+
+	t.Run("invalid upload", func(t *testing.T) {
+		_, err := a.PutBlob(user, fooHash, strings.NewReader("bar"))
+		assert.Equal(t, err, ErrBlobHashMismatch, "error mismatch")
+	})
+
+	t.Run("valid upload", func(t *testing.T) {
+		size, err := a.PutBlob(user, fooHash, strings.NewReader("foo"))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+		assert.Equal(t, size, int64(3), "size mismatch")
+	})
+
+	// the existing blob is kept intact
+	rc, err := a.GetBlob(user, fooHash)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the blob"))
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading the blob"))
+	}
+	assert.Equal(t, string(b), "foo", "content mismatch")
+}
+
+func TestCreateAttachment(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "b1"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n1"}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	b2 := database.Book{UserID: anotherUser.ID, Label: "b2"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+	n2 := database.Note{UserID: anotherUser.ID, BookUUID: b2.UUID, Body: "n2"}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+
+	a, cleanup := newTestBlobApp(t)
+	defer cleanup()
+
+	params := CreateAttachmentParams{
+		UUID:     "8c0d6f7f-0ae6-4f7a-8bb8-2d7a9b3f6a11",
+		NoteUUID: n1.UUID,
+		Name:     "foo.txt",
+		Hash:     fooHash,
+		Size:     3,
+		AddedOn:  1,
+	}
+
+	// the blob has not been uploaded
+	_, err := a.CreateAttachment(user, params)
+	assert.Equal(t, err, ErrBlobMissing, "error mismatch before upload")
+
+	if _, err := a.PutBlob(user, fooHash, strings.NewReader("foo")); err != nil {
+		t.Fatal(errors.Wrap(err, "putting the blob"))
+	}
+
+	// the note belongs to another user
+	p2 := params
+	p2.NoteUUID = n2.UUID
+	_, err = a.CreateAttachment(user, p2)
+	assert.Equal(t, err, ErrNotFound, "error mismatch for the note of another user")
+
+	attachment, err := a.CreateAttachment(user, params)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating the attachment"))
+	}
+	assert.Equal(t, attachment.UserID, user.ID, "UserID mismatch")
+	assert.Equal(t, attachment.NoteUUID, n1.UUID, "NoteUUID mismatch")
+	assert.Equal(t, attachment.Name, "foo.txt", "Name mismatch")
+
+	// creating the same attachment again is a no-op
+	again, err := a.CreateAttachment(user, params)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating the attachment again"))
+	}
+	assert.Equal(t, again.ID, attachment.ID, "ID mismatch")
+
+	var count int
+	testutils.MustExec(t, testutils.DB.Model(&database.Attachment{}).Count(&count), "counting attachments")
+	assert.Equal(t, count, 1, "count mismatch")
+}
+
+func TestGetAttachments(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "b1"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n1"}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Deleted: true}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+
+	a1 := database.Attachment{UUID: "8c0d6f7f-0ae6-4f7a-8bb8-2d7a9b3f6a11", UserID: user.ID, NoteUUID: n1.UUID, Name: "a1", Hash: fooHash, Size: 3}
+	testutils.MustExec(t, testutils.DB.Save(&a1), "preparing a1")
+	a2 := database.Attachment{UUID: "1b8e0a35-5a5c-4a63-9f0c-5d2a4f9e7b22", UserID: user.ID, NoteUUID: n2.UUID, Name: "a2", Hash: fooHash, Size: 3}
+	testutils.MustExec(t, testutils.DB.Save(&a2), "preparing a2")
+	a3 := database.Attachment{UUID: "f3c1d2e4-6b7a-4c8d-9e0f-1a2b3c4d5e33", UserID: anotherUser.ID, NoteUUID: n1.UUID, Name: "a3", Hash: fooHash, Size: 3}
+	testutils.MustExec(t, testutils.DB.Save(&a3), "preparing a3")
+
+	a := NewTest(nil)
+	result, err := a.GetAttachments(user)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	assert.Equal(t, len(result), 1, "length mismatch")
+	assert.Equal(t, result[0].UUID, a1.UUID, "UUID mismatch")
+}
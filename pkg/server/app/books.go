@@ -19,14 +19,18 @@
 package app
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
 
-// CreateBook creates a book with the next usn and updates the user's max_usn
-func (a *App) CreateBook(user database.User, name string) (database.Book, error) {
+// CreateBook creates a book with the next usn and updates the user's max_usn.
+// An encrypted book has its name encrypted by the client.
+func (a *App) CreateBook(user database.User, name string, encrypted bool) (database.Book, error) {
 	tx := a.DB.Begin()
 
 	nextUSN, err := incrementUserUSN(tx, user.ID)
@@ -46,7 +50,7 @@ func (a *App) CreateBook(user database.User, name string) (database.Book, error)
 		Label:     name,
 		AddedOn:   a.Clock.Now().UnixNano(),
 		USN:       nextUSN,
-		Encrypted: false,
+		Encrypted: encrypted,
 	}
 	if err := tx.Create(&book).Error; err != nil {
 		tx.Rollback()
@@ -81,8 +85,9 @@ func (a *App) DeleteBook(tx *gorm.DB, user database.User, book database.Book) (d
 	return book, nil
 }
 
-// UpdateBook updaates the book, the usn and the user's max_usn
-func (a *App) UpdateBook(tx *gorm.DB, user database.User, book database.Book, label *string) (database.Book, error) {
+// UpdateBook updaates the book, the usn and the user's max_usn. encrypted tells
+// if the given label is encrypted.
+func (a *App) UpdateBook(tx *gorm.DB, user database.User, book database.Book, label *string, encrypted bool) (database.Book, error) {
 	if user.ID != book.UserID {
 		return book, errors.New("Not allowed")
 	}
@@ -94,13 +99,12 @@ func (a *App) UpdateBook(tx *gorm.DB, user database.User, book database.Book, la
 
 	if label != nil {
 		book.Label = *label
+		book.Encrypted = encrypted
 	}
 
 	book.USN = nextUSN
 	book.EditedOn = a.Clock.Now().UnixNano()
 	book.Deleted = false
-	// TODO: remove after all users have been migrated
-	book.Encrypted = false
 
 	if err := tx.Save(&book).Error; err != nil {
 		return book, errors.Wrap(err, "updating the book")
@@ -108,3 +112,51 @@ func (a *App) UpdateBook(tx *gorm.DB, user database.User, book database.Book, la
 
 	return book, nil
 }
+
+// MergeBook moves the notes in the source book to the destination book and
+// marks the source book deleted. Every change gets the next usn so that the
+// merge is replicated to the clients. It returns the destination book.
+func (a *App) MergeBook(tx *gorm.DB, user database.User, src, dst database.Book) (database.Book, error) {
+	if user.ID != src.UserID || user.ID != dst.UserID {
+		return dst, errors.New("Not allowed")
+	}
+	if src.UUID == dst.UUID {
+		return dst, errors.New("cannot merge a book into itself")
+	}
+	if src.Deleted || dst.Deleted {
+		return dst, ErrNotFound
+	}
+
+	var notes []database.Note
+	if err := tx.Where("book_uuid = ? AND NOT deleted", src.UUID).Order("usn ASC").Find(&notes).Error; err != nil {
+		return dst, errors.Wrap(err, "finding notes in the source book")
+	}
+
+	for _, note := range notes {
+		if _, err := a.UpdateNote(tx, user, note, &UpdateNoteParams{BookUUID: &dst.UUID}); err != nil {
+			return dst, errors.Wrap(err, "moving a note")
+		}
+	}
+
+	if _, err := a.DeleteBook(tx, user, src); err != nil {
+		return dst, errors.Wrap(err, "deleting the source book")
+	}
+
+	return dst, nil
+}
+
+// SortBooks sorts the books by their labels so that the books nested in a book
+// come right after it, such as 'work', 'work/infra' and then 'work-old'
+func SortBooks(books []database.Book) {
+	sort.SliceStable(books, func(i, j int) bool {
+		a := strings.Split(books[i].Label, "/")
+		b := strings.Split(books[j].Label, "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return len(a) < len(b)
+	})
+}
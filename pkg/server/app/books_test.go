@@ -66,7 +66,7 @@ func TestCreateBook(t *testing.T) {
 				Clock: clock.NewMock(),
 			})
 
-			book, err := a.CreateBook(user, tc.label)
+			book, err := a.CreateBook(user, tc.label, false)
 			if err != nil {
 				t.Fatal(errors.Wrap(err, "creating book"))
 			}
@@ -215,7 +215,7 @@ func TestUpdateBook(t *testing.T) {
 			})
 
 			tx := testutils.DB.Begin()
-			book, err := a.UpdateBook(tx, user, b, tc.payloadLabel)
+			book, err := a.UpdateBook(tx, user, b, tc.payloadLabel, false)
 			if err != nil {
 				tx.Rollback()
 				t.Fatal(errors.Wrap(err, "updating book"))
@@ -245,3 +245,81 @@ func TestUpdateBook(t *testing.T) {
 		}()
 	}
 }
+
+func TestMergeBook(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 10), "preparing user max_usn")
+
+	b1 := database.Book{UserID: user.ID, Label: "js", USN: 1}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: user.ID, Label: "javascript", USN: 2}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n1 content", USN: 3}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n2 content", USN: 4}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "", USN: 5, Deleted: true}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+	n4 := database.Note{UserID: user.ID, BookUUID: b2.UUID, Body: "n4 content", USN: 6}
+	testutils.MustExec(t, testutils.DB.Save(&n4), "preparing n4")
+
+	tx := testutils.DB.Begin()
+	a := NewTest(nil)
+	if _, err := a.MergeBook(tx, user, b1, b2); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "merging book"))
+	}
+	tx.Commit()
+
+	var b1Record, b2Record database.Book
+	var n1Record, n2Record, n3Record, n4Record database.Note
+	var userRecord database.User
+
+	testutils.MustExec(t, testutils.DB.Where("id = ?", b1.ID).First(&b1Record), "finding b1")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", b2.ID).First(&b2Record), "finding b2")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", n1.ID).First(&n1Record), "finding n1")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", n2.ID).First(&n2Record), "finding n2")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", n3.ID).First(&n3Record), "finding n3")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", n4.ID).First(&n4Record), "finding n4")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user")
+
+	assert.Equal(t, userRecord.MaxUSN, 13, "user max_usn mismatch")
+
+	assert.Equal(t, b1Record.Deleted, true, "b1 deleted mismatch")
+	assert.Equal(t, b1Record.Label, "", "b1 label mismatch")
+	assert.Equal(t, b1Record.USN, 13, "b1 usn mismatch")
+	assert.Equal(t, b2Record.Deleted, false, "b2 deleted mismatch")
+	assert.Equal(t, b2Record.USN, 2, "b2 usn mismatch")
+
+	assert.Equal(t, n1Record.BookUUID, b2.UUID, "n1 book_uuid mismatch")
+	assert.Equal(t, n1Record.Body, "n1 content", "n1 body mismatch")
+	assert.Equal(t, n1Record.USN, 11, "n1 usn mismatch")
+	assert.Equal(t, n2Record.BookUUID, b2.UUID, "n2 book_uuid mismatch")
+	assert.Equal(t, n2Record.USN, 12, "n2 usn mismatch")
+	assert.Equal(t, n3Record.BookUUID, b1.UUID, "n3 book_uuid mismatch")
+	assert.Equal(t, n3Record.USN, 5, "n3 usn mismatch")
+	assert.Equal(t, n4Record.BookUUID, b2.UUID, "n4 book_uuid mismatch")
+	assert.Equal(t, n4Record.USN, 6, "n4 usn mismatch")
+}
+
+func TestSortBooks(t *testing.T) {
+	books := []database.Book{
+		{Label: "work-old"},
+		{Label: "work/infra/k8s"},
+		{Label: "js"},
+		{Label: "work"},
+		{Label: "work/infra"},
+	}
+
+	SortBooks(books)
+
+	got := []string{}
+	for _, b := range books {
+		got = append(got, b.Label)
+	}
+
+	assert.DeepEqual(t, got, []string{"js", "work", "work/infra", "work/infra/k8s", "work-old"}, "order mismatch")
+}
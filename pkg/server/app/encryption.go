@@ -0,0 +1,82 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// EncryptionParams is the parameters for setting up the end-to-end encryption
+// of an account. The password is the current password of the account, and only
+// authorizes the change. The cipher key is encrypted with a key derived from a
+// separate passphrase which is not sent to the server.
+type EncryptionParams struct {
+	Password     string
+	Salt         string
+	Iteration    int
+	CipherKeyEnc string
+	KeyCheck     string
+}
+
+// GetAccount returns the account of the given user
+func (a *App) GetAccount(user database.User) (database.Account, error) {
+	var account database.Account
+	conn := a.DB.Where("user_id = ?", user.ID).First(&account)
+	if conn.RecordNotFound() {
+		return account, ErrNotFound
+	} else if err := conn.Error; err != nil {
+		return account, errors.Wrap(err, "finding account")
+	}
+
+	return account, nil
+}
+
+// UpdateEncryption stores the end-to-end encryption settings of the account
+// of the given user. Once set, the cipher key can only be encrypted again with
+// a new passphrase, and not replaced by another key.
+func (a *App) UpdateEncryption(user database.User, p EncryptionParams) (database.Account, error) {
+	if p.Salt == "" || p.Iteration <= 0 || p.CipherKeyEnc == "" || p.KeyCheck == "" {
+		return database.Account{}, ErrInvalidEncryption
+	}
+
+	account, err := a.GetAccount(user)
+	if err != nil {
+		return account, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.Password.String), []byte(p.Password)); err != nil {
+		return account, ErrInvalidPassword
+	}
+	if account.KeyCheck != "" && account.KeyCheck != p.KeyCheck {
+		return account, ErrEncryptionKeyMismatch
+	}
+
+	account.EncryptionSalt = p.Salt
+	account.EncryptionIteration = p.Iteration
+	account.CipherKeyEnc = p.CipherKeyEnc
+	account.KeyCheck = p.KeyCheck
+
+	if err := a.DB.Save(&account).Error; err != nil {
+		return account, errors.Wrap(err, "saving the encryption settings")
+	}
+
+	return account, nil
+}
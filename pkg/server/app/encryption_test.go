@@ -0,0 +1,99 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func TestUpdateEncryption(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@example.com", "pass1234")
+
+	a := NewTest(nil)
+
+	params := EncryptionParams{
+		Password:     "pass1234",
+		Salt:         "salt1",
+		Iteration:    100000,
+		CipherKeyEnc: "cipherKeyEnc1",
+		KeyCheck:     "keyCheck1",
+	}
+
+	t.Run("incomplete", func(t *testing.T) {
+		p := params
+		p.KeyCheck = ""
+
+		_, err := a.UpdateEncryption(user, p)
+		assert.Equal(t, errors.Cause(err), ErrInvalidEncryption, "error mismatch")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		p := params
+		p.Password = "wrong1234"
+
+		_, err := a.UpdateEncryption(user, p)
+		assert.Equal(t, errors.Cause(err), ErrInvalidPassword, "error mismatch")
+	})
+
+	t.Run("set up", func(t *testing.T) {
+		if _, err := a.UpdateEncryption(user, params); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		var account database.Account
+		testutils.MustExec(t, testutils.DB.Where("user_id = ?", user.ID).First(&account), "finding account")
+		assert.Equal(t, account.EncryptionSalt, "salt1", "EncryptionSalt mismatch")
+		assert.Equal(t, account.EncryptionIteration, 100000, "EncryptionIteration mismatch")
+		assert.Equal(t, account.CipherKeyEnc, "cipherKeyEnc1", "CipherKeyEnc mismatch")
+		assert.Equal(t, account.KeyCheck, "keyCheck1", "KeyCheck mismatch")
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		p := params
+		p.CipherKeyEnc = "cipherKeyEnc2"
+		p.KeyCheck = "keyCheck2"
+
+		_, err := a.UpdateEncryption(user, p)
+		assert.Equal(t, errors.Cause(err), ErrEncryptionKeyMismatch, "error mismatch")
+	})
+
+	t.Run("new passphrase", func(t *testing.T) {
+		p := params
+		p.Salt = "salt2"
+		p.CipherKeyEnc = "cipherKeyEnc2"
+
+		if _, err := a.UpdateEncryption(user, p); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		var account database.Account
+		testutils.MustExec(t, testutils.DB.Where("user_id = ?", user.ID).First(&account), "finding account")
+		assert.Equal(t, account.EncryptionSalt, "salt2", "EncryptionSalt mismatch")
+		assert.Equal(t, account.CipherKeyEnc, "cipherKeyEnc2", "CipherKeyEnc mismatch")
+		assert.Equal(t, account.KeyCheck, "keyCheck1", "KeyCheck mismatch")
+	})
+}
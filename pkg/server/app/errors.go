@@ -58,9 +58,23 @@ var (
 	// ErrEmptyUpdate is an error for empty update params
 	ErrEmptyUpdate appError = "update is empty"
 
+	// ErrInvalidTag is an error for a tag containing whitespaces or commas
+	ErrInvalidTag appError = "tags cannot contain whitespaces or commas"
+
 	// ErrInvalidUUID is an error for invalid uuid
 	ErrInvalidUUID appError = "invalid uuid"
 
+	// ErrInvalidHash is an error for a blob hash that is not a hex encoded sha256 hash
+	ErrInvalidHash appError = "invalid hash"
+	// ErrBlobHashMismatch is an error for a blob whose content does not match its hash
+	ErrBlobHashMismatch appError = "the content does not match the hash"
+	// ErrBlobMissing is an error for an attachment whose blob has not been uploaded
+	ErrBlobMissing appError = "the content of the attachment has not been uploaded"
+	// ErrAttachmentTooLarge is an error for an attachment exceeding the size limit
+	ErrAttachmentTooLarge appError = "the attachment is too large"
+	// ErrAttachmentNameRequired is an error for an attachment missing a name
+	ErrAttachmentNameRequired appError = "attachment name required"
+
 	// ErrInvalidSMTPConfig is an error for invalid SMTP configuration
 	ErrInvalidSMTPConfig appError = "SMTP is not configured"
 
@@ -82,4 +96,9 @@ var (
 
 	// ErrEmailAlreadyVerified is an error for trying to verify email that is already verified
 	ErrEmailAlreadyVerified appError = "Email is already verified."
+
+	// ErrInvalidEncryption is an error for incomplete end-to-end encryption settings
+	ErrInvalidEncryption appError = "invalid encryption settings"
+	// ErrEncryptionKeyMismatch is an error for replacing the end-to-end encryption key of an account with another key
+	ErrEncryptionKeyMismatch appError = "the account is encrypted with another key"
 )
@@ -0,0 +1,246 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// minUUIDPrefixLength is the minimum length of a uuid prefix that links to a note
+const minUUIDPrefixLength = 4
+
+// linkRegex matches a link such as [[3f2a9c]] or [[golang/Channels]]
+var linkRegex = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// uuidPrefixRegex matches the characters that can appear in a uuid
+var uuidPrefixRegex = regexp.MustCompile(`^[0-9a-f-]+$`)
+
+func isUUIDPrefix(target string) bool {
+	return len(target) >= minUUIDPrefixLength && uuidPrefixRegex.MatchString(target)
+}
+
+// splitBookTitle splits a link target into a book label and a note title at
+// the last slash
+func splitBookTitle(target string) (string, string, bool) {
+	idx := strings.LastIndex(target, "/")
+	if idx == -1 {
+		return "", "", false
+	}
+
+	book := strings.TrimSpace(target[:idx])
+	title := strings.TrimSpace(target[idx+1:])
+	if book == "" || title == "" {
+		return "", "", false
+	}
+
+	return book, title, true
+}
+
+// noteTitle returns the first non-empty line of the body without the leading
+// heading markers
+func noteTitle(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
+
+// ExtractLinks returns the targets of the links in the given note body in the
+// order they appear. It follows the same rules as the CLI so that both index
+// the same links.
+func ExtractLinks(body string) []string {
+	seen := map[string]bool{}
+	ret := []string{}
+
+	for _, match := range linkRegex.FindAllStringSubmatch(body, -1) {
+		target := strings.TrimSpace(match[1])
+
+		if lower := strings.ToLower(target); isUUIDPrefix(lower) {
+			target = lower
+		} else if book, title, ok := splitBookTitle(target); ok {
+			target = book + "/" + title
+		} else {
+			continue
+		}
+
+		if seen[target] {
+			continue
+		}
+
+		seen[target] = true
+		ret = append(ret, target)
+	}
+
+	return ret
+}
+
+// indexNoteLinks replaces the links of the note with the links in its body
+func indexNoteLinks(tx *gorm.DB, note database.Note) error {
+	if err := tx.Where("note_uuid = ?", note.UUID).Delete(&database.NoteLink{}).Error; err != nil {
+		return errors.Wrap(err, "deleting links")
+	}
+
+	// The server cannot read the links in an encrypted note
+	if note.Deleted || note.Encrypted {
+		return nil
+	}
+
+	for _, target := range ExtractLinks(note.Body) {
+		link := database.NoteLink{
+			UserID:   note.UserID,
+			NoteUUID: note.UUID,
+			Target:   target,
+		}
+		if err := tx.Create(&link).Error; err != nil {
+			return errors.Wrapf(err, "inserting link '%s'", target)
+		}
+	}
+
+	return nil
+}
+
+// IndexMissingNoteLinks indexes the links of the notes that have links in their
+// body but none indexed, such as the notes saved before the links were indexed.
+// It is safe to run repeatedly. It returns the number of notes indexed.
+func (a *App) IndexMissingNoteLinks() (int, error) {
+	var notes []database.Note
+	if err := a.DB.Where(`NOT deleted AND NOT encrypted AND body LIKE ?
+		AND NOT EXISTS (SELECT 1 FROM note_links WHERE note_links.note_uuid = notes.uuid)`, "%[[%").
+		Find(&notes).Error; err != nil {
+		return 0, errors.Wrap(err, "finding notes")
+	}
+
+	tx := a.DB.Begin()
+	for _, note := range notes {
+		if err := indexNoteLinks(tx, note); err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "indexing links of note %s", note.UUID)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, errors.Wrap(err, "committing the transaction")
+	}
+
+	return len(notes), nil
+}
+
+// resolveLink returns the uuid of the note of the user that the link target
+// leads to. It returns an empty string if no note or more than one note matches.
+func resolveLink(db *gorm.DB, userID int, target string) (string, error) {
+	var uuids []string
+
+	if isUUIDPrefix(target) {
+		if err := db.Model(&database.Note{}).
+			Where("user_id = ? AND NOT deleted AND uuid::text LIKE ?", userID, target+"%").
+			Limit(2).Pluck("uuid", &uuids).Error; err != nil {
+			return "", errors.Wrap(err, "finding notes by uuid")
+		}
+	} else if book, title, ok := splitBookTitle(target); ok {
+		var notes []database.Note
+		if err := db.Joins("INNER JOIN books ON books.uuid = notes.book_uuid").
+			Where("notes.user_id = ? AND NOT notes.deleted AND books.label = ? AND NOT books.deleted", userID, book).
+			Find(&notes).Error; err != nil {
+			return "", errors.Wrap(err, "finding notes by title")
+		}
+
+		for _, n := range notes {
+			if strings.EqualFold(noteTitle(n.Body), title) {
+				uuids = append(uuids, n.UUID)
+			}
+		}
+	}
+
+	if len(uuids) != 1 {
+		return "", nil
+	}
+
+	return uuids[0], nil
+}
+
+// GetBacklinks returns the notes of the user that link to the given note
+func (a *App) GetBacklinks(user database.User, note database.Note) ([]database.Note, error) {
+	var book database.Book
+	if err := a.DB.Where("uuid = ?", note.BookUUID).First(&book).Error; err != nil {
+		return nil, errors.Wrap(err, "finding the book")
+	}
+	title := noteTitle(note.Body)
+
+	// Only consider the links whose target is a prefix of the uuid of the note,
+	// or its book label and title
+	var links []database.NoteLink
+	if err := a.DB.Where("user_id = ? AND note_uuid != ? AND (? LIKE (target || '%') OR lower(target) = lower(?))",
+		user.ID, note.UUID, note.UUID, book.Label+"/"+title).Order("id ASC").Find(&links).Error; err != nil {
+		return nil, errors.Wrap(err, "finding links")
+	}
+
+	resolved := map[string]string{}
+	seen := map[string]bool{}
+	uuids := []string{}
+	for _, l := range links {
+		if seen[l.NoteUUID] {
+			continue
+		}
+
+		if isUUIDPrefix(l.Target) {
+			if !strings.HasPrefix(note.UUID, l.Target) {
+				continue
+			}
+		} else if b, t, ok := splitBookTitle(l.Target); !ok || b != book.Label || !strings.EqualFold(t, title) {
+			continue
+		}
+
+		// Leave out the links that match other notes as well
+		uuid, ok := resolved[l.Target]
+		if !ok {
+			var err error
+			uuid, err = resolveLink(a.DB, user.ID, l.Target)
+			if err != nil {
+				return nil, errors.Wrapf(err, "resolving link '%s'", l.Target)
+			}
+			resolved[l.Target] = uuid
+		}
+		if uuid != note.UUID {
+			continue
+		}
+
+		seen[l.NoteUUID] = true
+		uuids = append(uuids, l.NoteUUID)
+	}
+
+	notes := []database.Note{}
+	if len(uuids) == 0 {
+		return notes, nil
+	}
+
+	conn := a.DB.Where("user_id = ? AND uuid IN (?) AND NOT deleted", user.ID, uuids).Order("id ASC")
+	if err := database.PreloadNote(conn).Find(&notes).Error; err != nil {
+		return nil, errors.Wrap(err, "finding notes")
+	}
+
+	return notes, nil
+}
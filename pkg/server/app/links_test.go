@@ -0,0 +1,192 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func TestExtractLinks(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "no links",
+			expected: []string{},
+		},
+		{
+			input:    "see [[3F2A9C]] and [[golang/Channels]] [[3f2a9c]]",
+			expected: []string{"3f2a9c", "golang/Channels"},
+		},
+		{
+			input:    "[[ work/infra / Deploy steps ]]",
+			expected: []string{"work/infra/Deploy steps"},
+		},
+		{
+			input:    "[[abc]] [[word]] [[/title]] [[book/]]",
+			expected: []string{},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, ExtractLinks(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func getLinkTargets(t *testing.T, noteUUID string) []string {
+	var links []database.NoteLink
+	testutils.MustExec(t, testutils.DB.Where("note_uuid = ?", noteUUID).Order("id ASC").Find(&links), "finding links")
+
+	ret := []string{}
+	for _, l := range links {
+		ret = append(ret, l.Target)
+	}
+
+	return ret
+}
+
+func TestIndexNoteLinks(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	a := NewTest(&App{
+		Clock: clock.NewMock(),
+	})
+
+	note, err := a.CreateNote(user, b1.UUID, "see [[js/closures]]", nil, nil, false, false, nil, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating note"))
+	}
+	assert.DeepEqual(t, getLinkTargets(t, note.UUID), []string{"js/closures"}, "links mismatch after create")
+
+	content := "see [[abcd1234]]"
+	tx := testutils.DB.Begin()
+	note, err = a.UpdateNote(tx, user, note, &UpdateNoteParams{Content: &content})
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "updating note"))
+	}
+	tx.Commit()
+	assert.DeepEqual(t, getLinkTargets(t, note.UUID), []string{"abcd1234"}, "links mismatch after update")
+
+	tx = testutils.DB.Begin()
+	if _, err := a.DeleteNote(tx, user, note); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "deleting note"))
+	}
+	tx.Commit()
+	assert.DeepEqual(t, getLinkTargets(t, note.UUID), []string{}, "links mismatch after delete")
+}
+
+func TestIndexMissingNoteLinks(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	// notes saved before the links were indexed
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "see [[js/closures]]"}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "no links"}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "encrypted [[js/closures]]", Encrypted: true}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+
+	a := NewTest(&App{
+		Clock: clock.NewMock(),
+	})
+
+	count, err := a.IndexMissingNoteLinks()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, count, 1, "count mismatch")
+	assert.DeepEqual(t, getLinkTargets(t, n1.UUID), []string{"js/closures"}, "n1 links mismatch")
+	assert.DeepEqual(t, getLinkTargets(t, n2.UUID), []string{}, "n2 links mismatch")
+	assert.DeepEqual(t, getLinkTargets(t, n3.UUID), []string{}, "n3 links mismatch")
+
+	// the indexed notes are skipped afterwards
+	count, err = a.IndexMissingNoteLinks()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing again"))
+	}
+	assert.Equal(t, count, 0, "count mismatch on the second run")
+}
+
+func TestGetBacklinks(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "golang"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: anotherUser.ID, Label: "golang"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+
+	a := NewTest(&App{
+		Clock: clock.NewMock(),
+	})
+
+	n1, err := a.CreateNote(user, b1.UUID, "# Channels\nsend and receive", nil, nil, false, false, nil, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating n1"))
+	}
+	n2, err := a.CreateNote(user, b1.UUID, "see [[golang/channels]]", nil, nil, false, false, nil, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating n2"))
+	}
+	n3, err := a.CreateNote(user, b1.UUID, fmt.Sprintf("[[%s]]", n1.UUID[:8]), nil, nil, false, false, nil, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating n3"))
+	}
+	// links to another user's notes are not backlinks
+	if _, err := a.CreateNote(anotherUser, b2.UUID, "[[golang/channels]]", nil, nil, false, false, nil, ""); err != nil {
+		t.Fatal(errors.Wrap(err, "creating n4"))
+	}
+
+	result, err := a.GetBacklinks(user, n1)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	assert.Equal(t, len(result), 2, "length mismatch")
+	assert.Equal(t, result[0].UUID, n2.UUID, "result[0] UUID mismatch")
+	assert.Equal(t, result[1].UUID, n3.UUID, "result[1] UUID mismatch")
+
+	result, err = a.GetBacklinks(user, n2)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, len(result), 0, "length mismatch for n2")
+}
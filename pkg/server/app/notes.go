@@ -19,18 +19,50 @@
 package app
 
 import (
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
+// NormalizeTags trims, lowercases, deduplicates and sorts the given tags. It
+// returns ErrInvalidTag if any tag contains whitespaces or commas.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	ret := []string{}
+
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "#"))
+		if t == "" || seen[t] {
+			continue
+		}
+		if strings.ContainsAny(t, " \t\n\r,") {
+			return nil, ErrInvalidTag
+		}
+
+		seen[t] = true
+		ret = append(ret, t)
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
 // CreateNote creates a note with the next usn and updates the user's max_usn.
-// It returns the created note.
-func (a *App) CreateNote(user database.User, bookUUID, content string, addedOn *int64, editedOn *int64, public bool, client string) (database.Note, error) {
+// It returns the created note. An encrypted note has its content and tags
+// encrypted by the client into the content.
+func (a *App) CreateNote(user database.User, bookUUID, content string, addedOn *int64, editedOn *int64, public, encrypted bool, tags []string, client string) (database.Note, error) {
+	noteTags, err := NormalizeTags(tags)
+	if err != nil {
+		return database.Note{}, err
+	}
+
 	tx := a.DB.Begin()
 
 	nextUSN, err := incrementUserUSN(tx, user.ID)
@@ -67,14 +99,20 @@ func (a *App) CreateNote(user database.User, bookUUID, content string, addedOn *
 		USN:       nextUSN,
 		Body:      content,
 		Public:    public,
-		Encrypted: false,
+		Encrypted: encrypted,
 		Client:    client,
+		Tags:      pq.StringArray(noteTags),
 	}
 	if err := tx.Create(&note).Error; err != nil {
 		tx.Rollback()
 		return note, errors.Wrap(err, "inserting note")
 	}
 
+	if err := indexNoteLinks(tx, note); err != nil {
+		tx.Rollback()
+		return note, errors.Wrap(err, "indexing links")
+	}
+
 	tx.Commit()
 
 	return note, nil
@@ -85,6 +123,9 @@ type UpdateNoteParams struct {
 	BookUUID *string
 	Content  *string
 	Public   *bool
+	Tags     *[]string
+	// Encrypted tells if the content is encrypted. It is only used with the content.
+	Encrypted *bool
 }
 
 // GetBookUUID gets the bookUUID from the UpdateNoteParams
@@ -114,8 +155,36 @@ func (r UpdateNoteParams) GetPublic() bool {
 	return *r.Public
 }
 
+// GetTags gets the tags from the UpdateNoteParams
+func (r UpdateNoteParams) GetTags() []string {
+	if r.Tags == nil {
+		return []string{}
+	}
+
+	return *r.Tags
+}
+
+// GetEncrypted gets the encrypted field from the UpdateNoteParams
+func (r UpdateNoteParams) GetEncrypted() bool {
+	if r.Encrypted == nil {
+		return false
+	}
+
+	return *r.Encrypted
+}
+
 // UpdateNote creates a note with the next usn and updates the user's max_usn
 func (a *App) UpdateNote(tx *gorm.DB, user database.User, note database.Note, p *UpdateNoteParams) (database.Note, error) {
+	var noteTags []string
+	if p.Tags != nil {
+		t, err := NormalizeTags(p.GetTags())
+		if err != nil {
+			return note, err
+		}
+
+		noteTags = t
+	}
+
 	nextUSN, err := incrementUserUSN(tx, user.ID)
 	if err != nil {
 		return note, errors.Wrap(err, "incrementing user max_usn")
@@ -126,21 +195,29 @@ func (a *App) UpdateNote(tx *gorm.DB, user database.User, note database.Note, p
 	}
 	if p.Content != nil {
 		note.Body = p.GetContent()
+		note.Encrypted = p.GetEncrypted()
 	}
 	if p.Public != nil {
 		note.Public = p.GetPublic()
 	}
+	if p.Tags != nil {
+		note.Tags = pq.StringArray(noteTags)
+	}
 
 	note.USN = nextUSN
 	note.EditedOn = a.Clock.Now().UnixNano()
 	note.Deleted = false
-	// TODO: remove after all users are migrated
-	note.Encrypted = false
 
 	if err := tx.Save(&note).Error; err != nil {
 		return note, errors.Wrap(err, "editing note")
 	}
 
+	if p.Content != nil {
+		if err := indexNoteLinks(tx, note); err != nil {
+			return note, errors.Wrap(err, "indexing links")
+		}
+	}
+
 	return note, nil
 }
 
@@ -160,6 +237,10 @@ func (a *App) DeleteNote(tx *gorm.DB, user database.User, note database.Note) (d
 		return note, errors.Wrap(err, "deleting note")
 	}
 
+	if err := indexNoteLinks(tx, note); err != nil {
+		return note, errors.Wrap(err, "deleting links")
+	}
+
 	return note, nil
 }
 
@@ -187,6 +268,8 @@ type GetNotesParams struct {
 	Search    string
 	Encrypted bool
 	PerPage   int
+	// Tags filters the notes that have all of the given tags
+	Tags []string
 }
 
 type ftsParams struct {
@@ -224,6 +307,7 @@ notes.edited_on,
 notes.usn,
 notes.deleted,
 notes.encrypted,
+notes.tags,
 ts_headline('english_nostop', notes.body, plainto_tsquery('english_nostop', ?), ?) AS body
 	`, search, headlineOpts)
 }
@@ -244,6 +328,10 @@ func getNotesBaseQuery(db *gorm.DB, userID int, q GetNotesParams) *gorm.DB {
 			Where("books.label in (?)", q.Books)
 	}
 
+	if len(q.Tags) > 0 {
+		conn = conn.Where("notes.tags @> ?", pq.Array(q.Tags))
+	}
+
 	if q.Year != 0 || q.Month != 0 {
 		dateLowerbound, dateUpperbound := getDateBounds(q.Year, q.Month)
 		conn = conn.Where("notes.added_on >= ? AND notes.added_on < ?", dateLowerbound, dateUpperbound)
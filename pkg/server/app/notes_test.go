@@ -90,7 +90,7 @@ func TestCreateNote(t *testing.T) {
 			})
 
 			tx := testutils.DB.Begin()
-			if _, err := a.CreateNote(user, b1.UUID, "note content", tc.addedOn, tc.editedOn, false, ""); err != nil {
+			if _, err := a.CreateNote(user, b1.UUID, "note content", tc.addedOn, tc.editedOn, false, false, nil, ""); err != nil {
 				tx.Rollback()
 				t.Fatal(errors.Wrap(err, "deleting note"))
 			}
@@ -154,6 +154,7 @@ func TestUpdateNote(t *testing.T) {
 			c := clock.NewMock()
 			content := "updated test content"
 			public := true
+			tags := []string{"Go", "go", "sql"}
 
 			a := NewTest(&App{
 				Clock: c,
@@ -163,6 +164,7 @@ func TestUpdateNote(t *testing.T) {
 			if _, err := a.UpdateNote(tx, user, note, &UpdateNoteParams{
 				Content: &content,
 				Public:  &public,
+				Tags:    &tags,
 			}); err != nil {
 				tx.Rollback()
 				t.Fatal(errors.Wrap(err, "deleting note"))
@@ -184,6 +186,7 @@ func TestUpdateNote(t *testing.T) {
 			assert.Equal(t, noteRecord.UserID, user.ID, "note UserID mismatch")
 			assert.Equal(t, noteRecord.Body, content, "note Body mismatch")
 			assert.Equal(t, noteRecord.Public, public, "note Public mismatch")
+			assert.DeepEqual(t, []string(noteRecord.Tags), []string{"go", "sql"}, "note Tags mismatch")
 			assert.Equal(t, noteRecord.Deleted, false, "note Deleted mismatch")
 			assert.Equal(t, noteRecord.USN, expectedUSN, "note USN mismatch")
 			assert.Equal(t, userRecord.MaxUSN, expectedUSN, "user MaxUSN mismatch")
@@ -191,6 +194,89 @@ func TestUpdateNote(t *testing.T) {
 	}
 }
 
+func TestUpdateNote_encrypted(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: user.ID, Label: "css"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+
+	a := NewTest(&App{
+		Clock: clock.NewMock(),
+	})
+
+	note, err := a.CreateNote(user, b1.UUID, "see [[js/closures]]", nil, nil, false, false, nil, "")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating note"))
+	}
+
+	update := func(p *UpdateNoteParams) database.Note {
+		tx := testutils.DB.Begin()
+		ret, err := a.UpdateNote(tx, user, note, p)
+		if err != nil {
+			tx.Rollback()
+			t.Fatal(errors.Wrap(err, "updating note"))
+		}
+		tx.Commit()
+
+		return ret
+	}
+
+	content := "encrypted [[js/closures]]"
+	encrypted := true
+	note = update(&UpdateNoteParams{Content: &content, Encrypted: &encrypted})
+	assert.Equal(t, note.Encrypted, true, "Encrypted mismatch after encrypting")
+
+	var linkCount int
+	testutils.MustExec(t, testutils.DB.Model(&database.NoteLink{}).Where("note_uuid = ?", note.UUID).Count(&linkCount), "counting links")
+	assert.Equal(t, linkCount, 0, "links of an encrypted note should not be indexed")
+
+	note = update(&UpdateNoteParams{BookUUID: &b2.UUID})
+	assert.Equal(t, note.Encrypted, true, "Encrypted mismatch after moving")
+
+	content = "plaintext"
+	note = update(&UpdateNoteParams{Content: &content})
+	assert.Equal(t, note.Encrypted, false, "Encrypted mismatch after replacing the content")
+}
+
+func TestNormalizeTags(t *testing.T) {
+	testCases := []struct {
+		input       []string
+		expected    []string
+		expectedErr error
+	}{
+		{
+			input:    nil,
+			expected: []string{},
+		},
+		{
+			input:    []string{"b", "#A", " a ", ""},
+			expected: []string{"a", "b"},
+		},
+		{
+			input:       []string{"foo bar"},
+			expectedErr: ErrInvalidTag,
+		},
+		{
+			input:       []string{"foo,bar"},
+			expectedErr: ErrInvalidTag,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			result, err := NormalizeTags(tc.input)
+
+			assert.Equal(t, err, tc.expectedErr, "error mismatch")
+			if tc.expectedErr == nil {
+				assert.DeepEqual(t, result, tc.expected, "result mismatch")
+			}
+		})
+	}
+}
+
 func TestDeleteNote(t *testing.T) {
 	testCases := []struct {
 		userUSN     int
@@ -20,10 +20,13 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/dnote/dnote/pkg/clock"
 	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/dnote/dnote/pkg/server/mailer"
+	"github.com/dnote/dnote/pkg/server/storage"
 	"github.com/dnote/dnote/pkg/server/testutils"
 )
 
@@ -39,6 +42,7 @@ func NewTest(appParams *App) App {
 		EmailBackend:   &testutils.MockEmailbackendImplementation{},
 		Config:         c,
 		HTTP500Page:    []byte("<html></html>"),
+		BlobStore:      storage.NewFSBlobStore(filepath.Join(os.TempDir(), "dnote-test-blobs")),
 	}
 
 	// Allow to override with appParams
@@ -54,6 +58,9 @@ func NewTest(appParams *App) App {
 	if appParams != nil && appParams.Config.OnPremises {
 		a.Config.OnPremises = appParams.Config.OnPremises
 	}
+	if appParams != nil && appParams.BlobStore != nil {
+		a.BlobStore = appParams.BlobStore
+	}
 	if appParams != nil && appParams.Config.WebURL != "" {
 		a.Config.WebURL = appParams.Config.WebURL
 	}
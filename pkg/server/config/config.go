@@ -108,6 +108,8 @@ type Config struct {
 	DB                  PostgresConfig
 	AssetBaseURL        string
 	HTTP500Page         []byte
+	// AttachmentsDir is the directory in which the contents of attachments are stored
+	AttachmentsDir string
 }
 
 func getAppEnv() string {
@@ -134,6 +136,11 @@ func Load() Config {
 		port = "3000"
 	}
 
+	attachmentsDir := os.Getenv("AttachmentsDir")
+	if attachmentsDir == "" {
+		attachmentsDir = "attachments"
+	}
+
 	checkDeprecatedEnvVars()
 
 	c := Config{
@@ -145,6 +152,7 @@ func Load() Config {
 		DB:                  loadDBConfig(),
 		AssetBaseURL:        "",
 		HTTP500Page:         assets.MustGetHTTP500ErrorPage(),
+		AttachmentsDir:      attachmentsDir,
 	}
 
 	if err := validate(c); err != nil {
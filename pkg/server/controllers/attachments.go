@@ -0,0 +1,193 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package controllers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/context"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/log"
+	"github.com/dnote/dnote/pkg/server/presenters"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// NewAttachments creates a new Attachments controller.
+func NewAttachments(app *app.App) *Attachments {
+	return &Attachments{
+		app: app,
+	}
+}
+
+// Attachments is a controller for attachments and their blobs.
+type Attachments struct {
+	app *app.App
+}
+
+// GetAttachmentsResponse is a response for getting the attachments
+type GetAttachmentsResponse struct {
+	Attachments []presenters.Attachment `json:"attachments"`
+}
+
+// V3Index gets the attachments of the notes of the user
+func (a *Attachments) V3Index(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "getting attachments")
+		return
+	}
+
+	attachments, err := a.app.GetAttachments(*user)
+	if err != nil {
+		handleJSONError(w, err, "getting attachments")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, GetAttachmentsResponse{
+		Attachments: presenters.PresentAttachments(attachments),
+	})
+}
+
+type createAttachmentPayload struct {
+	UUID     string `schema:"uuid" json:"uuid"`
+	NoteUUID string `schema:"note_uuid" json:"note_uuid"`
+	Name     string `schema:"name" json:"name"`
+	Hash     string `schema:"hash" json:"hash"`
+	Size     int64  `schema:"size" json:"size"`
+	AddedOn  int64  `schema:"added_on" json:"added_on"`
+}
+
+// CreateAttachmentResp is a response for creating an attachment
+type CreateAttachmentResp struct {
+	Attachment presenters.Attachment `json:"attachment"`
+}
+
+func (a *Attachments) create(r *http.Request) (database.Attachment, error) {
+	user := context.User(r.Context())
+	if user == nil {
+		return database.Attachment{}, app.ErrLoginRequired
+	}
+
+	var params createAttachmentPayload
+	if err := parseRequestData(r, &params); err != nil {
+		return database.Attachment{}, errors.Wrap(err, "parsing request payload")
+	}
+
+	attachment, err := a.app.CreateAttachment(*user, app.CreateAttachmentParams{
+		UUID:     params.UUID,
+		NoteUUID: params.NoteUUID,
+		Name:     params.Name,
+		Hash:     params.Hash,
+		Size:     params.Size,
+		AddedOn:  params.AddedOn,
+	})
+	if err != nil {
+		return database.Attachment{}, errors.Wrap(err, "creating attachment")
+	}
+
+	return attachment, nil
+}
+
+// V3Create creates an attachment. Its blob must have been uploaded beforehand.
+func (a *Attachments) V3Create(w http.ResponseWriter, r *http.Request) {
+	attachment, err := a.create(r)
+	if err != nil {
+		handleJSONError(w, err, "creating attachment")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, CreateAttachmentResp{
+		Attachment: presenters.PresentAttachment(attachment),
+	})
+}
+
+// PutBlobResp is a response for uploading a blob
+type PutBlobResp struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// V3PutBlob uploads the content of a blob in the request body. The content
+// must match the hash in the path.
+func (a *Attachments) V3PutBlob(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "uploading blob")
+		return
+	}
+
+	hash := mux.Vars(r)["hash"]
+	size, err := a.app.PutBlob(*user, hash, r.Body)
+	if err != nil {
+		handleJSONError(w, err, "uploading blob")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, PutBlobResp{
+		Hash: hash,
+		Size: size,
+	})
+}
+
+// V3HeadBlob checks if a blob exists
+func (a *Attachments) V3HeadBlob(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "checking blob")
+		return
+	}
+
+	ok, err := a.app.HasBlob(*user, mux.Vars(r)["hash"])
+	if err != nil {
+		handleJSONError(w, err, "checking blob")
+		return
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// V3GetBlob downloads the content of a blob
+func (a *Attachments) V3GetBlob(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "getting blob")
+		return
+	}
+
+	rc, err := a.app.GetBlob(*user, mux.Vars(r)["hash"])
+	if err != nil {
+		handleJSONError(w, err, "getting blob")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, rc); err != nil {
+		log.ErrorWrap(err, "writing blob")
+	}
+}
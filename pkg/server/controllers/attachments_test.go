@@ -0,0 +1,129 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/config"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/storage"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func TestAttachments(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	dir, err := ioutil.TempDir("", "dnote-blobs")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	server := MustNewServer(t, &app.App{
+		Clock:     clock.NewMock(),
+		Config:    config.Config{},
+		BlobStore: storage.NewFSBlobStore(dir),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+	anotherUser := testutils.SetupUserData()
+	testutils.SetupAccountData(anotherUser, "bob@test.com", "pass1234")
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "Closures", USN: 1}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+	// sha256 of "foo"
+	hash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	blobEndpoint := fmt.Sprintf("/api/v3/blobs/%s", hash)
+
+	t.Run("blob", func(t *testing.T) {
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "HEAD", blobEndpoint, ""), user)
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "head before upload")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "PUT", blobEndpoint, "bar"), user)
+		assert.StatusCodeEquals(t, res, http.StatusBadRequest, "upload with mismatching content")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "PUT", blobEndpoint, "foo"), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "upload")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "HEAD", blobEndpoint, ""), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "head after upload")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "GET", blobEndpoint, ""), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "download")
+		assert.Equal(t, res.Header.Get("Content-Type"), "application/octet-stream", "Content-Type mismatch")
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the body"))
+		}
+		assert.Equal(t, string(b), "foo", "content mismatch")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "GET", blobEndpoint, ""), anotherUser)
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "download by another user")
+	})
+
+	t.Run("attachment", func(t *testing.T) {
+		payload := fmt.Sprintf(`{"uuid": "8c0d6f7f-0ae6-4f7a-8bb8-2d7a9b3f6a11", "note_uuid": "%s", "name": "foo.txt", "hash": "%s", "size": 3, "added_on": 1}`, n1.UUID, hash)
+
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "POST", "/api/v3/attachments", payload), anotherUser)
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "create by another user")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "POST", "/api/v3/attachments", payload), user)
+		assert.StatusCodeEquals(t, res, http.StatusCreated, "create")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "GET", "/api/v3/attachments", ""), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "index")
+
+		var resp GetAttachmentsResponse
+		if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		assert.Equal(t, len(resp.Attachments), 1, "length mismatch")
+		assert.Equal(t, resp.Attachments[0].UUID, "8c0d6f7f-0ae6-4f7a-8bb8-2d7a9b3f6a11", "UUID mismatch")
+		assert.Equal(t, resp.Attachments[0].NoteUUID, n1.UUID, "NoteUUID mismatch")
+		assert.Equal(t, resp.Attachments[0].Name, "foo.txt", "Name mismatch")
+		assert.Equal(t, resp.Attachments[0].Hash, hash, "Hash mismatch")
+		assert.Equal(t, resp.Attachments[0].Size, int64(3), "Size mismatch")
+
+		res = testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "GET", "/api/v3/attachments", ""), anotherUser)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "index by another user")
+
+		var anotherResp GetAttachmentsResponse
+		if err := json.NewDecoder(res.Body).Decode(&anotherResp); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+		assert.Equal(t, len(anotherResp.Attachments), 0, "length mismatch for another user")
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/dnote/dnote/pkg/server/presenters"
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
 
@@ -75,6 +76,7 @@ func (b *Books) getBooks(r *http.Request) ([]database.Book, error) {
 	if err := conn.Find(&books).Error; err != nil {
 		return []database.Book{}, nil
 	}
+	app.SortBooks(books)
 
 	return books, nil
 }
@@ -123,6 +125,8 @@ func (b *Books) V3Show(w http.ResponseWriter, r *http.Request) {
 
 type createBookPayload struct {
 	Name string `schema:"name" json:"name"`
+	// Encrypted tells if the name is encrypted by the client
+	Encrypted bool `schema:"encrypted" json:"encrypted"`
 }
 
 func validateCreateBookPayload(p createBookPayload) error {
@@ -159,7 +163,7 @@ func (b *Books) create(r *http.Request) (database.Book, error) {
 		return database.Book{}, app.ErrDuplicateBook
 	}
 
-	book, err := b.app.CreateBook(*user, params.Name)
+	book, err := b.app.CreateBook(*user, params.Name, params.Encrypted)
 	if err != nil {
 		return database.Book{}, errors.Wrap(err, "inserting a book")
 	}
@@ -188,6 +192,8 @@ func (b *Books) V3Create(w http.ResponseWriter, r *http.Request) {
 
 type updateBookPayload struct {
 	Name *string `schema:"name" json:"name"`
+	// Encrypted tells if the name is encrypted by the client
+	Encrypted bool `schema:"encrypted" json:"encrypted"`
 }
 
 // UpdateBookResp is the response from create book api
@@ -220,7 +226,7 @@ func (b *Books) update(r *http.Request) (database.Book, error) {
 		return database.Book{}, errors.Wrap(err, "decoding payload")
 	}
 
-	book, err := b.app.UpdateBook(tx, *user, book, params.Name)
+	book, err := b.app.UpdateBook(tx, *user, book, params.Name, params.Encrypted)
 	if err != nil {
 		tx.Rollback()
 		return database.Book{}, errors.Wrap(err, "updating a book")
@@ -308,6 +314,94 @@ func (b *Books) V3Delete(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, resp)
 }
 
+type mergeBookPayload struct {
+	TargetBookUUID string `schema:"target_book_uuid" json:"target_book_uuid"`
+}
+
+// mergeBookResp is the response from merge book api
+type mergeBookResp struct {
+	Status int             `json:"status"`
+	Book   presenters.Book `json:"book"`
+}
+
+func findUserBook(tx *gorm.DB, userID int, uuid string) (database.Book, error) {
+	var book database.Book
+	conn := tx.Where("user_id = ? AND uuid = ? AND NOT deleted", userID, uuid).First(&book)
+	if conn.RecordNotFound() {
+		return book, app.ErrNotFound
+	}
+	if err := conn.Error; err != nil {
+		return book, errors.Wrap(err, "finding a book")
+	}
+
+	return book, nil
+}
+
+func (b *Books) merge(r *http.Request) (database.Book, error) {
+	user := context.User(r.Context())
+	if user == nil {
+		return database.Book{}, app.ErrLoginRequired
+	}
+
+	vars := mux.Vars(r)
+	uuid := vars["bookUUID"]
+
+	if !helpers.ValidateUUID(uuid) {
+		return database.Book{}, app.ErrInvalidUUID
+	}
+
+	var params mergeBookPayload
+	if err := parseRequestData(r, &params); err != nil {
+		return database.Book{}, errors.Wrap(err, "decoding payload")
+	}
+	if params.TargetBookUUID == "" {
+		return database.Book{}, app.ErrBookUUIDRequired
+	}
+	if !helpers.ValidateUUID(params.TargetBookUUID) {
+		return database.Book{}, app.ErrInvalidUUID
+	}
+
+	tx := b.app.DB.Begin()
+
+	src, err := findUserBook(tx, user.ID, uuid)
+	if err != nil {
+		tx.Rollback()
+		return database.Book{}, errors.Wrap(err, "finding the source book")
+	}
+	dst, err := findUserBook(tx, user.ID, params.TargetBookUUID)
+	if err != nil {
+		tx.Rollback()
+		return database.Book{}, errors.Wrap(err, "finding the target book")
+	}
+
+	book, err := b.app.MergeBook(tx, *user, src, dst)
+	if err != nil {
+		tx.Rollback()
+		return database.Book{}, errors.Wrap(err, "merging the book")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return database.Book{}, errors.Wrap(err, "committing a transaction")
+	}
+
+	return book, nil
+}
+
+// V3Merge moves all notes in a book to the target book and deletes the book
+func (b *Books) V3Merge(w http.ResponseWriter, r *http.Request) {
+	book, err := b.merge(r)
+	if err != nil {
+		handleJSONError(w, err, "merging a book")
+		return
+	}
+
+	resp := mergeBookResp{
+		Status: http.StatusOK,
+		Book:   presenters.PresentBook(book),
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
 // IndexOptions is a handler for OPTIONS endpoint for notes
 func (b *Books) IndexOptions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
@@ -104,6 +104,7 @@ func TestGetBooks(t *testing.T) {
 			CreatedAt: b2Record.CreatedAt,
 			UpdatedAt: b2Record.UpdatedAt,
 			Label:     b2Record.Label,
+			Name:      b2Record.Label,
 			USN:       b2Record.USN,
 		},
 		{
@@ -111,6 +112,7 @@ func TestGetBooks(t *testing.T) {
 			CreatedAt: b1Record.CreatedAt,
 			UpdatedAt: b1Record.UpdatedAt,
 			Label:     b1Record.Label,
+			Name:      b1Record.Label,
 			USN:       b1Record.USN,
 		},
 	}
@@ -118,6 +120,60 @@ func TestGetBooks(t *testing.T) {
 	assert.DeepEqual(t, payload, expected, "payload mismatch")
 }
 
+func TestGetBooksNested(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock:  clock.NewMock(),
+		Config: config.Config{},
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+
+	for _, label := range []string{"work-old", "work/infra/k8s", "work"} {
+		b := database.Book{
+			UserID: user.ID,
+			Label:  label,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b), "preparing a book")
+	}
+
+	// Execute
+	req := testutils.MakeReq(server.URL, "GET", "/api/v3/books", "")
+	res := testutils.HTTPAuthDo(t, req, user)
+
+	// Test
+	assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+	var payload []presenters.Book
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding payload"))
+	}
+
+	type bookPath struct {
+		Label  string
+		Name   string
+		Parent string
+		Depth  int
+	}
+
+	got := []bookPath{}
+	for _, b := range payload {
+		got = append(got, bookPath{Label: b.Label, Name: b.Name, Parent: b.Parent, Depth: b.Depth})
+	}
+
+	expected := []bookPath{
+		{Label: "work", Name: "work", Parent: "", Depth: 0},
+		{Label: "work/infra/k8s", Name: "k8s", Parent: "work/infra", Depth: 2},
+		{Label: "work-old", Name: "work-old", Parent: "", Depth: 0},
+	}
+
+	assert.DeepEqual(t, got, expected, "payload mismatch")
+}
+
 func TestGetBooksByName(t *testing.T) {
 	defer testutils.ClearData(testutils.DB)
 
@@ -172,6 +228,7 @@ func TestGetBooksByName(t *testing.T) {
 			CreatedAt: b1Record.CreatedAt,
 			UpdatedAt: b1Record.UpdatedAt,
 			Label:     b1Record.Label,
+			Name:      b1Record.Label,
 			USN:       b1Record.USN,
 		},
 	}
@@ -231,6 +288,7 @@ func TestGetBook(t *testing.T) {
 		CreatedAt: b1Record.CreatedAt,
 		UpdatedAt: b1Record.UpdatedAt,
 		Label:     b1Record.Label,
+		Name:      b1Record.Label,
 		USN:       b1Record.USN,
 	}
 
@@ -326,6 +384,7 @@ func TestCreateBook(t *testing.T) {
 				CreatedAt: bookRecord.CreatedAt,
 				UpdatedAt: bookRecord.UpdatedAt,
 				Label:     "js",
+				Name:      "js",
 			},
 		}
 
@@ -655,3 +714,140 @@ func TestDeleteBook(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeBook(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock:  clock.NewMock(),
+			Config: config.Config{},
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+		testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+		testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 58), "preparing user max_usn")
+
+		b1 := database.Book{
+			UserID: user.ID,
+			Label:  "js",
+			USN:    1,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing a book data")
+		b2 := database.Book{
+			UserID: user.ID,
+			Label:  "javascript",
+			USN:    2,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b2), "preparing a book data")
+
+		n1 := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     "n1 content",
+			USN:      3,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n1), "preparing a note data")
+		n2 := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     "",
+			USN:      4,
+			Deleted:  true,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n2), "preparing a note data")
+		n3 := database.Note{
+			UserID:   user.ID,
+			BookUUID: b2.UUID,
+			Body:     "n3 content",
+			USN:      5,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n3), "preparing a note data")
+
+		// Execute
+		endpoint := fmt.Sprintf("/api/v3/books/%s/merge", b1.UUID)
+		payload := fmt.Sprintf(`{"target_book_uuid": "%s"}`, b2.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, payload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		var b1Record, b2Record database.Book
+		var n1Record, n2Record, n3Record database.Note
+		var userRecord database.User
+
+		testutils.MustExec(t, testutils.DB.Where("id = ?", b1.ID).First(&b1Record), "finding b1")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", b2.ID).First(&b2Record), "finding b2")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", n1.ID).First(&n1Record), "finding n1")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", n2.ID).First(&n2Record), "finding n2")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", n3.ID).First(&n3Record), "finding n3")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user record")
+
+		assert.Equal(t, userRecord.MaxUSN, 60, "user max_usn mismatch")
+
+		assert.Equal(t, b1Record.Deleted, true, "b1 deleted mismatch")
+		assert.Equal(t, b1Record.Label, "", "b1 label mismatch")
+		assert.Equal(t, b1Record.USN, 60, "b1 usn mismatch")
+		assert.Equal(t, b2Record.Deleted, false, "b2 deleted mismatch")
+		assert.Equal(t, b2Record.Label, b2.Label, "b2 label mismatch")
+		assert.Equal(t, b2Record.USN, b2.USN, "b2 usn mismatch")
+
+		assert.Equal(t, n1Record.BookUUID, b2.UUID, "n1 book_uuid mismatch")
+		assert.Equal(t, n1Record.Body, n1.Body, "n1 content mismatch")
+		assert.Equal(t, n1Record.USN, 59, "n1 usn mismatch")
+		assert.Equal(t, n2Record.BookUUID, b1.UUID, "n2 book_uuid mismatch")
+		assert.Equal(t, n2Record.USN, n2.USN, "n2 usn mismatch")
+		assert.Equal(t, n3Record.BookUUID, b2.UUID, "n3 book_uuid mismatch")
+		assert.Equal(t, n3Record.USN, n3.USN, "n3 usn mismatch")
+	})
+
+	t.Run("target book of another user", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock:  clock.NewMock(),
+			Config: config.Config{},
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+		testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+		testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 58), "preparing user max_usn")
+		anotherUser := testutils.SetupUserData()
+		testutils.SetupAccountData(anotherUser, "bob@test.com", "pass1234")
+
+		b1 := database.Book{
+			UserID: user.ID,
+			Label:  "js",
+			USN:    1,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing a book data")
+		b2 := database.Book{
+			UserID: anotherUser.ID,
+			Label:  "javascript",
+			USN:    2,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b2), "preparing a book data")
+
+		// Execute
+		endpoint := fmt.Sprintf("/api/v3/books/%s/merge", b1.UUID)
+		payload := fmt.Sprintf(`{"target_book_uuid": "%s"}`, b2.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, payload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+
+		var b1Record database.Book
+		var userRecord database.User
+		testutils.MustExec(t, testutils.DB.Where("id = ?", b1.ID).First(&b1Record), "finding b1")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user record")
+
+		assert.Equal(t, b1Record.Deleted, false, "b1 deleted mismatch")
+		assert.Equal(t, userRecord.MaxUSN, 58, "user max_usn mismatch")
+	})
+}
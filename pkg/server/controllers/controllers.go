@@ -25,12 +25,14 @@ import (
 
 // Controllers is a group of controllers
 type Controllers struct {
-	Users  *Users
-	Notes  *Notes
-	Books  *Books
-	Sync   *Sync
-	Static *Static
-	Health *Health
+	Users       *Users
+	Notes       *Notes
+	Books       *Books
+	Attachments *Attachments
+	Encryption  *Encryption
+	Sync        *Sync
+	Static      *Static
+	Health      *Health
 }
 
 // New returns a new group of controllers
@@ -42,6 +44,8 @@ func New(app *app.App) *Controllers {
 	c.Users = NewUsers(app, viewEngine)
 	c.Notes = NewNotes(app)
 	c.Books = NewBooks(app)
+	c.Attachments = NewAttachments(app)
+	c.Encryption = NewEncryption(app)
 	c.Sync = NewSync(app)
 	c.Static = NewStatic(app, viewEngine)
 	c.Health = NewHealth(app)
@@ -0,0 +1,97 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/context"
+	"github.com/dnote/dnote/pkg/server/presenters"
+	"github.com/pkg/errors"
+)
+
+// NewEncryption creates a new Encryption controller.
+func NewEncryption(app *app.App) *Encryption {
+	return &Encryption{
+		app: app,
+	}
+}
+
+// Encryption is a controller for the end-to-end encryption settings of the
+// accounts. The server only stores the cipher key encrypted by the clients.
+type Encryption struct {
+	app *app.App
+}
+
+// V3Show gets the end-to-end encryption settings of the account of the user
+func (e *Encryption) V3Show(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "getting encryption settings")
+		return
+	}
+
+	account, err := e.app.GetAccount(*user)
+	if err != nil {
+		handleJSONError(w, err, "getting encryption settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, presenters.PresentEncryption(account))
+}
+
+type updateEncryptionPayload struct {
+	Password     string `schema:"password" json:"password"`
+	Salt         string `schema:"salt" json:"salt"`
+	Iteration    int    `schema:"iteration" json:"iteration"`
+	CipherKeyEnc string `schema:"cipher_key_enc" json:"cipher_key_enc"`
+	KeyCheck     string `schema:"key_check" json:"key_check"`
+}
+
+// V3Update sets up the end-to-end encryption of the account of the user, or
+// stores its cipher key encrypted with a new passphrase. The password in the
+// payload is the password of the account, and authorizes the change.
+func (e *Encryption) V3Update(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	if user == nil {
+		handleJSONError(w, app.ErrLoginRequired, "updating encryption settings")
+		return
+	}
+
+	var params updateEncryptionPayload
+	if err := parseRequestData(r, &params); err != nil {
+		handleJSONError(w, errors.Wrap(err, "parsing request payload"), "updating encryption settings")
+		return
+	}
+
+	account, err := e.app.UpdateEncryption(*user, app.EncryptionParams{
+		Password:     params.Password,
+		Salt:         params.Salt,
+		Iteration:    params.Iteration,
+		CipherKeyEnc: params.CipherKeyEnc,
+		KeyCheck:     params.KeyCheck,
+	})
+	if err != nil {
+		handleJSONError(w, err, "updating encryption settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, presenters.PresentEncryption(account))
+}
@@ -0,0 +1,91 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/config"
+	"github.com/dnote/dnote/pkg/server/presenters"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func TestEncryption(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock:  clock.NewMock(),
+		Config: config.Config{},
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+
+	getEncryption := func(t *testing.T) presenters.Encryption {
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "GET", "/api/v3/encryption", ""), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "get")
+
+		var ret presenters.Encryption
+		if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		return ret
+	}
+
+	t.Run("not set up", func(t *testing.T) {
+		got := getEncryption(t)
+		assert.Equal(t, got.Enabled, false, "Enabled mismatch")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		payload := `{"password": "wrong1234", "salt": "salt1", "iteration": 100000, "cipher_key_enc": "cipherKeyEnc1", "key_check": "keyCheck1"}`
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "PUT", "/api/v3/encryption", payload), user)
+		assert.StatusCodeEquals(t, res, http.StatusUnauthorized, "put")
+	})
+
+	t.Run("set up", func(t *testing.T) {
+		payload := `{"password": "pass1234", "salt": "salt1", "iteration": 100000, "cipher_key_enc": "cipherKeyEnc1", "key_check": "keyCheck1"}`
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "PUT", "/api/v3/encryption", payload), user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "put")
+
+		got := getEncryption(t)
+		assert.Equal(t, got, presenters.Encryption{
+			Enabled:      true,
+			Salt:         "salt1",
+			Iteration:    100000,
+			CipherKeyEnc: "cipherKeyEnc1",
+			KeyCheck:     "keyCheck1",
+		}, "settings mismatch")
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		payload := `{"password": "pass1234", "salt": "salt2", "iteration": 100000, "cipher_key_enc": "cipherKeyEnc2", "key_check": "keyCheck2"}`
+		res := testutils.HTTPAuthDo(t, testutils.MakeReq(server.URL, "PUT", "/api/v3/encryption", payload), user)
+		assert.StatusCodeEquals(t, res, http.StatusConflict, "put")
+	})
+}
@@ -223,6 +223,8 @@ func getStatusCode(err error) int {
 		return http.StatusBadRequest
 	case app.ErrEmptyUpdate:
 		return http.StatusBadRequest
+	case app.ErrInvalidTag:
+		return http.StatusBadRequest
 	case app.ErrInvalidUUID:
 		return http.StatusBadRequest
 	case app.ErrDuplicateBook:
@@ -245,6 +247,14 @@ func getStatusCode(err error) int {
 		return http.StatusBadRequest
 	case app.ErrExpiredToken:
 		return http.StatusGone
+	case app.ErrInvalidHash, app.ErrBlobHashMismatch, app.ErrBlobMissing, app.ErrAttachmentNameRequired:
+		return http.StatusBadRequest
+	case app.ErrAttachmentTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case app.ErrInvalidEncryption:
+		return http.StatusBadRequest
+	case app.ErrEncryptionKeyMismatch:
+		return http.StatusConflict
 	}
 
 	return http.StatusInternalServerError
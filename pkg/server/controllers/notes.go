@@ -72,6 +72,17 @@ func parsePageQuery(q url.Values) (int, error) {
 	return p, err
 }
 
+// parseTagsQuery parses the tags in the query. The tags can be given either
+// as repeated parameters or separated by commas, e.g. tags=a&tags=b or tags=a,b
+func parseTagsQuery(q url.Values) ([]string, error) {
+	var tags []string
+	for _, v := range q["tags"] {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+
+	return app.NormalizeTags(tags)
+}
+
 func parseGetNotesQuery(q url.Values) (app.GetNotesParams, error) {
 	yearStr := q.Get("year")
 	monthStr := q.Get("month")
@@ -110,6 +121,11 @@ func parseGetNotesQuery(q url.Values) (app.GetNotesParams, error) {
 		month = m
 	}
 
+	tags, err := parseTagsQuery(q)
+	if err != nil {
+		return app.GetNotesParams{}, errors.Wrap(err, "invalid tags")
+	}
+
 	var encrypted bool
 	if strings.ToLower(encryptedStr) == "true" {
 		encrypted = true
@@ -125,6 +141,7 @@ func parseGetNotesQuery(q url.Values) (app.GetNotesParams, error) {
 		Books:     books,
 		Encrypted: encrypted,
 		PerPage:   notesPerPage,
+		Tags:      tags,
 	}
 
 	return ret, nil
@@ -261,11 +278,54 @@ func (n *Notes) V3Show(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, presenters.PresentNote(note))
 }
 
+// GetBacklinksResponse is a response for getting the backlinks to a note
+type GetBacklinksResponse struct {
+	Notes []presenters.Note `json:"notes"`
+}
+
+func (n *Notes) getBacklinks(r *http.Request) ([]database.Note, error) {
+	user := context.User(r.Context())
+	if user == nil {
+		return nil, app.ErrLoginRequired
+	}
+
+	note, err := n.getNote(r)
+	if err != nil {
+		return nil, err
+	}
+	if note.UserID != user.ID {
+		return nil, app.ErrNotFound
+	}
+
+	notes, err := n.app.GetBacklinks(*user, note)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting backlinks")
+	}
+
+	return notes, nil
+}
+
+// V3Backlinks gets the notes that link to a note
+func (n *Notes) V3Backlinks(w http.ResponseWriter, r *http.Request) {
+	notes, err := n.getBacklinks(r)
+	if err != nil {
+		handleJSONError(w, err, "getting backlinks")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, GetBacklinksResponse{
+		Notes: presenters.PresentNotes(notes),
+	})
+}
+
 type createNotePayload struct {
-	BookUUID string `schema:"book_uuid" json:"book_uuid"`
-	Content  string `schema:"content" json:"content"`
-	AddedOn  *int64 `schema:"added_on" json:"added_on"`
-	EditedOn *int64 `schema:"edited_on" json:"edited_on"`
+	BookUUID string   `schema:"book_uuid" json:"book_uuid"`
+	Content  string   `schema:"content" json:"content"`
+	AddedOn  *int64   `schema:"added_on" json:"added_on"`
+	EditedOn *int64   `schema:"edited_on" json:"edited_on"`
+	Tags     []string `schema:"tags" json:"tags"`
+	// Encrypted tells if the content is encrypted by the client
+	Encrypted bool `schema:"encrypted" json:"encrypted"`
 }
 
 func validateCreateNotePayload(p createNotePayload) error {
@@ -297,7 +357,7 @@ func (n *Notes) create(r *http.Request) (database.Note, error) {
 	}
 
 	client := getClientType(r)
-	note, err := n.app.CreateNote(*user, params.BookUUID, params.Content, params.AddedOn, params.EditedOn, false, client)
+	note, err := n.app.CreateNote(*user, params.BookUUID, params.Content, params.AddedOn, params.EditedOn, false, params.Encrypted, params.Tags, client)
 	if err != nil {
 		return database.Note{}, errors.Wrap(err, "creating note")
 	}
@@ -374,13 +434,16 @@ func (n *Notes) V3Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 type updateNotePayload struct {
-	BookUUID *string `schema:"book_uuid" json:"book_uuid"`
-	Content  *string `schema:"content" json:"content"`
-	Public   *bool   `schema:"public" json:"public"`
+	BookUUID *string   `schema:"book_uuid" json:"book_uuid"`
+	Content  *string   `schema:"content" json:"content"`
+	Public   *bool     `schema:"public" json:"public"`
+	Tags     *[]string `schema:"tags" json:"tags"`
+	// Encrypted tells if the content is encrypted by the client
+	Encrypted *bool `schema:"encrypted" json:"encrypted"`
 }
 
 func validateUpdateNotePayload(p updateNotePayload) error {
-	if p.BookUUID == nil && p.Content == nil && p.Public == nil {
+	if p.BookUUID == nil && p.Content == nil && p.Public == nil && p.Tags == nil {
 		return app.ErrEmptyUpdate
 	}
 
@@ -414,9 +477,11 @@ func (n *Notes) update(r *http.Request) (database.Note, error) {
 	tx := n.app.DB.Begin()
 
 	note, err = n.app.UpdateNote(tx, *user, note, &app.UpdateNoteParams{
-		BookUUID: params.BookUUID,
-		Content:  params.Content,
-		Public:   params.Public,
+		BookUUID:  params.BookUUID,
+		Content:   params.Content,
+		Public:    params.Public,
+		Tags:      params.Tags,
+		Encrypted: params.Encrypted,
 	})
 	if err != nil {
 		tx.Rollback()
@@ -33,6 +33,7 @@ import (
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/presenters"
 	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
@@ -52,6 +53,7 @@ func getExpectedNotePayload(n database.Note, b database.Book, u database.User) p
 		User: presenters.NoteUser{
 			UUID: u.UUID,
 		},
+		Tags: presenters.PresentTags(n.Tags),
 	}
 }
 
@@ -170,6 +172,74 @@ func TestGetNotes(t *testing.T) {
 	assert.DeepEqual(t, payload, expected, "payload mismatch")
 }
 
+func TestGetNotes_tags(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock:  clock.NewMock(),
+		Config: config.Config{},
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	n1 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n1 content",
+		USN:      11,
+		Tags:     pq.StringArray{"es6", "types"},
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n2 content",
+		USN:      12,
+		Tags:     pq.StringArray{"es6"},
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n3 content",
+		USN:      13,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+
+	// Execute
+	req := testutils.MakeReq(server.URL, "GET", "/api/v3/notes?tags=ES6,types", "")
+	res := testutils.HTTPAuthDo(t, req, user)
+
+	// Test
+	assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+	var payload GetNotesResponse
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding payload"))
+	}
+
+	var n1Record database.Note
+	testutils.MustExec(t, testutils.DB.Where("uuid = ?", n1.UUID).First(&n1Record), "finding n1Record")
+
+	expected := GetNotesResponse{
+		Notes: []presenters.Note{
+			getExpectedNotePayload(n1Record, b1, user),
+		},
+		Total: 1,
+	}
+
+	assert.DeepEqual(t, payload, expected, "payload mismatch")
+}
+
 func TestGetNote(t *testing.T) {
 	defer testutils.ClearData(testutils.DB)
 
@@ -758,3 +828,82 @@ func TestUpdateNote(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBacklinks(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock:  clock.NewMock(),
+		Config: config.Config{},
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	testutils.SetupAccountData(user, "alice@test.com", "pass1234")
+	anotherUser := testutils.SetupUserData()
+	testutils.SetupAccountData(anotherUser, "bob@test.com", "pass1234")
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	n1 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "Closures",
+		USN:      1,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "see [[js/closures]]",
+		USN:      2,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	l1 := database.NoteLink{
+		UserID:   user.ID,
+		NoteUUID: n2.UUID,
+		Target:   "js/closures",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&l1), "preparing l1")
+
+	t.Run("owner", func(t *testing.T) {
+		// Execute
+		endpoint := fmt.Sprintf("/api/v3/notes/%s/backlinks", n1.UUID)
+		req := testutils.MakeReq(server.URL, "GET", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		var payload GetBacklinksResponse
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		var n2Record database.Note
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", n2.UUID).First(&n2Record), "finding n2Record")
+
+		expected := GetBacklinksResponse{
+			Notes: []presenters.Note{
+				getExpectedNotePayload(n2Record, b1, user),
+			},
+		}
+
+		assert.DeepEqual(t, payload, expected, "payload mismatch")
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		// Execute
+		endpoint := fmt.Sprintf("/api/v3/notes/%s/backlinks", n1.UUID)
+		req := testutils.MakeReq(server.URL, "GET", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, anotherUser)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+	})
+}
@@ -88,6 +88,7 @@ func NewAPIRoutes(a *app.App, c *Controllers) []Route {
 		{"OPTIONS", "/v3/signout", mw.Cors(c.Users.logoutOptions), true},
 		{"GET", "/v3/notes", mw.Cors(mw.Auth(a, c.Notes.V3Index, nil)), true},
 		{"GET", "/v3/notes/{noteUUID}", c.Notes.V3Show, true},
+		{"GET", "/v3/notes/{noteUUID}/backlinks", mw.Cors(mw.Auth(a, c.Notes.V3Backlinks, nil)), true},
 		{"POST", "/v3/notes", mw.Cors(mw.Auth(a, c.Notes.V3Create, nil)), true},
 		{"DELETE", "/v3/notes/{noteUUID}", mw.Cors(mw.Auth(a, c.Notes.V3Delete, nil)), true},
 		{"PATCH", "/v3/notes/{noteUUID}", mw.Cors(mw.Auth(a, c.Notes.V3Update, nil)), true},
@@ -97,7 +98,15 @@ func NewAPIRoutes(a *app.App, c *Controllers) []Route {
 		{"POST", "/v3/books", mw.Cors(mw.Auth(a, c.Books.V3Create, nil)), true},
 		{"PATCH", "/v3/books/{bookUUID}", mw.Cors(mw.Auth(a, c.Books.V3Update, nil)), true},
 		{"DELETE", "/v3/books/{bookUUID}", mw.Cors(mw.Auth(a, c.Books.V3Delete, nil)), true},
+		{"POST", "/v3/books/{bookUUID}/merge", mw.Cors(mw.Auth(a, c.Books.V3Merge, nil)), true},
 		{"OPTIONS", "/v3/books", mw.Cors(c.Books.IndexOptions), true},
+		{"GET", "/v3/attachments", mw.Cors(mw.Auth(a, c.Attachments.V3Index, nil)), true},
+		{"POST", "/v3/attachments", mw.Cors(mw.Auth(a, c.Attachments.V3Create, nil)), true},
+		{"HEAD", "/v3/blobs/{hash}", mw.Cors(mw.Auth(a, c.Attachments.V3HeadBlob, nil)), true},
+		{"GET", "/v3/blobs/{hash}", mw.Cors(mw.Auth(a, c.Attachments.V3GetBlob, nil)), true},
+		{"PUT", "/v3/blobs/{hash}", mw.Cors(mw.Auth(a, c.Attachments.V3PutBlob, nil)), true},
+		{"GET", "/v3/encryption", mw.Cors(mw.Auth(a, c.Encryption.V3Show, nil)), true},
+		{"PUT", "/v3/encryption", mw.Cors(mw.Auth(a, c.Encryption.V3Update, nil)), true},
 	}
 }
 
@@ -30,6 +30,7 @@ import (
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/log"
 	"github.com/dnote/dnote/pkg/server/middleware"
+	"github.com/dnote/dnote/pkg/server/presenters"
 	"github.com/pkg/errors"
 
 	"github.com/dnote/dnote/pkg/server/app"
@@ -77,6 +78,8 @@ type SyncFragNote struct {
 	Body      string    `json:"content"`
 	Public    bool      `json:"public"`
 	Deleted   bool      `json:"deleted"`
+	Encrypted bool      `json:"encrypted"`
+	Tags      []string  `json:"tags"`
 }
 
 // NewFragNote presents the given note as a SyncFragNote
@@ -91,7 +94,9 @@ func NewFragNote(note database.Note) SyncFragNote {
 		Body:      note.Body,
 		Public:    note.Public,
 		Deleted:   note.Deleted,
+		Encrypted: note.Encrypted,
 		BookUUID:  note.BookUUID,
+		Tags:      presenters.PresentTags(note.Tags),
 	}
 }
 
@@ -105,6 +110,7 @@ type SyncFragBook struct {
 	AddedOn   int64     `json:"added_on"`
 	Label     string    `json:"label"`
 	Deleted   bool      `json:"deleted"`
+	Encrypted bool      `json:"encrypted"`
 }
 
 // NewFragBook presents the given book as a SyncFragBook
@@ -117,6 +123,7 @@ func NewFragBook(book database.Book) SyncFragBook {
 		AddedOn:   book.AddedOn,
 		Label:     book.Label,
 		Deleted:   book.Deleted,
+		Encrypted: book.Encrypted,
 	}
 }
 
@@ -47,6 +47,8 @@ func InitSchema(db *gorm.DB) {
 		Token{},
 		EmailPreference{},
 		Session{},
+		NoteLink{},
+		Attachment{},
 	).Error; err != nil {
 		panic(err)
 	}
@@ -0,0 +1,11 @@
+-- add-tags-to-notes.sql adds the tags column to notes and indexes it for filtering.
+
+-- +migrate Up
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS tags text[];
+CREATE INDEX IF NOT EXISTS idx_notes_tags ON notes USING GIN (tags);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notes_tags;
+ALTER TABLE notes DROP COLUMN IF EXISTS tags;
@@ -20,6 +20,8 @@ package database
 
 import (
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Model is the base model definition
@@ -46,20 +48,44 @@ type Book struct {
 // Note is a model for a note
 type Note struct {
 	Model
-	UUID      string `json:"uuid" gorm:"index;type:uuid;default:uuid_generate_v4()"`
-	Book      Book   `json:"book" gorm:"foreignkey:BookUUID"`
-	User      User   `json:"user"`
-	UserID    int    `json:"user_id" gorm:"index"`
-	BookUUID  string `json:"book_uuid" gorm:"index;type:uuid"`
-	Body      string `json:"content"`
-	AddedOn   int64  `json:"added_on"`
-	EditedOn  int64  `json:"edited_on"`
-	TSV       string `json:"-" gorm:"type:tsvector"`
-	Public    bool   `json:"public" gorm:"default:false"`
-	USN       int    `json:"-" gorm:"index"`
-	Deleted   bool   `json:"-" gorm:"default:false"`
-	Encrypted bool   `json:"-" gorm:"default:false"`
-	Client    string `gorm:"index"`
+	UUID      string         `json:"uuid" gorm:"index;type:uuid;default:uuid_generate_v4()"`
+	Book      Book           `json:"book" gorm:"foreignkey:BookUUID"`
+	User      User           `json:"user"`
+	UserID    int            `json:"user_id" gorm:"index"`
+	BookUUID  string         `json:"book_uuid" gorm:"index;type:uuid"`
+	Body      string         `json:"content"`
+	AddedOn   int64          `json:"added_on"`
+	EditedOn  int64          `json:"edited_on"`
+	TSV       string         `json:"-" gorm:"type:tsvector"`
+	Public    bool           `json:"public" gorm:"default:false"`
+	USN       int            `json:"-" gorm:"index"`
+	Deleted   bool           `json:"-" gorm:"default:false"`
+	Encrypted bool           `json:"-" gorm:"default:false"`
+	Client    string         `gorm:"index"`
+	Tags      pq.StringArray `json:"tags" gorm:"type:text[]"`
+}
+
+// NoteLink is a wiki-style link from a note to another note. Target is either
+// a prefix of the uuid of the linked note, or its book label and title
+// separated by a slash.
+type NoteLink struct {
+	Model
+	UserID   int    `gorm:"index"`
+	NoteUUID string `gorm:"index;type:uuid"`
+	Target   string
+}
+
+// Attachment is a file attached to a note. Its content is stored in the blob
+// store under the hash of the content.
+type Attachment struct {
+	Model
+	UUID     string `json:"uuid" gorm:"index;type:uuid"`
+	UserID   int    `json:"user_id" gorm:"index"`
+	NoteUUID string `json:"note_uuid" gorm:"index;type:uuid"`
+	Name     string `json:"name"`
+	Hash     string `json:"hash" gorm:"index"`
+	Size     int64  `json:"size"`
+	AddedOn  int64  `json:"added_on"`
 }
 
 // User is a model for a user
@@ -79,6 +105,13 @@ type Account struct {
 	Email         NullString
 	EmailVerified bool `gorm:"default:false"`
 	Password      NullString
+	// The key for the end-to-end encryption is generated by a client and stored
+	// encrypted with a key derived from a passphrase that the server never
+	// receives. KeyCheck lets the clients verify that they hold the same key.
+	EncryptionSalt      string
+	EncryptionIteration int
+	CipherKeyEnc        string
+	KeyCheck            string
 }
 
 // Token is a model for a token
@@ -32,6 +32,7 @@ import (
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/job"
 	"github.com/dnote/dnote/pkg/server/mailer"
+	"github.com/dnote/dnote/pkg/server/storage"
 	"github.com/jinzhu/gorm"
 
 	"github.com/pkg/errors"
@@ -59,6 +60,7 @@ func initApp(cfg config.Config) app.App {
 		EmailBackend:   &mailer.SimpleBackendImplementation{},
 		Config:         cfg,
 		HTTP500Page:    cfg.HTTP500Page,
+		BlobStore:      storage.NewFSBlobStore(cfg.AttachmentsDir),
 	}
 }
 
@@ -87,6 +89,14 @@ func startCmd() {
 	if err := runJob(app); err != nil {
 		panic(errors.Wrap(err, "running job"))
 	}
+	// Index the links of the notes saved before the links were indexed
+	n, err := app.IndexMissingNoteLinks()
+	if err != nil {
+		panic(errors.Wrap(err, "indexing note links"))
+	}
+	if n > 0 {
+		log.Printf("Indexed the links of %d notes", n)
+	}
 
 	ctl := controllers.New(&app)
 	rc := controllers.RouteConfig{
@@ -0,0 +1,60 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package presenters
+
+import (
+	"time"
+
+	"github.com/dnote/dnote/pkg/server/database"
+)
+
+// Attachment is a result of PresentAttachment
+type Attachment struct {
+	UUID      string    `json:"uuid"`
+	NoteUUID  string    `json:"note_uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	AddedOn   int64     `json:"added_on"`
+}
+
+// PresentAttachment presents an attachment
+func PresentAttachment(attachment database.Attachment) Attachment {
+	return Attachment{
+		UUID:      attachment.UUID,
+		NoteUUID:  attachment.NoteUUID,
+		CreatedAt: FormatTS(attachment.CreatedAt),
+		Name:      attachment.Name,
+		Hash:      attachment.Hash,
+		Size:      attachment.Size,
+		AddedOn:   attachment.AddedOn,
+	}
+}
+
+// PresentAttachments presents attachments
+func PresentAttachments(attachments []database.Attachment) []Attachment {
+	ret := []Attachment{}
+
+	for _, attachment := range attachments {
+		ret = append(ret, PresentAttachment(attachment))
+	}
+
+	return ret
+}
@@ -19,6 +19,7 @@
 package presenters
 
 import (
+	"strings"
 	"time"
 
 	"github.com/dnote/dnote/pkg/server/database"
@@ -31,16 +32,34 @@ type Book struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Label     string    `json:"label"`
+	// Name is the last part of the label of a nested book such as 'k8s' in
+	// 'work/infra/k8s'
+	Name string `json:"name"`
+	// Parent is the label of the book that the book is nested in, such as
+	// 'work/infra' in 'work/infra/k8s'. It is empty for a top level book.
+	Parent string `json:"parent"`
+	// Depth is the number of the books that the book is nested in
+	Depth int `json:"depth"`
 }
 
 // PresentBook presents a book
 func PresentBook(book database.Book) Book {
+	var parent string
+	name := book.Label
+	if idx := strings.LastIndex(book.Label, "/"); idx != -1 {
+		parent = book.Label[:idx]
+		name = book.Label[idx+1:]
+	}
+
 	return Book{
 		UUID:      book.UUID,
 		USN:       book.USN,
 		CreatedAt: FormatTS(book.CreatedAt),
 		UpdatedAt: FormatTS(book.UpdatedAt),
 		Label:     book.Label,
+		Name:      name,
+		Parent:    parent,
+		Depth:     strings.Count(book.Label, "/"),
 	}
 }
 
@@ -0,0 +1,43 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package presenters
+
+import (
+	"github.com/dnote/dnote/pkg/server/database"
+)
+
+// Encryption is a result of PresentEncryption
+type Encryption struct {
+	Enabled      bool   `json:"enabled"`
+	Salt         string `json:"salt"`
+	Iteration    int    `json:"iteration"`
+	CipherKeyEnc string `json:"cipher_key_enc"`
+	KeyCheck     string `json:"key_check"`
+}
+
+// PresentEncryption presents the end-to-end encryption settings of an account
+func PresentEncryption(account database.Account) Encryption {
+	return Encryption{
+		Enabled:      account.KeyCheck != "",
+		Salt:         account.EncryptionSalt,
+		Iteration:    account.EncryptionIteration,
+		CipherKeyEnc: account.CipherKeyEnc,
+		KeyCheck:     account.KeyCheck,
+	}
+}
@@ -35,6 +35,7 @@ type Note struct {
 	USN       int       `json:"usn"`
 	Book      NoteBook  `json:"book"`
 	User      NoteUser  `json:"user"`
+	Tags      []string  `json:"tags"`
 }
 
 // NoteBook is a nested book for PresentNotesResult
@@ -66,11 +67,19 @@ func PresentNote(note database.Note) Note {
 		User: NoteUser{
 			UUID: note.User.UUID,
 		},
+		Tags: PresentTags(note.Tags),
 	}
 
 	return ret
 }
 
+// PresentTags presents the tags of a note as a list that is never null
+func PresentTags(tags []string) []string {
+	ret := []string{}
+
+	return append(ret, tags...)
+}
+
 // PresentNotes presents notes
 func PresentNotes(notes []database.Note) []Note {
 	ret := []Note{}
@@ -0,0 +1,154 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package storage provides the storage for the contents of attachments
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBlobNotFound is an error for a blob that does not exist in the store
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores blobs under keys. Keys are slash separated paths that do
+// not contain '.' or '..' elements.
+type BlobStore interface {
+	// Put stores the content of the reader under the key, replacing any
+	// existing blob with the same key
+	Put(key string, r io.Reader) error
+	// Get opens the blob with the key for reading. It returns ErrBlobNotFound
+	// if the blob does not exist.
+	Get(key string) (io.ReadCloser, error)
+	// Has checks if the blob with the key exists
+	Has(key string) (bool, error)
+	// Delete removes the blob with the key. Deleting a blob that does not
+	// exist is not an error.
+	Delete(key string) error
+}
+
+// FSBlobStore is a BlobStore that stores blobs as files in a directory
+type FSBlobStore struct {
+	Dir string
+}
+
+// NewFSBlobStore returns a new FSBlobStore that stores blobs in the given directory
+func NewFSBlobStore(dir string) *FSBlobStore {
+	return &FSBlobStore{Dir: dir}
+}
+
+func (s *FSBlobStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", errors.Errorf("invalid key '%s'", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", errors.Errorf("invalid key '%s'", key)
+		}
+	}
+
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put stores the content of the reader under the key. The content is written
+// to a temporary file first so that a partially written blob is never visible.
+func (s *FSBlobStore) Put(key string, r io.Reader) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "creating the directory")
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "creating a temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing the blob")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing the temporary file")
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return errors.Wrap(err, "moving the blob")
+	}
+
+	return nil
+}
+
+// Get opens the blob with the key for reading
+func (s *FSBlobStore) Get(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "opening the blob")
+	}
+
+	return f, nil
+}
+
+// Has checks if the blob with the key exists
+func (s *FSBlobStore) Has(key string) (bool, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(p)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "checking the blob")
+	}
+
+	return true, nil
+}
+
+// Delete removes the blob with the key
+func (s *FSBlobStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "removing the blob")
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+/* Copyright (C) 2019, 2020, 2021, 2022, 2023, 2024 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestFSBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnote-blobs")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewFSBlobStore(dir)
+	key := "user-uuid/2c/2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	ok, err := s.Has(key)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "checking before put"))
+	}
+	assert.Equal(t, ok, false, "blob should not exist before put")
+
+	_, err = s.Get(key)
+	assert.Equal(t, err, ErrBlobNotFound, "get error mismatch before put")
+
+	if err := s.Put(key, strings.NewReader("foo")); err != nil {
+		t.Fatal(errors.Wrap(err, "putting"))
+	}
+
+	ok, err = s.Has(key)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "checking after put"))
+	}
+	assert.Equal(t, ok, true, "blob should exist after put")
+
+	rc, err := s.Get(key)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting"))
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "reading"))
+	}
+	assert.Equal(t, string(b), "foo", "content mismatch")
+
+	if err := s.Delete(key); err != nil {
+		t.Fatal(errors.Wrap(err, "deleting"))
+	}
+	if err := s.Delete(key); err != nil {
+		t.Fatal(errors.Wrap(err, "deleting a missing blob"))
+	}
+
+	ok, err = s.Has(key)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "checking after delete"))
+	}
+	assert.Equal(t, ok, false, "blob should not exist after delete")
+}
+
+func TestFSBlobStoreInvalidKey(t *testing.T) {
+	s := NewFSBlobStore("/tmp/dnote-blobs")
+
+	testCases := []string{
+		"",
+		"/etc/passwd",
+		"../secret",
+		"user/../../secret",
+		"user//blob",
+		"user/./blob",
+	}
+
+	for _, key := range testCases {
+		t.Run(key, func(t *testing.T) {
+			_, err := s.Has(key)
+			assert.NotEqual(t, err, nil, "error should be returned")
+		})
+	}
+}
@@ -85,6 +85,12 @@ func ClearData(db *gorm.DB) {
 	if err := db.Delete(&database.Session{}).Error; err != nil {
 		panic(errors.Wrap(err, "Failed to clear sessions"))
 	}
+	if err := db.Delete(&database.NoteLink{}).Error; err != nil {
+		panic(errors.Wrap(err, "Failed to clear note links"))
+	}
+	if err := db.Delete(&database.Attachment{}).Error; err != nil {
+		panic(errors.Wrap(err, "Failed to clear attachments"))
+	}
 }
 
 // SetupUserData creates and returns a new user for testing purposes